@@ -0,0 +1,205 @@
+// Command relevance-eval scores a workspace's relevance config against a
+// judgment list, and optionally compares it with a candidate change. It is
+// the command-line counterpart of POST /relevance/evaluate, and can also
+// read judgments from a local file without storing them.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/quckapp/search-service/internal/config"
+	"github.com/quckapp/search-service/internal/db"
+	"github.com/quckapp/search-service/internal/embed"
+	"github.com/quckapp/search-service/internal/models"
+	"github.com/quckapp/search-service/internal/service"
+	"github.com/quckapp/search-service/internal/tenant"
+)
+
+func main() {
+	workspaceID := flag.String("workspace", "", "workspace whose relevance config and documents to evaluate")
+	listID := flag.String("list", "", "ID of a stored judgment list")
+	judgmentsFile := flag.String("judgments", "", "CSV (query,doc_id,grade) or JSON file of judgments, instead of -list")
+	index := flag.String("index", "quckapp_messages", "index the queries of -judgments search")
+	k := flag.Int("k", 10, "how many results of each query to score")
+	mode := flag.String("mode", "lexical", "search mode to run the queries in: lexical, semantic or hybrid")
+	baselineFile := flag.String("baseline", "", "JSON file of changes to the current config to evaluate as the baseline")
+	candidateFile := flag.String("candidate", "", "JSON file of changes to the current config to compare with the baseline")
+	asJSON := flag.Bool("json", false, "print the full report as JSON")
+	connectTimeout := flag.Duration("connect-timeout", 30*time.Second, "how long to wait for Elasticsearch and Redis")
+	flag.Parse()
+
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.JSONFormatter{})
+
+	if *workspaceID == "" || (*listID == "") == (*judgmentsFile == "") {
+		fmt.Fprintln(os.Stderr, "relevance-eval needs -workspace and exactly one of -list or -judgments")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	baseline, err := readChanges(*baselineFile)
+	if err != nil {
+		logger.Fatalf("Failed to read baseline: %v", err)
+	}
+	candidate, err := readChanges(*candidateFile)
+	if err != nil {
+		logger.Fatalf("Failed to read candidate: %v", err)
+	}
+
+	cfg := config.Load()
+	embedder, err := embed.New(cfg.Embedding)
+	if err != nil {
+		logger.Fatalf("Invalid embedding configuration: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	ctx = tenant.WithScope(ctx, tenant.Scope{WorkspaceID: *workspaceID})
+
+	esClient := db.NewElasticsearchManager(cfg.ElasticsearchURL, logger)
+	esClient.Start(ctx)
+	defer esClient.Close()
+
+	redisClient := db.NewRedisManager(cfg.RedisHost, cfg.RedisPort, cfg.RedisPassword, logger)
+	redisClient.Start(ctx)
+	defer redisClient.Close()
+
+	if !waitConnected(ctx, *connectTimeout, esClient.State, redisClient.State) {
+		logger.Fatal("Elasticsearch and Redis must both be reachable to evaluate relevance")
+	}
+
+	tenants := service.NewTenantRoutingService(esClient, redisClient, logger)
+	relevance := service.NewRelevanceService(esClient, redisClient, tenants, logger)
+	deadLetters := service.NewDeadLetterService(esClient, redisClient, tenants, cfg.DeadLetter, logger)
+	clicks := service.NewClickService(redisClient, cfg.Clicks, logger)
+	search := service.NewSearchService(esClient, redisClient, tenants, deadLetters, service.NewEmbeddingService(embedder, logger), relevance, clicks, logger)
+	feedback := service.NewFeedbackService(esClient, redisClient, logger)
+	evaluation := service.NewEvaluationService(redisClient, search, relevance, feedback, logger)
+
+	var list *models.JudgmentList
+	if *listID != "" {
+		list, err = evaluation.GetList(ctx, *workspaceID, *listID)
+		if err != nil {
+			logger.Fatalf("Failed to load judgment list: %v", err)
+		}
+	} else {
+		data, err := os.ReadFile(*judgmentsFile)
+		if err != nil {
+			logger.Fatalf("Failed to read judgments: %v", err)
+		}
+		queries, err := service.ParseJudgments(*judgmentsFile, data)
+		if err != nil {
+			logger.Fatalf("Failed to parse judgments: %v", err)
+		}
+		list = &models.JudgmentList{Name: *judgmentsFile, Index: *index, Source: "file", Queries: queries}
+	}
+
+	report, err := evaluation.EvaluateList(ctx, *workspaceID, list, *k, *mode, baseline, candidate)
+	if err != nil {
+		logger.Fatalf("Evaluation failed: %v", err)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(report)
+		return
+	}
+	printReport(report)
+}
+
+// readChanges reads a JSON file of relevance config changes; no file means
+// no changes.
+func readChanges(path string) (*models.UpdateRelevanceRequest, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var changes models.UpdateRelevanceRequest
+	if err := json.Unmarshal(data, &changes); err != nil {
+		return nil, err
+	}
+	return &changes, nil
+}
+
+// printReport writes each query's nDCG and then the averaged metrics, side
+// by side when a candidate was evaluated.
+func printReport(report *models.EvaluationReport) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	k := report.K
+
+	if report.Candidate != nil {
+		fmt.Fprintf(w, "QUERY\tRELEVANT\tBASELINE nDCG@%d\tCANDIDATE nDCG@%d\tDELTA\n", k, k)
+		for _, q := range report.Queries {
+			fmt.Fprintf(w, "%s\t%d\t%.4f\t%.4f\t%+.4f\n", q.Query, q.Relevant, q.Baseline.NDCG, q.Candidate.NDCG, q.NDCGDelta)
+		}
+	} else {
+		fmt.Fprintf(w, "QUERY\tRELEVANT\tnDCG@%d\n", k)
+		for _, q := range report.Queries {
+			fmt.Fprintf(w, "%s\t%d\t%.4f\n", q.Query, q.Relevant, q.Baseline.NDCG)
+		}
+	}
+	fmt.Fprintln(w)
+
+	base := report.Baseline.Metrics
+	if report.Candidate != nil {
+		cand := report.Candidate.Metrics
+		fmt.Fprintf(w, "METRIC\tBASELINE\tCANDIDATE\tDELTA\n")
+		fmt.Fprintf(w, "nDCG@%d\t%.4f\t%.4f\t%+.4f\n", k, base.NDCG, cand.NDCG, cand.NDCG-base.NDCG)
+		fmt.Fprintf(w, "MRR@%d\t%.4f\t%.4f\t%+.4f\n", k, base.MRR, cand.MRR, cand.MRR-base.MRR)
+		fmt.Fprintf(w, "P@%d\t%.4f\t%.4f\t%+.4f\n", k, base.Precision, cand.Precision, cand.Precision-base.Precision)
+		fmt.Fprintf(w, "R@%d\t%.4f\t%.4f\t%+.4f\n", k, base.Recall, cand.Recall, cand.Recall-base.Recall)
+	} else {
+		fmt.Fprintf(w, "METRIC\tVALUE\n")
+		fmt.Fprintf(w, "nDCG@%d\t%.4f\n", k, base.NDCG)
+		fmt.Fprintf(w, "MRR@%d\t%.4f\n", k, base.MRR)
+		fmt.Fprintf(w, "P@%d\t%.4f\n", k, base.Precision)
+		fmt.Fprintf(w, "R@%d\t%.4f\n", k, base.Recall)
+	}
+	if len(report.NotApplied) > 0 {
+		fmt.Fprintf(w, "\nNot applied by searches, so not evaluated: %s\n", strings.Join(report.NotApplied, ", "))
+	}
+	w.Flush()
+}
+
+// waitConnected polls the given connection states until all report
+// connected, the timeout passes or ctx is cancelled.
+func waitConnected(ctx context.Context, timeout time.Duration, states ...func() db.State) bool {
+	deadline := time.After(timeout)
+	ticker := time.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		connected := true
+		for _, state := range states {
+			if state() != db.StateConnected {
+				connected = false
+			}
+		}
+		if connected {
+			return true
+		}
+
+		select {
+		case <-ticker.C:
+		case <-deadline:
+			return false
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
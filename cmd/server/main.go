@@ -12,8 +12,11 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"github.com/quckapp/search-service/internal/api"
+	"github.com/quckapp/search-service/internal/auth"
+	"github.com/quckapp/search-service/internal/blob"
 	"github.com/quckapp/search-service/internal/config"
 	"github.com/quckapp/search-service/internal/db"
+	"github.com/quckapp/search-service/internal/embed"
 	"github.com/quckapp/search-service/internal/handler"
 	"github.com/quckapp/search-service/internal/service"
 )
@@ -23,31 +26,73 @@ func main() {
 	logger.SetFormatter(&logrus.JSONFormatter{})
 
 	cfg := config.Load()
+	if err := cfg.Validate(); err != nil {
+		logger.Fatalf("Invalid configuration: %v", err)
+	}
+
+	verifier, err := auth.NewVerifier(context.Background(), cfg, logger)
+	if err != nil {
+		logger.Fatalf("Failed to initialize token verification: %v", err)
+	}
+
+	// Connection managers keep retrying in the background, so a dependency
+	// that is down at boot is picked up once it becomes reachable.
+	connCtx, stopConns := context.WithCancel(context.Background())
+	defer stopConns()
 
 	// Initialize Elasticsearch
-	esClient := db.NewElasticsearch(cfg.ElasticsearchURL, logger)
+	esClient := db.NewElasticsearchManager(cfg.ElasticsearchURL, logger)
+	esClient.Start(connCtx)
 
 	// Initialize Redis (optional)
-	redisClient := db.NewRedis(cfg.RedisHost, cfg.RedisPort, cfg.RedisPassword)
-	if redisClient != nil {
-		logger.Info("Connected to Redis")
-		defer redisClient.Close()
+	redisClient := db.NewRedisManager(cfg.RedisHost, cfg.RedisPort, cfg.RedisPassword, logger)
+	redisClient.Start(connCtx)
+	defer redisClient.Close()
+
+	embedder, err := embed.New(cfg.Embedding)
+	if err != nil {
+		logger.Fatalf("Invalid embedding configuration: %v", err)
 	}
 
 	// -- Initialize Services --
-	searchService := service.NewSearchService(esClient, redisClient, logger)
+	embeddingService := service.NewEmbeddingService(embedder, logger)
+	tenantRoutingService := service.NewTenantRoutingService(esClient, redisClient, logger)
+	deadLetterService := service.NewDeadLetterService(esClient, redisClient, tenantRoutingService, cfg.DeadLetter, logger)
+	relevanceService := service.NewRelevanceService(esClient, redisClient, tenantRoutingService, logger)
+	clickService := service.NewClickService(redisClient, cfg.Clicks, logger)
+	searchService := service.NewSearchService(esClient, redisClient, tenantRoutingService, deadLetterService, embeddingService, relevanceService, clickService, logger)
 	historyService := service.NewHistoryService(redisClient, logger)
 	savedSearchService := service.NewSavedSearchService(redisClient, logger)
 	indexMgmtService := service.NewIndexManagementService(esClient, logger)
-	extSearchService := service.NewExtendedSearchService(esClient, redisClient, logger)
+	extSearchService := service.NewExtendedSearchService(esClient, redisClient, tenantRoutingService, deadLetterService, logger)
 	analyticsService := service.NewAnalyticsService(redisClient, logger)
-	facetService := service.NewFacetService(esClient, redisClient, logger)
+	facetService := service.NewFacetService(esClient, redisClient, tenantRoutingService, logger)
 	synonymService := service.NewSynonymService(redisClient, logger)
-	relevanceService := service.NewRelevanceService(esClient, redisClient, logger)
 	alertService := service.NewAlertService(redisClient, logger)
 	spellCheckService := service.NewSpellCheckService(esClient, logger)
 	searchScopeService := service.NewSearchScopeService(redisClient, logger)
 	extended2Service := service.NewExtended2Service(redisClient, logger)
+	feedbackService := service.NewFeedbackService(esClient, redisClient, logger)
+	evaluationService := service.NewEvaluationService(redisClient, searchService, relevanceService, feedbackService, logger)
+	curationService := service.NewCurationService(redisClient, searchService, logger)
+	rateLimitService := service.NewRateLimitService(redisClient, cfg.RateLimits, cfg.DailyIndexQuota, logger)
+	apiKeyService := service.NewAPIKeyService(redisClient, logger)
+	dataDeletionService := service.NewDataDeletionService(esClient, redisClient, tenantRoutingService, deadLetterService, cfg.Ingest.Streams, logger)
+	auditService := service.NewAuditService(esClient, logger)
+	fileExtractionService := service.NewFileExtractionService(searchService, blob.NewLocalReader(cfg.Extraction.BlobRoot), cfg.Extraction, logger)
+
+	// Index domain events published to Redis Streams in the background.
+	ingestionConsumer := service.NewIngestionConsumer(esClient, redisClient, tenantRoutingService, deadLetterService, embeddingService, rateLimitService, cfg.Ingest, logger)
+	go ingestionConsumer.Run(connCtx)
+
+	// Retry failed index writes with backoff.
+	go deadLetterService.Run(connCtx)
+
+	// Turn logged clicks into click-through rates and search boosts.
+	go clickService.Run(connCtx)
+
+	// Move feedback left in Redis by earlier versions into its index.
+	go feedbackService.MigrateLegacy(connCtx)
 
 	// -- Initialize Handlers --
 	searchHandler := handler.NewSearchHandler(searchService, logger)
@@ -63,6 +108,17 @@ func main() {
 	spellCheckHandler := handler.NewSpellCheckHandler(spellCheckService, logger)
 	searchScopeHandler := handler.NewSearchScopeHandler(searchScopeService, logger)
 	ext2Handler := handler.NewExtended2Handler(extended2Service, logger)
+	quotaHandler := handler.NewQuotaHandler(rateLimitService, logger)
+	apiKeyHandler := handler.NewAPIKeyHandler(apiKeyService, logger)
+	tenantHandler := handler.NewTenantHandler(tenantRoutingService, logger)
+	dataDeletionHandler := handler.NewDataDeletionHandler(dataDeletionService, logger)
+	auditHandler := handler.NewAuditHandler(auditService, logger)
+	deadLetterHandler := handler.NewDeadLetterHandler(deadLetterService, logger)
+	fileHandler := handler.NewFileHandler(fileExtractionService, logger)
+	clickHandler := handler.NewClickHandler(clickService, logger)
+	feedbackHandler := handler.NewFeedbackHandler(feedbackService, logger)
+	evaluationHandler := handler.NewEvaluationHandler(evaluationService, logger)
+	curationHandler := handler.NewCurationHandler(curationService, logger)
 
 	// Setup router
 	router := api.NewRouter(
@@ -79,6 +135,21 @@ func main() {
 		spellCheckHandler,
 		searchScopeHandler,
 		ext2Handler,
+		quotaHandler,
+		apiKeyHandler,
+		tenantHandler,
+		dataDeletionHandler,
+		auditHandler,
+		deadLetterHandler,
+		fileHandler,
+		clickHandler,
+		feedbackHandler,
+		evaluationHandler,
+		curationHandler,
+		rateLimitService,
+		verifier,
+		apiKeyService,
+		auditService,
 		cfg,
 		logger,
 	)
@@ -104,5 +175,6 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 	srv.Shutdown(ctx)
+	stopConns()
 	log.Println("Search service stopped")
 }
@@ -0,0 +1,96 @@
+// Command tenant-migrate moves a workspace's documents to another index
+// placement (shared, routed or dedicated) and waits until the move is done.
+// It is the synchronous counterpart of POST /admin/tenants/:id/migrate.
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/quckapp/search-service/internal/config"
+	"github.com/quckapp/search-service/internal/db"
+	"github.com/quckapp/search-service/internal/service"
+)
+
+func main() {
+	workspaceID := flag.String("workspace", "", "workspace to migrate")
+	mode := flag.String("mode", "", "target placement: shared, routed or dedicated")
+	connectTimeout := flag.Duration("connect-timeout", 30*time.Second, "how long to wait for Elasticsearch and Redis")
+	flag.Parse()
+
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.JSONFormatter{})
+
+	if *workspaceID == "" || *mode == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cfg := config.Load()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	esClient := db.NewElasticsearchManager(cfg.ElasticsearchURL, logger)
+	esClient.Start(ctx)
+	defer esClient.Close()
+
+	redisClient := db.NewRedisManager(cfg.RedisHost, cfg.RedisPort, cfg.RedisPassword, logger)
+	redisClient.Start(ctx)
+	defer redisClient.Close()
+
+	if !waitConnected(ctx, *connectTimeout, esClient.State, redisClient.State) {
+		logger.Fatal("Elasticsearch and Redis must both be reachable to migrate a tenant")
+	}
+
+	tenants := service.NewTenantRoutingService(esClient, redisClient, logger)
+
+	placement, err := tenants.StartMigration(ctx, *workspaceID, *mode)
+	if err != nil {
+		logger.Fatalf("Failed to start migration: %v", err)
+	}
+	logger.WithFields(logrus.Fields{
+		"workspace_id": *workspaceID,
+		"migration_id": placement.Migration.ID,
+		"from":         placement.Migration.From,
+		"to":           placement.Migration.To,
+	}).Info("Migration started; waiting for replicas to pick up the new placement")
+
+	if err := tenants.RunMigration(ctx, *workspaceID); err != nil {
+		logger.Fatalf("Migration failed: %v", err)
+	}
+}
+
+// waitConnected polls the given connection states until all report
+// connected, the timeout passes or ctx is cancelled.
+func waitConnected(ctx context.Context, timeout time.Duration, states ...func() db.State) bool {
+	deadline := time.After(timeout)
+	ticker := time.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		connected := true
+		for _, state := range states {
+			if state() != db.StateConnected {
+				connected = false
+			}
+		}
+		if connected {
+			return true
+		}
+
+		select {
+		case <-ticker.C:
+		case <-deadline:
+			return false
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
@@ -4,9 +4,11 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 
+	"github.com/quckapp/search-service/internal/auth"
 	"github.com/quckapp/search-service/internal/config"
 	"github.com/quckapp/search-service/internal/handler"
 	"github.com/quckapp/search-service/internal/middleware"
+	"github.com/quckapp/search-service/internal/service"
 )
 
 func NewRouter(
@@ -23,6 +25,21 @@ func NewRouter(
 	spellCheckHandler *handler.SpellCheckHandler,
 	searchScopeHandler *handler.SearchScopeHandler,
 	ext2Handler *handler.Extended2Handler,
+	quotaHandler *handler.QuotaHandler,
+	apiKeyHandler *handler.APIKeyHandler,
+	tenantHandler *handler.TenantHandler,
+	dataDeletionHandler *handler.DataDeletionHandler,
+	auditHandler *handler.AuditHandler,
+	deadLetterHandler *handler.DeadLetterHandler,
+	fileHandler *handler.FileHandler,
+	clickHandler *handler.ClickHandler,
+	feedbackHandler *handler.FeedbackHandler,
+	evaluationHandler *handler.EvaluationHandler,
+	curationHandler *handler.CurationHandler,
+	rateLimiter *service.RateLimitService,
+	verifier *auth.Verifier,
+	apiKeys *service.APIKeyService,
+	audit *service.AuditService,
 	cfg *config.Config,
 	logger *logrus.Logger,
 ) *gin.Engine {
@@ -40,156 +57,255 @@ func NewRouter(
 	r.GET("/health", searchHandler.Health)
 
 	api := r.Group("/api/v1")
-	api.Use(middleware.Auth(cfg.JWTSecret))
+	// Every authenticated route runs inside the caller's workspace scope,
+	// which the services apply to all reads and writes.
+	users := api.Group("", middleware.Auth(verifier, logger), middleware.Workspace())
+
+	// Each route group has its own token bucket (see config.RateLimits).
+	search := users.Group("", middleware.RateLimit(rateLimiter, "search", logger))
 	{
 		// -- Core Search --
-		api.GET("/search", searchHandler.GlobalSearch)
-		api.GET("/search/messages", searchHandler.SearchMessages)
-		api.GET("/search/files", searchHandler.SearchFiles)
-		api.GET("/search/users", searchHandler.SearchUsers)
-		api.GET("/search/channels", searchHandler.SearchChannels)
-		api.GET("/search/suggest", searchHandler.Suggest)
+		search.GET("/search", searchHandler.GlobalSearch)
+		search.GET("/search/messages", searchHandler.SearchMessages)
+		search.GET("/search/files", searchHandler.SearchFiles)
+		search.GET("/search/users", searchHandler.SearchUsers)
+		search.GET("/search/channels", searchHandler.SearchChannels)
+		search.GET("/search/explain", searchHandler.Explain)
 
 		// -- Extended Search --
-		api.GET("/search/bookmarks", extSearchHandler.SearchBookmarks)
-		api.GET("/search/tasks", extSearchHandler.SearchTasks)
-		api.GET("/search/emoji", extSearchHandler.SearchEmoji)
-		api.POST("/search/advanced", extSearchHandler.AdvancedSearch)
+		search.GET("/search/bookmarks", extSearchHandler.SearchBookmarks)
+		search.GET("/search/tasks", extSearchHandler.SearchTasks)
+		search.GET("/search/emoji", extSearchHandler.SearchEmoji)
+		search.POST("/search/advanced", extSearchHandler.AdvancedSearch)
 
 		// -- Search History --
-		api.GET("/search/history", historyHandler.GetHistory)
-		api.DELETE("/search/history", historyHandler.ClearHistory)
-		api.DELETE("/search/history/:id", historyHandler.DeleteHistoryItem)
+		search.GET("/search/history", historyHandler.GetHistory)
+		search.DELETE("/search/history", historyHandler.ClearHistory)
+		search.DELETE("/search/history/:id", historyHandler.DeleteHistoryItem)
 
 		// -- Saved Searches --
-		api.POST("/search/saved", savedSearchHandler.Create)
-		api.GET("/search/saved", savedSearchHandler.GetByUser)
-		api.GET("/search/saved/:id", savedSearchHandler.GetByID)
-		api.PUT("/search/saved/:id", savedSearchHandler.Update)
-		api.DELETE("/search/saved/:id", savedSearchHandler.Delete)
-
-		// -- Analytics --
-		api.GET("/analytics", analyticsHandler.GetAnalytics)
-		api.GET("/analytics/popular-queries", analyticsHandler.GetPopularQueries)
-		api.DELETE("/analytics", analyticsHandler.ClearAnalytics)
+		search.POST("/search/saved", savedSearchHandler.Create)
+		search.GET("/search/saved", savedSearchHandler.GetByUser)
+		search.GET("/search/saved/:id", savedSearchHandler.GetByID)
+		search.PUT("/search/saved/:id", savedSearchHandler.Update)
+		search.DELETE("/search/saved/:id", savedSearchHandler.Delete)
 
 		// -- Aggregation --
-		api.POST("/search/aggregate", extSearchHandler.Aggregate)
+		search.POST("/search/aggregate", extSearchHandler.Aggregate)
 
-		// -- Core Index Management --
-		api.POST("/index", searchHandler.IndexDocument)
-		api.POST("/index/message", searchHandler.IndexMessage)
-		api.POST("/index/file", searchHandler.IndexFile)
-		api.POST("/index/bulk", searchHandler.BulkIndex)
-		api.DELETE("/index/:type/:id", searchHandler.DeleteFromIndex)
-		api.POST("/index/reindex", searchHandler.Reindex)
+		// -- Document Count --
+		search.GET("/index/:index/count", extSearchHandler.CountDocuments)
 
-		// -- Typed Index Endpoints --
-		api.POST("/index/user", extSearchHandler.IndexUser)
-		api.POST("/index/channel", extSearchHandler.IndexChannel)
-		api.POST("/index/bookmark", extSearchHandler.IndexBookmark)
-		api.POST("/index/task", extSearchHandler.IndexTask)
+		// -- Search Facets/Filters --
+		search.POST("/search/facets", facetHandler.GetFacets)
+		search.GET("/search/faceted", facetHandler.GetFacetedSearch)
+		search.GET("/search/filters", facetHandler.GetFilterOptions)
 
-		// -- Batch Operations --
-		api.POST("/index/batch-delete", extSearchHandler.BatchDelete)
-		api.PUT("/index/:index/:id", extSearchHandler.UpdateDocument)
+		// -- Search Alerts --
+		search.POST("/alerts", alertHandler.Create)
+		search.GET("/alerts", alertHandler.List)
+		search.PUT("/alerts/:id", alertHandler.Update)
+		search.DELETE("/alerts/:id", alertHandler.Delete)
+		search.GET("/alerts/:id/history", alertHandler.GetHistory)
 
-		// -- Document Count --
-		api.GET("/index/:index/count", extSearchHandler.CountDocuments)
+		// -- Search Permissions/Scoping --
+		search.POST("/search/scope", searchScopeHandler.SetScope)
+		search.GET("/search/scope", searchScopeHandler.GetScope)
+		search.GET("/search/scopes", searchScopeHandler.ListAvailableScopes)
 
-		// -- Index Administration --
-		api.GET("/indices", indexMgmtHandler.ListIndices)
-		api.GET("/indices/:index", indexMgmtHandler.GetIndexInfo)
-		api.POST("/indices", indexMgmtHandler.CreateIndex)
-		api.DELETE("/indices/:index", indexMgmtHandler.DeleteIndex)
-		api.PUT("/indices/mappings", indexMgmtHandler.PutMapping)
-		api.PUT("/indices/settings", indexMgmtHandler.UpdateSettings)
-		api.POST("/indices/aliases", indexMgmtHandler.CreateAlias)
-		api.DELETE("/indices/aliases", indexMgmtHandler.DeleteAlias)
-		api.POST("/indices/:index/refresh", indexMgmtHandler.RefreshIndex)
-		api.POST("/indices/:index/flush", indexMgmtHandler.FlushIndex)
+		// -- Search Templates --
+		search.POST("/search/templates", ext2Handler.CreateTemplate)
+		search.GET("/search/templates", ext2Handler.ListTemplates)
+		search.GET("/search/templates/:id", ext2Handler.GetTemplate)
+		search.PUT("/search/templates/:id", ext2Handler.UpdateTemplate)
+		search.DELETE("/search/templates/:id", ext2Handler.DeleteTemplate)
+		search.POST("/search/templates/:id/use", ext2Handler.UseTemplate)
 
-		// -- Search Facets/Filters --
-		api.POST("/search/facets", facetHandler.GetFacets)
-		api.GET("/search/faceted", facetHandler.GetFacetedSearch)
-		api.GET("/search/filters", facetHandler.GetFilterOptions)
+		// -- Search Result Bookmarks --
+		search.POST("/search/result-bookmarks", ext2Handler.BookmarkResult)
+		search.GET("/search/result-bookmarks", ext2Handler.ListBookmarks)
+		search.DELETE("/search/result-bookmarks/:id", ext2Handler.DeleteBookmark)
 
-		// -- Synonym Management --
-		api.POST("/synonyms", synonymHandler.Create)
-		api.GET("/synonyms", synonymHandler.List)
-		api.PUT("/synonyms/:id", synonymHandler.Update)
-		api.DELETE("/synonyms/:id", synonymHandler.Delete)
-		api.POST("/synonyms/apply", synonymHandler.ApplyToIndex)
+		// -- Search Feedback --
+		search.POST("/search/feedback", feedbackHandler.Submit)
+		search.GET("/search/feedback", feedbackHandler.ListMine)
+		search.GET("/search/feedback/stats", feedbackHandler.StatsMine)
 
-		// -- Relevance Tuning --
-		api.GET("/relevance", relevanceHandler.GetConfig)
-		api.PUT("/relevance", relevanceHandler.UpdateConfig)
-		api.GET("/relevance/preview", relevanceHandler.PreviewTuning)
-		api.POST("/relevance/reset", relevanceHandler.ResetToDefaults)
+		// -- Click Tracking --
+		search.POST("/search/impressions", clickHandler.LogImpression)
+		search.POST("/search/clicks", clickHandler.LogClick)
+	}
 
-		// -- Search Alerts --
-		api.POST("/alerts", alertHandler.Create)
-		api.GET("/alerts", alertHandler.List)
-		api.PUT("/alerts/:id", alertHandler.Update)
-		api.DELETE("/alerts/:id", alertHandler.Delete)
-		api.GET("/alerts/:id/history", alertHandler.GetHistory)
+	suggest := users.Group("", middleware.RateLimit(rateLimiter, "suggest", logger))
+	{
+		// -- Suggestions --
+		suggest.GET("/search/suggest", searchHandler.Suggest)
 
 		// -- Spell Check / Did You Mean --
-		api.GET("/search/spellcheck", spellCheckHandler.GetSuggestions)
-		api.GET("/search/didyoumean", spellCheckHandler.DidYouMean)
+		suggest.GET("/search/spellcheck", spellCheckHandler.GetSuggestions)
+		suggest.GET("/search/didyoumean", spellCheckHandler.DidYouMean)
+	}
 
-		// -- Search Permissions/Scoping --
-		api.POST("/search/scope", searchScopeHandler.SetScope)
-		api.GET("/search/scope", searchScopeHandler.GetScope)
-		api.GET("/search/scopes", searchScopeHandler.ListAvailableScopes)
+	// Indexing routes also accept service API keys, scoped per operation and
+	// index. Document writes are charged against the workspace's daily quota.
+	quota := middleware.IndexQuota(rateLimiter, logger)
+	index := api.Group("", middleware.AuthOrAPIKey(verifier, apiKeys, logger), middleware.Workspace(), middleware.RateLimit(rateLimiter, "index", logger))
+	{
+		// -- Core Index Management --
+		index.POST("/index", middleware.RequireIndexScope(middleware.OpIndex), quota, searchHandler.IndexDocument)
+		index.POST("/index/message", middleware.RequireIndexScope(middleware.OpIndex, "quckapp_messages"), quota, searchHandler.IndexMessage)
+		index.POST("/index/file", middleware.RequireIndexScope(middleware.OpIndex, "quckapp_files"), quota, searchHandler.IndexFile)
+		index.POST("/index/file/upload", middleware.RequireIndexScope(middleware.OpIndex, "quckapp_files"), quota, fileHandler.Upload)
+		index.POST("/index/file/extract", middleware.RequireIndexScope(middleware.OpIndex, "quckapp_files"), quota, fileHandler.Extract)
+		index.POST("/index/bulk", middleware.RequireIndexScope(middleware.OpIndex), quota, searchHandler.BulkIndex)
+		index.DELETE("/index/:type/:id", middleware.RequireIndexScope(middleware.OpDelete), searchHandler.DeleteFromIndex)
+		index.POST("/index/reindex", middleware.RequireIndexScope(middleware.OpReindex), searchHandler.Reindex)
 
-		// -- Search Templates --
-		api.POST("/search/templates", ext2Handler.CreateTemplate)
-		api.GET("/search/templates", ext2Handler.ListTemplates)
-		api.GET("/search/templates/:id", ext2Handler.GetTemplate)
-		api.PUT("/search/templates/:id", ext2Handler.UpdateTemplate)
-		api.DELETE("/search/templates/:id", ext2Handler.DeleteTemplate)
-		api.POST("/search/templates/:id/use", ext2Handler.UseTemplate)
+		// -- Typed Index Endpoints --
+		index.POST("/index/user", middleware.RequireIndexScope(middleware.OpIndex, "quckapp_users"), quota, extSearchHandler.IndexUser)
+		index.POST("/index/channel", middleware.RequireIndexScope(middleware.OpIndex, "quckapp_channels"), quota, extSearchHandler.IndexChannel)
+		index.POST("/index/bookmark", middleware.RequireIndexScope(middleware.OpIndex, "quckapp_bookmarks"), quota, extSearchHandler.IndexBookmark)
+		index.POST("/index/task", middleware.RequireIndexScope(middleware.OpIndex, "quckapp_tasks"), quota, extSearchHandler.IndexTask)
 
-		// -- Search Result Bookmarks --
-		api.POST("/search/result-bookmarks", ext2Handler.BookmarkResult)
-		api.GET("/search/result-bookmarks", ext2Handler.ListBookmarks)
-		api.DELETE("/search/result-bookmarks/:id", ext2Handler.DeleteBookmark)
+		// -- Batch Operations --
+		index.POST("/index/batch-delete", middleware.RequireIndexScope(middleware.OpDelete), extSearchHandler.BatchDelete)
+		index.PUT("/index/:index/:id", middleware.RequireIndexScope(middleware.OpIndex), quota, extSearchHandler.UpdateDocument)
+	}
 
-		// -- Search Feedback --
-		api.POST("/search/feedback", ext2Handler.SubmitFeedback)
-		api.GET("/search/feedback", ext2Handler.ListFeedback)
-		api.GET("/search/feedback/stats", ext2Handler.GetFeedbackStats)
+	// Administrative groups require the permission for their resource; see
+	// middleware.rolePermissions for which roles hold which permissions. Every
+	// mutating request is written to the audit log, with before/after state
+	// for the routes that take an AuditSnapshot.
+	admin := users.Group("", middleware.RateLimit(rateLimiter, "admin", logger), middleware.Audit(audit, logger))
+	{
+		// -- Analytics --
+		analytics := admin.Group("/analytics")
+		analytics.GET("", middleware.RequirePermission(middleware.PermAnalyticsRead), analyticsHandler.GetAnalytics)
+		analytics.GET("/popular-queries", middleware.RequirePermission(middleware.PermAnalyticsRead), analyticsHandler.GetPopularQueries)
+		analytics.DELETE("", middleware.RequirePermission(middleware.PermAnalyticsManage), middleware.AuditSnapshot(analyticsHandler.AnalyticsSnapshot), analyticsHandler.ClearAnalytics)
+		analytics.GET("/clicks", middleware.RequirePermission(middleware.PermAnalyticsRead), clickHandler.GetQueryCTR)
+		analytics.POST("/clicks/aggregate", middleware.RequirePermission(middleware.PermAnalyticsManage), clickHandler.Aggregate)
+		analytics.GET("/feedback", middleware.RequirePermission(middleware.PermAnalyticsRead), feedbackHandler.List)
+		analytics.GET("/feedback/stats", middleware.RequirePermission(middleware.PermAnalyticsRead), feedbackHandler.Stats)
+		analytics.GET("/feedback/export", middleware.RequirePermission(middleware.PermAnalyticsRead), feedbackHandler.Export)
+
+		// -- Index Administration --
+		indices := admin.Group("/indices", middleware.RequirePermission(middleware.PermIndicesManage))
+		indices.GET("", indexMgmtHandler.ListIndices)
+		indices.GET("/:index", indexMgmtHandler.GetIndexInfo)
+		indices.POST("", indexMgmtHandler.CreateIndex)
+		indices.DELETE("/:index", middleware.AuditSnapshot(indexMgmtHandler.IndexSnapshot), indexMgmtHandler.DeleteIndex)
+		indices.PUT("/mappings", middleware.AuditSnapshot(indexMgmtHandler.IndexSnapshot), indexMgmtHandler.PutMapping)
+		indices.PUT("/settings", middleware.AuditSnapshot(indexMgmtHandler.IndexSnapshot), indexMgmtHandler.UpdateSettings)
+		indices.POST("/aliases", indexMgmtHandler.CreateAlias)
+		indices.DELETE("/aliases", indexMgmtHandler.DeleteAlias)
+		indices.POST("/:index/refresh", indexMgmtHandler.RefreshIndex)
+		indices.POST("/:index/flush", indexMgmtHandler.FlushIndex)
+
+		// -- Synonym Management --
+		synonyms := admin.Group("/synonyms", middleware.RequirePermission(middleware.PermSynonymsManage))
+		synonyms.POST("", synonymHandler.Create)
+		synonyms.GET("", synonymHandler.List)
+		synonyms.PUT("/:id", middleware.AuditSnapshot(synonymHandler.SynonymSnapshot), synonymHandler.Update)
+		synonyms.DELETE("/:id", middleware.AuditSnapshot(synonymHandler.SynonymSnapshot), synonymHandler.Delete)
+		synonyms.POST("/apply", synonymHandler.ApplyToIndex)
+
+		// -- Relevance Tuning --
+		relevance := admin.Group("/relevance", middleware.RequirePermission(middleware.PermRelevanceManage))
+		relevance.GET("", relevanceHandler.GetConfig)
+		relevance.PUT("", middleware.AuditSnapshot(relevanceHandler.ConfigSnapshot), relevanceHandler.UpdateConfig)
+		relevance.GET("/preview", relevanceHandler.PreviewTuning)
+		relevance.POST("/reset", middleware.AuditSnapshot(relevanceHandler.ConfigSnapshot), relevanceHandler.ResetToDefaults)
+		relevance.GET("/judgments", evaluationHandler.ListJudgmentLists)
+		relevance.POST("/judgments", evaluationHandler.CreateJudgmentList)
+		relevance.POST("/judgments/import", evaluationHandler.ImportJudgmentList)
+		relevance.POST("/judgments/from-feedback", evaluationHandler.JudgmentListFromFeedback)
+		relevance.GET("/judgments/:id", evaluationHandler.GetJudgmentList)
+		relevance.DELETE("/judgments/:id", evaluationHandler.DeleteJudgmentList)
+		relevance.POST("/evaluate", evaluationHandler.Evaluate)
+
+		// -- Curations --
+		curations := admin.Group("/curations", middleware.RequirePermission(middleware.PermRelevanceManage))
+		curations.GET("", curationHandler.List)
+		curations.POST("", curationHandler.Create)
+		curations.POST("/preview", curationHandler.Preview)
+		curations.GET("/:id", curationHandler.Get)
+		curations.PUT("/:id", middleware.AuditSnapshot(curationHandler.CurationSnapshot), curationHandler.Update)
+		curations.DELETE("/:id", middleware.AuditSnapshot(curationHandler.CurationSnapshot), curationHandler.Delete)
+		curations.GET("/:id/preview", curationHandler.PreviewSaved)
 
 		// -- A/B Tests --
-		api.POST("/search/ab-tests", ext2Handler.CreateABTest)
-		api.GET("/search/ab-tests", ext2Handler.ListABTests)
-		api.GET("/search/ab-tests/:id", ext2Handler.GetABTest)
-		api.DELETE("/search/ab-tests/:id", ext2Handler.DeleteABTest)
+		abTests := admin.Group("/search/ab-tests", middleware.RequirePermission(middleware.PermABTestsManage))
+		abTests.POST("", ext2Handler.CreateABTest)
+		abTests.GET("", ext2Handler.ListABTests)
+		abTests.GET("/:id", ext2Handler.GetABTest)
+		abTests.DELETE("/:id", ext2Handler.DeleteABTest)
 
 		// -- Search Pipelines --
-		api.POST("/search/pipelines", ext2Handler.CreatePipeline)
-		api.GET("/search/pipelines", ext2Handler.ListPipelines)
-		api.GET("/search/pipelines/:id", ext2Handler.GetPipeline)
-		api.PUT("/search/pipelines/:id", ext2Handler.UpdatePipeline)
-		api.DELETE("/search/pipelines/:id", ext2Handler.DeletePipeline)
+		pipelines := admin.Group("/search/pipelines", middleware.RequirePermission(middleware.PermPipelinesManage))
+		pipelines.POST("", ext2Handler.CreatePipeline)
+		pipelines.GET("", ext2Handler.ListPipelines)
+		pipelines.GET("/:id", ext2Handler.GetPipeline)
+		pipelines.PUT("/:id", middleware.AuditSnapshot(ext2Handler.PipelineSnapshot), ext2Handler.UpdatePipeline)
+		pipelines.DELETE("/:id", middleware.AuditSnapshot(ext2Handler.PipelineSnapshot), ext2Handler.DeletePipeline)
 
 		// -- Stop Words --
-		api.POST("/stop-words", ext2Handler.AddStopWord)
-		api.GET("/stop-words", ext2Handler.ListStopWords)
-		api.DELETE("/stop-words/:id", ext2Handler.DeleteStopWord)
+		stopWords := admin.Group("/stop-words", middleware.RequirePermission(middleware.PermStopWordsManage))
+		stopWords.POST("", ext2Handler.AddStopWord)
+		stopWords.GET("", ext2Handler.ListStopWords)
+		stopWords.DELETE("/:id", ext2Handler.DeleteStopWord)
 
 		// -- Query Rewrites --
-		api.POST("/query-rewrites", ext2Handler.CreateRewrite)
-		api.GET("/query-rewrites", ext2Handler.ListRewrites)
-		api.PUT("/query-rewrites/:id", ext2Handler.UpdateRewrite)
-		api.DELETE("/query-rewrites/:id", ext2Handler.DeleteRewrite)
+		rewrites := admin.Group("/query-rewrites", middleware.RequirePermission(middleware.PermRewritesManage))
+		rewrites.POST("", ext2Handler.CreateRewrite)
+		rewrites.GET("", ext2Handler.ListRewrites)
+		rewrites.PUT("/:id", middleware.AuditSnapshot(ext2Handler.RewriteSnapshot), ext2Handler.UpdateRewrite)
+		rewrites.DELETE("/:id", middleware.AuditSnapshot(ext2Handler.RewriteSnapshot), ext2Handler.DeleteRewrite)
 
 		// -- Index Schedules --
-		api.POST("/index-schedules", ext2Handler.CreateSchedule)
-		api.GET("/index-schedules", ext2Handler.ListSchedules)
-		api.PUT("/index-schedules/:id", ext2Handler.UpdateSchedule)
-		api.DELETE("/index-schedules/:id", ext2Handler.DeleteSchedule)
+		schedules := admin.Group("/index-schedules", middleware.RequirePermission(middleware.PermSchedulesManage))
+		schedules.POST("", ext2Handler.CreateSchedule)
+		schedules.GET("", ext2Handler.ListSchedules)
+		schedules.PUT("/:id", ext2Handler.UpdateSchedule)
+		schedules.DELETE("/:id", ext2Handler.DeleteSchedule)
+
+		// -- Quotas --
+		quotas := admin.Group("/admin/quotas", middleware.RequirePermission(middleware.PermQuotasManage))
+		quotas.GET("/:workspace_id", quotaHandler.GetIndexQuota)
+		quotas.PUT("/:workspace_id", middleware.AuditSnapshot(quotaHandler.QuotaSnapshot), quotaHandler.UpdateIndexQuota)
+
+		// -- Service API Keys --
+		apiKeyRoutes := admin.Group("/admin/api-keys", middleware.RequirePermission(middleware.PermAPIKeysManage))
+		apiKeyRoutes.POST("", apiKeyHandler.Create)
+		apiKeyRoutes.GET("", apiKeyHandler.List)
+		apiKeyRoutes.GET("/:id", apiKeyHandler.Get)
+		apiKeyRoutes.DELETE("/:id", apiKeyHandler.Delete)
+
+		// -- Tenant Placement --
+		// Placements and migrations are cluster operations, so they also
+		// need the cross-workspace grant.
+		tenants := admin.Group("/admin/tenants", middleware.RequirePermission(middleware.PermTenantsManage), middleware.RequirePermission(middleware.PermCrossWorkspace))
+		tenants.GET("", tenantHandler.List)
+		tenants.GET("/:workspace_id", tenantHandler.Get)
+		tenants.POST("/:workspace_id/migrate", tenantHandler.Migrate)
+		tenants.POST("/:workspace_id/migrate/abort", tenantHandler.AbortMigration)
+
+		// -- Data Deletion --
+		erase := admin.Group("", middleware.RequirePermission(middleware.PermDataErase))
+		erase.DELETE("/workspaces/:workspace_id/data", dataDeletionHandler.DeleteWorkspaceData)
+		erase.DELETE("/users/:user_id/data", dataDeletionHandler.DeleteUserData)
+		erase.GET("/admin/data-deletions", dataDeletionHandler.ListJobs)
+		erase.GET("/admin/data-deletions/:id", dataDeletionHandler.GetJob)
+
+		// -- Audit Log --
+		admin.GET("/admin/audit", middleware.RequirePermission(middleware.PermAuditRead), auditHandler.Query)
+
+		// -- Dead Letters --
+		deadLetters := admin.Group("/admin/dead-letters", middleware.RequirePermission(middleware.PermDeadLetters))
+		deadLetters.GET("", deadLetterHandler.List)
+		deadLetters.GET("/:id", deadLetterHandler.Get)
+		deadLetters.POST("/:id/replay", deadLetterHandler.Replay)
+		deadLetters.DELETE("/:id", middleware.AuditSnapshot(deadLetterHandler.DeadLetterSnapshot), deadLetterHandler.Discard)
 	}
 
 	return r
@@ -0,0 +1,163 @@
+// Package apperror defines the typed errors that services return and
+// handlers translate into HTTP responses.
+package apperror
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+type Kind string
+
+const (
+	KindUnavailable   Kind = "backend_unavailable"
+	KindBadQuery      Kind = "bad_query"
+	KindIndexNotFound Kind = "index_not_found"
+	KindTimeout       Kind = "timeout"
+	KindUnauthorized  Kind = "unauthorized"
+	KindForbidden     Kind = "forbidden"
+	KindNotFound      Kind = "not_found"
+	KindConflict      Kind = "conflict"
+	KindRateLimited   Kind = "rate_limited"
+	KindQuotaExceeded Kind = "quota_exceeded"
+	KindInternal      Kind = "internal"
+)
+
+type Error struct {
+	Kind    Kind
+	Message string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func New(kind Kind, message string) *Error {
+	return &Error{Kind: kind, Message: message}
+}
+
+func Wrap(kind Kind, message string, err error) *Error {
+	return &Error{Kind: kind, Message: message, Err: err}
+}
+
+func Unavailable(message string, err error) *Error {
+	return Wrap(KindUnavailable, message, err)
+}
+
+func BadQuery(message string, err error) *Error {
+	return Wrap(KindBadQuery, message, err)
+}
+
+func IndexNotFound(message string, err error) *Error {
+	return Wrap(KindIndexNotFound, message, err)
+}
+
+func Timeout(message string, err error) *Error {
+	return Wrap(KindTimeout, message, err)
+}
+
+func Forbidden(message string) *Error {
+	return New(KindForbidden, message)
+}
+
+func NotFound(message string) *Error {
+	return New(KindNotFound, message)
+}
+
+func Conflict(message string, err error) *Error {
+	return Wrap(KindConflict, message, err)
+}
+
+func RateLimited(message string) *Error {
+	return New(KindRateLimited, message)
+}
+
+func QuotaExceeded(message string) *Error {
+	return New(KindQuotaExceeded, message)
+}
+
+func Internal(message string, err error) *Error {
+	return Wrap(KindInternal, message, err)
+}
+
+// FromTransport classifies an error returned before any response was read,
+// e.g. a refused connection or an expired context.
+func FromTransport(message string, err error) *Error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return Timeout(message, err)
+	}
+	return Unavailable(message, err)
+}
+
+// FromStatus classifies a non-2xx response from a backend by its status code.
+func FromStatus(status int, message string, err error) *Error {
+	switch {
+	case status == http.StatusBadRequest:
+		return BadQuery(message, err)
+	case status == http.StatusNotFound:
+		return IndexNotFound(message, err)
+	case status == http.StatusUnauthorized, status == http.StatusForbidden:
+		return Wrap(KindForbidden, message, err)
+	case status == http.StatusConflict:
+		return Conflict(message, err)
+	case status == http.StatusRequestTimeout, status == http.StatusGatewayTimeout:
+		return Timeout(message, err)
+	case status == http.StatusTooManyRequests, status >= 500:
+		return Unavailable(message, err)
+	default:
+		return Internal(message, err)
+	}
+}
+
+// As returns the typed error in err's chain, or nil.
+func As(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return nil
+}
+
+// KindOf returns the kind of err, treating untyped errors as internal.
+func KindOf(err error) Kind {
+	if appErr := As(err); appErr != nil {
+		return appErr.Kind
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return KindTimeout
+	}
+	return KindInternal
+}
+
+// HTTPStatus maps an error kind onto the status code handlers respond with.
+func HTTPStatus(kind Kind) int {
+	switch kind {
+	case KindBadQuery:
+		return http.StatusBadRequest
+	case KindIndexNotFound, KindNotFound:
+		return http.StatusNotFound
+	case KindUnauthorized:
+		return http.StatusUnauthorized
+	case KindForbidden:
+		return http.StatusForbidden
+	case KindConflict:
+		return http.StatusConflict
+	case KindRateLimited, KindQuotaExceeded:
+		return http.StatusTooManyRequests
+	case KindTimeout:
+		return http.StatusGatewayTimeout
+	case KindUnavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
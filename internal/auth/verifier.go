@@ -0,0 +1,154 @@
+// Package auth validates the bearer tokens presented to the API.
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sirupsen/logrus"
+
+	"github.com/quckapp/search-service/internal/config"
+)
+
+var supportedAlgorithms = map[string]bool{
+	"HS256": true,
+	"RS256": true,
+	"ES256": true,
+}
+
+// Verifier checks a token's signature against the configured keys and its
+// registered claims against the configured issuer, audience and leeway.
+type Verifier struct {
+	secret    []byte
+	publicKey crypto.PublicKey
+	keySet    *KeySet
+	parser    *jwt.Parser
+}
+
+func NewVerifier(ctx context.Context, cfg *config.Config, logger *logrus.Logger) (*Verifier, error) {
+	v := &Verifier{secret: []byte(cfg.JWTSecret)}
+
+	asymmetric := false
+	for _, alg := range cfg.JWTAlgorithms {
+		if !supportedAlgorithms[alg] {
+			return nil, fmt.Errorf("unsupported JWT algorithm %q", alg)
+		}
+		if !strings.HasPrefix(alg, "HS") {
+			asymmetric = true
+		}
+	}
+
+	if cfg.JWTPublicKeyFile != "" {
+		key, err := loadPublicKey(cfg.JWTPublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		v.publicKey = key
+	}
+	if cfg.JWKSURL != "" {
+		ks, err := NewKeySet(ctx, cfg.JWKSURL, cfg.JWKSRefresh, logger)
+		if err != nil {
+			return nil, err
+		}
+		v.keySet = ks
+	}
+	if asymmetric && v.publicKey == nil && v.keySet == nil {
+		return nil, errors.New("RS256/ES256 require JWT_PUBLIC_KEY_FILE or JWT_JWKS_URL")
+	}
+
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(cfg.JWTAlgorithms),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(cfg.JWTLeeway),
+	}
+	if cfg.JWTIssuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.JWTIssuer))
+	}
+	if len(cfg.JWTAudience) > 0 {
+		opts = append(opts, jwt.WithAudience(cfg.JWTAudience...))
+	}
+	v.parser = jwt.NewParser(opts...)
+
+	return v, nil
+}
+
+// Verify parses and validates tokenString and returns its claims.
+func (v *Verifier) Verify(ctx context.Context, tokenString string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	token, err := v.parser.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return v.key(ctx, token)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, errors.New("token is invalid")
+	}
+	return claims, nil
+}
+
+// key picks the verification key for the token's algorithm family. The
+// parser has already checked the alg against the allow-list; checking the
+// key type here as well stops an RSA public key being used as an HMAC secret.
+func (v *Verifier) key(ctx context.Context, token *jwt.Token) (interface{}, error) {
+	switch token.Method.(type) {
+	case *jwt.SigningMethodHMAC:
+		return v.secret, nil
+	case *jwt.SigningMethodRSA:
+		key, err := v.asymmetricKey(ctx, token)
+		if err != nil {
+			return nil, err
+		}
+		if rsaKey, ok := key.(*rsa.PublicKey); ok {
+			return rsaKey, nil
+		}
+		return nil, errors.New("key is not an RSA public key")
+	case *jwt.SigningMethodECDSA:
+		key, err := v.asymmetricKey(ctx, token)
+		if err != nil {
+			return nil, err
+		}
+		if ecKey, ok := key.(*ecdsa.PublicKey); ok {
+			return ecKey, nil
+		}
+		return nil, errors.New("key is not an ECDSA public key")
+	}
+	return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+}
+
+// asymmetricKey prefers the JWKS entry named by the token's kid and falls
+// back to the static PEM key.
+func (v *Verifier) asymmetricKey(ctx context.Context, token *jwt.Token) (crypto.PublicKey, error) {
+	kid, _ := token.Header["kid"].(string)
+	if v.keySet != nil {
+		key, err := v.keySet.Key(ctx, kid)
+		if err == nil || v.publicKey == nil {
+			return key, err
+		}
+	}
+	if v.publicKey == nil {
+		return nil, errors.New("no public key configured")
+	}
+	return v.publicKey, nil
+}
+
+func loadPublicKey(path string) (crypto.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read public key: %w", err)
+	}
+	if key, err := jwt.ParseRSAPublicKeyFromPEM(data); err == nil {
+		return key, nil
+	}
+	if key, err := jwt.ParseECPublicKeyFromPEM(data); err == nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%s is not a PEM-encoded RSA or ECDSA public key", path)
+}
@@ -0,0 +1,138 @@
+// Package blob reads stored file contents by reference, so files already
+// uploaded to storage can be indexed without passing through the API.
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	// ErrNotFound is returned when no blob exists for a reference.
+	ErrNotFound = errors.New("blob not found")
+	// ErrInvalidRef is returned for references the reader cannot resolve,
+	// including paths that escape its root.
+	ErrInvalidRef = errors.New("invalid blob reference")
+	// ErrDisabled is returned by readers with no storage configured.
+	ErrDisabled = errors.New("blob storage is not configured")
+)
+
+// Reader opens a stored file. A reference is a path or URL whose meaning is
+// up to the implementation.
+type Reader interface {
+	Open(ctx context.Context, ref string) (io.ReadCloser, error)
+}
+
+// LocalReader reads files under a directory on the local filesystem, such
+// as a mounted volume. References are paths relative to Root, or file://
+// URLs whose path is taken relative to Root. Symbolic links are followed
+// only while they stay inside the ref's top directory under Root.
+type LocalReader struct {
+	Root string
+}
+
+func NewLocalReader(root string) *LocalReader {
+	return &LocalReader{Root: root}
+}
+
+func (r *LocalReader) Open(ctx context.Context, ref string) (io.ReadCloser, error) {
+	if r.Root == "" {
+		return nil, ErrDisabled
+	}
+	path, err := r.resolve(ref)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if info, err := f.Stat(); err == nil && info.IsDir() {
+		f.Close()
+		return nil, ErrInvalidRef
+	}
+	return f, nil
+}
+
+// TopDir returns the first directory of ref's path as a LocalReader
+// resolves it, so callers can keep each tenant to its own directory. A ref
+// naming a file directly under the root has none and is invalid.
+func TopDir(ref string) (string, error) {
+	rel, err := cleanRef(ref)
+	if err != nil {
+		return "", err
+	}
+	parts := strings.SplitN(strings.TrimPrefix(filepath.ToSlash(rel), "/"), "/", 2)
+	if len(parts) < 2 {
+		return "", ErrInvalidRef
+	}
+	return parts[0], nil
+}
+
+// resolve maps ref to a path inside Root, with symbolic links evaluated so
+// a link cannot lead out of it, nor out of the ref's top directory. The top
+// directory itself may not be a link, which could only alias a sibling.
+func (r *LocalReader) resolve(ref string) (string, error) {
+	rel, err := cleanRef(ref)
+	if err != nil {
+		return "", err
+	}
+	root, err := filepath.EvalSymlinks(r.Root)
+	if err != nil {
+		return "", err
+	}
+	path, err := filepath.EvalSymlinks(filepath.Join(root, rel))
+	if err != nil {
+		return "", err
+	}
+	if !within(root, path) {
+		return "", ErrInvalidRef
+	}
+	if dir, err := TopDir(ref); err == nil {
+		top, err := filepath.EvalSymlinks(filepath.Join(root, dir))
+		if err != nil || top != filepath.Join(root, dir) || !within(top, path) {
+			return "", ErrInvalidRef
+		}
+	}
+	return path, nil
+}
+
+// within reports whether path is base or lies under it. Both must be
+// clean, absolute and free of symbolic links.
+func within(base, path string) bool {
+	rel, err := filepath.Rel(base, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// cleanRef turns ref into a path rooted at "/". Cleaning the path as if it
+// were rooted means ".." cannot climb above the root.
+func cleanRef(ref string) (string, error) {
+	if strings.Contains(ref, "://") {
+		u, err := url.Parse(ref)
+		if err != nil || u.Scheme != "file" || (u.Host != "" && u.Host != "localhost") {
+			return "", ErrInvalidRef
+		}
+		ref = u.Path
+	}
+	if ref == "" || strings.ContainsRune(ref, 0) {
+		return "", ErrInvalidRef
+	}
+
+	rel := filepath.Clean("/" + filepath.FromSlash(ref))
+	if rel == string(filepath.Separator) {
+		return "", ErrInvalidRef
+	}
+	return rel, nil
+}
@@ -1,6 +1,15 @@
 package config
 
-import "os"
+import (
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultJWTSecret is only acceptable outside production.
+const defaultJWTSecret = "dev-secret"
 
 type Config struct {
 	Port             string
@@ -10,6 +19,102 @@ type Config struct {
 	RedisPort        string
 	RedisPassword    string
 	JWTSecret        string
+
+	// JWTAlgorithms is the allow-list of signing algorithms (HS256, RS256,
+	// ES256). Tokens signed with anything else are rejected.
+	JWTAlgorithms []string
+	// JWTPublicKeyFile is a PEM file holding an RSA or ECDSA public key.
+	JWTPublicKeyFile string
+	// JWKSURL points at a JWKS document, either http(s):// or a file path.
+	JWKSURL     string
+	JWKSRefresh time.Duration
+	JWTIssuer   string
+	JWTAudience []string
+	JWTLeeway   time.Duration
+
+	// RateLimits holds the token bucket for each route group, keyed by group
+	// name (search, suggest, index, admin).
+	RateLimits map[string]RateLimit
+	// DailyIndexQuota is the default number of documents a workspace may
+	// index per UTC day. Admins can override it per workspace; 0 disables it.
+	DailyIndexQuota int64
+
+	// Ingest configures the Redis Streams consumer that indexes domain
+	// events published by other services.
+	Ingest Ingest
+
+	// DeadLetter configures automatic retries of failed index writes.
+	DeadLetter DeadLetter
+
+	// Extraction limits the files indexed through the upload and blob
+	// endpoints.
+	Extraction Extraction
+
+	// Embedding selects the provider of the vectors behind semantic search.
+	Embedding Embedding
+
+	// Clicks configures click-through tracking and its aggregation job.
+	Clicks Clicks
+}
+
+// Ingest configures event ingestion. An empty stream list disables it.
+type Ingest struct {
+	Streams  []string
+	Group    string
+	Consumer string
+	// BatchSize caps the events read, and sent to _bulk, at once.
+	BatchSize int
+	Block     time.Duration
+	// RetryAfter is how long a failed event stays pending before it is
+	// retried; after MaxDeliveries attempts it is dead-lettered.
+	RetryAfter    time.Duration
+	MaxDeliveries int
+}
+
+// DeadLetter configures the retry schedule for failed writes: attempt n
+// waits Backoff*2^(n-1), capped at MaxBackoff, and after MaxAttempts the
+// entry is left for an admin to replay or discard.
+type DeadLetter struct {
+	MaxAttempts int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+}
+
+// Extraction bounds file indexing: larger files are rejected, and
+// extracted text is cut to MaxChars characters. BlobRoot is the directory
+// the local blob reader serves, with a directory per workspace; empty
+// disables indexing by reference.
+type Extraction struct {
+	MaxFileSize int64
+	MaxChars    int
+	BlobRoot    string
+}
+
+// Embedding configures the embedder: "none" (the default; semantic search
+// is off), "http" (an OpenAI-compatible embeddings endpoint at URL) or
+// "hashing" (local and model-free, for tests). Dims must match the
+// provider's output; changing it or the provider needs a reindex.
+type Embedding struct {
+	Provider string
+	Dims     int
+	URL      string
+	Model    string
+	APIKey   string
+	Timeout  time.Duration
+}
+
+// Clicks configures click tracking: counts for a query are kept for
+// Retention after its last impression, and click-through rates and boosts
+// are recomputed every AggregateInterval.
+type Clicks struct {
+	AggregateInterval time.Duration
+	Retention         time.Duration
+}
+
+// RateLimit is a token bucket: Burst tokens refilled at PerMinute per minute.
+type RateLimit struct {
+	PerMinute int
+	Burst     int
 }
 
 func Load() *Config {
@@ -20,13 +125,119 @@ func Load() *Config {
 		RedisHost:        getEnv("REDIS_HOST", "localhost"),
 		RedisPort:        getEnv("REDIS_PORT", "6379"),
 		RedisPassword:    getEnv("REDIS_PASSWORD", ""),
-		JWTSecret:        getEnv("JWT_SECRET", "dev-secret"),
+		JWTSecret:        getEnv("JWT_SECRET", defaultJWTSecret),
+		JWTAlgorithms:    getEnvList("JWT_ALGORITHMS", "HS256"),
+		JWTPublicKeyFile: getEnv("JWT_PUBLIC_KEY_FILE", ""),
+		JWKSURL:          getEnv("JWT_JWKS_URL", ""),
+		JWKSRefresh:      getEnvDuration("JWT_JWKS_REFRESH", 15*time.Minute),
+		JWTIssuer:        getEnv("JWT_ISSUER", ""),
+		JWTAudience:      getEnvList("JWT_AUDIENCE", ""),
+		JWTLeeway:        getEnvDuration("JWT_LEEWAY", 30*time.Second),
+		RateLimits: map[string]RateLimit{
+			"search":  loadRateLimit("SEARCH", 120, 40),
+			"suggest": loadRateLimit("SUGGEST", 600, 100),
+			"index":   loadRateLimit("INDEX", 300, 100),
+			"admin":   loadRateLimit("ADMIN", 60, 20),
+		},
+		DailyIndexQuota: int64(getEnvInt("DAILY_INDEX_QUOTA", 100000)),
+		Ingest: Ingest{
+			Streams:       getEnvList("INGEST_STREAMS", "quckapp:events"),
+			Group:         getEnv("INGEST_GROUP", "search-service"),
+			Consumer:      getEnv("INGEST_CONSUMER", hostname()),
+			BatchSize:     getEnvInt("INGEST_BATCH_SIZE", 200),
+			Block:         getEnvDuration("INGEST_BLOCK", 2*time.Second),
+			RetryAfter:    getEnvDuration("INGEST_RETRY_AFTER", 30*time.Second),
+			MaxDeliveries: getEnvInt("INGEST_MAX_DELIVERIES", 5),
+		},
+		DeadLetter: DeadLetter{
+			MaxAttempts: getEnvInt("DEADLETTER_MAX_ATTEMPTS", 10),
+			Backoff:     getEnvDuration("DEADLETTER_BACKOFF", 30*time.Second),
+			MaxBackoff:  getEnvDuration("DEADLETTER_MAX_BACKOFF", time.Hour),
+		},
+		Extraction: Extraction{
+			MaxFileSize: int64(getEnvInt("EXTRACT_MAX_FILE_SIZE", 50<<20)),
+			MaxChars:    getEnvInt("EXTRACT_MAX_CHARS", 500000),
+			BlobRoot:    getEnv("BLOB_ROOT", ""),
+		},
+		Embedding: Embedding{
+			Provider: getEnv("EMBEDDING_PROVIDER", "none"),
+			Dims:     getEnvInt("EMBEDDING_DIMS", 256),
+			URL:      getEnv("EMBEDDING_URL", ""),
+			Model:    getEnv("EMBEDDING_MODEL", ""),
+			APIKey:   getEnv("EMBEDDING_API_KEY", ""),
+			Timeout:  getEnvDuration("EMBEDDING_TIMEOUT", 10*time.Second),
+		},
+		Clicks: Clicks{
+			AggregateInterval: getEnvDuration("CLICK_AGGREGATE_INTERVAL", 5*time.Minute),
+			Retention:         getEnvDuration("CLICK_RETENTION", 30*24*time.Hour),
+		},
 	}
 }
 
+// Validate rejects configurations that must not reach a running server.
+func (c *Config) Validate() error {
+	if c.Environment == "production" && c.JWTSecret == defaultJWTSecret {
+		for _, alg := range c.JWTAlgorithms {
+			if strings.HasPrefix(alg, "HS") {
+				return errors.New("JWT_SECRET must be set in production when HMAC algorithms are allowed")
+			}
+		}
+	}
+	if len(c.JWTAlgorithms) == 0 {
+		return errors.New("JWT_ALGORITHMS must list at least one algorithm")
+	}
+	return nil
+}
+
+// loadRateLimit reads RATE_LIMIT_<GROUP>_PER_MINUTE and RATE_LIMIT_<GROUP>_BURST.
+func loadRateLimit(group string, perMinute, burst int) RateLimit {
+	return RateLimit{
+		PerMinute: getEnvInt("RATE_LIMIT_"+group+"_PER_MINUTE", perMinute),
+		Burst:     getEnvInt("RATE_LIMIT_"+group+"_BURST", burst),
+	}
+}
+
+// hostname names this replica's stream consumer by default, so replicas
+// share a consumer group without stealing each other's pending events.
+func hostname() string {
+	if name, err := os.Hostname(); err == nil && name != "" {
+		return name
+	}
+	return "search-service"
+}
+
 func getEnv(key, def string) string {
 	if v := os.Getenv(key); v != "" {
 		return v
 	}
 	return def
 }
+
+func getEnvInt(key string, def int) int {
+	if v := os.Getenv(key); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			return n
+		}
+	}
+	return def
+}
+
+func getEnvDuration(key string, def time.Duration) time.Duration {
+	if v := os.Getenv(key); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			return d
+		}
+	}
+	return def
+}
+
+// getEnvList reads a comma-separated list.
+func getEnvList(key, def string) []string {
+	var out []string
+	for _, item := range strings.Split(getEnv(key, def), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
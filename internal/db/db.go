@@ -3,45 +3,57 @@ package db
 import (
 	"context"
 	"fmt"
-	"time"
 
 	"github.com/elastic/go-elasticsearch/v8"
 	"github.com/redis/go-redis/v9"
-	"github.com/sirupsen/logrus"
 )
 
-func NewElasticsearch(url string, logger *logrus.Logger) *elasticsearch.Client {
+func dialElasticsearch(ctx context.Context, url string) (*elasticsearch.Client, error) {
 	es, err := elasticsearch.NewClient(elasticsearch.Config{
 		Addresses: []string{url},
 	})
 	if err != nil {
-		logger.Warnf("Failed to create Elasticsearch client: %v", err)
-		return nil
+		return nil, fmt.Errorf("create elasticsearch client: %w", err)
 	}
 
 	// Test connection
-	res, err := es.Info()
+	res, err := es.Info(es.Info.WithContext(ctx))
 	if err != nil {
-		logger.Warnf("Elasticsearch not available: %v", err)
-		return nil
+		return nil, err
 	}
 	res.Body.Close()
-	logger.Info("Connected to Elasticsearch")
-	return es
+	if res.IsError() {
+		return nil, fmt.Errorf("elasticsearch info returned %s", res.Status())
+	}
+	return es, nil
+}
+
+func pingElasticsearch(ctx context.Context, es *elasticsearch.Client) error {
+	res, err := es.Ping(es.Ping.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	if res.IsError() {
+		return fmt.Errorf("elasticsearch ping returned %s", res.Status())
+	}
+	return nil
 }
 
-func NewRedis(host, port, password string) *redis.Client {
+func dialRedis(ctx context.Context, host, port, password string) (*redis.Client, error) {
 	client := redis.NewClient(&redis.Options{
 		Addr:     fmt.Sprintf("%s:%s", host, port),
 		Password: password,
 		DB:       8,
 	})
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-
 	if err := client.Ping(ctx).Err(); err != nil {
-		return nil
+		client.Close()
+		return nil, err
 	}
-	return client
+	return client, nil
+}
+
+func pingRedis(ctx context.Context, client *redis.Client) error {
+	return client.Ping(ctx).Err()
 }
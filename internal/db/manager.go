@@ -0,0 +1,213 @@
+package db
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+// State describes the connection state of a backing dependency.
+type State string
+
+const (
+	StateConnecting   State = "connecting"
+	StateConnected    State = "connected"
+	StateDisconnected State = "disconnected"
+)
+
+const (
+	dialTimeout    = 3 * time.Second
+	initialBackoff = 1 * time.Second
+	maxBackoff     = 30 * time.Second
+	healthInterval = 10 * time.Second
+)
+
+// Status is a point-in-time snapshot of a connection manager, suitable for
+// health endpoints.
+type Status struct {
+	State     State     `json:"state"`
+	Since     time.Time `json:"since"`
+	Attempts  int       `json:"attempts,omitempty"`
+	LastError string    `json:"last_error,omitempty"`
+}
+
+// manager keeps a client for a dependency that may not be reachable at boot.
+// Until the first successful dial it retries with exponential backoff; after
+// that it keeps the client and only tracks reachability, since both the ES
+// and Redis clients reconnect on their own once they exist.
+type manager[T comparable] struct {
+	name    string
+	dial    func(ctx context.Context) (T, error)
+	ping    func(ctx context.Context, client T) error
+	closeFn func(client T)
+	logger  *logrus.Logger
+
+	mu       sync.RWMutex
+	client   T
+	state    State
+	since    time.Time
+	attempts int
+	lastErr  error
+}
+
+func newManager[T comparable](name string, logger *logrus.Logger) *manager[T] {
+	return &manager[T]{name: name, logger: logger, state: StateConnecting, since: time.Now()}
+}
+
+// Client returns the live client, or the zero value while the dependency has
+// never been reached.
+func (m *manager[T]) Client() T {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.client
+}
+
+func (m *manager[T]) State() State {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.state
+}
+
+func (m *manager[T]) Status() Status {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	st := Status{State: m.state, Since: m.since, Attempts: m.attempts}
+	if m.lastErr != nil {
+		st.LastError = m.lastErr.Error()
+	}
+	return st
+}
+
+// Start makes one synchronous connection attempt so a healthy dependency is
+// usable as soon as Start returns, then keeps retrying and monitoring in the
+// background until ctx is cancelled.
+func (m *manager[T]) Start(ctx context.Context) {
+	m.connect(ctx)
+	go m.run(ctx)
+}
+
+func (m *manager[T]) Close() {
+	var zero T
+	m.mu.Lock()
+	client := m.client
+	m.client = zero
+	m.mu.Unlock()
+	if client != zero && m.closeFn != nil {
+		m.closeFn(client)
+	}
+}
+
+func (m *manager[T]) run(ctx context.Context) {
+	var zero T
+	backoff := initialBackoff
+	for {
+		wait := healthInterval
+		if m.Client() == zero {
+			wait = backoff
+			backoff *= 2
+			if backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+
+		if client := m.Client(); client == zero {
+			if m.connect(ctx) {
+				backoff = initialBackoff
+			}
+		} else {
+			m.check(ctx, client)
+		}
+	}
+}
+
+func (m *manager[T]) connect(ctx context.Context) bool {
+	dialCtx, cancel := context.WithTimeout(ctx, dialTimeout)
+	defer cancel()
+
+	client, err := m.dial(dialCtx)
+	if err != nil {
+		m.mu.Lock()
+		m.attempts++
+		attempts := m.attempts
+		m.setState(StateDisconnected, err)
+		m.mu.Unlock()
+		m.logger.WithFields(logrus.Fields{"attempt": attempts, "error": err}).Warnf("%s not available", m.name)
+		return false
+	}
+
+	m.mu.Lock()
+	m.client = client
+	m.attempts = 0
+	m.setState(StateConnected, nil)
+	m.mu.Unlock()
+	m.logger.Infof("Connected to %s", m.name)
+	return true
+}
+
+func (m *manager[T]) check(ctx context.Context, client T) {
+	pingCtx, cancel := context.WithTimeout(ctx, dialTimeout)
+	defer cancel()
+
+	err := m.ping(pingCtx, client)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	switch {
+	case err != nil && m.state == StateConnected:
+		m.logger.WithField("error", err).Warnf("Lost connection to %s", m.name)
+		m.setState(StateDisconnected, err)
+	case err != nil:
+		m.lastErr = err
+	case m.state != StateConnected:
+		m.logger.Infof("Reconnected to %s", m.name)
+		m.setState(StateConnected, nil)
+	}
+}
+
+// setState must be called with mu held.
+func (m *manager[T]) setState(state State, err error) {
+	if m.state != state {
+		m.since = time.Now()
+	}
+	m.state = state
+	m.lastErr = err
+}
+
+// ElasticsearchManager owns the Elasticsearch client for the process.
+type ElasticsearchManager struct {
+	*manager[*elasticsearch.Client]
+}
+
+func NewElasticsearchManager(url string, logger *logrus.Logger) *ElasticsearchManager {
+	m := newManager[*elasticsearch.Client]("Elasticsearch", logger)
+	m.dial = func(ctx context.Context) (*elasticsearch.Client, error) {
+		return dialElasticsearch(ctx, url)
+	}
+	m.ping = pingElasticsearch
+	return &ElasticsearchManager{m}
+}
+
+// RedisManager owns the Redis client for the process.
+type RedisManager struct {
+	*manager[*redis.Client]
+}
+
+func NewRedisManager(host, port, password string, logger *logrus.Logger) *RedisManager {
+	m := newManager[*redis.Client]("Redis", logger)
+	m.dial = func(ctx context.Context) (*redis.Client, error) {
+		return dialRedis(ctx, host, port, password)
+	}
+	m.ping = pingRedis
+	m.closeFn = func(client *redis.Client) { client.Close() }
+	return &RedisManager{m}
+}
@@ -0,0 +1,48 @@
+// Package embed turns text into dense vectors for semantic search. The
+// provider is chosen by configuration: a local hashing embedder that needs
+// no model, or an HTTP service.
+package embed
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/quckapp/search-service/internal/config"
+)
+
+// Providers accepted in config.Embedding.Provider.
+const (
+	ProviderNone    = "none"
+	ProviderHashing = "hashing"
+	ProviderHTTP    = "http"
+)
+
+// Embedder computes vectors for texts. Vectors from one embedder are
+// comparable by cosine similarity; vectors from different embedders, or of
+// different dimensions, are not.
+type Embedder interface {
+	// Embed returns one vector of Dims values per text, in order.
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+	Dims() int
+}
+
+// New returns the embedder cfg selects, or nil when semantic search is
+// disabled.
+func New(cfg config.Embedding) (Embedder, error) {
+	if cfg.Provider == ProviderNone || cfg.Provider == "" {
+		return nil, nil
+	}
+	if cfg.Dims < 1 {
+		return nil, fmt.Errorf("embedding dimensions must be positive, got %d", cfg.Dims)
+	}
+	switch cfg.Provider {
+	case ProviderHashing:
+		return NewHashingEmbedder(cfg.Dims), nil
+	case ProviderHTTP:
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("EMBEDDING_URL is required for the %s provider", ProviderHTTP)
+		}
+		return NewHTTPEmbedder(cfg), nil
+	}
+	return nil, fmt.Errorf("unknown embedding provider %q", cfg.Provider)
+}
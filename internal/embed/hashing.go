@@ -0,0 +1,79 @@
+package embed
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// trigramWeight scales character trigrams against whole words. Trigrams let
+// inflections and typos share part of a vector ("index", "indexes",
+// "idnex") where whole words alone would not.
+const trigramWeight = 0.5
+
+// HashingEmbedder maps text to vectors by feature hashing: each word and
+// character trigram adds ±weight to a bucket chosen by its hash. It needs
+// no model and is deterministic, so the same text always gets the same
+// vector; it captures shared vocabulary, not meaning, which makes it a
+// baseline and a stand-in for tests rather than a substitute for a model.
+type HashingEmbedder struct {
+	dims int
+}
+
+func NewHashingEmbedder(dims int) *HashingEmbedder {
+	return &HashingEmbedder{dims: dims}
+}
+
+func (e *HashingEmbedder) Dims() int {
+	return e.dims
+}
+
+func (e *HashingEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = e.embed(text)
+	}
+	return vectors, nil
+}
+
+func (e *HashingEmbedder) embed(text string) []float32 {
+	vec := make([]float64, e.dims)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	for _, word := range words {
+		e.add(vec, "w:"+word, 1)
+		runes := []rune("^" + word + "$")
+		for j := 0; j+3 <= len(runes); j++ {
+			e.add(vec, "t:"+string(runes[j:j+3]), trigramWeight)
+		}
+	}
+
+	var norm float64
+	for _, v := range vec {
+		norm += v * v
+	}
+	out := make([]float32, e.dims)
+	if norm == 0 {
+		return out
+	}
+	norm = math.Sqrt(norm)
+	for j, v := range vec {
+		out[j] = float32(v / norm)
+	}
+	return out
+}
+
+// add hashes feature to a bucket; the top bit picks the sign, so unrelated
+// features that collide tend to cancel rather than pile up.
+func (e *HashingEmbedder) add(vec []float64, feature string, weight float64) {
+	h := fnv.New64a()
+	h.Write([]byte(feature))
+	sum := h.Sum64()
+	if sum>>63 == 1 {
+		weight = -weight
+	}
+	vec[sum%uint64(e.dims)] += weight
+}
@@ -0,0 +1,108 @@
+package embed
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/quckapp/search-service/internal/config"
+)
+
+// httpBatchSize caps the texts sent in one request.
+const httpBatchSize = 64
+
+// HTTPEmbedder calls an embedding service that speaks the OpenAI embeddings
+// protocol, which most hosted and self-hosted model servers accept:
+//
+//	POST {"model": "...", "input": ["text", ...]}
+//	->   {"data": [{"index": 0, "embedding": [0.1, ...]}, ...]}
+type HTTPEmbedder struct {
+	url    string
+	model  string
+	apiKey string
+	dims   int
+	client *http.Client
+}
+
+func NewHTTPEmbedder(cfg config.Embedding) *HTTPEmbedder {
+	return &HTTPEmbedder{
+		url:    cfg.URL,
+		model:  cfg.Model,
+		apiKey: cfg.APIKey,
+		dims:   cfg.Dims,
+		client: &http.Client{Timeout: cfg.Timeout},
+	}
+}
+
+func (e *HTTPEmbedder) Dims() int {
+	return e.dims
+}
+
+func (e *HTTPEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += httpBatchSize {
+		end := start + httpBatchSize
+		if end > len(texts) {
+			end = len(texts)
+		}
+		batch, err := e.embedBatch(ctx, texts[start:end])
+		if err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, batch...)
+	}
+	return vectors, nil
+}
+
+func (e *HTTPEmbedder) embedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	body, err := json.Marshal(map[string]interface{}{"model": e.model, "input": texts})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.apiKey)
+	}
+
+	res, err := e.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("embedding request: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+		return nil, fmt.Errorf("embedding service returned %d: %s", res.StatusCode, bytes.TrimSpace(msg))
+	}
+
+	var parsed struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&parsed); err != nil {
+		return nil, fmt.Errorf("decode embedding response: %w", err)
+	}
+	if len(parsed.Data) != len(texts) {
+		return nil, fmt.Errorf("embedding service returned %d vectors for %d texts", len(parsed.Data), len(texts))
+	}
+
+	vectors := make([][]float32, len(texts))
+	for _, d := range parsed.Data {
+		if d.Index < 0 || d.Index >= len(texts) {
+			return nil, fmt.Errorf("embedding service returned index %d out of range", d.Index)
+		}
+		if len(d.Embedding) != e.dims {
+			return nil, fmt.Errorf("embedding service returned %d dimensions, configured for %d", len(d.Embedding), e.dims)
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	return vectors, nil
+}
@@ -0,0 +1,66 @@
+package extract
+
+import "unicode"
+
+// Passage is a window of a file's text, searched and returned on its own so
+// a long file is scored by its best part rather than its average.
+type Passage struct {
+	Text string
+	// Section is the 1-based page, slide or sheet the passage starts in.
+	Section int
+	// Offset is the passage's position in its section, in words.
+	Offset int
+}
+
+// Chunk splits each section into passages of up to size words, each
+// sharing overlap words with the one before, so a phrase across a boundary
+// still lands whole in one passage. Passages do not span sections, and
+// line breaks inside a passage are kept.
+func Chunk(sections []string, size, overlap int) []Passage {
+	if size < 1 {
+		return nil
+	}
+	if overlap < 0 || overlap >= size {
+		overlap = 0
+	}
+	step := size - overlap
+
+	var passages []Passage
+	for i, section := range sections {
+		words := wordSpans(section)
+		for start := 0; start < len(words); start += step {
+			end := start + size
+			if end > len(words) {
+				end = len(words)
+			}
+			passages = append(passages, Passage{
+				Text:    section[words[start][0]:words[end-1][1]],
+				Section: i + 1,
+				Offset:  start,
+			})
+			if end == len(words) {
+				break
+			}
+		}
+	}
+	return passages
+}
+
+// wordSpans returns the byte range of each white-space separated word.
+func wordSpans(text string) [][2]int {
+	var spans [][2]int
+	start := -1
+	for i, r := range text {
+		switch {
+		case unicode.IsSpace(r) && start >= 0:
+			spans = append(spans, [2]int{start, i})
+			start = -1
+		case !unicode.IsSpace(r) && start < 0:
+			start = i
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, [2]int{start, len(text)})
+	}
+	return spans
+}
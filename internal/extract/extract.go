@@ -0,0 +1,206 @@
+// Package extract turns uploaded file bytes into plain text for indexing.
+// Every format is parsed in pure Go: PDF, DOCX, XLSX, PPTX, HTML, Markdown
+// and plain text.
+package extract
+
+import (
+	"errors"
+	"mime"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gabriel-vasile/mimetype"
+)
+
+// ErrUnsupported is returned for files whose type has no extractor. The
+// file can still be indexed by name.
+var ErrUnsupported = errors.New("unsupported file type")
+
+// MIME types with an extractor.
+const (
+	MimePDF      = "application/pdf"
+	MimeDOCX     = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+	MimeXLSX     = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	MimePPTX     = "application/vnd.openxmlformats-officedocument.presentationml.presentation"
+	MimeHTML     = "text/html"
+	MimeMarkdown = "text/markdown"
+	MimeText     = "text/plain"
+)
+
+// Section types: what a numbered section of a file is.
+const (
+	SectionPage  = "page"
+	SectionSlide = "slide"
+	SectionSheet = "sheet"
+)
+
+// Result is the text extracted from one file.
+type Result struct {
+	Text string
+	// Sections splits Text by page, slide or sheet, as SectionType says.
+	// Formats without such divisions have a single section.
+	Sections    []string
+	SectionType string
+	MimeType    string
+	// FileType is a short name for the format, e.g. "pdf" or "docx".
+	FileType string
+	// Truncated is set when the text was cut to the size limit.
+	Truncated bool
+}
+
+// An extractor returns a file's text, one string per section. maxChars is
+// the caller's limit on the text, for extractors that bound their work by
+// it; it is not applied to what they return.
+type extractor func(data []byte, maxChars int) ([]string, error)
+
+var extractors = map[string]extractor{
+	MimePDF:      extractPDF,
+	MimeDOCX:     extractDOCX,
+	MimeXLSX:     extractXLSX,
+	MimePPTX:     extractPPTX,
+	MimeHTML:     extractHTML,
+	MimeMarkdown: extractMarkdown,
+	MimeText:     extractText,
+}
+
+var sectionTypes = map[string]string{
+	MimePDF:  SectionPage,
+	MimePPTX: SectionSlide,
+	MimeXLSX: SectionSheet,
+}
+
+var fileTypes = map[string]string{
+	MimePDF:      "pdf",
+	MimeDOCX:     "docx",
+	MimeXLSX:     "xlsx",
+	MimePPTX:     "pptx",
+	MimeHTML:     "html",
+	MimeMarkdown: "markdown",
+	MimeText:     "text",
+}
+
+// byExtension settles what content sniffing cannot: Markdown looks like
+// plain text, and Office files look like any other zip archive.
+var byExtension = map[string]string{
+	".pdf":      MimePDF,
+	".docx":     MimeDOCX,
+	".xlsx":     MimeXLSX,
+	".pptx":     MimePPTX,
+	".html":     MimeHTML,
+	".htm":      MimeHTML,
+	".md":       MimeMarkdown,
+	".markdown": MimeMarkdown,
+	".txt":      MimeText,
+}
+
+// DetectMIME returns the MIME type of data, without parameters. The file
+// name is consulted only where the content is ambiguous.
+func DetectMIME(data []byte, filename string) string {
+	detected, _, _ := mime.ParseMediaType(mimetype.Detect(data).String())
+	byName := byExtension[strings.ToLower(filepath.Ext(filename))]
+
+	switch {
+	case detected == MimeText && byName == MimeMarkdown:
+		return MimeMarkdown
+	case detected == "application/zip" && byName != "":
+		return byName
+	case detected == "application/octet-stream" && byName != "":
+		return byName
+	}
+	return detected
+}
+
+// Extract detects the type of data and returns its text, cut to at most
+// maxChars characters when maxChars is positive.
+func Extract(data []byte, filename string, maxChars int) (*Result, error) {
+	mimeType := DetectMIME(data, filename)
+	extract, ok := extractors[mimeType]
+	if !ok {
+		return &Result{MimeType: mimeType}, ErrUnsupported
+	}
+
+	result := &Result{MimeType: mimeType, FileType: fileTypes[mimeType], SectionType: sectionTypes[mimeType]}
+	sections, err := extract(data, maxChars)
+	if err != nil {
+		return result, err
+	}
+
+	// The limit applies to the whole file: sections past it are dropped and
+	// the one that crosses it is cut.
+	remaining := maxChars
+	var texts []string
+	for _, section := range sections {
+		if maxChars > 0 && remaining <= 0 {
+			result.Truncated = true
+			break
+		}
+		text, cut := truncate(normalizeSpace(section), remaining)
+		result.Sections = append(result.Sections, text)
+		result.Truncated = result.Truncated || cut
+		remaining -= utf8.RuneCountInString(text)
+		if text != "" {
+			texts = append(texts, text)
+		}
+	}
+	result.Text = strings.Join(texts, "\n\n")
+	return result, nil
+}
+
+// normalizeSpace collapses runs of spaces within lines and of blank lines,
+// and drops control characters and invalid UTF-8.
+func normalizeSpace(text string) string {
+	text = strings.ToValidUTF8(text, "")
+	var b strings.Builder
+	b.Grow(len(text))
+
+	blankLines, pendingSpace := 0, false
+	lineStart := true
+	for _, r := range text {
+		switch {
+		case r == '\n':
+			// Keep at most one blank line between paragraphs.
+			if lineStart {
+				blankLines++
+			} else {
+				blankLines = 0
+			}
+			if blankLines <= 1 {
+				b.WriteByte('\n')
+			}
+			lineStart, pendingSpace = true, false
+		case unicode.IsSpace(r):
+			pendingSpace = !lineStart
+		case unicode.IsControl(r):
+		default:
+			if pendingSpace {
+				b.WriteByte(' ')
+				pendingSpace = false
+			}
+			lineStart = false
+			b.WriteRune(r)
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// truncate cuts text to maxChars characters, at a word boundary when one
+// is near the limit.
+func truncate(text string, maxChars int) (string, bool) {
+	if maxChars <= 0 || utf8.RuneCountInString(text) <= maxChars {
+		return text, false
+	}
+
+	cut := 0
+	for i := 0; i < maxChars; i++ {
+		_, size := utf8.DecodeRuneInString(text[cut:])
+		cut += size
+	}
+	// Back off to the last space in the final tenth, so the last word is
+	// not cut in half.
+	if space := strings.LastIndexFunc(text[:cut], unicode.IsSpace); space > cut-cut/10 {
+		cut = space
+	}
+	return strings.TrimSpace(text[:cut]), true
+}
@@ -0,0 +1,341 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxPartSize bounds how much of one archive member is decompressed, so a
+// small zip cannot expand without limit.
+const maxPartSize = 64 << 20
+
+var errPartTooLarge = errors.New("archive member too large")
+
+// ooxml is an Office Open XML package: a zip archive of XML parts.
+type ooxml struct {
+	parts map[string]*zip.File
+}
+
+func openOOXML(data []byte) (*ooxml, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("open archive: %w", err)
+	}
+	pkg := &ooxml{parts: make(map[string]*zip.File, len(zr.File))}
+	for _, f := range zr.File {
+		pkg.parts[f.Name] = f
+	}
+	return pkg, nil
+}
+
+func (p *ooxml) read(name string) ([]byte, error) {
+	f, ok := p.parts[name]
+	if !ok {
+		return nil, fmt.Errorf("missing part %s", name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(io.LimitReader(rc, maxPartSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxPartSize {
+		return nil, errPartTooLarge
+	}
+	return data, nil
+}
+
+// numbered lists the parts matching pattern, whose first group is a number,
+// in numeric order: slide2 comes before slide10.
+func (p *ooxml) numbered(pattern *regexp.Regexp) []string {
+	type part struct {
+		name string
+		n    int
+	}
+	var found []part
+	for name := range p.parts {
+		if m := pattern.FindStringSubmatch(name); m != nil {
+			n, _ := strconv.Atoi(m[1])
+			found = append(found, part{name, n})
+		}
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].n < found[j].n })
+
+	names := make([]string, len(found))
+	for i, f := range found {
+		names[i] = f.name
+	}
+	return names
+}
+
+// xmlText walks an XML part and collects character data inside elements
+// named textTag. Elements in breakTags end a line; tabTag inserts a tab.
+func xmlText(data []byte, textTag string, breakTags map[string]bool, tabTag string) (string, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	var b strings.Builder
+	inText := 0
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return b.String(), nil
+		}
+		if err != nil {
+			return b.String(), err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case textTag:
+				inText++
+			case tabTag:
+				b.WriteByte('\t')
+			case "br", "cr":
+				b.WriteByte('\n')
+			}
+		case xml.EndElement:
+			if t.Name.Local == textTag && inText > 0 {
+				inText--
+			}
+			if breakTags[t.Name.Local] {
+				b.WriteByte('\n')
+			}
+		case xml.CharData:
+			if inText > 0 {
+				b.Write(t)
+			}
+		}
+	}
+}
+
+var docxParts = regexp.MustCompile(`^word/(header|footer)(\d+)\.xml$`)
+
+// extractDOCX returns the body of a Word document, followed by its
+// headers, footers, footnotes and endnotes.
+func extractDOCX(data []byte, _ int) ([]string, error) {
+	pkg, err := openOOXML(data)
+	if err != nil {
+		return nil, err
+	}
+	paragraphs := map[string]bool{"p": true, "tr": true}
+
+	body, err := pkg.read("word/document.xml")
+	if err != nil {
+		return nil, err
+	}
+	text, err := xmlText(body, "t", paragraphs, "tab")
+	if err != nil {
+		return nil, fmt.Errorf("parse document: %w", err)
+	}
+
+	var extra []string
+	for name := range pkg.parts {
+		if docxParts.MatchString(name) || name == "word/footnotes.xml" || name == "word/endnotes.xml" {
+			extra = append(extra, name)
+		}
+	}
+	sort.Strings(extra)
+	for _, name := range extra {
+		part, err := pkg.read(name)
+		if err != nil {
+			continue
+		}
+		if more, err := xmlText(part, "t", paragraphs, "tab"); err == nil {
+			text += "\n\n" + more
+		}
+	}
+	// Page breaks are decided at render time, so a document is one section.
+	return []string{text}, nil
+}
+
+var slideParts = regexp.MustCompile(`^ppt/slides/slide(\d+)\.xml$`)
+var notesParts = regexp.MustCompile(`^ppt/notesSlides/notesSlide(\d+)\.xml$`)
+
+// extractPPTX returns the text of each slide in order, followed by its
+// speaker notes.
+func extractPPTX(data []byte, _ int) ([]string, error) {
+	pkg, err := openOOXML(data)
+	if err != nil {
+		return nil, err
+	}
+	paragraphs := map[string]bool{"p": true}
+
+	notes := map[string]string{}
+	for _, name := range pkg.numbered(notesParts) {
+		part, err := pkg.read(name)
+		if err != nil {
+			continue
+		}
+		if text, err := xmlText(part, "t", paragraphs, "tab"); err == nil {
+			notes[notesParts.FindStringSubmatch(name)[1]] = text
+		}
+	}
+
+	var slides []string
+	for _, name := range pkg.numbered(slideParts) {
+		part, err := pkg.read(name)
+		if err != nil {
+			return nil, err
+		}
+		text, err := xmlText(part, "t", paragraphs, "tab")
+		if err != nil {
+			return nil, fmt.Errorf("parse %s: %w", path.Base(name), err)
+		}
+		// Notes parts are numbered like the slides they belong to.
+		if note := notes[slideParts.FindStringSubmatch(name)[1]]; note != "" {
+			text += "\n\n" + note
+		}
+		slides = append(slides, text)
+	}
+	return slides, nil
+}
+
+var sheetParts = regexp.MustCompile(`^xl/worksheets/sheet(\d+)\.xml$`)
+
+// extractXLSX returns each sheet's cells, a row per line and a tab between
+// cells. Formulas are represented by their cached values.
+func extractXLSX(data []byte, _ int) ([]string, error) {
+	pkg, err := openOOXML(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var shared []string
+	if part, err := pkg.read("xl/sharedStrings.xml"); err == nil {
+		if shared, err = sharedStrings(part); err != nil {
+			return nil, fmt.Errorf("parse shared strings: %w", err)
+		}
+	}
+
+	var sheets []string
+	for _, name := range pkg.numbered(sheetParts) {
+		part, err := pkg.read(name)
+		if err != nil {
+			return nil, err
+		}
+		var b strings.Builder
+		if err := sheetText(part, shared, &b); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", path.Base(name), err)
+		}
+		sheets = append(sheets, b.String())
+	}
+	return sheets, nil
+}
+
+// sharedStrings reads the workbook's string table. Each <si> is one entry,
+// possibly split over several rich-text runs.
+func sharedStrings(data []byte) ([]string, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	var table []string
+	var cur strings.Builder
+	inT := false
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return table, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "si":
+				cur.Reset()
+			case "t":
+				inT = true
+			case "rPh":
+				// Phonetic hints repeat the text; skip them.
+				dec.Skip()
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "si":
+				table = append(table, cur.String())
+			case "t":
+				inT = false
+			}
+		case xml.CharData:
+			if inT {
+				cur.Write(t)
+			}
+		}
+	}
+}
+
+func sheetText(data []byte, shared []string, b *strings.Builder) error {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	var cellType string
+	var value strings.Builder
+	inValue := false
+	firstInRow := true
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "row":
+				firstInRow = true
+			case "c":
+				cellType = ""
+				for _, a := range t.Attr {
+					if a.Name.Local == "t" {
+						cellType = a.Value
+					}
+				}
+				value.Reset()
+			case "v", "t":
+				inValue = true
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "v", "t":
+				inValue = false
+			case "c":
+				text := value.String()
+				if cellType == "s" {
+					if i, err := strconv.Atoi(strings.TrimSpace(text)); err == nil && i >= 0 && i < len(shared) {
+						text = shared[i]
+					}
+				}
+				if text == "" {
+					continue
+				}
+				if !firstInRow {
+					b.WriteByte('\t')
+				}
+				b.WriteString(text)
+				firstInRow = false
+			case "row":
+				if !firstInRow {
+					b.WriteByte('\n')
+				}
+			}
+		case xml.CharData:
+			if inValue {
+				value.Write(t)
+			}
+		}
+	}
+}
@@ -0,0 +1,744 @@
+package extract
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// The PDF extractor reads the text that pages draw. It does not lay out
+// glyphs: lines break where the text position moves down, and words split
+// where it jumps ahead. Fonts with a ToUnicode map decode through it; other
+// fonts are read as Latin-1, which covers the standard encodings for
+// unaccented text. Scanned pages, which hold only images, yield nothing.
+
+var errEncryptedPDF = errors.New("encrypted PDF")
+
+// A PDF decodes within a budget of bytes, spent on decompressed streams and
+// on the page contents put together from them, so a small file cannot
+// inflate without bound. Content streams run to many bytes of operators per
+// character they draw, hence the multiple of the character limit.
+const (
+	pdfBytesPerChar = 64
+	maxPDFDecoded   = 256 << 20
+)
+
+var (
+	pdfObjectStart = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+	pdfRef         = regexp.MustCompile(`^\s*(\d+)\s+\d+\s+R`)
+	pdfNameRef     = regexp.MustCompile(`/([^\s/<>\[\]()]+)\s+(\d+)\s+\d+\s+R`)
+	pdfLength      = regexp.MustCompile(`/Length\s+(\d+)(\s+\d+\s+R)?`)
+	pdfToUnicode   = regexp.MustCompile(`/ToUnicode\s+(\d+)\s+\d+\s+R`)
+)
+
+// pdfObject is one indirect object: its dictionary or value, and the
+// decoded stream when it has one.
+type pdfObject struct {
+	dict   []byte
+	stream []byte
+}
+
+type pdfDocument struct {
+	data    []byte
+	objects map[int]*pdfObject
+	// budget is how many more bytes may be decoded.
+	budget int
+}
+
+func extractPDF(data []byte, maxChars int) ([]string, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, "\x00\t\r\n "), []byte("%PDF-")) {
+		return nil, errors.New("not a PDF file")
+	}
+	budget := maxPDFDecoded
+	if maxChars > 0 && maxChars < maxPDFDecoded/pdfBytesPerChar {
+		budget = maxChars * pdfBytesPerChar
+	}
+	doc := &pdfDocument{data: data, objects: map[int]*pdfObject{}, budget: budget}
+	doc.readObjects()
+	if bytes.Contains(doc.trailer(), []byte("/Encrypt")) {
+		return nil, errEncryptedPDF
+	}
+
+	fonts := doc.fontMaps()
+	var pages []string
+	for _, content := range doc.pageContents() {
+		pages = append(pages, showText(content, fonts))
+	}
+	return pages, nil
+}
+
+// ── Objects ──
+
+// readObjects indexes every object in the file, then the objects packed
+// into object streams. Later definitions win, as incremental updates
+// append new versions of objects to the end of the file.
+func (d *pdfDocument) readObjects() {
+	matches := pdfObjectStart.FindAllSubmatchIndex(d.data, -1)
+	for i, m := range matches {
+		num, _ := strconv.Atoi(string(d.data[m[2]:m[3]]))
+		end := len(d.data)
+		if i+1 < len(matches) {
+			end = matches[i+1][0]
+		}
+		d.objects[num] = d.parseObject(d.data[m[1]:end])
+	}
+
+	for _, obj := range d.objects {
+		if obj.stream != nil && bytes.Contains(obj.dict, []byte("/ObjStm")) {
+			d.unpackObjectStream(obj)
+		}
+	}
+}
+
+func (d *pdfDocument) parseObject(body []byte) *pdfObject {
+	if end := bytes.Index(body, []byte("endobj")); end >= 0 {
+		body = body[:end]
+	}
+	start := bytes.Index(body, []byte("stream"))
+	if start < 0 || !bytes.Contains(body[:start], []byte("<<")) {
+		return &pdfObject{dict: body}
+	}
+
+	obj := &pdfObject{dict: body[:start]}
+	raw := body[start+len("stream"):]
+	raw = bytes.TrimPrefix(raw, []byte("\r"))
+	raw = bytes.TrimPrefix(raw, []byte("\n"))
+	if m := pdfLength.FindSubmatch(obj.dict); m != nil && m[2] == nil {
+		if n, err := strconv.Atoi(string(m[1])); err == nil && n <= len(raw) {
+			raw = raw[:n]
+		}
+	} else if end := bytes.LastIndex(raw, []byte("endstream")); end >= 0 {
+		raw = raw[:end]
+	}
+	obj.stream = d.decodeStream(obj.dict, raw)
+	return obj
+}
+
+// decodeStream applies the stream's filters. Streams in formats that hold
+// no text (images) or that this reader does not decode come back nil, as
+// do compressed streams once the budget is spent.
+func (d *pdfDocument) decodeStream(dict, raw []byte) []byte {
+	if bytes.Contains(dict, []byte("/Image")) {
+		return nil
+	}
+	switch {
+	case bytes.Contains(dict, []byte("/FlateDecode")):
+		if bytes.Contains(dict, []byte("/DCTDecode")) || bytes.Contains(dict, []byte("/JPXDecode")) {
+			return nil
+		}
+		if d.budget <= 0 {
+			return nil
+		}
+		zr, err := zlib.NewReader(bytes.NewReader(raw))
+		if err != nil {
+			return nil
+		}
+		defer zr.Close()
+		// A truncated stream still yields what decoded before the damage.
+		limit := int64(maxPartSize)
+		if int64(d.budget) < limit {
+			limit = int64(d.budget)
+		}
+		out, _ := io.ReadAll(io.LimitReader(zr, limit))
+		d.budget -= len(out)
+		return out
+	case bytes.Contains(dict, []byte("/Filter")):
+		return nil
+	}
+	return raw
+}
+
+// unpackObjectStream adds the objects compressed into an object stream.
+// The stream starts with pairs of object number and offset, relative to
+// /First.
+func (d *pdfDocument) unpackObjectStream(obj *pdfObject) {
+	first := dictInt(obj.dict, "First")
+	n := dictInt(obj.dict, "N")
+	if first <= 0 || first > len(obj.stream) || n <= 0 {
+		return
+	}
+	header := strings.Fields(string(obj.stream[:first]))
+	body := obj.stream[first:]
+	for i := 0; i+1 < len(header) && i/2 < n; i += 2 {
+		num, err1 := strconv.Atoi(header[i])
+		off, err2 := strconv.Atoi(header[i+1])
+		if err1 != nil || err2 != nil || num < 0 || off < 0 || off > len(body) {
+			continue
+		}
+		end := len(body)
+		if i+3 < len(header) {
+			if next, err := strconv.Atoi(header[i+3]); err == nil && next >= off && next <= len(body) {
+				end = next
+			}
+		}
+		if _, exists := d.objects[num]; !exists {
+			d.objects[num] = &pdfObject{dict: body[off:end]}
+		}
+	}
+}
+
+func (d *pdfDocument) trailer() []byte {
+	if i := bytes.LastIndex(d.data, []byte("trailer")); i >= 0 {
+		return d.data[i:]
+	}
+	// Files with cross-reference streams keep the trailer keys there.
+	for _, obj := range d.objects {
+		if bytes.Contains(obj.dict, []byte("/XRef")) {
+			return obj.dict
+		}
+	}
+	return nil
+}
+
+// resolve follows an indirect reference at the start of value, if any.
+func (d *pdfDocument) resolve(value []byte) []byte {
+	if m := pdfRef.FindSubmatch(value); m != nil {
+		num, _ := strconv.Atoi(string(m[1]))
+		if obj, ok := d.objects[num]; ok {
+			return obj.dict
+		}
+	}
+	return value
+}
+
+func dictInt(dict []byte, key string) int {
+	re := regexp.MustCompile(`/` + key + `\s+(\d+)`)
+	if m := re.FindSubmatch(dict); m != nil {
+		n, _ := strconv.Atoi(string(m[1]))
+		return n
+	}
+	return 0
+}
+
+// dictValue returns the raw value following /key in dict, up to the next
+// key at the same nesting level.
+func dictValue(dict []byte, key string) []byte {
+	re := regexp.MustCompile(`/` + key + `\b`)
+	loc := re.FindIndex(dict)
+	if loc == nil {
+		return nil
+	}
+	rest := bytes.TrimLeft(dict[loc[1]:], "\x00\t\r\n\f ")
+	depth := 0
+	for i := 0; i < len(rest); i++ {
+		switch {
+		case bytes.HasPrefix(rest[i:], []byte("<<")):
+			depth++
+			i++
+		case rest[i] == '[':
+			depth++
+		case bytes.HasPrefix(rest[i:], []byte(">>")):
+			if depth == 0 {
+				return rest[:i]
+			}
+			if depth--; depth == 0 {
+				return rest[:i+2]
+			}
+			i++
+		case rest[i] == ']':
+			if depth == 0 {
+				return rest[:i]
+			}
+			if depth--; depth == 0 {
+				return rest[:i+1]
+			}
+		case rest[i] == '/' && depth == 0 && i > 0:
+			return rest[:i]
+		}
+	}
+	return rest
+}
+
+// ── Pages ──
+
+// pageContents returns each page's content streams, concatenated, in page
+// order. Without a readable page tree it falls back to every stream that
+// draws text, in object order.
+func (d *pdfDocument) pageContents() [][]byte {
+	var pages [][]byte
+	if root := dictValue(d.trailer(), "Root"); root != nil {
+		if pagesRoot := dictValue(d.resolve(root), "Pages"); pagesRoot != nil {
+			d.walkPages(d.resolve(pagesRoot), &pages, map[int]bool{}, 0)
+		}
+	}
+	if len(pages) > 0 {
+		return pages
+	}
+
+	nums := make([]int, 0, len(d.objects))
+	for num := range d.objects {
+		nums = append(nums, num)
+	}
+	sort.Ints(nums)
+	for _, num := range nums {
+		if s := d.objects[num].stream; s != nil && bytes.Contains(s, []byte("BT")) && !bytes.Contains(s, []byte("begincmap")) {
+			pages = append(pages, s)
+		}
+	}
+	return pages
+}
+
+// walkPages appends the contents of the pages under node. Each object of
+// the tree is visited once, however often it is listed, and pages stop
+// once their contents have spent the budget.
+func (d *pdfDocument) walkPages(node []byte, pages *[][]byte, visited map[int]bool, depth int) {
+	if depth > 32 || d.budget <= 0 {
+		return
+	}
+	if kids := dictValue(node, "Kids"); kids != nil {
+		for _, num := range pdfRefs(kids) {
+			if kid, ok := d.objects[num]; ok && !visited[num] {
+				visited[num] = true
+				d.walkPages(kid.dict, pages, visited, depth+1)
+			}
+		}
+		return
+	}
+
+	contents := dictValue(node, "Contents")
+	if contents == nil {
+		return
+	}
+	if resolved := d.resolve(contents); bytes.HasPrefix(bytes.TrimSpace(resolved), []byte("[")) {
+		// An indirect array of content streams.
+		contents = resolved
+	}
+	var page []byte
+	for _, num := range pdfRefs(contents) {
+		if obj, ok := d.objects[num]; ok {
+			if len(obj.stream)+1 > d.budget {
+				d.budget = 0
+				break
+			}
+			d.budget -= len(obj.stream) + 1
+			page = append(page, obj.stream...)
+			page = append(page, '\n')
+		}
+	}
+	*pages = append(*pages, page)
+}
+
+var pdfAnyRef = regexp.MustCompile(`(\d+)\s+\d+\s+R`)
+
+func pdfRefs(value []byte) []int {
+	var nums []int
+	for _, m := range pdfAnyRef.FindAllSubmatch(value, -1) {
+		num, _ := strconv.Atoi(string(m[1]))
+		nums = append(nums, num)
+	}
+	return nums
+}
+
+// ── Fonts ──
+
+// fontMaps maps font resource names to their ToUnicode maps. Resource
+// names are per page, but producers almost always reuse a name for the
+// same font, so one document-wide table is used.
+func (d *pdfDocument) fontMaps() map[string]*cmap {
+	cmaps := map[int]*cmap{}
+	fonts := map[int]*cmap{}
+	for num, obj := range d.objects {
+		if obj.stream != nil && bytes.Contains(obj.stream, []byte("begincmap")) {
+			cmaps[num] = parseCMap(obj.stream)
+		}
+	}
+	for num, obj := range d.objects {
+		if m := pdfToUnicode.FindSubmatch(obj.dict); m != nil {
+			ref, _ := strconv.Atoi(string(m[1]))
+			if cm, ok := cmaps[ref]; ok {
+				fonts[num] = cm
+			}
+		}
+	}
+
+	names := map[string]*cmap{}
+	for _, obj := range d.objects {
+		fontDict := dictValue(obj.dict, "Font")
+		if fontDict == nil {
+			continue
+		}
+		for _, m := range pdfNameRef.FindAllSubmatch(d.resolve(fontDict), -1) {
+			ref, _ := strconv.Atoi(string(m[2]))
+			if _, seen := names[string(m[1])]; !seen {
+				names[string(m[1])] = fonts[ref]
+			}
+		}
+	}
+	return names
+}
+
+// cmap is a ToUnicode map from character codes to text.
+type cmap struct {
+	codeBytes int
+	chars     map[uint32]string
+}
+
+var (
+	cmapBFChar  = regexp.MustCompile(`(?s)beginbfchar(.*?)endbfchar`)
+	cmapBFRange = regexp.MustCompile(`(?s)beginbfrange(.*?)endbfrange`)
+	cmapHex     = regexp.MustCompile(`<([0-9A-Fa-f\s]*)>|\[([^\]]*)\]`)
+	cmapDst     = regexp.MustCompile(`<([0-9A-Fa-f\s]*)>`)
+)
+
+func parseCMap(data []byte) *cmap {
+	cm := &cmap{codeBytes: 1, chars: map[uint32]string{}}
+	for _, block := range cmapBFChar.FindAllSubmatch(data, -1) {
+		toks := cmapHex.FindAllSubmatch(block[1], -1)
+		for i := 0; i+1 < len(toks); i += 2 {
+			code, width := hexCode(toks[i][1])
+			cm.widen(width)
+			cm.chars[code] = utf16Hex(toks[i+1][1])
+		}
+	}
+	for _, block := range cmapBFRange.FindAllSubmatch(data, -1) {
+		toks := cmapHex.FindAllSubmatch(block[1], -1)
+		for i := 0; i+2 < len(toks); i += 3 {
+			lo, width := hexCode(toks[i][1])
+			hi, _ := hexCode(toks[i+1][1])
+			cm.widen(width)
+			if hi < lo || hi-lo > 0xFFFF {
+				continue
+			}
+			if toks[i+2][2] != nil {
+				// An array gives each code its own destination.
+				dsts := cmapDst.FindAllSubmatch(toks[i+2][2], -1)
+				for j, dst := range dsts {
+					if lo+uint32(j) > hi {
+						break
+					}
+					cm.chars[lo+uint32(j)] = utf16Hex(dst[1])
+				}
+				continue
+			}
+			base := []rune(utf16Hex(toks[i+2][1]))
+			if len(base) == 0 {
+				continue
+			}
+			for code := lo; code <= hi; code++ {
+				r := append([]rune{}, base...)
+				r[len(r)-1] += rune(code - lo)
+				cm.chars[code] = string(r)
+			}
+		}
+	}
+	return cm
+}
+
+func (cm *cmap) widen(width int) {
+	if width > cm.codeBytes {
+		cm.codeBytes = width
+	}
+}
+
+func (cm *cmap) decode(s []byte) string {
+	var b strings.Builder
+	for i := 0; i+cm.codeBytes <= len(s); i += cm.codeBytes {
+		var code uint32
+		for _, c := range s[i : i+cm.codeBytes] {
+			code = code<<8 | uint32(c)
+		}
+		b.WriteString(cm.chars[code])
+	}
+	return b.String()
+}
+
+func hexCode(h []byte) (uint32, int) {
+	h = bytes.Join(bytes.Fields(h), nil)
+	n, _ := strconv.ParseUint(string(h), 16, 32)
+	return uint32(n), (len(h) + 1) / 2
+}
+
+func utf16Hex(h []byte) string {
+	raw := hexBytes(h)
+	units := make([]uint16, 0, len(raw)/2)
+	for i := 0; i+1 < len(raw); i += 2 {
+		units = append(units, uint16(raw[i])<<8|uint16(raw[i+1]))
+	}
+	return string(utf16.Decode(units))
+}
+
+func hexBytes(h []byte) []byte {
+	h = bytes.Join(bytes.Fields(h), nil)
+	if len(h)%2 == 1 {
+		h = append(h, '0')
+	}
+	out := make([]byte, len(h)/2)
+	for i := range out {
+		n, _ := strconv.ParseUint(string(h[2*i:2*i+2]), 16, 8)
+		out[i] = byte(n)
+	}
+	return out
+}
+
+// ── Content Streams ──
+
+type pdfToken struct {
+	kind  byte // 'n' number, 's' string, '/' name, 'o' operator, '[' and ']'
+	num   float64
+	bytes []byte
+}
+
+// showText runs the text operators of a content stream and returns the
+// text they draw. It follows only the vertical text position: text shown
+// lower than the previous text starts a new line.
+func showText(content []byte, fonts map[string]*cmap) string {
+	lx := &pdfLexer{data: content}
+	var b strings.Builder
+	var operands []pdfToken
+	var font *cmap
+	y, shownY := 0.0, math.NaN()
+	newLine, space := false, false
+
+	show := func(s []byte) {
+		text := decodePDFString(s, font)
+		if text == "" {
+			return
+		}
+		if b.Len() > 0 {
+			if newLine || (!math.IsNaN(shownY) && math.Abs(y-shownY) > 0.5) {
+				b.WriteByte('\n')
+			} else if space {
+				b.WriteByte(' ')
+			}
+		}
+		newLine, space, shownY = false, false, y
+		b.WriteString(text)
+	}
+	operand := func(back int) pdfToken {
+		if back > len(operands) {
+			return pdfToken{}
+		}
+		return operands[len(operands)-back]
+	}
+
+	for {
+		tok, ok := lx.next()
+		if !ok {
+			return b.String()
+		}
+		if tok.kind != 'o' {
+			operands = append(operands, tok)
+			continue
+		}
+
+		switch string(tok.bytes) {
+		case "BT":
+			// Each text object starts at the origin, apart from what came
+			// before it.
+			y, space = 0, true
+		case "Tf":
+			if name := operand(2); name.kind == '/' {
+				font = fonts[string(name.bytes)]
+			}
+		case "Td", "TD":
+			if ty := operand(1).num; ty != 0 {
+				y += ty
+			} else if operand(2).num != 0 {
+				space = true
+			}
+		case "Tm":
+			y, space = operand(1).num, true
+		case "T*":
+			newLine = true
+		case "Tj":
+			show(operand(1).bytes)
+		case "'", "\"":
+			newLine = true
+			show(operand(1).bytes)
+		case "TJ":
+			for _, o := range operands {
+				switch {
+				case o.kind == 's':
+					show(o.bytes)
+				case o.kind == 'n' && o.num < -200:
+					// A large negative adjustment is a word gap.
+					space = true
+				}
+			}
+		case "ID":
+			lx.skipInlineImage()
+		}
+		operands = operands[:0]
+	}
+}
+
+func decodePDFString(s []byte, font *cmap) string {
+	if font != nil && len(font.chars) > 0 {
+		return font.decode(s)
+	}
+	if bytes.HasPrefix(s, []byte{0xFE, 0xFF}) {
+		return utf16Hex([]byte(fmt.Sprintf("%X", s[2:])))
+	}
+	// Latin-1: every byte is the code point of the same value.
+	runes := make([]rune, 0, len(s))
+	for _, c := range s {
+		if c >= 0x20 || c == '\t' {
+			runes = append(runes, rune(c))
+		}
+	}
+	return string(runes)
+}
+
+// pdfLexer splits a content stream into operands and operators.
+// Dictionaries (marked-content properties) are skipped whole.
+type pdfLexer struct {
+	data []byte
+	pos  int
+}
+
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '\f' || c == 0
+}
+
+func isPDFDelimiter(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+func (lx *pdfLexer) next() (pdfToken, bool) {
+	for lx.pos < len(lx.data) {
+		c := lx.data[lx.pos]
+		switch {
+		case isPDFSpace(c):
+			lx.pos++
+		case c == '%':
+			for lx.pos < len(lx.data) && lx.data[lx.pos] != '\n' && lx.data[lx.pos] != '\r' {
+				lx.pos++
+			}
+		case c == '(':
+			return pdfToken{kind: 's', bytes: lx.literalString()}, true
+		case c == '<' && lx.pos+1 < len(lx.data) && lx.data[lx.pos+1] == '<':
+			lx.skipDict()
+		case c == '<':
+			end := bytes.IndexByte(lx.data[lx.pos:], '>')
+			if end < 0 {
+				lx.pos = len(lx.data)
+				return pdfToken{}, false
+			}
+			h := lx.data[lx.pos+1 : lx.pos+end]
+			lx.pos += end + 1
+			return pdfToken{kind: 's', bytes: hexBytes(h)}, true
+		case c == '[' || c == ']':
+			lx.pos++
+			return pdfToken{kind: c}, true
+		case c == '/':
+			start := lx.pos + 1
+			lx.pos++
+			for lx.pos < len(lx.data) && !isPDFSpace(lx.data[lx.pos]) && !isPDFDelimiter(lx.data[lx.pos]) {
+				lx.pos++
+			}
+			return pdfToken{kind: '/', bytes: lx.data[start:lx.pos]}, true
+		case c == '>' || c == ')' || c == '{' || c == '}':
+			lx.pos++
+		default:
+			start := lx.pos
+			for lx.pos < len(lx.data) && !isPDFSpace(lx.data[lx.pos]) && !isPDFDelimiter(lx.data[lx.pos]) {
+				lx.pos++
+			}
+			word := lx.data[start:lx.pos]
+			if n, err := strconv.ParseFloat(string(word), 64); err == nil {
+				return pdfToken{kind: 'n', num: n}, true
+			}
+			return pdfToken{kind: 'o', bytes: word}, true
+		}
+	}
+	return pdfToken{}, false
+}
+
+// literalString reads a parenthesised string, with its escapes and
+// balanced inner parentheses.
+func (lx *pdfLexer) literalString() []byte {
+	lx.pos++ // (
+	var out []byte
+	depth := 1
+	for lx.pos < len(lx.data) {
+		c := lx.data[lx.pos]
+		lx.pos++
+		switch c {
+		case '(':
+			depth++
+			out = append(out, c)
+		case ')':
+			depth--
+			if depth == 0 {
+				return out
+			}
+			out = append(out, c)
+		case '\\':
+			if lx.pos >= len(lx.data) {
+				return out
+			}
+			e := lx.data[lx.pos]
+			lx.pos++
+			switch e {
+			case 'n':
+				out = append(out, '\n')
+			case 'r':
+				out = append(out, '\r')
+			case 't':
+				out = append(out, '\t')
+			case 'b', 'f':
+			case '\r':
+				if lx.pos < len(lx.data) && lx.data[lx.pos] == '\n' {
+					lx.pos++
+				}
+			case '\n':
+			default:
+				if e >= '0' && e <= '7' {
+					n := int(e - '0')
+					for k := 0; k < 2 && lx.pos < len(lx.data) && lx.data[lx.pos] >= '0' && lx.data[lx.pos] <= '7'; k++ {
+						n = n*8 + int(lx.data[lx.pos]-'0')
+						lx.pos++
+					}
+					out = append(out, byte(n))
+				} else {
+					out = append(out, e)
+				}
+			}
+		default:
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func (lx *pdfLexer) skipDict() {
+	depth := 0
+	for lx.pos < len(lx.data) {
+		switch {
+		case bytes.HasPrefix(lx.data[lx.pos:], []byte("<<")):
+			depth++
+			lx.pos += 2
+		case bytes.HasPrefix(lx.data[lx.pos:], []byte(">>")):
+			depth--
+			lx.pos += 2
+			if depth == 0 {
+				return
+			}
+		case lx.data[lx.pos] == '(':
+			lx.literalString()
+		default:
+			lx.pos++
+		}
+	}
+}
+
+// skipInlineImage moves past the binary data of an inline image, which
+// runs from ID to an EI surrounded by white space.
+func (lx *pdfLexer) skipInlineImage() {
+	for i := lx.pos; i+2 < len(lx.data); i++ {
+		if lx.data[i] == 'E' && lx.data[i+1] == 'I' && isPDFSpace(lx.data[i-1]) &&
+			(i+2 == len(lx.data) || isPDFSpace(lx.data[i+2])) {
+			lx.pos = i + 2
+			return
+		}
+	}
+	lx.pos = len(lx.data)
+}
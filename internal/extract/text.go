@@ -0,0 +1,116 @@
+package extract
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+func extractText(data []byte, _ int) ([]string, error) {
+	return []string{plainText(data)}, nil
+}
+
+// plainText drops a UTF-8 byte order mark; invalid sequences are removed
+// later.
+func plainText(data []byte) string {
+	return string(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+}
+
+var (
+	mdFence     = regexp.MustCompile("(?m)^\\s*(```|~~~).*$")
+	mdImage     = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	mdLink      = regexp.MustCompile(`\[([^\]]+)\]\([^)]*\)`)
+	mdRefLink   = regexp.MustCompile(`(?m)^\s*\[[^\]]+\]:\s*\S+.*$`)
+	mdHeading   = regexp.MustCompile(`(?m)^\s{0,3}#{1,6}\s*`)
+	mdQuote     = regexp.MustCompile(`(?m)^\s{0,3}>\s?`)
+	mdListItem  = regexp.MustCompile(`(?m)^\s*([-*+]|\d+[.)])\s+`)
+	mdRule      = regexp.MustCompile(`(?m)^\s{0,3}([-*_]\s*){3,}$`)
+	mdEmphasis  = regexp.MustCompile(`(\*{1,3}|_{1,3}|~~)([^*_~\n]+)(\*{1,3}|_{1,3}|~~)`)
+	mdCodeSpan  = regexp.MustCompile("`([^`]*)`")
+	mdTableRule = regexp.MustCompile(`(?m)^\s*\|?(\s*:?-+:?\s*\|)+\s*:?-*:?\s*$`)
+)
+
+// extractMarkdown strips Markdown syntax, keeping link and image text, code
+// and table cells.
+func extractMarkdown(data []byte, _ int) ([]string, error) {
+	text := plainText(data)
+	for _, rule := range []struct {
+		re   *regexp.Regexp
+		repl string
+	}{
+		{mdFence, ""},
+		{mdRefLink, ""},
+		{mdTableRule, ""},
+		{mdRule, ""},
+		{mdImage, "$1"},
+		{mdLink, "$1"},
+		{mdHeading, ""},
+		{mdQuote, ""},
+		{mdListItem, ""},
+		{mdEmphasis, "$2"},
+		{mdCodeSpan, "$1"},
+	} {
+		text = rule.re.ReplaceAllString(text, rule.repl)
+	}
+	return []string{strings.ReplaceAll(text, "|", " ")}, nil
+}
+
+// htmlSkipped elements hold no readable text.
+var htmlSkipped = map[string]bool{
+	"script": true, "style": true, "noscript": true, "template": true,
+	"svg": true, "canvas": true, "iframe": true, "object": true,
+}
+
+// htmlBlocks end a line of text.
+var htmlBlocks = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true,
+	"br": true, "dd": true, "div": true, "dl": true, "dt": true,
+	"fieldset": true, "figcaption": true, "figure": true, "footer": true,
+	"form": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true,
+	"h6": true, "header": true, "hr": true, "li": true, "main": true,
+	"nav": true, "ol": true, "p": true, "pre": true, "section": true,
+	"table": true, "title": true, "tr": true, "ul": true,
+}
+
+// extractHTML returns the text of an HTML document with entities decoded
+// and a line break per block element.
+func extractHTML(data []byte, _ int) ([]string, error) {
+	z := html.NewTokenizer(bytes.NewReader(data))
+	var b strings.Builder
+	skipDepth := 0
+
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			return []string{b.String()}, nil
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, _ := z.TagName()
+			tag := string(name)
+			if htmlSkipped[tag] && tt == html.StartTagToken {
+				skipDepth++
+			}
+			if htmlBlocks[tag] {
+				b.WriteByte('\n')
+			}
+			if tag == "td" || tag == "th" {
+				b.WriteByte('\t')
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			tag := string(name)
+			if htmlSkipped[tag] && skipDepth > 0 {
+				skipDepth--
+			}
+			if htmlBlocks[tag] {
+				b.WriteByte('\n')
+			}
+		case html.TextToken:
+			if skipDepth == 0 {
+				b.Write(z.Text())
+			}
+		}
+	}
+}
@@ -34,7 +34,7 @@ func (h *AlertHandler) Create(c *gin.Context) {
 
 	alert, err := h.service.Create(c.Request.Context(), userID, &req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create alert"})
+		respondError(c, err, "Failed to create alert")
 		return
 	}
 	c.JSON(http.StatusCreated, alert)
@@ -49,7 +49,7 @@ func (h *AlertHandler) List(c *gin.Context) {
 
 	alerts, err := h.service.List(c.Request.Context(), userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list alerts"})
+		respondError(c, err, "Failed to list alerts")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"alerts": alerts})
@@ -71,7 +71,7 @@ func (h *AlertHandler) Update(c *gin.Context) {
 
 	alert, err := h.service.Update(c.Request.Context(), userID, alertID, &req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update alert"})
+		respondError(c, err, "Failed to update alert")
 		return
 	}
 	c.JSON(http.StatusOK, alert)
@@ -86,7 +86,7 @@ func (h *AlertHandler) Delete(c *gin.Context) {
 
 	alertID := c.Param("id")
 	if err := h.service.Delete(c.Request.Context(), userID, alertID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete alert"})
+		respondError(c, err, "Failed to delete alert")
 		return
 	}
 	c.JSON(http.StatusNoContent, nil)
@@ -101,7 +101,7 @@ func (h *AlertHandler) GetHistory(c *gin.Context) {
 
 	history, err := h.service.GetHistory(c.Request.Context(), alertID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get alert history"})
+		respondError(c, err, "Failed to get alert history")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"history": history})
@@ -28,7 +28,7 @@ func (h *AnalyticsHandler) GetAnalytics(c *gin.Context) {
 
 	analytics, err := h.service.GetAnalytics(c.Request.Context(), workspaceID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get analytics"})
+		respondError(c, err, "Failed to get analytics")
 		return
 	}
 	c.JSON(http.StatusOK, analytics)
@@ -50,7 +50,7 @@ func (h *AnalyticsHandler) GetPopularQueries(c *gin.Context) {
 
 	queries, err := h.service.GetPopularQueries(c.Request.Context(), workspaceID, limit)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get popular queries"})
+		respondError(c, err, "Failed to get popular queries")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"popular_queries": queries})
@@ -64,7 +64,7 @@ func (h *AnalyticsHandler) ClearAnalytics(c *gin.Context) {
 	}
 
 	if err := h.service.ClearAnalytics(c.Request.Context(), workspaceID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clear analytics"})
+		respondError(c, err, "Failed to clear analytics")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "Analytics cleared"})
@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/quckapp/search-service/internal/models"
+	"github.com/quckapp/search-service/internal/service"
+)
+
+type APIKeyHandler struct {
+	service *service.APIKeyService
+	logger  *logrus.Logger
+}
+
+func NewAPIKeyHandler(svc *service.APIKeyService, logger *logrus.Logger) *APIKeyHandler {
+	return &APIKeyHandler{service: svc, logger: logger}
+}
+
+func (h *APIKeyHandler) Create(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req models.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	key, err := h.service.Create(c.Request.Context(), userID, &req)
+	if err != nil {
+		respondError(c, err, "Failed to create API key")
+		return
+	}
+	c.JSON(http.StatusCreated, key)
+}
+
+func (h *APIKeyHandler) List(c *gin.Context) {
+	keys, err := h.service.List(c.Request.Context())
+	if err != nil {
+		respondError(c, err, "Failed to list API keys")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"api_keys": keys})
+}
+
+func (h *APIKeyHandler) Get(c *gin.Context) {
+	key, err := h.service.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondError(c, err, "Failed to get API key")
+		return
+	}
+	c.JSON(http.StatusOK, key)
+}
+
+func (h *APIKeyHandler) Delete(c *gin.Context) {
+	if err := h.service.Delete(c.Request.Context(), c.Param("id")); err != nil {
+		respondError(c, err, "Failed to delete API key")
+		return
+	}
+	c.JSON(http.StatusNoContent, nil)
+}
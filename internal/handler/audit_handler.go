@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/quckapp/search-service/internal/apperror"
+	"github.com/quckapp/search-service/internal/models"
+	"github.com/quckapp/search-service/internal/service"
+)
+
+type AuditHandler struct {
+	service *service.AuditService
+	logger  *logrus.Logger
+}
+
+func NewAuditHandler(svc *service.AuditService, logger *logrus.Logger) *AuditHandler {
+	return &AuditHandler{service: svc, logger: logger}
+}
+
+func (h *AuditHandler) Query(c *gin.Context) {
+	var q models.AuditQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := h.service.Query(c.Request.Context(), &q)
+	if err != nil {
+		respondError(c, err, "Failed to query audit log")
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// ── Audit Snapshots ──
+//
+// These load the state a mutating admin route is about to change, for
+// middleware.AuditSnapshot. They read the same parameters as the handler.
+
+func (h *IndexManagementHandler) IndexSnapshot(c *gin.Context, body []byte) (interface{}, error) {
+	index := c.Param("index")
+	if index == "" {
+		var req struct {
+			Index string `json:"index"`
+		}
+		json.Unmarshal(body, &req)
+		index = req.Index
+	}
+	if index == "" {
+		return nil, apperror.BadQuery("Index name is required", nil)
+	}
+	return h.service.GetIndexInfo(c.Request.Context(), index)
+}
+
+func (h *RelevanceHandler) ConfigSnapshot(c *gin.Context, _ []byte) (interface{}, error) {
+	return h.service.GetConfig(c.Request.Context(), c.Query("workspace_id"))
+}
+
+func (h *SynonymHandler) SynonymSnapshot(c *gin.Context, _ []byte) (interface{}, error) {
+	groups, err := h.service.List(c.Request.Context(), c.Query("workspace_id"))
+	if err != nil {
+		return nil, err
+	}
+	for _, group := range groups {
+		if group.ID == c.Param("id") {
+			return group, nil
+		}
+	}
+	return nil, apperror.NotFound("Synonym group not found")
+}
+
+func (h *AnalyticsHandler) AnalyticsSnapshot(c *gin.Context, _ []byte) (interface{}, error) {
+	return h.service.GetAnalytics(c.Request.Context(), c.Query("workspace_id"))
+}
+
+func (h *Extended2Handler) RewriteSnapshot(c *gin.Context, _ []byte) (interface{}, error) {
+	return h.service.GetRewrite(c.Request.Context(), c.Param("id"))
+}
+
+func (h *Extended2Handler) PipelineSnapshot(c *gin.Context, _ []byte) (interface{}, error) {
+	return h.service.GetPipeline(c.Request.Context(), c.Param("id"))
+}
+
+func (h *QuotaHandler) QuotaSnapshot(c *gin.Context, _ []byte) (interface{}, error) {
+	return h.service.GetIndexQuota(c.Request.Context(), c.Param("workspace_id"))
+}
+
+func (h *CurationHandler) CurationSnapshot(c *gin.Context, _ []byte) (interface{}, error) {
+	return h.service.Get(c.Request.Context(), c.Query("workspace_id"), c.Param("id"))
+}
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/quckapp/search-service/internal/models"
+	"github.com/quckapp/search-service/internal/service"
+)
+
+type ClickHandler struct {
+	service *service.ClickService
+	logger  *logrus.Logger
+}
+
+func NewClickHandler(svc *service.ClickService, logger *logrus.Logger) *ClickHandler {
+	return &ClickHandler{service: svc, logger: logger}
+}
+
+func (h *ClickHandler) LogImpression(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req models.ImpressionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.service.LogImpression(c.Request.Context(), userID, &req); err != nil {
+		respondError(c, err, "Failed to log impression")
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "Impression logged"})
+}
+
+func (h *ClickHandler) LogClick(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req models.ClickRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.service.LogClick(c.Request.Context(), userID, &req); err != nil {
+		respondError(c, err, "Failed to log click")
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "Click logged"})
+}
+
+// GetQueryCTR returns the click-through of the documents shown for the
+// query q among results of the given type.
+func (h *ClickHandler) GetQueryCTR(c *gin.Context) {
+	query := c.Query("q")
+	if query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter 'q' is required"})
+		return
+	}
+
+	ctr, err := h.service.QueryCTR(c.Request.Context(), c.DefaultQuery("type", "messages"), query)
+	if err != nil {
+		respondError(c, err, "Failed to get click-through rates")
+		return
+	}
+	c.JSON(http.StatusOK, ctr)
+}
+
+// Aggregate runs the click aggregation job now rather than waiting for its
+// next scheduled run.
+func (h *ClickHandler) Aggregate(c *gin.Context) {
+	result, err := h.service.Aggregate(c.Request.Context())
+	if err != nil {
+		respondError(c, err, "Failed to aggregate clicks")
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
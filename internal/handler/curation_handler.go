@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/quckapp/search-service/internal/models"
+	"github.com/quckapp/search-service/internal/service"
+)
+
+type CurationHandler struct {
+	service *service.CurationService
+	logger  *logrus.Logger
+}
+
+func NewCurationHandler(svc *service.CurationService, logger *logrus.Logger) *CurationHandler {
+	return &CurationHandler{service: svc, logger: logger}
+}
+
+// List returns the workspace's curations, optionally only those of the
+// search type given by type.
+func (h *CurationHandler) List(c *gin.Context) {
+	workspaceID := c.Query("workspace_id")
+	if workspaceID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "workspace_id is required"})
+		return
+	}
+
+	curations, err := h.service.List(c.Request.Context(), workspaceID, c.Query("type"))
+	if err != nil {
+		respondError(c, err, "Failed to list curations")
+		return
+	}
+	c.JSON(http.StatusOK, curations)
+}
+
+func (h *CurationHandler) Create(c *gin.Context) {
+	workspaceID := c.Query("workspace_id")
+	if workspaceID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "workspace_id is required"})
+		return
+	}
+
+	var req models.CurationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	curation, err := h.service.Create(c.Request.Context(), workspaceID, &req)
+	if err != nil {
+		respondError(c, err, "Failed to create curation")
+		return
+	}
+	c.JSON(http.StatusCreated, curation)
+}
+
+func (h *CurationHandler) Get(c *gin.Context) {
+	workspaceID := c.Query("workspace_id")
+	if workspaceID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "workspace_id is required"})
+		return
+	}
+
+	curation, err := h.service.Get(c.Request.Context(), workspaceID, c.Param("id"))
+	if err != nil {
+		respondError(c, err, "Failed to get curation")
+		return
+	}
+	c.JSON(http.StatusOK, curation)
+}
+
+func (h *CurationHandler) Update(c *gin.Context) {
+	workspaceID := c.Query("workspace_id")
+	if workspaceID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "workspace_id is required"})
+		return
+	}
+
+	var req models.CurationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	curation, err := h.service.Update(c.Request.Context(), workspaceID, c.Param("id"), &req)
+	if err != nil {
+		respondError(c, err, "Failed to update curation")
+		return
+	}
+	c.JSON(http.StatusOK, curation)
+}
+
+func (h *CurationHandler) Delete(c *gin.Context) {
+	workspaceID := c.Query("workspace_id")
+	if workspaceID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "workspace_id is required"})
+		return
+	}
+
+	if err := h.service.Delete(c.Request.Context(), workspaceID, c.Param("id")); err != nil {
+		respondError(c, err, "Failed to delete curation")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Curation deleted"})
+}
+
+// Preview shows a query's results with and without an unsaved curation.
+func (h *CurationHandler) Preview(c *gin.Context) {
+	workspaceID := c.Query("workspace_id")
+	if workspaceID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "workspace_id is required"})
+		return
+	}
+
+	var req models.CurationPreviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	preview, err := h.service.Preview(c.Request.Context(), workspaceID, &req)
+	if err != nil {
+		respondError(c, err, "Failed to preview curation")
+		return
+	}
+	c.JSON(http.StatusOK, preview)
+}
+
+// PreviewSaved shows the results of q, or of the curation's pattern, with
+// and without a stored curation.
+func (h *CurationHandler) PreviewSaved(c *gin.Context) {
+	workspaceID := c.Query("workspace_id")
+	if workspaceID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "workspace_id is required"})
+		return
+	}
+
+	preview, err := h.service.PreviewSaved(c.Request.Context(), workspaceID, c.Param("id"), c.Query("q"))
+	if err != nil {
+		respondError(c, err, "Failed to preview curation")
+		return
+	}
+	c.JSON(http.StatusOK, preview)
+}
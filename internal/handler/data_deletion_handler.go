@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/quckapp/search-service/internal/models"
+	"github.com/quckapp/search-service/internal/service"
+)
+
+type DataDeletionHandler struct {
+	service *service.DataDeletionService
+	logger  *logrus.Logger
+}
+
+func NewDataDeletionHandler(svc *service.DataDeletionService, logger *logrus.Logger) *DataDeletionHandler {
+	return &DataDeletionHandler{service: svc, logger: logger}
+}
+
+func (h *DataDeletionHandler) DeleteWorkspaceData(c *gin.Context) {
+	job, err := h.service.StartWorkspaceDeletion(c.Request.Context(), c.Param("workspace_id"), getUserID(c))
+	if err != nil {
+		respondError(c, err, "Failed to start workspace data deletion")
+		return
+	}
+	h.run(job)
+	c.JSON(http.StatusAccepted, job)
+}
+
+func (h *DataDeletionHandler) DeleteUserData(c *gin.Context) {
+	job, err := h.service.StartUserErasure(c.Request.Context(), c.Param("user_id"), getUserID(c))
+	if err != nil {
+		respondError(c, err, "Failed to start user data erasure")
+		return
+	}
+	h.run(job)
+	c.JSON(http.StatusAccepted, job)
+}
+
+// run performs the job in the background; poll GetJob for progress and the
+// completion report.
+func (h *DataDeletionHandler) run(job *models.DataDeletionJob) {
+	go func() {
+		if err := h.service.Run(context.Background(), job.ID); err != nil {
+			h.logger.WithError(err).WithField("job_id", job.ID).Error("Data deletion failed")
+		}
+	}()
+}
+
+func (h *DataDeletionHandler) ListJobs(c *gin.Context) {
+	jobs, err := h.service.List(c.Request.Context())
+	if err != nil {
+		respondError(c, err, "Failed to list data deletion jobs")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"jobs": jobs, "total": len(jobs)})
+}
+
+func (h *DataDeletionHandler) GetJob(c *gin.Context) {
+	job, err := h.service.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondError(c, err, "Failed to get data deletion job")
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/quckapp/search-service/internal/models"
+	"github.com/quckapp/search-service/internal/service"
+)
+
+type DeadLetterHandler struct {
+	service *service.DeadLetterService
+	logger  *logrus.Logger
+}
+
+func NewDeadLetterHandler(svc *service.DeadLetterService, logger *logrus.Logger) *DeadLetterHandler {
+	return &DeadLetterHandler{service: svc, logger: logger}
+}
+
+func (h *DeadLetterHandler) List(c *gin.Context) {
+	var q models.DeadLetterQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	list, err := h.service.List(c.Request.Context(), &q)
+	if err != nil {
+		respondError(c, err, "Failed to list dead letters")
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+func (h *DeadLetterHandler) Get(c *gin.Context) {
+	dl, err := h.service.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondError(c, err, "Failed to get dead letter")
+		return
+	}
+	c.JSON(http.StatusOK, dl)
+}
+
+// Replay retries the write now. On success the entry is gone; on failure
+// the attempt is recorded and the error returned.
+func (h *DeadLetterHandler) Replay(c *gin.Context) {
+	if err := h.service.Replay(c.Request.Context(), c.Param("id")); err != nil {
+		respondError(c, err, "Failed to replay dead letter")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Dead letter replayed"})
+}
+
+func (h *DeadLetterHandler) Discard(c *gin.Context) {
+	if err := h.service.Discard(c.Request.Context(), c.Param("id")); err != nil {
+		respondError(c, err, "Failed to discard dead letter")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Dead letter discarded"})
+}
+
+func (h *DeadLetterHandler) DeadLetterSnapshot(c *gin.Context, _ []byte) (interface{}, error) {
+	return h.service.Get(c.Request.Context(), c.Param("id"))
+}
@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/quckapp/search-service/internal/apperror"
+)
+
+// respondError writes err as a JSON error body with the status code for its
+// kind. Untyped errors are reported as internal errors with the fallback
+// message so backend details do not leak to clients.
+func respondError(c *gin.Context, err error, fallback string) {
+	c.Error(err)
+
+	kind := apperror.KindOf(err)
+	body := gin.H{"error": fallback, "code": kind}
+	if appErr := apperror.As(err); appErr != nil {
+		if appErr.Message != "" {
+			body["error"] = appErr.Message
+		}
+		// Client-caused failures carry the backend's reason so callers can
+		// fix their request.
+		switch appErr.Kind {
+		case apperror.KindBadQuery, apperror.KindIndexNotFound, apperror.KindNotFound, apperror.KindConflict:
+			if appErr.Err != nil {
+				body["details"] = appErr.Err.Error()
+			}
+		}
+	}
+	if requestID, ok := c.Get("request_id"); ok {
+		body["request_id"] = requestID
+	}
+
+	c.JSON(apperror.HTTPStatus(kind), body)
+}
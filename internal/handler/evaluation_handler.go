@@ -0,0 +1,185 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/quckapp/search-service/internal/models"
+	"github.com/quckapp/search-service/internal/service"
+)
+
+// maxJudgmentFileSize caps judgment list uploads.
+const maxJudgmentFileSize = 10 << 20
+
+type EvaluationHandler struct {
+	service *service.EvaluationService
+	logger  *logrus.Logger
+}
+
+func NewEvaluationHandler(svc *service.EvaluationService, logger *logrus.Logger) *EvaluationHandler {
+	return &EvaluationHandler{service: svc, logger: logger}
+}
+
+func (h *EvaluationHandler) ListJudgmentLists(c *gin.Context) {
+	workspaceID := c.Query("workspace_id")
+	if workspaceID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "workspace_id is required"})
+		return
+	}
+
+	lists, err := h.service.ListLists(c.Request.Context(), workspaceID)
+	if err != nil {
+		respondError(c, err, "Failed to list judgment lists")
+		return
+	}
+	c.JSON(http.StatusOK, lists)
+}
+
+func (h *EvaluationHandler) CreateJudgmentList(c *gin.Context) {
+	workspaceID := c.Query("workspace_id")
+	if workspaceID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "workspace_id is required"})
+		return
+	}
+
+	var req models.CreateJudgmentListRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	list, err := h.service.CreateList(c.Request.Context(), workspaceID, "api", &req)
+	if err != nil {
+		respondError(c, err, "Failed to create judgment list")
+		return
+	}
+	c.JSON(http.StatusCreated, list)
+}
+
+// ImportJudgmentList creates a judgment list from an uploaded CSV or JSON
+// file, sent as the multipart part "file" beside "name" and "index" fields.
+func (h *EvaluationHandler) ImportJudgmentList(c *gin.Context) {
+	workspaceID := c.Query("workspace_id")
+	if workspaceID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "workspace_id is required"})
+		return
+	}
+	name := c.PostForm("name")
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+		return
+	}
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Request must include a 'file' part"})
+		return
+	}
+	if header.Size > maxJudgmentFileSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "File is too large", "max_bytes": maxJudgmentFileSize})
+		return
+	}
+
+	f, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read uploaded file"})
+		return
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read uploaded file"})
+		return
+	}
+
+	queries, err := service.ParseJudgments(header.Filename, data)
+	if err != nil {
+		respondError(c, err, "Failed to parse judgments")
+		return
+	}
+	list, err := h.service.CreateList(c.Request.Context(), workspaceID, "file", &models.CreateJudgmentListRequest{
+		Name:    name,
+		Index:   c.PostForm("index"),
+		Queries: queries,
+	})
+	if err != nil {
+		respondError(c, err, "Failed to import judgment list")
+		return
+	}
+	c.JSON(http.StatusCreated, list)
+}
+
+func (h *EvaluationHandler) JudgmentListFromFeedback(c *gin.Context) {
+	workspaceID := c.Query("workspace_id")
+	if workspaceID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "workspace_id is required"})
+		return
+	}
+
+	var req models.JudgmentsFromFeedbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	list, err := h.service.ListFromFeedback(c.Request.Context(), workspaceID, &req)
+	if err != nil {
+		respondError(c, err, "Failed to build judgment list from feedback")
+		return
+	}
+	c.JSON(http.StatusCreated, list)
+}
+
+func (h *EvaluationHandler) GetJudgmentList(c *gin.Context) {
+	workspaceID := c.Query("workspace_id")
+	if workspaceID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "workspace_id is required"})
+		return
+	}
+
+	list, err := h.service.GetList(c.Request.Context(), workspaceID, c.Param("id"))
+	if err != nil {
+		respondError(c, err, "Failed to get judgment list")
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+func (h *EvaluationHandler) DeleteJudgmentList(c *gin.Context) {
+	workspaceID := c.Query("workspace_id")
+	if workspaceID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "workspace_id is required"})
+		return
+	}
+
+	if err := h.service.DeleteList(c.Request.Context(), workspaceID, c.Param("id")); err != nil {
+		respondError(c, err, "Failed to delete judgment list")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Judgment list deleted"})
+}
+
+// Evaluate scores the workspace's relevance config, and optionally a
+// candidate change to it, against a stored judgment list.
+func (h *EvaluationHandler) Evaluate(c *gin.Context) {
+	workspaceID := c.Query("workspace_id")
+	if workspaceID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "workspace_id is required"})
+		return
+	}
+
+	var req models.EvaluationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := h.service.Evaluate(c.Request.Context(), workspaceID, &req)
+	if err != nil {
+		respondError(c, err, "Failed to evaluate relevance")
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
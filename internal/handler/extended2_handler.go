@@ -48,7 +48,7 @@ func (h *Extended2Handler) CreateTemplate(c *gin.Context) {
 		IsPublic:    req.IsPublic,
 	}
 	if err := h.service.CreateTemplate(c.Request.Context(), t); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create template"})
+		respondError(c, err, "Failed to create template")
 		return
 	}
 	c.JSON(http.StatusCreated, gin.H{"success": true, "data": t})
@@ -68,7 +68,7 @@ func (h *Extended2Handler) ListTemplates(c *gin.Context) {
 	userID := getUserID(c)
 	results, err := h.service.ListTemplates(c.Request.Context(), userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list templates"})
+		respondError(c, err, "Failed to list templates")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"success": true, "data": results})
@@ -82,7 +82,7 @@ func (h *Extended2Handler) UpdateTemplate(c *gin.Context) {
 		return
 	}
 	if err := h.service.UpdateTemplate(c.Request.Context(), userID, c.Param("id"), req); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update template"})
+		respondError(c, err, "Failed to update template")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"success": true})
@@ -91,7 +91,7 @@ func (h *Extended2Handler) UpdateTemplate(c *gin.Context) {
 func (h *Extended2Handler) DeleteTemplate(c *gin.Context) {
 	userID := getUserID(c)
 	if err := h.service.DeleteTemplate(c.Request.Context(), userID, c.Param("id")); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete template"})
+		respondError(c, err, "Failed to delete template")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"success": true})
@@ -100,7 +100,7 @@ func (h *Extended2Handler) DeleteTemplate(c *gin.Context) {
 func (h *Extended2Handler) UseTemplate(c *gin.Context) {
 	userID := getUserID(c)
 	if err := h.service.IncrementTemplateUsage(c.Request.Context(), userID, c.Param("id")); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record usage"})
+		respondError(c, err, "Failed to record usage")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"success": true})
@@ -134,7 +134,7 @@ func (h *Extended2Handler) BookmarkResult(c *gin.Context) {
 		Snippet:    req.Snippet,
 	}
 	if err := h.service.BookmarkResult(c.Request.Context(), b); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to bookmark"})
+		respondError(c, err, "Failed to bookmark")
 		return
 	}
 	c.JSON(http.StatusCreated, gin.H{"success": true, "data": b})
@@ -144,7 +144,7 @@ func (h *Extended2Handler) ListBookmarks(c *gin.Context) {
 	userID := getUserID(c)
 	results, err := h.service.ListBookmarks(c.Request.Context(), userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list bookmarks"})
+		respondError(c, err, "Failed to list bookmarks")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"success": true, "data": results})
@@ -153,63 +153,12 @@ func (h *Extended2Handler) ListBookmarks(c *gin.Context) {
 func (h *Extended2Handler) DeleteBookmark(c *gin.Context) {
 	userID := getUserID(c)
 	if err := h.service.DeleteBookmark(c.Request.Context(), userID, c.Param("id")); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete bookmark"})
+		respondError(c, err, "Failed to delete bookmark")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"success": true})
 }
 
-// ── Search Feedback ──
-
-func (h *Extended2Handler) SubmitFeedback(c *gin.Context) {
-	userID := getUserID(c)
-	if userID == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
-		return
-	}
-	var req struct {
-		Query    string `json:"query" binding:"required"`
-		ResultID string `json:"result_id" binding:"required"`
-		Rating   int    `json:"rating" binding:"required"`
-		Comment  string `json:"comment"`
-	}
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-	f := &service.SearchFeedback{
-		UserID:   userID,
-		Query:    req.Query,
-		ResultID: req.ResultID,
-		Rating:   req.Rating,
-		Comment:  req.Comment,
-	}
-	if err := h.service.SubmitFeedback(c.Request.Context(), f); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to submit feedback"})
-		return
-	}
-	c.JSON(http.StatusCreated, gin.H{"success": true, "data": f})
-}
-
-func (h *Extended2Handler) ListFeedback(c *gin.Context) {
-	userID := getUserID(c)
-	results, err := h.service.ListFeedback(c.Request.Context(), userID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list feedback"})
-		return
-	}
-	c.JSON(http.StatusOK, gin.H{"success": true, "data": results})
-}
-
-func (h *Extended2Handler) GetFeedbackStats(c *gin.Context) {
-	stats, err := h.service.GetFeedbackStats(c.Request.Context())
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get stats"})
-		return
-	}
-	c.JSON(http.StatusOK, gin.H{"success": true, "data": stats})
-}
-
 // ── A/B Tests ──
 
 func (h *Extended2Handler) CreateABTest(c *gin.Context) {
@@ -233,7 +182,7 @@ func (h *Extended2Handler) CreateABTest(c *gin.Context) {
 		IsActive:    true,
 	}
 	if err := h.service.CreateABTest(c.Request.Context(), t); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create A/B test"})
+		respondError(c, err, "Failed to create A/B test")
 		return
 	}
 	c.JSON(http.StatusCreated, gin.H{"success": true, "data": t})
@@ -251,7 +200,7 @@ func (h *Extended2Handler) GetABTest(c *gin.Context) {
 func (h *Extended2Handler) ListABTests(c *gin.Context) {
 	results, err := h.service.ListABTests(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list A/B tests"})
+		respondError(c, err, "Failed to list A/B tests")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"success": true, "data": results})
@@ -259,7 +208,7 @@ func (h *Extended2Handler) ListABTests(c *gin.Context) {
 
 func (h *Extended2Handler) DeleteABTest(c *gin.Context) {
 	if err := h.service.DeleteABTest(c.Request.Context(), c.Param("id")); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete A/B test"})
+		respondError(c, err, "Failed to delete A/B test")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"success": true})
@@ -284,7 +233,7 @@ func (h *Extended2Handler) CreatePipeline(c *gin.Context) {
 		IsActive:    true,
 	}
 	if err := h.service.CreatePipeline(c.Request.Context(), p); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create pipeline"})
+		respondError(c, err, "Failed to create pipeline")
 		return
 	}
 	c.JSON(http.StatusCreated, gin.H{"success": true, "data": p})
@@ -302,7 +251,7 @@ func (h *Extended2Handler) GetPipeline(c *gin.Context) {
 func (h *Extended2Handler) ListPipelines(c *gin.Context) {
 	results, err := h.service.ListPipelines(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list pipelines"})
+		respondError(c, err, "Failed to list pipelines")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"success": true, "data": results})
@@ -315,7 +264,7 @@ func (h *Extended2Handler) UpdatePipeline(c *gin.Context) {
 		return
 	}
 	if err := h.service.UpdatePipeline(c.Request.Context(), c.Param("id"), req); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update pipeline"})
+		respondError(c, err, "Failed to update pipeline")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"success": true})
@@ -323,7 +272,7 @@ func (h *Extended2Handler) UpdatePipeline(c *gin.Context) {
 
 func (h *Extended2Handler) DeletePipeline(c *gin.Context) {
 	if err := h.service.DeletePipeline(c.Request.Context(), c.Param("id")); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete pipeline"})
+		respondError(c, err, "Failed to delete pipeline")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"success": true})
@@ -344,7 +293,7 @@ func (h *Extended2Handler) AddStopWord(c *gin.Context) {
 	if lang == "" { lang = "en" }
 	sw := &service.StopWord{Word: req.Word, Language: lang}
 	if err := h.service.AddStopWord(c.Request.Context(), sw); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add stop word"})
+		respondError(c, err, "Failed to add stop word")
 		return
 	}
 	c.JSON(http.StatusCreated, gin.H{"success": true, "data": sw})
@@ -354,7 +303,7 @@ func (h *Extended2Handler) ListStopWords(c *gin.Context) {
 	lang := c.DefaultQuery("language", "en")
 	results, err := h.service.ListStopWords(c.Request.Context(), lang)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list stop words"})
+		respondError(c, err, "Failed to list stop words")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"success": true, "data": results})
@@ -363,7 +312,7 @@ func (h *Extended2Handler) ListStopWords(c *gin.Context) {
 func (h *Extended2Handler) DeleteStopWord(c *gin.Context) {
 	lang := c.DefaultQuery("language", "en")
 	if err := h.service.DeleteStopWord(c.Request.Context(), lang, c.Param("id")); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete stop word"})
+		respondError(c, err, "Failed to delete stop word")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"success": true})
@@ -383,7 +332,7 @@ func (h *Extended2Handler) CreateRewrite(c *gin.Context) {
 	}
 	r := &service.QueryRewrite{Pattern: req.Pattern, Replacement: req.Replacement, Priority: req.Priority}
 	if err := h.service.CreateRewrite(c.Request.Context(), r); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create rewrite rule"})
+		respondError(c, err, "Failed to create rewrite rule")
 		return
 	}
 	c.JSON(http.StatusCreated, gin.H{"success": true, "data": r})
@@ -392,7 +341,7 @@ func (h *Extended2Handler) CreateRewrite(c *gin.Context) {
 func (h *Extended2Handler) ListRewrites(c *gin.Context) {
 	results, err := h.service.ListRewrites(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list rewrite rules"})
+		respondError(c, err, "Failed to list rewrite rules")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"success": true, "data": results})
@@ -405,7 +354,7 @@ func (h *Extended2Handler) UpdateRewrite(c *gin.Context) {
 		return
 	}
 	if err := h.service.UpdateRewrite(c.Request.Context(), c.Param("id"), req); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update rewrite rule"})
+		respondError(c, err, "Failed to update rewrite rule")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"success": true})
@@ -413,7 +362,7 @@ func (h *Extended2Handler) UpdateRewrite(c *gin.Context) {
 
 func (h *Extended2Handler) DeleteRewrite(c *gin.Context) {
 	if err := h.service.DeleteRewrite(c.Request.Context(), c.Param("id")); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete rewrite rule"})
+		respondError(c, err, "Failed to delete rewrite rule")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"success": true})
@@ -432,7 +381,7 @@ func (h *Extended2Handler) CreateSchedule(c *gin.Context) {
 	}
 	is := &service.IndexSchedule{IndexName: req.IndexName, Schedule: req.Schedule}
 	if err := h.service.CreateSchedule(c.Request.Context(), is); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create schedule"})
+		respondError(c, err, "Failed to create schedule")
 		return
 	}
 	c.JSON(http.StatusCreated, gin.H{"success": true, "data": is})
@@ -441,7 +390,7 @@ func (h *Extended2Handler) CreateSchedule(c *gin.Context) {
 func (h *Extended2Handler) ListSchedules(c *gin.Context) {
 	results, err := h.service.ListSchedules(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list schedules"})
+		respondError(c, err, "Failed to list schedules")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"success": true, "data": results})
@@ -454,7 +403,7 @@ func (h *Extended2Handler) UpdateSchedule(c *gin.Context) {
 		return
 	}
 	if err := h.service.UpdateSchedule(c.Request.Context(), c.Param("id"), req); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update schedule"})
+		respondError(c, err, "Failed to update schedule")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"success": true})
@@ -462,7 +411,7 @@ func (h *Extended2Handler) UpdateSchedule(c *gin.Context) {
 
 func (h *Extended2Handler) DeleteSchedule(c *gin.Context) {
 	if err := h.service.DeleteSchedule(c.Request.Context(), c.Param("id")); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete schedule"})
+		respondError(c, err, "Failed to delete schedule")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"success": true})
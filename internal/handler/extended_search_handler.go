@@ -35,7 +35,7 @@ func (h *ExtendedSearchHandler) SearchBookmarks(c *gin.Context) {
 
 	result, err := h.service.SearchBookmarks(c.Request.Context(), &params)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Search failed"})
+		respondError(c, err, "Search failed")
 		return
 	}
 	c.JSON(http.StatusOK, result)
@@ -54,7 +54,7 @@ func (h *ExtendedSearchHandler) SearchTasks(c *gin.Context) {
 
 	result, err := h.service.SearchTasks(c.Request.Context(), &params)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Search failed"})
+		respondError(c, err, "Search failed")
 		return
 	}
 	c.JSON(http.StatusOK, result)
@@ -70,7 +70,7 @@ func (h *ExtendedSearchHandler) SearchEmoji(c *gin.Context) {
 
 	result, err := h.service.SearchEmoji(c.Request.Context(), query, workspaceID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Search failed"})
+		respondError(c, err, "Search failed")
 		return
 	}
 	c.JSON(http.StatusOK, result)
@@ -85,7 +85,7 @@ func (h *ExtendedSearchHandler) AdvancedSearch(c *gin.Context) {
 
 	result, err := h.service.AdvancedSearch(c.Request.Context(), &req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Advanced search failed"})
+		respondError(c, err, "Advanced search failed")
 		return
 	}
 	c.JSON(http.StatusOK, result)
@@ -102,7 +102,7 @@ func (h *ExtendedSearchHandler) Aggregate(c *gin.Context) {
 
 	result, err := h.service.Aggregate(c.Request.Context(), &req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Aggregation failed"})
+		respondError(c, err, "Aggregation failed")
 		return
 	}
 	c.JSON(http.StatusOK, result)
@@ -136,7 +136,7 @@ func (h *ExtendedSearchHandler) UpdateDocument(c *gin.Context) {
 	}
 
 	if err := h.service.UpdateDocument(c.Request.Context(), index, id, req.Document); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update document"})
+		respondError(c, err, "Failed to update document")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"updated": true})
@@ -152,7 +152,7 @@ func (h *ExtendedSearchHandler) IndexUser(c *gin.Context) {
 	}
 
 	if err := h.service.IndexUser(c.Request.Context(), &req); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to index user"})
+		respondError(c, err, "Failed to index user")
 		return
 	}
 	c.JSON(http.StatusCreated, gin.H{"indexed": true, "id": req.ID})
@@ -166,7 +166,7 @@ func (h *ExtendedSearchHandler) IndexChannel(c *gin.Context) {
 	}
 
 	if err := h.service.IndexChannel(c.Request.Context(), &req); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to index channel"})
+		respondError(c, err, "Failed to index channel")
 		return
 	}
 	c.JSON(http.StatusCreated, gin.H{"indexed": true, "id": req.ID})
@@ -180,7 +180,7 @@ func (h *ExtendedSearchHandler) IndexBookmark(c *gin.Context) {
 	}
 
 	if err := h.service.IndexBookmark(c.Request.Context(), &req); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to index bookmark"})
+		respondError(c, err, "Failed to index bookmark")
 		return
 	}
 	c.JSON(http.StatusCreated, gin.H{"indexed": true, "id": req.ID})
@@ -194,7 +194,7 @@ func (h *ExtendedSearchHandler) IndexTask(c *gin.Context) {
 	}
 
 	if err := h.service.IndexTask(c.Request.Context(), &req); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to index task"})
+		respondError(c, err, "Failed to index task")
 		return
 	}
 	c.JSON(http.StatusCreated, gin.H{"indexed": true, "id": req.ID})
@@ -209,7 +209,7 @@ func (h *ExtendedSearchHandler) CountDocuments(c *gin.Context) {
 
 	count, err := h.service.CountDocuments(c.Request.Context(), index)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count documents"})
+		respondError(c, err, "Failed to count documents")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"index": index, "count": count})
@@ -28,7 +28,7 @@ func (h *FacetHandler) GetFacets(c *gin.Context) {
 
 	result, err := h.service.GetFacets(c.Request.Context(), &req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get facets"})
+		respondError(c, err, "Failed to get facets")
 		return
 	}
 	c.JSON(http.StatusOK, result)
@@ -46,7 +46,7 @@ func (h *FacetHandler) GetFacetedSearch(c *gin.Context) {
 
 	results, facets, err := h.service.GetFacetedSearch(c.Request.Context(), index, query, facetField, 10)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Faceted search failed"})
+		respondError(c, err, "Faceted search failed")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"results": results, "facets": facets})
@@ -61,7 +61,7 @@ func (h *FacetHandler) GetFilterOptions(c *gin.Context) {
 
 	options, err := h.service.GetFilterOptions(c.Request.Context(), workspaceID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get filter options"})
+		respondError(c, err, "Failed to get filter options")
 		return
 	}
 	c.JSON(http.StatusOK, options)
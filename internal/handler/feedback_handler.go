@@ -0,0 +1,200 @@
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/quckapp/search-service/internal/models"
+	"github.com/quckapp/search-service/internal/service"
+)
+
+// feedbackCSVColumns heads CSV exports, naming the columns of each row.
+var feedbackCSVColumns = []string{"id", "created_at", "workspace_id", "user_id", "query", "result_type", "result_id", "rating", "comment"}
+
+type FeedbackHandler struct {
+	service *service.FeedbackService
+	logger  *logrus.Logger
+}
+
+func NewFeedbackHandler(svc *service.FeedbackService, logger *logrus.Logger) *FeedbackHandler {
+	return &FeedbackHandler{service: svc, logger: logger}
+}
+
+func (h *FeedbackHandler) Submit(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	var req models.FeedbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	f, err := h.service.Submit(c.Request.Context(), userID, &req)
+	if err != nil {
+		respondError(c, err, "Failed to submit feedback")
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"success": true, "data": f})
+}
+
+// ListMine returns the caller's own feedback.
+func (h *FeedbackHandler) ListMine(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	var q models.FeedbackQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	q.UserID = userID
+
+	list, err := h.service.List(c.Request.Context(), &q)
+	if err != nil {
+		respondError(c, err, "Failed to list feedback")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": list.Entries, "total": list.Total})
+}
+
+// StatsMine aggregates the caller's own feedback, in the envelope the
+// endpoint has always used.
+func (h *FeedbackHandler) StatsMine(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	var q models.FeedbackQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	q.UserID = userID
+
+	stats, err := h.service.Stats(c.Request.Context(), &q)
+	if err != nil {
+		respondError(c, err, "Failed to get stats")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": stats})
+}
+
+// List returns the workspace's feedback, optionally filtered by user,
+// query, result, rating and time.
+func (h *FeedbackHandler) List(c *gin.Context) {
+	var q models.FeedbackQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	list, err := h.service.List(c.Request.Context(), &q)
+	if err != nil {
+		respondError(c, err, "Failed to list feedback")
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+func (h *FeedbackHandler) Stats(c *gin.Context) {
+	var q models.FeedbackQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	stats, err := h.service.Stats(c.Request.Context(), &q)
+	if err != nil {
+		respondError(c, err, "Failed to get feedback stats")
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
+// Export streams the feedback matching the query as a CSV file or a JSON
+// array, chosen by format. Entries are written as they are read, so an
+// error partway through can only end the download early.
+func (h *FeedbackHandler) Export(c *gin.Context) {
+	var q models.FeedbackQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or csv"})
+		return
+	}
+
+	w := csv.NewWriter(c.Writer)
+	enc := json.NewEncoder(c.Writer)
+	written := 0
+	begin := func() {
+		filename := "feedback-" + time.Now().UTC().Format("20060102") + "." + format
+		c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+		if format == "csv" {
+			c.Header("Content-Type", "text/csv; charset=utf-8")
+			c.Status(http.StatusOK)
+			w.Write(feedbackCSVColumns)
+		} else {
+			c.Header("Content-Type", "application/json")
+			c.Status(http.StatusOK)
+			c.Writer.WriteString("[")
+		}
+	}
+
+	err := h.service.Export(c.Request.Context(), &q, func(f *models.SearchFeedback) error {
+		if written == 0 {
+			begin()
+		}
+		written++
+		if format == "csv" {
+			return w.Write([]string{
+				f.ID, f.CreatedAt.UTC().Format(time.RFC3339), f.WorkspaceID, f.UserID,
+				csvText(f.Query), f.ResultType, f.ResultID, strconv.Itoa(f.Rating), csvText(f.Comment),
+			})
+		}
+		if written > 1 {
+			c.Writer.WriteString(",")
+		}
+		return enc.Encode(f)
+	})
+	if err != nil {
+		if written == 0 {
+			respondError(c, err, "Failed to export feedback")
+			return
+		}
+		h.logger.WithError(err).WithField("written", written).Error("Feedback export ended early")
+	}
+
+	if written == 0 {
+		begin()
+	}
+	if format == "csv" {
+		w.Flush()
+	} else {
+		c.Writer.WriteString("]")
+	}
+}
+
+// csvText keeps user-written text from being read as a formula when the
+// export is opened in a spreadsheet.
+func csvText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
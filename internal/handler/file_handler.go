@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/quckapp/search-service/internal/models"
+	"github.com/quckapp/search-service/internal/service"
+)
+
+// multipartOverhead allows for the form fields and part headers sent along
+// with an upload.
+const multipartOverhead = 1 << 20
+
+type FileHandler struct {
+	service *service.FileExtractionService
+	logger  *logrus.Logger
+}
+
+func NewFileHandler(svc *service.FileExtractionService, logger *logrus.Logger) *FileHandler {
+	return &FileHandler{service: svc, logger: logger}
+}
+
+// Upload indexes a file sent as multipart/form-data: the content in the
+// "file" part and the FileIndexRequest fields beside it.
+func (h *FileHandler) Upload(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.service.MaxFileSize()+multipartOverhead)
+
+	var req models.FileIndexRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Request must include a 'file' part"})
+		return
+	}
+	if header.Size > h.service.MaxFileSize() {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "File is too large", "max_bytes": h.service.MaxFileSize()})
+		return
+	}
+	if req.Filename == "" {
+		req.Filename = header.Filename
+	}
+
+	f, err := header.Open()
+	if err != nil {
+		respondError(c, err, "Failed to read upload")
+		return
+	}
+	defer f.Close()
+
+	result, err := h.service.IndexUpload(c.Request.Context(), &req, f)
+	if err != nil {
+		respondError(c, err, "Failed to index file")
+		return
+	}
+	c.JSON(http.StatusCreated, result)
+}
+
+// Extract indexes a file already in storage, named by the request's ref.
+func (h *FileHandler) Extract(c *gin.Context) {
+	var req models.FileIndexRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.service.IndexRef(c.Request.Context(), &req)
+	if err != nil {
+		respondError(c, err, "Failed to index file")
+		return
+	}
+	c.JSON(http.StatusCreated, result)
+}
@@ -35,7 +35,7 @@ func (h *HistoryHandler) GetHistory(c *gin.Context) {
 
 	history, err := h.service.GetHistory(c.Request.Context(), userID, limit)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get search history"})
+		respondError(c, err, "Failed to get search history")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"history": history})
@@ -49,7 +49,7 @@ func (h *HistoryHandler) ClearHistory(c *gin.Context) {
 	}
 
 	if err := h.service.ClearHistory(c.Request.Context(), userID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clear history"})
+		respondError(c, err, "Failed to clear history")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "History cleared"})
@@ -69,7 +69,7 @@ func (h *HistoryHandler) DeleteHistoryItem(c *gin.Context) {
 	}
 
 	if err := h.service.DeleteHistoryItem(c.Request.Context(), userID, historyID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete history item"})
+		respondError(c, err, "Failed to delete history item")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "History item deleted"})
@@ -22,7 +22,7 @@ func NewIndexManagementHandler(svc *service.IndexManagementService, logger *logr
 func (h *IndexManagementHandler) ListIndices(c *gin.Context) {
 	indices, err := h.service.ListIndices(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list indices"})
+		respondError(c, err, "Failed to list indices")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"indices": indices})
@@ -37,7 +37,7 @@ func (h *IndexManagementHandler) GetIndexInfo(c *gin.Context) {
 
 	info, err := h.service.GetIndexInfo(c.Request.Context(), index)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get index info"})
+		respondError(c, err, "Failed to get index info")
 		return
 	}
 	c.JSON(http.StatusOK, info)
@@ -54,7 +54,7 @@ func (h *IndexManagementHandler) CreateIndex(c *gin.Context) {
 	}
 
 	if err := h.service.CreateIndex(c.Request.Context(), req.Index, req.Mappings); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err, "Failed to create index")
 		return
 	}
 	c.JSON(http.StatusCreated, gin.H{"message": "Index created", "index": req.Index})
@@ -68,7 +68,7 @@ func (h *IndexManagementHandler) DeleteIndex(c *gin.Context) {
 	}
 
 	if err := h.service.DeleteIndex(c.Request.Context(), index); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err, "Failed to delete index")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "Index deleted", "index": index})
@@ -82,7 +82,7 @@ func (h *IndexManagementHandler) PutMapping(c *gin.Context) {
 	}
 
 	if err := h.service.PutMapping(c.Request.Context(), &req); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update mapping"})
+		respondError(c, err, "Failed to update mapping")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "Mapping updated"})
@@ -96,7 +96,7 @@ func (h *IndexManagementHandler) UpdateSettings(c *gin.Context) {
 	}
 
 	if err := h.service.UpdateSettings(c.Request.Context(), &req); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update settings"})
+		respondError(c, err, "Failed to update settings")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "Settings updated"})
@@ -110,7 +110,7 @@ func (h *IndexManagementHandler) CreateAlias(c *gin.Context) {
 	}
 
 	if err := h.service.CreateAlias(c.Request.Context(), &req); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create alias"})
+		respondError(c, err, "Failed to create alias")
 		return
 	}
 	c.JSON(http.StatusCreated, gin.H{"message": "Alias created"})
@@ -125,7 +125,7 @@ func (h *IndexManagementHandler) DeleteAlias(c *gin.Context) {
 	}
 
 	if err := h.service.DeleteAlias(c.Request.Context(), index, alias); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete alias"})
+		respondError(c, err, "Failed to delete alias")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "Alias deleted"})
@@ -139,7 +139,7 @@ func (h *IndexManagementHandler) RefreshIndex(c *gin.Context) {
 	}
 
 	if err := h.service.RefreshIndex(c.Request.Context(), index); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh index"})
+		respondError(c, err, "Failed to refresh index")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "Index refreshed", "index": index})
@@ -153,7 +153,7 @@ func (h *IndexManagementHandler) FlushIndex(c *gin.Context) {
 	}
 
 	if err := h.service.FlushIndex(c.Request.Context(), index); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to flush index"})
+		respondError(c, err, "Failed to flush index")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "Index flushed", "index": index})
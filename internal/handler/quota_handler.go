@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/quckapp/search-service/internal/models"
+	"github.com/quckapp/search-service/internal/service"
+)
+
+type QuotaHandler struct {
+	service *service.RateLimitService
+	logger  *logrus.Logger
+}
+
+func NewQuotaHandler(svc *service.RateLimitService, logger *logrus.Logger) *QuotaHandler {
+	return &QuotaHandler{service: svc, logger: logger}
+}
+
+func (h *QuotaHandler) GetIndexQuota(c *gin.Context) {
+	workspaceID := c.Param("workspace_id")
+
+	quota, err := h.service.GetIndexQuota(c.Request.Context(), workspaceID)
+	if err != nil {
+		respondError(c, err, "Failed to get indexing quota")
+		return
+	}
+	c.JSON(http.StatusOK, quota)
+}
+
+func (h *QuotaHandler) UpdateIndexQuota(c *gin.Context) {
+	workspaceID := c.Param("workspace_id")
+
+	var req models.UpdateIndexQuotaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	quota, err := h.service.UpdateIndexQuota(c.Request.Context(), workspaceID, &req)
+	if err != nil {
+		respondError(c, err, "Failed to update indexing quota")
+		return
+	}
+	c.JSON(http.StatusOK, quota)
+}
@@ -28,7 +28,7 @@ func (h *RelevanceHandler) GetConfig(c *gin.Context) {
 
 	config, err := h.service.GetConfig(c.Request.Context(), workspaceID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get relevance config"})
+		respondError(c, err, "Failed to get relevance config")
 		return
 	}
 	c.JSON(http.StatusOK, config)
@@ -49,7 +49,7 @@ func (h *RelevanceHandler) UpdateConfig(c *gin.Context) {
 
 	config, err := h.service.UpdateConfig(c.Request.Context(), workspaceID, &req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update relevance config"})
+		respondError(c, err, "Failed to update relevance config")
 		return
 	}
 	c.JSON(http.StatusOK, config)
@@ -66,7 +66,7 @@ func (h *RelevanceHandler) PreviewTuning(c *gin.Context) {
 
 	preview, err := h.service.PreviewTuning(c.Request.Context(), workspaceID, query, index)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to preview tuning"})
+		respondError(c, err, "Failed to preview tuning")
 		return
 	}
 	c.JSON(http.StatusOK, preview)
@@ -81,7 +81,7 @@ func (h *RelevanceHandler) ResetToDefaults(c *gin.Context) {
 
 	config, err := h.service.ResetToDefaults(c.Request.Context(), workspaceID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset relevance config"})
+		respondError(c, err, "Failed to reset relevance config")
 		return
 	}
 	c.JSON(http.StatusOK, config)
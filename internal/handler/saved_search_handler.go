@@ -34,7 +34,7 @@ func (h *SavedSearchHandler) Create(c *gin.Context) {
 
 	saved, err := h.service.Create(c.Request.Context(), userID, &req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save search"})
+		respondError(c, err, "Failed to save search")
 		return
 	}
 	c.JSON(http.StatusCreated, saved)
@@ -65,7 +65,7 @@ func (h *SavedSearchHandler) GetByUser(c *gin.Context) {
 
 	searches, err := h.service.GetByUser(c.Request.Context(), userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get saved searches"})
+		respondError(c, err, "Failed to get saved searches")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"saved_searches": searches})
@@ -87,7 +87,7 @@ func (h *SavedSearchHandler) Update(c *gin.Context) {
 
 	saved, err := h.service.Update(c.Request.Context(), userID, searchID, &req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update saved search"})
+		respondError(c, err, "Failed to update saved search")
 		return
 	}
 	c.JSON(http.StatusOK, saved)
@@ -102,7 +102,7 @@ func (h *SavedSearchHandler) Delete(c *gin.Context) {
 
 	searchID := c.Param("id")
 	if err := h.service.Delete(c.Request.Context(), userID, searchID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete saved search"})
+		respondError(c, err, "Failed to delete saved search")
 		return
 	}
 	c.JSON(http.StatusNoContent, nil)
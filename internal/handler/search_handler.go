@@ -2,6 +2,7 @@ package handler
 
 import (
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
@@ -34,9 +35,10 @@ func (h *SearchHandler) GlobalSearch(c *gin.Context) {
 
 	result, err := h.service.GlobalSearch(c.Request.Context(), &params)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Search failed"})
+		respondError(c, err, "Search failed")
 		return
 	}
+	h.service.RecordGlobalServed(c.Request.Context(), c.GetString("request_id"), getUserID(c), &params, result)
 	c.JSON(http.StatusOK, result)
 }
 
@@ -53,9 +55,10 @@ func (h *SearchHandler) SearchMessages(c *gin.Context) {
 
 	result, err := h.service.SearchMessages(c.Request.Context(), &params)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Search failed"})
+		respondError(c, err, "Search failed")
 		return
 	}
+	h.service.RecordServed(c.Request.Context(), "messages", c.GetString("request_id"), getUserID(c), &params, result)
 	c.JSON(http.StatusOK, result)
 }
 
@@ -72,9 +75,10 @@ func (h *SearchHandler) SearchFiles(c *gin.Context) {
 
 	result, err := h.service.SearchFiles(c.Request.Context(), &params)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Search failed"})
+		respondError(c, err, "Search failed")
 		return
 	}
+	h.service.RecordServed(c.Request.Context(), "files", c.GetString("request_id"), getUserID(c), &params, result)
 	c.JSON(http.StatusOK, result)
 }
 
@@ -91,9 +95,10 @@ func (h *SearchHandler) SearchUsers(c *gin.Context) {
 
 	result, err := h.service.SearchUsers(c.Request.Context(), &params)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Search failed"})
+		respondError(c, err, "Search failed")
 		return
 	}
+	h.service.RecordServed(c.Request.Context(), "users", c.GetString("request_id"), getUserID(c), &params, result)
 	c.JSON(http.StatusOK, result)
 }
 
@@ -110,7 +115,30 @@ func (h *SearchHandler) SearchChannels(c *gin.Context) {
 
 	result, err := h.service.SearchChannels(c.Request.Context(), &params)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Search failed"})
+		respondError(c, err, "Search failed")
+		return
+	}
+	h.service.RecordServed(c.Request.Context(), "channels", c.GetString("request_id"), getUserID(c), &params, result)
+	c.JSON(http.StatusOK, result)
+}
+
+// Explain runs a search with explanations on. The search query parameter
+// picks messages (default), files, users or channels, and id names a
+// document to explain whether or not it is on the page.
+func (h *SearchHandler) Explain(c *gin.Context) {
+	var params models.SearchParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if params.Query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter 'q' is required"})
+		return
+	}
+
+	result, err := h.service.Explain(c.Request.Context(), c.DefaultQuery("search", "messages"), c.Query("id"), &params)
+	if err != nil {
+		respondError(c, err, "Explain failed")
 		return
 	}
 	c.JSON(http.StatusOK, result)
@@ -126,7 +154,7 @@ func (h *SearchHandler) Suggest(c *gin.Context) {
 
 	result, err := h.service.Suggest(c.Request.Context(), query, workspaceID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Suggestion failed"})
+		respondError(c, err, "Suggestion failed")
 		return
 	}
 	c.JSON(http.StatusOK, result)
@@ -141,8 +169,8 @@ func (h *SearchHandler) IndexDocument(c *gin.Context) {
 		return
 	}
 
-	if err := h.service.IndexDocument(c.Request.Context(), req.Index, req.ID, req.Document); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to index document"})
+	if err := h.service.IndexDocument(c.Request.Context(), req.Index, req.ID, req.Document, req.Version); err != nil {
+		respondError(c, err, "Failed to index document")
 		return
 	}
 	c.JSON(http.StatusCreated, gin.H{"indexed": true, "id": req.ID})
@@ -161,8 +189,14 @@ func (h *SearchHandler) IndexMessage(c *gin.Context) {
 		return
 	}
 
-	if err := h.service.IndexDocument(c.Request.Context(), "quckapp_messages", id, doc); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to index message"})
+	version, err := service.DocumentVersion(doc)
+	if err != nil {
+		respondError(c, err, "Invalid document version")
+		return
+	}
+
+	if err := h.service.IndexDocument(c.Request.Context(), "quckapp_messages", id, doc, version); err != nil {
+		respondError(c, err, "Failed to index message")
 		return
 	}
 	c.JSON(http.StatusCreated, gin.H{"indexed": true})
@@ -181,8 +215,14 @@ func (h *SearchHandler) IndexFile(c *gin.Context) {
 		return
 	}
 
-	if err := h.service.IndexDocument(c.Request.Context(), "quckapp_files", id, doc); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to index file"})
+	version, err := service.DocumentVersion(doc)
+	if err != nil {
+		respondError(c, err, "Invalid document version")
+		return
+	}
+
+	if err := h.service.IndexDocument(c.Request.Context(), "quckapp_files", id, doc, version); err != nil {
+		respondError(c, err, "Failed to index file")
 		return
 	}
 	c.JSON(http.StatusCreated, gin.H{"indexed": true})
@@ -203,9 +243,19 @@ func (h *SearchHandler) DeleteFromIndex(c *gin.Context) {
 	indexType := c.Param("type")
 	id := c.Param("id")
 
+	var version int64
+	if raw := c.Query("version"); raw != "" {
+		v, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || v < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "version must be a positive integer"})
+			return
+		}
+		version = v
+	}
+
 	index := "quckapp_" + indexType
-	if err := h.service.DeleteDocument(c.Request.Context(), index, id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete document"})
+	if err := h.service.DeleteDocument(c.Request.Context(), index, id, version); err != nil {
+		respondError(c, err, "Failed to delete document")
 		return
 	}
 	c.JSON(http.StatusNoContent, nil)
@@ -219,7 +269,7 @@ func (h *SearchHandler) Reindex(c *gin.Context) {
 	}
 
 	if err := h.service.Reindex(c.Request.Context(), req.Index); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Reindex failed"})
+		respondError(c, err, "Reindex failed")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "Reindex started", "index": req.Index})
@@ -34,7 +34,7 @@ func (h *SearchScopeHandler) SetScope(c *gin.Context) {
 
 	scope, err := h.service.SetScope(c.Request.Context(), userID, &req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set search scope"})
+		respondError(c, err, "Failed to set search scope")
 		return
 	}
 	c.JSON(http.StatusOK, scope)
@@ -55,7 +55,7 @@ func (h *SearchScopeHandler) GetScope(c *gin.Context) {
 
 	scope, err := h.service.GetScope(c.Request.Context(), userID, workspaceID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get search scope"})
+		respondError(c, err, "Failed to get search scope")
 		return
 	}
 	c.JSON(http.StatusOK, scope)
@@ -70,7 +70,7 @@ func (h *SearchScopeHandler) ListAvailableScopes(c *gin.Context) {
 
 	scopes, err := h.service.ListAvailableScopes(c.Request.Context(), workspaceID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list available scopes"})
+		respondError(c, err, "Failed to list available scopes")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"available_scopes": scopes})
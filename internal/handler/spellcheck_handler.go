@@ -28,7 +28,7 @@ func (h *SpellCheckHandler) GetSuggestions(c *gin.Context) {
 
 	result, err := h.service.GetSuggestions(c.Request.Context(), text, index)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get spelling suggestions"})
+		respondError(c, err, "Failed to get spelling suggestions")
 		return
 	}
 	c.JSON(http.StatusOK, result)
@@ -44,7 +44,7 @@ func (h *SpellCheckHandler) DidYouMean(c *gin.Context) {
 
 	result, err := h.service.DidYouMean(c.Request.Context(), text, index)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get suggestions"})
+		respondError(c, err, "Failed to get suggestions")
 		return
 	}
 	c.JSON(http.StatusOK, result)
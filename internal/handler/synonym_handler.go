@@ -34,7 +34,7 @@ func (h *SynonymHandler) Create(c *gin.Context) {
 
 	group, err := h.service.Create(c.Request.Context(), userID, &req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create synonym group"})
+		respondError(c, err, "Failed to create synonym group")
 		return
 	}
 	c.JSON(http.StatusCreated, group)
@@ -49,7 +49,7 @@ func (h *SynonymHandler) List(c *gin.Context) {
 
 	groups, err := h.service.List(c.Request.Context(), workspaceID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list synonym groups"})
+		respondError(c, err, "Failed to list synonym groups")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"synonym_groups": groups})
@@ -71,7 +71,7 @@ func (h *SynonymHandler) Update(c *gin.Context) {
 
 	group, err := h.service.Update(c.Request.Context(), workspaceID, synonymID, &req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update synonym group"})
+		respondError(c, err, "Failed to update synonym group")
 		return
 	}
 	c.JSON(http.StatusOK, group)
@@ -86,7 +86,7 @@ func (h *SynonymHandler) Delete(c *gin.Context) {
 	}
 
 	if err := h.service.Delete(c.Request.Context(), workspaceID, synonymID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete synonym group"})
+		respondError(c, err, "Failed to delete synonym group")
 		return
 	}
 	c.JSON(http.StatusNoContent, nil)
@@ -101,7 +101,7 @@ func (h *SynonymHandler) ApplyToIndex(c *gin.Context) {
 
 	count, err := h.service.ApplyToIndex(c.Request.Context(), workspaceID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to apply synonyms"})
+		respondError(c, err, "Failed to apply synonyms")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "Synonyms applied", "groups_applied": count})
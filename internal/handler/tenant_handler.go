@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/quckapp/search-service/internal/models"
+	"github.com/quckapp/search-service/internal/service"
+)
+
+type TenantHandler struct {
+	service *service.TenantRoutingService
+	logger  *logrus.Logger
+}
+
+func NewTenantHandler(svc *service.TenantRoutingService, logger *logrus.Logger) *TenantHandler {
+	return &TenantHandler{service: svc, logger: logger}
+}
+
+func (h *TenantHandler) List(c *gin.Context) {
+	placements, err := h.service.List(c.Request.Context())
+	if err != nil {
+		respondError(c, err, "Failed to list tenant placements")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"tenants": placements, "total": len(placements)})
+}
+
+func (h *TenantHandler) Get(c *gin.Context) {
+	placement, err := h.service.Get(c.Request.Context(), c.Param("workspace_id"))
+	if err != nil {
+		respondError(c, err, "Failed to get tenant placement")
+		return
+	}
+	c.JSON(http.StatusOK, placement)
+}
+
+// Migrate starts moving a workspace to another placement. The data is moved
+// in the background; poll Get for progress.
+func (h *TenantHandler) Migrate(c *gin.Context) {
+	workspaceID := c.Param("workspace_id")
+
+	var req models.MigrateTenantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	placement, err := h.service.StartMigration(c.Request.Context(), workspaceID, req.Mode)
+	if err != nil {
+		respondError(c, err, "Failed to start tenant migration")
+		return
+	}
+
+	go func() {
+		if err := h.service.RunMigration(context.Background(), workspaceID); err != nil {
+			h.logger.WithError(err).WithField("workspace_id", workspaceID).Error("Tenant migration failed")
+		}
+	}()
+	c.JSON(http.StatusAccepted, placement)
+}
+
+// AbortMigration ends a running migration, for one that was interrupted,
+// so the workspace accepts writes again.
+func (h *TenantHandler) AbortMigration(c *gin.Context) {
+	placement, err := h.service.AbortMigration(c.Request.Context(), c.Param("workspace_id"))
+	if err != nil {
+		respondError(c, err, "Failed to abort tenant migration")
+		return
+	}
+	c.JSON(http.StatusOK, placement)
+}
@@ -0,0 +1,120 @@
+package middleware
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/quckapp/search-service/internal/apperror"
+	"github.com/quckapp/search-service/internal/auth"
+	"github.com/quckapp/search-service/internal/models"
+	"github.com/quckapp/search-service/internal/service"
+)
+
+// API key operations, matching the scopes a key can be issued with.
+const (
+	OpIndex   = "index"
+	OpDelete  = "delete"
+	OpReindex = "reindex"
+)
+
+// AuthOrAPIKey accepts either a service API key (X-API-Key header or an
+// "ApiKey" authorization scheme) or a user JWT. Machine callers get no roles,
+// so an API key can never reach a route guarded by RequirePermission.
+func AuthOrAPIKey(verifier *auth.Verifier, apiKeys *service.APIKeyService, logger *logrus.Logger) gin.HandlerFunc {
+	userAuth := Auth(verifier, logger)
+	return func(c *gin.Context) {
+		raw := c.GetHeader("X-API-Key")
+		if raw == "" {
+			if scheme, value, ok := strings.Cut(c.GetHeader("Authorization"), " "); ok && scheme == "ApiKey" {
+				raw = value
+			}
+		}
+		if raw == "" {
+			userAuth(c)
+			return
+		}
+
+		key, err := apiKeys.Authenticate(c.Request.Context(), raw)
+		if err != nil {
+			abortWithError(c, err)
+			return
+		}
+
+		c.Set("api_key", key)
+		c.Set("user_id", "apikey:"+key.ID)
+		c.Set("roles", []string{})
+		// A key bound to a workspace acts only there. An unbound ingestion
+		// key writes for every tenant, limited by its index scopes.
+		if key.WorkspaceID != "" {
+			c.Set("workspaces", []string{key.WorkspaceID})
+			c.Set("permissions", []string{})
+		} else {
+			c.Set("workspaces", []string{})
+			c.Set("permissions", []string{PermCrossWorkspace})
+		}
+		c.Next()
+	}
+}
+
+// RequireIndexScope checks that an API key caller may perform operation on
+// the indices the request touches. With no indices given they are read from
+// the request: the :index or :type path parameter, or the "index" fields of
+// the JSON body. User callers pass through unchanged.
+func RequireIndexScope(operation string, indices ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		v, ok := c.Get("api_key")
+		if !ok {
+			c.Next()
+			return
+		}
+		key := v.(*models.APIKey)
+
+		targets := indices
+		if len(targets) == 0 {
+			targets = requestIndices(c)
+		}
+		if len(targets) == 0 || !service.APIKeyAllows(key, operation, targets) {
+			abortWithError(c, apperror.Forbidden("API key is not allowed to "+operation+" "+strings.Join(targets, ", ")))
+			return
+		}
+		c.Next()
+	}
+}
+
+func requestIndices(c *gin.Context) []string {
+	if index := c.Param("index"); index != "" {
+		return []string{index}
+	}
+	if indexType := c.Param("type"); indexType != "" {
+		return []string{"quckapp_" + indexType}
+	}
+
+	body := peekJSONBody(c)
+	if body == nil {
+		return nil
+	}
+
+	var payload struct {
+		Index     string `json:"index"`
+		Documents []struct {
+			Index string `json:"index"`
+		} `json:"documents"`
+	}
+	if json.Unmarshal(body, &payload) != nil {
+		return nil
+	}
+
+	var out []string
+	if payload.Index != "" {
+		out = append(out, payload.Index)
+	}
+	for _, doc := range payload.Documents {
+		if doc.Index != "" && !containsString(out, doc.Index) {
+			out = append(out, doc.Index)
+		}
+	}
+	return out
+}
@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/quckapp/search-service/internal/models"
+	"github.com/quckapp/search-service/internal/service"
+)
+
+// Snapshot loads the current state of the resource a route changes. body is
+// the request body, which the handler has not consumed yet.
+type Snapshot func(c *gin.Context, body []byte) (interface{}, error)
+
+// Audit appends an entry to the audit log for every mutating request in the
+// group, including refused ones, once the handler has run. Routes using
+// AuditSnapshot contribute before/after state; for the others the request
+// body is recorded as the after state.
+func Audit(audit *service.AuditService, logger *logrus.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead || c.Request.Method == http.MethodOptions {
+			c.Next()
+			return
+		}
+
+		body := peekJSONBody(c)
+		c.Next()
+
+		entry := &models.AuditEntry{
+			Actor:       c.GetString("user_id"),
+			Roles:       c.GetStringSlice("roles"),
+			WorkspaceID: c.GetString("workspace_id"),
+			Action:      c.Request.Method + " " + strings.TrimPrefix(c.FullPath(), "/api/v1"),
+			Status:      c.Writer.Status(),
+			RequestID:   c.GetString("request_id"),
+		}
+		if len(c.Params) > 0 {
+			entry.Target = make(map[string]string, len(c.Params))
+			for _, p := range c.Params {
+				entry.Target[p.Key] = p.Value
+			}
+		}
+
+		if before, ok := c.Get("audit_before"); ok {
+			entry.Before = before
+			entry.After, _ = c.Get("audit_after")
+		} else if len(body) > 0 {
+			var after interface{}
+			if json.Unmarshal(body, &after) == nil {
+				entry.After = after
+			}
+		}
+
+		// Record logs entries it cannot store, so the error needs no handling.
+		audit.Record(c.Request.Context(), entry)
+	}
+}
+
+// AuditSnapshot records the state load returns before the handler runs and,
+// if the change succeeded, after it. Deletions have no after state.
+func AuditSnapshot(load Snapshot) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body := peekJSONBody(c)
+		before, err := load(c, body)
+		if err != nil {
+			// The resource may not exist yet; the handler reports real errors.
+			before = nil
+		}
+		c.Set("audit_before", before)
+		c.Next()
+
+		if c.Request.Method != http.MethodDelete && c.Writer.Status() < http.StatusBadRequest {
+			if after, err := load(c, body); err == nil {
+				c.Set("audit_after", after)
+			}
+		}
+	}
+}
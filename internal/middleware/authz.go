@@ -0,0 +1,151 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/quckapp/search-service/internal/apperror"
+)
+
+// Permissions guarding the administrative route groups.
+const (
+	PermIndicesManage   = "indices:manage"
+	PermAnalyticsRead   = "analytics:read"
+	PermAnalyticsManage = "analytics:manage"
+	PermRelevanceManage = "relevance:manage"
+	PermSynonymsManage  = "synonyms:manage"
+	PermStopWordsManage = "stopwords:manage"
+	PermRewritesManage  = "rewrites:manage"
+	PermPipelinesManage = "pipelines:manage"
+	PermSchedulesManage = "schedules:manage"
+	PermABTestsManage   = "abtests:manage"
+	PermQuotasManage    = "quotas:manage"
+	PermAPIKeysManage   = "apikeys:manage"
+	PermTenantsManage   = "tenants:manage"
+	PermAuditRead       = "audit:read"
+	PermDeadLetters     = "deadletters:manage"
+	// PermDataErase deletes a workspace's or user's data for good. No role
+	// holds it; grant it directly to the operators who need it.
+	PermDataErase = "data:erase"
+)
+
+// explicitPermissions are never implied by a wildcard, not even admin's
+// "*": a token must grant them by name.
+var explicitPermissions = map[string]bool{
+	PermCrossWorkspace: true,
+	PermDataErase:      true,
+}
+
+const (
+	RoleAdmin       = "admin"
+	RoleSearchAdmin = "search_admin"
+	RoleEditor      = "editor"
+	RoleAnalyst     = "analyst"
+	RoleMember      = "member"
+)
+
+// rolePermissions is the permission matrix: what each role may do in
+// addition to any permissions granted directly in the token.
+var rolePermissions = map[string][]string{
+	RoleAdmin: {"*"},
+	RoleSearchAdmin: {
+		PermIndicesManage, PermAnalyticsRead, PermAnalyticsManage,
+		PermRelevanceManage, PermSynonymsManage, PermStopWordsManage,
+		PermRewritesManage, PermPipelinesManage, PermSchedulesManage,
+		PermABTestsManage, PermQuotasManage, PermAPIKeysManage,
+		PermTenantsManage, PermAuditRead, PermDeadLetters,
+	},
+	RoleEditor: {
+		PermAnalyticsRead, PermRelevanceManage, PermSynonymsManage,
+		PermStopWordsManage, PermRewritesManage, PermPipelinesManage,
+		PermABTestsManage,
+	},
+	RoleAnalyst: {PermAnalyticsRead},
+	RoleMember:  {},
+}
+
+// RequireRole lets the request through if the caller holds any of roles.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		held := c.GetStringSlice("roles")
+		for _, role := range roles {
+			if containsString(held, role) {
+				c.Next()
+				return
+			}
+		}
+		abortWithError(c, apperror.Forbidden("Requires role: "+strings.Join(roles, " or ")))
+	}
+}
+
+// RequirePermission lets the request through if the caller's roles or token
+// grant perm.
+func RequirePermission(perm string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !HasPermission(c, perm) {
+			abortWithError(c, apperror.Forbidden("Requires permission: "+perm))
+			return
+		}
+		c.Next()
+	}
+}
+
+// HasPermission reports whether the caller holds perm, either exactly, as a
+// "resource:*" wildcard, or through the "*" grant. The explicitPermissions
+// match exactly only.
+func HasPermission(c *gin.Context, perm string) bool {
+	resource, _, _ := strings.Cut(perm, ":")
+	for _, p := range c.GetStringSlice("permissions") {
+		if p == perm || (!explicitPermissions[perm] && (p == "*" || p == resource+":*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// setAuthorization stores the caller's roles and effective permissions on
+// the context. Roles come from a "role" or "roles" claim; direct grants from
+// "permissions" or an OAuth-style space-separated "scope".
+func setAuthorization(c *gin.Context, claims jwt.MapClaims) {
+	roles := append(claimStrings(claims, "role"), claimStrings(claims, "roles")...)
+	if len(roles) == 0 {
+		roles = []string{RoleMember}
+	}
+
+	perms := append(claimStrings(claims, "permissions"), claimStrings(claims, "scope")...)
+	for _, role := range roles {
+		perms = append(perms, rolePermissions[role]...)
+	}
+
+	c.Set("roles", roles)
+	c.Set("permissions", perms)
+}
+
+// claimStrings reads a claim that may be a single string (space separated)
+// or an array of strings.
+func claimStrings(claims jwt.MapClaims, key string) []string {
+	switch v := claims[key].(type) {
+	case string:
+		return strings.Fields(v)
+	case []interface{}:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok && s != "" {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
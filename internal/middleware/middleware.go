@@ -6,21 +6,29 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
+
+	"github.com/quckapp/search-service/internal/auth"
 )
 
 func Logger(logger *logrus.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
 		c.Next()
-		logger.WithFields(logrus.Fields{
+		fields := logrus.Fields{
 			"status":  c.Writer.Status(),
 			"latency": time.Since(start),
 			"method":  c.Request.Method,
 			"path":    c.Request.URL.Path,
-		}).Info("Request")
+		}
+		if requestID, ok := c.Get("request_id"); ok {
+			fields["request_id"] = requestID
+		}
+		if len(c.Errors) > 0 {
+			fields["errors"] = c.Errors.String()
+		}
+		logger.WithFields(fields).Info("Request")
 	}
 }
 
@@ -49,7 +57,9 @@ func RequestID() gin.HandlerFunc {
 	}
 }
 
-func Auth(jwtSecret string) gin.HandlerFunc {
+// Auth validates the bearer token with verifier and copies the caller's
+// identity, workspace and authorization claims onto the context.
+func Auth(verifier *auth.Verifier, logger *logrus.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -65,17 +75,20 @@ func Auth(jwtSecret string) gin.HandlerFunc {
 			return
 		}
 
-		token, err := jwt.Parse(parts[1], func(token *jwt.Token) (interface{}, error) {
-			return []byte(jwtSecret), nil
-		})
-		if err != nil || !token.Valid {
+		claims, err := verifier.Verify(c.Request.Context(), parts[1])
+		if err != nil {
+			logger.WithError(err).Debug("Rejected bearer token")
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
 			c.Abort()
 			return
 		}
 
-		claims := token.Claims.(jwt.MapClaims)
 		c.Set("user_id", claims["sub"])
+		// A token names its workspace in "workspace_id", or lists several in
+		// "workspaces"; Workspace picks the one the request acts on.
+		workspaces := append(claimStrings(claims, "workspace_id"), claimStrings(claims, "workspaces")...)
+		c.Set("workspaces", workspaces)
+		setAuthorization(c, claims)
 		c.Next()
 	}
 }
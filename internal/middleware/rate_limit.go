@@ -0,0 +1,191 @@
+package middleware
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/quckapp/search-service/internal/apperror"
+	"github.com/quckapp/search-service/internal/service"
+)
+
+// RateLimit applies the token bucket configured for group. Buckets are kept
+// per workspace and JWT subject, so one noisy user cannot exhaust the limit
+// for the rest of their workspace.
+func RateLimit(limiter *service.RateLimitService, group string, logger *logrus.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := callerWorkspace(c) + ":" + callerID(c)
+		decision, err := limiter.Allow(c.Request.Context(), group, key)
+		if err != nil {
+			// Fail open: a Redis hiccup should not reject traffic.
+			logger.WithError(err).WithField("group", group).Warn("Rate limit check failed")
+		}
+
+		if decision.Limit > 0 {
+			h := c.Writer.Header()
+			h.Set("RateLimit-Limit", strconv.Itoa(decision.Limit))
+			h.Set("RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+			h.Set("RateLimit-Reset", strconv.Itoa(int(decision.Reset.Seconds())))
+		}
+		if !decision.Allowed {
+			c.Writer.Header().Set("Retry-After", strconv.Itoa(int(decision.RetryAfter.Seconds())))
+			abortWithError(c, apperror.RateLimited("Rate limit exceeded"))
+			return
+		}
+		c.Next()
+	}
+}
+
+// IndexQuota charges the request's documents against the daily indexing
+// quota of the workspace each is written to. Bulk requests are charged one
+// unit per document; if any workspace's quota is exceeded, nothing is.
+// The charge is taken up front so an over-quota write never runs, and
+// refunded if the handler then rejects or fails the request.
+func IndexQuota(limiter *service.RateLimitService, logger *logrus.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		charges, err := quotaCharges(c)
+		if err != nil {
+			abortWithError(c, err)
+			return
+		}
+		workspaces := make([]string, 0, len(charges))
+		for ws := range charges {
+			workspaces = append(workspaces, ws)
+		}
+		sort.Strings(workspaces)
+
+		charged := make([]string, 0, len(workspaces))
+		for _, ws := range workspaces {
+			quota, err := limiter.ConsumeIndexQuota(c.Request.Context(), ws, charges[ws])
+			if quota != nil && quota.DailyLimit > 0 && len(workspaces) == 1 {
+				c.Writer.Header().Set("X-Index-Quota-Limit", strconv.FormatInt(quota.DailyLimit, 10))
+				c.Writer.Header().Set("X-Index-Quota-Remaining", strconv.FormatInt(quota.Remaining, 10))
+			}
+			if err == nil {
+				charged = append(charged, ws)
+				continue
+			}
+			if apperror.KindOf(err) == apperror.KindQuotaExceeded {
+				refundIndexQuota(c, limiter, charges, charged)
+				abortWithError(c, err)
+				return
+			}
+			logger.WithError(err).WithField("workspace_id", ws).Warn("Index quota check failed")
+		}
+		c.Next()
+
+		if c.Writer.Status() >= 400 {
+			refundIndexQuota(c, limiter, charges, charged)
+		}
+	}
+}
+
+// refundIndexQuota returns the charges taken from the given workspaces.
+func refundIndexQuota(c *gin.Context, limiter *service.RateLimitService, charges map[string]int64, workspaces []string) {
+	for _, ws := range workspaces {
+		limiter.RefundIndexQuota(c.Request.Context(), ws, charges[ws])
+	}
+}
+
+// quotaCharges returns how many documents the request writes to each
+// workspace. A caller scoped to a workspace writes only there. An
+// unrestricted caller's documents each name their own workspace, so they
+// are counted from the JSON body; other bodies, such as uploads, must have
+// their workspace named on the query string or in X-Workspace-ID, which
+// scopes the request. Documents naming no workspace are refused when
+// written, so they are not charged, but a request must name at least one:
+// a partial update is charged to the workspace it is scoped to.
+func quotaCharges(c *gin.Context) (map[string]int64, error) {
+	if ws := c.GetString("workspace_id"); ws != "" {
+		return map[string]int64{ws: documentCount(c)}, nil
+	}
+	body := peekJSONBody(c)
+	if body == nil {
+		return nil, apperror.BadQuery("workspace_id is required", nil)
+	}
+
+	type quotaDocument struct {
+		WorkspaceID string `json:"workspace_id"`
+		Document    struct {
+			WorkspaceID string `json:"workspace_id"`
+		} `json:"document"`
+	}
+	var payload struct {
+		quotaDocument
+		Documents []quotaDocument `json:"documents"`
+	}
+	json.Unmarshal(body, &payload)
+	docs := payload.Documents
+	if len(docs) == 0 {
+		docs = []quotaDocument{payload.quotaDocument}
+	}
+
+	charges := map[string]int64{}
+	for _, doc := range docs {
+		ws := doc.WorkspaceID
+		if ws == "" {
+			ws = doc.Document.WorkspaceID
+		}
+		if ws != "" {
+			charges[ws]++
+		}
+	}
+	if len(charges) == 0 {
+		return nil, apperror.BadQuery("workspace_id is required", nil)
+	}
+	return charges, nil
+}
+
+// documentCount returns the length of a "documents" array in the JSON body,
+// or 1 for single-document requests. The body is restored for the handler.
+func documentCount(c *gin.Context) int64 {
+	var payload struct {
+		Documents []json.RawMessage `json:"documents"`
+	}
+	if body := peekJSONBody(c); body != nil && json.Unmarshal(body, &payload) == nil && len(payload.Documents) > 0 {
+		return int64(len(payload.Documents))
+	}
+	return 1
+}
+
+// callerWorkspace prefers the workspace from the token and falls back to the
+// one the request names, for tokens issued without a workspace claim.
+func callerWorkspace(c *gin.Context) string {
+	if ws := c.GetString("workspace_id"); ws != "" {
+		return ws
+	}
+	if ws := c.GetHeader("X-Workspace-ID"); ws != "" {
+		return ws
+	}
+	if ws := c.Query("workspace_id"); ws != "" {
+		return ws
+	}
+	return "none"
+}
+
+func callerID(c *gin.Context) string {
+	if uid, ok := c.Get("user_id"); ok {
+		if s, ok := uid.(string); ok && s != "" {
+			return s
+		}
+	}
+	return c.ClientIP()
+}
+
+// abortWithError writes the same error body as the handlers' respondError.
+func abortWithError(c *gin.Context, err error) {
+	c.Error(err)
+
+	kind := apperror.KindOf(err)
+	body := gin.H{"error": err.Error(), "code": kind}
+	if appErr := apperror.As(err); appErr != nil {
+		body["error"] = appErr.Message
+	}
+	if requestID, ok := c.Get("request_id"); ok {
+		body["request_id"] = requestID
+	}
+	c.AbortWithStatusJSON(apperror.HTTPStatus(kind), body)
+}
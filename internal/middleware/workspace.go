@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/quckapp/search-service/internal/apperror"
+	"github.com/quckapp/search-service/internal/tenant"
+)
+
+// PermCrossWorkspace lets a caller act outside the workspaces in its token.
+const PermCrossWorkspace = "workspaces:cross"
+
+// Workspace resolves the workspace a request acts on and stores it on the
+// request context, where the services apply it to every query and write.
+//
+// The workspace a client names (:workspace_id path parameter, workspace_id
+// query parameter, X-Workspace-ID header or top-level JSON field) must be
+// one of the token's workspaces. If
+// the client names none, the token's only workspace is used and written back
+// into the query string so handlers reading workspace_id see it. Callers with
+// PermCrossWorkspace may name any workspace, or none to span all of them.
+func Workspace() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requested, ok := requestedWorkspace(c)
+		if !ok {
+			abortWithError(c, apperror.BadQuery("Conflicting workspace_id values in request", nil))
+			return
+		}
+		allowed := c.GetStringSlice("workspaces")
+		cross := HasPermission(c, PermCrossWorkspace)
+
+		var scope tenant.Scope
+		switch {
+		case requested != "" && containsString(allowed, requested):
+			scope.WorkspaceID = requested
+		case requested != "" && cross:
+			scope = tenant.Scope{WorkspaceID: requested, AllWorkspaces: true}
+		case requested != "":
+			abortWithError(c, apperror.Forbidden("Not a member of workspace "+requested))
+			return
+		case len(allowed) == 1:
+			scope.WorkspaceID = allowed[0]
+		case cross:
+			scope.AllWorkspaces = true
+		case len(allowed) > 1:
+			abortWithError(c, apperror.BadQuery("workspace_id is required", nil))
+			return
+		default:
+			abortWithError(c, apperror.Forbidden("Token is not scoped to a workspace"))
+			return
+		}
+
+		if requested == "" && scope.WorkspaceID != "" {
+			q := c.Request.URL.Query()
+			q.Set("workspace_id", scope.WorkspaceID)
+			c.Request.URL.RawQuery = q.Encode()
+		}
+		c.Set("workspace_id", scope.WorkspaceID)
+		c.Request = c.Request.WithContext(tenant.WithScope(c.Request.Context(), scope))
+		c.Next()
+	}
+}
+
+// requestedWorkspace returns the workspace the client asked for. A request
+// naming different workspaces in different places is rejected, since the
+// handler might act on either.
+func requestedWorkspace(c *gin.Context) (string, bool) {
+	var payload struct {
+		WorkspaceID string `json:"workspace_id"`
+	}
+	if body := peekJSONBody(c); body != nil {
+		json.Unmarshal(body, &payload)
+	}
+
+	requested := ""
+	for _, ws := range []string{c.Param("workspace_id"), payload.WorkspaceID, c.Query("workspace_id"), c.GetHeader("X-Workspace-ID")} {
+		if ws == "" {
+			continue
+		}
+		if requested != "" && ws != requested {
+			return "", false
+		}
+		requested = ws
+	}
+	return requested, true
+}
+
+// peekJSONBody returns the request body without consuming it, so a handler
+// can still bind it. Bodyless and non-JSON requests return nil.
+func peekJSONBody(c *gin.Context) []byte {
+	if c.Request.Body == nil || c.Request.Body == http.NoBody {
+		return nil
+	}
+	if ct := c.ContentType(); ct != "" && ct != gin.MIMEJSON {
+		return nil
+	}
+	body, err := io.ReadAll(c.Request.Body)
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return nil
+	}
+	return body
+}
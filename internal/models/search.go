@@ -12,6 +12,11 @@ type MessageDocument struct {
 	ChannelID   string    `json:"channel_id"`
 	WorkspaceID string    `json:"workspace_id"`
 	CreatedAt   time.Time `json:"created_at"`
+	// ThreadID is the ID of the thread's root message; a root carries its
+	// own ID. It defaults to ParentID, or ID for a message with no parent.
+	ThreadID   string `json:"thread_id"`
+	ParentID   string `json:"parent_id,omitempty"`
+	ReplyCount int    `json:"reply_count"`
 }
 
 type FileDocument struct {
@@ -25,6 +30,20 @@ type FileDocument struct {
 	ChannelID   string    `json:"channel_id"`
 	WorkspaceID string    `json:"workspace_id"`
 	CreatedAt   time.Time `json:"created_at"`
+	// ContentTruncated is set when Content holds only the start of a long
+	// file's text.
+	ContentTruncated bool `json:"content_truncated,omitempty"`
+	// Passages are overlapping windows of Content, stored as nested
+	// documents so search can score and return them one by one. SectionType
+	// says what their section numbers count: page, slide or sheet.
+	Passages    []FilePassage `json:"passages,omitempty"`
+	SectionType string        `json:"section_type,omitempty"`
+}
+
+type FilePassage struct {
+	Text    string `json:"text"`
+	Section int    `json:"section"`
+	Offset  int    `json:"offset"` // in words, from the start of the section
 }
 
 type UserDocument struct {
@@ -58,8 +77,58 @@ type SearchParams struct {
 	Page        int    `form:"page,default=1"`
 	PerPage     int    `form:"per_page,default=20"`
 	Sort        string `form:"sort,default=relevance"` // relevance, newest, oldest
+	// ThreadID limits message search to one thread.
+	ThreadID string `form:"thread_id"`
+	// CollapseThreads returns one result per thread, with its best matches.
+	CollapseThreads bool `form:"collapse_threads"`
+	// Mode picks how messages and files are matched: by their words
+	// (lexical), by the meaning of the query (semantic), or both with the
+	// rankings fused (hybrid).
+	Mode string `form:"mode,default=lexical"` // lexical, semantic, hybrid
+	// Explain returns how each hit was scored and how the query was built.
+	// Explained searches are never cached.
+	Explain bool `form:"explain"`
+	// DocumentID limits the search to one document, to explain a hit that
+	// is not on the page. Set by the explain endpoint only.
+	DocumentID string `form:"-"`
+	// Curation replaces the workspace's curation for the query; an empty
+	// one searches uncurated. Set by curation previews only, and never
+	// cached.
+	Curation *Curation `form:"-"`
+	// Relevance replaces the workspace's relevance config. Set by
+	// relevance evaluations only, and never cached.
+	Relevance *RelevanceConfig `form:"-"`
+
+	// HighlightFields lists the fields to highlight, comma-separated; by
+	// default every text field the search matches on.
+	HighlightFields string `form:"highlight_fields"`
+	// FragmentSize is the length of a highlighted fragment in characters,
+	// 100 by default, and Fragments how many each field returns, 3 by
+	// default.
+	FragmentSize int `form:"fragment_size"`
+	Fragments    int `form:"fragments"`
+	// PreTag and PostTag wrap each match, <em> and </em> by default. With
+	// the html encoder, the default, fragment text is HTML-escaped and only
+	// a plain element such as <mark> or <span class="hit"> may be used.
+	PreTag           string `form:"pre_tag"`
+	PostTag          string `form:"post_tag"`
+	HighlightEncoder string `form:"highlight_encoder"` // html, none
+	// HighlightOffsets returns fragments as plain, unescaped text with the
+	// character offsets of each match, instead of tagged.
+	HighlightOffsets bool `form:"highlight_offsets"`
 }
 
+const (
+	SearchModeLexical  = "lexical"
+	SearchModeSemantic = "semantic"
+	SearchModeHybrid   = "hybrid"
+)
+
+const (
+	HighlightEncoderHTML = "html"
+	HighlightEncoderNone = "none"
+)
+
 func (p *SearchParams) Validate() {
 	if p.Page < 1 {
 		p.Page = 1
@@ -70,6 +139,21 @@ func (p *SearchParams) Validate() {
 	if p.PerPage > 100 {
 		p.PerPage = 100
 	}
+	if p.FragmentSize < 1 {
+		p.FragmentSize = 100
+	}
+	if p.FragmentSize > 1000 {
+		p.FragmentSize = 1000
+	}
+	if p.Fragments < 1 {
+		p.Fragments = 3
+	}
+	if p.Fragments > 10 {
+		p.Fragments = 10
+	}
+	if p.HighlightEncoder != HighlightEncoderNone {
+		p.HighlightEncoder = HighlightEncoderHTML
+	}
 }
 
 func (p *SearchParams) From() int {
@@ -84,6 +168,29 @@ type SearchResponse struct {
 	Page       int         `json:"page"`
 	PerPage    int         `json:"per_page"`
 	TotalPages int         `json:"total_pages"`
+	TimedOut   bool        `json:"timed_out,omitempty"`
+	Shards     *ShardInfo  `json:"shards,omitempty"` // set only when some shards failed
+	// Explain describes the search that ran, when explain=true.
+	Explain *QueryExplanation `json:"explain,omitempty"`
+	// CurationID is the curation applied to the results, if any.
+	CurationID string `json:"curation_id,omitempty"`
+}
+
+// ShardInfo reports a partial search: results are present but incomplete.
+type ShardInfo struct {
+	Total      int            `json:"total"`
+	Successful int            `json:"successful"`
+	Skipped    int            `json:"skipped"`
+	Failed     int            `json:"failed"`
+	Failures   []ShardFailure `json:"failures,omitempty"`
+}
+
+type ShardFailure struct {
+	Index  string `json:"index,omitempty"`
+	Shard  int    `json:"shard"`
+	Node   string `json:"node,omitempty"`
+	Type   string `json:"type,omitempty"`
+	Reason string `json:"reason,omitempty"`
 }
 
 type SearchHit struct {
@@ -91,6 +198,124 @@ type SearchHit struct {
 	ID     string                 `json:"id"`
 	Score  float64                `json:"score"`
 	Source map[string]interface{} `json:"source"`
+	Thread *ThreadContext         `json:"thread,omitempty"` // message hits only
+	// Highlights are the hit's matching fragments, by field. A hit with
+	// none gets a fallback snippet from the start of its first field.
+	Highlights []Highlight `json:"highlights,omitempty"`
+	// Passages are the best-matching passages of a file hit, best first.
+	Passages []PassageHit `json:"passages,omitempty"`
+	// Hybrid explains the score of a hit from a hybrid search.
+	Hybrid *HybridScore `json:"hybrid,omitempty"`
+	// Explanation is how ES scored the hit, when explain=true. Hybrid hits
+	// carry one per retriever instead.
+	Explanation *HitExplanation `json:"explanation,omitempty"`
+	// Pinned marks a hit placed by a curation rather than ranked.
+	Pinned bool `json:"pinned,omitempty"`
+}
+
+// Highlight is the matching text of one field of a hit.
+type Highlight struct {
+	Field     string   `json:"field"`
+	Fragments []string `json:"fragments"`
+	// Offsets are the [start, end) character offsets of the matches in
+	// each fragment, when highlight_offsets is set.
+	Offsets [][][2]int `json:"offsets,omitempty"`
+	// Fallback marks a snippet from the start of the field, given when no
+	// field matched.
+	Fallback bool `json:"fallback,omitempty"`
+}
+
+// HybridScore breaks a hybrid hit's score into what each retriever
+// contributed. A nil component means that retriever did not return the hit.
+type HybridScore struct {
+	Fusion   string          `json:"fusion"`
+	Lexical  *ComponentScore `json:"lexical,omitempty"`
+	Semantic *ComponentScore `json:"semantic,omitempty"`
+}
+
+type ComponentScore struct {
+	Rank  int     `json:"rank,omitempty"` // 1-based, within that retriever's results
+	Score float64 `json:"score"`          // the retriever's own score
+	// Contribution is this component's share of the fused score.
+	Contribution float64 `json:"contribution,omitempty"`
+	// Explanation is how the retriever scored the hit, when explain=true.
+	Explanation *HitExplanation `json:"explanation,omitempty"`
+}
+
+// HitExplanation is the ES explanation of a hit's score, and the same
+// explanation reduced to what each field and term contributed.
+type HitExplanation struct {
+	Score         float64             `json:"score"`
+	Contributions []ScoreContribution `json:"contributions"`
+	Tree          *ExplanationNode    `json:"tree,omitempty"`
+}
+
+// ScoreContribution is the score one term added in one field. Boost is the
+// query-time boost applied to it, when not 1.
+type ScoreContribution struct {
+	Field string  `json:"field"`
+	Term  string  `json:"term,omitempty"`
+	Score float64 `json:"score"`
+	Boost float64 `json:"boost,omitempty"`
+}
+
+// ExplanationNode is a node of an ES _explanation tree.
+type ExplanationNode struct {
+	Value       float64           `json:"value"`
+	Description string            `json:"description"`
+	Details     []ExplanationNode `json:"details,omitempty"`
+}
+
+// QueryExplanation describes what this service did to a search before ES
+// scored it: the steps it applied, in order, and the request bodies sent.
+// NotApplied lists ranking stages that can be configured but that searches
+// do not run, so their settings had no effect on the results.
+type QueryExplanation struct {
+	Mode       string                   `json:"mode"`
+	Index      string                   `json:"index"`
+	Steps      []string                 `json:"steps"`
+	NotApplied []string                 `json:"not_applied"`
+	Requests   []map[string]interface{} `json:"requests"`
+}
+
+// ExplainResponse answers "why is this result here?": an explained page of
+// results and, when a document was named, that document's explanation.
+type ExplainResponse struct {
+	Type   string          `json:"type"`
+	Search *SearchResponse `json:"search"`
+	// Document is the named document's hit, explained, or nil when it does
+	// not match the query at all.
+	Document *SearchHit `json:"document,omitempty"`
+	// Rank is the document's 1-based position on the page, or 0 when it
+	// matched but ranked outside the page.
+	Rank int `json:"rank,omitempty"`
+}
+
+// PassageHit is a passage of a file that matched the query.
+type PassageHit struct {
+	Section     int     `json:"section"`
+	SectionType string  `json:"section_type,omitempty"` // page, slide or sheet
+	Offset      int     `json:"offset"`
+	Score       float64 `json:"score"`
+	Text        string  `json:"text"`
+	// Highlights are the passage's matching fragments, marked up as the
+	// search's highlight settings ask.
+	Highlights []string `json:"highlights,omitempty"`
+	// Offsets are the [start, end) character offsets of the matches in
+	// each fragment, when highlight_offsets is set.
+	Offsets [][][2]int `json:"offsets,omitempty"`
+}
+
+// ThreadContext places a message hit in its thread.
+type ThreadContext struct {
+	ID string `json:"id"`
+	// Root is the thread's root message, for a hit that is a reply. It is
+	// missing when the root has been deleted.
+	Root map[string]interface{} `json:"root,omitempty"`
+	// Matches and Replies are set when results are collapsed by thread: how
+	// many messages in the thread matched, and the first few of them.
+	Matches int64       `json:"matches,omitempty"`
+	Replies []SearchHit `json:"replies,omitempty"`
 }
 
 type GlobalSearchResponse struct {
@@ -98,6 +323,9 @@ type GlobalSearchResponse struct {
 	Files    *SearchResponse `json:"files"`
 	Users    *SearchResponse `json:"users"`
 	Channels *SearchResponse `json:"channels"`
+	// Errors maps a result type to the error kind that prevented it from
+	// being returned, when only some of the sub-searches failed.
+	Errors map[string]string `json:"errors,omitempty"`
 }
 
 // ── Index Requests ──
@@ -106,6 +334,10 @@ type IndexRequest struct {
 	Index    string                 `json:"index" binding:"required"`
 	ID       string                 `json:"id" binding:"required"`
 	Document map[string]interface{} `json:"document" binding:"required"`
+	// Version is the source system's version of the document, such as its
+	// edit timestamp in milliseconds or a sequence number. When set, a write
+	// older than the indexed document is rejected with 409.
+	Version int64 `json:"version,omitempty"`
 }
 
 type BulkIndexRequest struct {
@@ -332,6 +564,7 @@ type IndexUserRequest struct {
 	Email       string `json:"email"`
 	AvatarURL   string `json:"avatar_url"`
 	WorkspaceID string `json:"workspace_id" binding:"required"`
+	Version     int64  `json:"version,omitempty"`
 }
 
 type IndexChannelRequest struct {
@@ -341,6 +574,7 @@ type IndexChannelRequest struct {
 	Topic       string `json:"topic"`
 	Type        string `json:"type"`
 	WorkspaceID string `json:"workspace_id" binding:"required"`
+	Version     int64  `json:"version,omitempty"`
 }
 
 type IndexBookmarkRequest struct {
@@ -351,6 +585,7 @@ type IndexBookmarkRequest struct {
 	Tags        []string `json:"tags"`
 	UserID      string   `json:"user_id" binding:"required"`
 	WorkspaceID string   `json:"workspace_id" binding:"required"`
+	Version     int64    `json:"version,omitempty"`
 }
 
 type IndexTaskRequest struct {
@@ -362,6 +597,7 @@ type IndexTaskRequest struct {
 	AssigneeID  string `json:"assignee_id"`
 	UserID      string `json:"user_id" binding:"required"`
 	WorkspaceID string `json:"workspace_id" binding:"required"`
+	Version     int64  `json:"version,omitempty"`
 }
 
 // -- Search Facets/Filters --
@@ -420,8 +656,28 @@ type RelevanceConfig struct {
 	ContentBoost    float64            `json:"content_boost"`
 	RecencyWeight   float64            `json:"recency_weight"`
 	ExactMatchBoost float64            `json:"exact_match_boost"`
-	CreatedAt       time.Time          `json:"created_at"`
-	UpdatedAt       time.Time          `json:"updated_at"`
+	Hybrid          HybridConfig       `json:"hybrid"`
+	// ClickBoost adds up to this much score to documents users click for
+	// similar queries, scaled by their position-corrected CTR. 0 disables it.
+	ClickBoost float64   `json:"click_boost"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+const (
+	FusionRRF    = "rrf"
+	FusionLinear = "linear"
+)
+
+// HybridConfig sets how hybrid search fuses its lexical and semantic
+// rankings. With rrf, a hit scores weight/(RRFK+rank) from each ranking it
+// appears in; with linear, each ranking's scores are scaled to 0..1 and
+// summed by weight.
+type HybridConfig struct {
+	Fusion         string  `json:"fusion"` // rrf, linear
+	LexicalWeight  float64 `json:"lexical_weight"`
+	SemanticWeight float64 `json:"semantic_weight"`
+	RRFK           int     `json:"rrf_k"`
 }
 
 type UpdateRelevanceRequest struct {
@@ -430,6 +686,8 @@ type UpdateRelevanceRequest struct {
 	ContentBoost    *float64           `json:"content_boost"`
 	RecencyWeight   *float64           `json:"recency_weight"`
 	ExactMatchBoost *float64           `json:"exact_match_boost"`
+	Hybrid          *HybridConfig      `json:"hybrid"`
+	ClickBoost      *float64           `json:"click_boost"`
 }
 
 type RelevancePreview struct {
@@ -505,3 +763,523 @@ type SetSearchScopeRequest struct {
 	DeniedChannels []string `json:"denied_channels"`
 	WorkspaceID    string   `json:"workspace_id" binding:"required"`
 }
+
+// -- Rate Limits / Quotas --
+
+type IndexQuota struct {
+	WorkspaceID string    `json:"workspace_id"`
+	Date        string    `json:"date"`
+	Used        int64     `json:"used"`
+	DailyLimit  int64     `json:"daily_limit"`
+	Remaining   int64     `json:"remaining"`
+	IsOverride  bool      `json:"is_override"`
+	ResetsAt    time.Time `json:"resets_at"`
+}
+
+type UpdateIndexQuotaRequest struct {
+	// DailyLimit of 0 disables the quota for the workspace; nil leaves the
+	// limit as it is.
+	DailyLimit *int64 `json:"daily_limit"`
+	// ResetLimit removes the override so the configured default applies
+	// again. It cannot be combined with DailyLimit.
+	ResetLimit bool `json:"reset_limit"`
+	ResetUsage bool `json:"reset_usage"`
+}
+
+// -- Service API Keys --
+
+type APIKey struct {
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	Prefix      string     `json:"prefix"`
+	Indices     []string   `json:"indices"`
+	Operations  []string   `json:"operations"`
+	WorkspaceID string     `json:"workspace_id,omitempty"`
+	CreatedBy   string     `json:"created_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
+}
+
+type CreateAPIKeyRequest struct {
+	Name string `json:"name" binding:"required"`
+	// Indices may end in "*" to match a prefix, e.g. "quckapp_*".
+	Indices     []string `json:"indices" binding:"required,min=1"`
+	Operations  []string `json:"operations" binding:"required,min=1,dive,oneof=index delete reindex"`
+	WorkspaceID string   `json:"workspace_id"`
+}
+
+// CreateAPIKeyResponse is the only place the raw key is ever returned.
+type CreateAPIKeyResponse struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+// -- Tenant Placement --
+
+type TenantPlacement struct {
+	WorkspaceID string `json:"workspace_id"`
+	// Mode is "shared" (shared indices, default routing), "routed" (shared
+	// indices with _routing on workspace_id) or "dedicated" (own indices).
+	Mode      string           `json:"mode"`
+	Migration *TenantMigration `json:"migration,omitempty"`
+	UpdatedAt time.Time        `json:"updated_at"`
+}
+
+type TenantMigration struct {
+	ID         string     `json:"id"`
+	From       string     `json:"from"`
+	To         string     `json:"to"`
+	State      string     `json:"state"` // running, completed, failed, aborted
+	Step       string     `json:"step"`
+	Copied     int64      `json:"copied"`
+	Deleted    int64      `json:"deleted"`
+	Error      string     `json:"error,omitempty"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+type MigrateTenantRequest struct {
+	Mode string `json:"mode" binding:"required,oneof=shared routed dedicated"`
+}
+
+// -- Data Deletion --
+
+// DataDeletionJob tracks the asynchronous removal of a workspace's or a
+// user's data. Once finished it is kept as the completion report.
+type DataDeletionJob struct {
+	ID          string `json:"id"`
+	Subject     string `json:"subject"` // workspace or user
+	SubjectID   string `json:"subject_id"`
+	RequestedBy string `json:"requested_by"`
+	State       string `json:"state"` // pending, running, completed, failed
+	Step        string `json:"step"`
+	Progress    int    `json:"progress"` // percent of steps done
+	// Documents counts deleted documents per index; Records counts deleted
+	// Redis records per category (history, saved_searches, ...).
+	Documents        map[string]int64 `json:"documents"`
+	Records          map[string]int64 `json:"records"`
+	DocumentsDeleted int64            `json:"documents_deleted"`
+	RecordsDeleted   int64            `json:"records_deleted"`
+	Errors           []string         `json:"errors,omitempty"`
+	CreatedAt        time.Time        `json:"created_at"`
+	StartedAt        *time.Time       `json:"started_at,omitempty"`
+	FinishedAt       *time.Time       `json:"finished_at,omitempty"`
+}
+
+// -- Audit Log --
+
+// AuditEntry records one mutating administrative request. Action is the
+// route template, e.g. "DELETE /indices/:index"; Target holds its path
+// parameters.
+type AuditEntry struct {
+	ID          string            `json:"id"`
+	Timestamp   time.Time         `json:"timestamp"`
+	Actor       string            `json:"actor"`
+	Roles       []string          `json:"roles,omitempty"`
+	WorkspaceID string            `json:"workspace_id,omitempty"`
+	Action      string            `json:"action"`
+	Target      map[string]string `json:"target,omitempty"`
+	Status      int               `json:"status"`
+	RequestID   string            `json:"request_id"`
+	// Before and After are snapshots of the changed resource where the route
+	// provides one; otherwise After is the request body.
+	Before interface{}   `json:"before,omitempty"`
+	After  interface{}   `json:"after,omitempty"`
+	Diff   []AuditChange `json:"diff,omitempty"`
+}
+
+type AuditChange struct {
+	Field  string      `json:"field"`
+	Before interface{} `json:"before,omitempty"`
+	After  interface{} `json:"after,omitempty"`
+}
+
+type AuditQuery struct {
+	Actor   string    `form:"actor"`
+	Action  string    `form:"action"`
+	From    time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To      time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	Page    int       `form:"page,default=1"`
+	PerPage int       `form:"per_page,default=50"`
+}
+
+type AuditLogPage struct {
+	Entries []AuditEntry `json:"entries"`
+	Total   int64        `json:"total"`
+	Page    int          `json:"page"`
+	PerPage int          `json:"per_page"`
+}
+
+// -- Dead Letters --
+
+// DeadLetter is a failed index, update or delete kept for retry and replay.
+type DeadLetter struct {
+	ID         string `json:"id"`
+	Operation  string `json:"operation"` // index, update, delete
+	Index      string `json:"index"`
+	DocumentID string `json:"document_id"`
+	// WorkspaceID is the scope the write was made in; empty for writes by
+	// unrestricted callers.
+	WorkspaceID string                 `json:"workspace_id,omitempty"`
+	Document    map[string]interface{} `json:"document,omitempty"`
+	// Version is the source version the write carried, if any.
+	Version int64 `json:"version,omitempty"`
+	// Upsert marks an update that creates the document if it is missing.
+	Upsert        bool       `json:"upsert,omitempty"`
+	Source        string     `json:"source"` // api or ingest
+	State         string     `json:"state"`  // retrying or failed
+	Attempts      int        `json:"attempts"`
+	LastError     string     `json:"last_error"`
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+type DeadLetterQuery struct {
+	State   string `form:"state"`
+	Index   string `form:"index"`
+	Page    int    `form:"page,default=1"`
+	PerPage int    `form:"per_page,default=50"`
+}
+
+type DeadLetterList struct {
+	Entries []DeadLetter `json:"entries"`
+	Total   int          `json:"total"`
+	Page    int          `json:"page"`
+	PerPage int          `json:"per_page"`
+}
+
+// -- File Extraction --
+
+// FileIndexRequest describes a file to extract and index into
+// quckapp_files. Uploads send it as form fields beside the file; indexing by
+// reference sends it as JSON with Ref set.
+type FileIndexRequest struct {
+	ID       string `json:"id" form:"id" binding:"required"`
+	Filename string `json:"filename" form:"filename"`
+	// Ref locates a stored file for the blob reader: a path under the blob
+	// root, or a file:// URL, starting with the workspace's directory
+	// ("<workspace_id>/...").
+	Ref         string `json:"ref" form:"-"`
+	UserID      string `json:"user_id" form:"user_id"`
+	ChannelID   string `json:"channel_id" form:"channel_id"`
+	WorkspaceID string `json:"workspace_id" form:"workspace_id"`
+	Version     int64  `json:"version,omitempty" form:"version"`
+}
+
+type FileIndexResult struct {
+	ID         string `json:"id"`
+	Filename   string `json:"filename"`
+	MimeType   string `json:"mime_type"`
+	FileType   string `json:"file_type"`
+	Size       int64  `json:"size"`
+	Characters int    `json:"characters"`
+	Passages   int    `json:"passages"`
+	Truncated  bool   `json:"truncated"`
+	// Warning explains why a file was indexed by name only: an unsupported
+	// type, or content that failed to parse.
+	Warning string `json:"warning,omitempty"`
+}
+
+// -- Click Tracking --
+
+// ImpressionRequest records the results a user was shown for a search. The
+// request ID is the X-Request-ID of the search response, and ties later
+// clicks to this impression. Results and positions must be ones that search
+// served.
+type ImpressionRequest struct {
+	RequestID   string             `json:"request_id" binding:"required"`
+	Query       string             `json:"query" binding:"required"`
+	Type        string             `json:"type" binding:"required"` // messages, files, users, channels
+	WorkspaceID string             `json:"workspace_id"`
+	Results     []ImpressionResult `json:"results" binding:"required"`
+}
+
+type ImpressionResult struct {
+	ID       string `json:"id" binding:"required"`
+	Position int    `json:"position" binding:"required"` // 1-based
+}
+
+// ClickRequest records a click on a result of a logged impression.
+type ClickRequest struct {
+	RequestID string `json:"request_id" binding:"required"`
+	ID        string `json:"id" binding:"required"`
+	// Type names the section of a global search the result was shown in.
+	// It is needed only when the ID was shown in more than one.
+	Type string `json:"type"`
+}
+
+// QueryCTR is the click-through of each document shown for a query, as of
+// the last aggregation.
+type QueryCTR struct {
+	Query     string        `json:"query"`
+	Type      string        `json:"type"`
+	Documents []DocumentCTR `json:"documents"`
+}
+
+// DocumentCTR is a document's click-through for one query. Examinations
+// is how many of its impressions users are estimated to have looked at,
+// given the positions it was shown in; CTR is Clicks over that, smoothed
+// toward zero while there is little data.
+type DocumentCTR struct {
+	ID           string  `json:"id"`
+	Impressions  int64   `json:"impressions"`
+	Clicks       int64   `json:"clicks"`
+	Examinations float64 `json:"examinations"`
+	CTR          float64 `json:"ctr"`
+}
+
+// ClickAggregation reports a run of the click aggregation job.
+type ClickAggregation struct {
+	Queries    int       `json:"queries"`
+	Propensity []float64 `json:"propensity"` // by position, from 1
+	RanAt      time.Time `json:"ran_at"`
+}
+
+// -- Search Feedback --
+
+// SearchFeedback is a user's rating of one search result, from 1 (useless)
+// to 5 (exactly what they were looking for).
+type SearchFeedback struct {
+	ID          string    `json:"id"`
+	UserID      string    `json:"user_id"`
+	WorkspaceID string    `json:"workspace_id,omitempty"`
+	Query       string    `json:"query"`
+	ResultID    string    `json:"result_id"`
+	ResultType  string    `json:"result_type,omitempty"` // messages, files, users, channels
+	Rating      int       `json:"rating"`
+	Comment     string    `json:"comment"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type FeedbackRequest struct {
+	Query      string `json:"query" binding:"required"`
+	ResultID   string `json:"result_id" binding:"required"`
+	ResultType string `json:"result_type"`
+	Rating     int    `json:"rating" binding:"required,min=1,max=5"`
+	Comment    string `json:"comment"`
+}
+
+// FeedbackQuery selects the feedback a listing, dashboard or export covers.
+type FeedbackQuery struct {
+	UserID    string    `form:"user_id"`
+	Query     string    `form:"q"`
+	ResultID  string    `form:"result_id"`
+	MaxRating int       `form:"max_rating"`
+	From      time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To        time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	// Interval buckets the volume over time: day, week or month.
+	Interval string `form:"interval,default=day"`
+	// Limit caps the rows of each ranked table in the statistics.
+	Limit   int `form:"limit,default=10"`
+	Page    int `form:"page,default=1"`
+	PerPage int `form:"per_page,default=50"`
+}
+
+type FeedbackList struct {
+	Entries []SearchFeedback `json:"entries"`
+	Total   int64            `json:"total"`
+	Page    int              `json:"page"`
+	PerPage int              `json:"per_page"`
+}
+
+// FeedbackStats summarises a workspace's feedback. Queries are grouped by
+// their normalised form, so case and word order do not split them.
+type FeedbackStats struct {
+	Total         int64         `json:"total_feedback"`
+	AverageRating float64       `json:"avg_rating"`
+	PositivePct   float64       `json:"positive_pct"` // rated 4 or 5
+	Distribution  []RatingCount `json:"distribution"`
+	// Queries are the most rated queries; WorstQueries those with the lowest
+	// average rating among queries rated at least a few times.
+	Queries      []QueryRating    `json:"queries"`
+	WorstQueries []QueryRating    `json:"worst_queries"`
+	Volume       []FeedbackVolume `json:"volume"`
+	// PoorResults are the results most often rated 1 or 2.
+	PoorResults []ResultRating `json:"poor_results"`
+}
+
+type RatingCount struct {
+	Rating int   `json:"rating"`
+	Count  int64 `json:"count"`
+}
+
+type QueryRating struct {
+	Query         string  `json:"query"`
+	Count         int64   `json:"count"`
+	AverageRating float64 `json:"avg_rating"`
+}
+
+type FeedbackVolume struct {
+	Date          time.Time `json:"date"`
+	Count         int64     `json:"count"`
+	AverageRating float64   `json:"avg_rating"`
+}
+
+type ResultRating struct {
+	ResultID      string  `json:"result_id"`
+	ResultType    string  `json:"result_type,omitempty"`
+	PoorRatings   int64   `json:"poor_ratings"`
+	Count         int64   `json:"count"`
+	AverageRating float64 `json:"avg_rating"`
+}
+
+// -- Relevance Evaluation --
+
+// JudgmentList grades how relevant documents are to each of a set of
+// queries, for measuring how well a relevance config ranks them.
+type JudgmentList struct {
+	ID          string           `json:"id"`
+	WorkspaceID string           `json:"workspace_id"`
+	Name        string           `json:"name"`
+	Index       string           `json:"index"`  // the index the queries search
+	Source      string           `json:"source"` // api, file or feedback
+	Queries     []QueryJudgments `json:"queries"`
+	CreatedAt   time.Time        `json:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+}
+
+type QueryJudgments struct {
+	Query     string     `json:"query"`
+	Judgments []Judgment `json:"judgments"`
+}
+
+// Judgment grades one document for a query, from 0 (irrelevant) to 3
+// (exactly what the query is after). Unjudged documents count as 0.
+type Judgment struct {
+	DocumentID string `json:"doc_id"`
+	Grade      int    `json:"grade"`
+}
+
+// JudgmentListSummary describes a stored list without its judgments.
+type JudgmentListSummary struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Index     string    `json:"index"`
+	Source    string    `json:"source"`
+	Queries   int       `json:"queries"`
+	Judgments int       `json:"judgments"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type CreateJudgmentListRequest struct {
+	Name    string           `json:"name" binding:"required"`
+	Index   string           `json:"index"`
+	Queries []QueryJudgments `json:"queries" binding:"required,min=1"`
+}
+
+// JudgmentsFromFeedbackRequest builds a judgment list from the feedback
+// given on results of Index: each result's average rating for a query
+// becomes its grade.
+type JudgmentsFromFeedbackRequest struct {
+	Name  string    `json:"name" binding:"required"`
+	Index string    `json:"index"`
+	From  time.Time `json:"from"`
+	To    time.Time `json:"to"`
+	// MinRatings is how often a result must have been rated for a query to
+	// be judged for it.
+	MinRatings int `json:"min_ratings"`
+}
+
+// EvaluationRequest runs a judgment list's queries and scores the rankings.
+// Baseline and Candidate are changes to the workspace's current relevance
+// config; a nil Baseline evaluates the current config as is, and a nil
+// Candidate evaluates the baseline alone. Mode is the search mode the
+// queries run in, lexical by default; messages and files only.
+type EvaluationRequest struct {
+	JudgmentListID string                  `json:"judgment_list_id" binding:"required"`
+	K              int                     `json:"k"`
+	Mode           string                  `json:"mode"`
+	Baseline       *UpdateRelevanceRequest `json:"baseline"`
+	Candidate      *UpdateRelevanceRequest `json:"candidate"`
+}
+
+// EvaluationMetrics score a ranking's top K against the judgments: nDCG
+// with gain 2^grade-1, the reciprocal rank of the first relevant result,
+// and precision and recall of results graded 1 or above.
+type EvaluationMetrics struct {
+	NDCG      float64 `json:"ndcg"`
+	MRR       float64 `json:"mrr"`
+	Precision float64 `json:"precision"`
+	Recall    float64 `json:"recall"`
+}
+
+type ConfigEvaluation struct {
+	Config  *RelevanceConfig  `json:"config"`
+	Metrics EvaluationMetrics `json:"metrics"` // averaged over queries
+}
+
+type QueryEvaluation struct {
+	Query     string             `json:"query"`
+	Relevant  int                `json:"relevant"`
+	Baseline  EvaluationMetrics  `json:"baseline"`
+	Candidate *EvaluationMetrics `json:"candidate,omitempty"`
+	// NDCGDelta is the candidate's nDCG less the baseline's.
+	NDCGDelta float64 `json:"ndcg_delta,omitempty"`
+}
+
+type EvaluationReport struct {
+	JudgmentListID string            `json:"judgment_list_id"`
+	Index          string            `json:"index"`
+	K              int               `json:"k"`
+	Mode           string            `json:"mode"`
+	Baseline       ConfigEvaluation  `json:"baseline"`
+	Candidate      *ConfigEvaluation `json:"candidate,omitempty"`
+	Queries        []QueryEvaluation `json:"queries"`
+	RanAt          time.Time         `json:"ran_at"`
+	// NotApplied names the changed settings searches ignore, which
+	// therefore cannot move the metrics.
+	NotApplied []string `json:"not_applied,omitempty"`
+}
+
+// -- Curations --
+
+// Curation pins and hides results of one search type for the queries its
+// pattern matches. Pinned documents lead relevance-sorted results in the
+// order given, if they pass the search's filters; excluded documents never
+// appear.
+type Curation struct {
+	ID          string `json:"id"`
+	WorkspaceID string `json:"workspace_id"`
+	Pattern     string `json:"pattern"`
+	// Match is how Pattern matches a query, both normalised to their
+	// distinct lowercase terms: exact (the same terms) or contains (the
+	// query has all of the pattern's terms).
+	Match     string    `json:"match"`
+	Type      string    `json:"type"` // messages, files, users, channels
+	Pinned    []string  `json:"pinned"`
+	Excluded  []string  `json:"excluded"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+const (
+	CurationMatchExact    = "exact"
+	CurationMatchContains = "contains"
+)
+
+type CurationRequest struct {
+	Pattern  string   `json:"pattern" binding:"required"`
+	Match    string   `json:"match"`
+	Type     string   `json:"type"`
+	Pinned   []string `json:"pinned"`
+	Excluded []string `json:"excluded"`
+}
+
+// CurationPreviewRequest shows what a rule would do to a query's results
+// before it is saved.
+type CurationPreviewRequest struct {
+	CurationRequest
+	Query string `json:"query"` // defaults to the pattern
+}
+
+// CurationPreview sets a query's first page of results with a curation
+// beside the same page without one. Matches tells whether the curation's
+// pattern matches the query; the preview applies it either way.
+type CurationPreview struct {
+	Query    string      `json:"query"`
+	Matches  bool        `json:"matches"`
+	Curation *Curation   `json:"curation"`
+	Original []SearchHit `json:"original"`
+	Curated  []SearchHit `json:"curated"`
+}
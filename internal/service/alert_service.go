@@ -7,24 +7,25 @@ import (
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/redis/go-redis/v9"
 	"github.com/sirupsen/logrus"
 
+	"github.com/quckapp/search-service/internal/db"
 	"github.com/quckapp/search-service/internal/models"
 )
 
 type AlertService struct {
-	redis  *redis.Client
+	redis  *db.RedisManager
 	logger *logrus.Logger
 }
 
-func NewAlertService(redis *redis.Client, logger *logrus.Logger) *AlertService {
+func NewAlertService(redis *db.RedisManager, logger *logrus.Logger) *AlertService {
 	return &AlertService{redis: redis, logger: logger}
 }
 
 func (s *AlertService) Create(ctx context.Context, userID string, req *models.CreateAlertRequest) (*models.SearchAlert, error) {
-	if s.redis == nil {
-		return nil, fmt.Errorf("storage not available")
+	rdb := s.redis.Client()
+	if rdb == nil {
+		return nil, errStorageUnavailable
 	}
 
 	alert := &models.SearchAlert{
@@ -45,21 +46,22 @@ func (s *AlertService) Create(ctx context.Context, userID string, req *models.Cr
 	}
 
 	key := fmt.Sprintf("search_alert:%s:%s", userID, alert.ID)
-	s.redis.Set(ctx, key, data, 0)
+	rdb.Set(ctx, key, data, 0)
 
 	listKey := fmt.Sprintf("search_alerts:%s", userID)
-	s.redis.SAdd(ctx, listKey, alert.ID)
+	rdb.SAdd(ctx, listKey, alert.ID)
 
 	return alert, nil
 }
 
 func (s *AlertService) List(ctx context.Context, userID string) ([]models.SearchAlert, error) {
-	if s.redis == nil {
+	rdb := s.redis.Client()
+	if rdb == nil {
 		return []models.SearchAlert{}, nil
 	}
 
 	listKey := fmt.Sprintf("search_alerts:%s", userID)
-	ids, err := s.redis.SMembers(ctx, listKey).Result()
+	ids, err := rdb.SMembers(ctx, listKey).Result()
 	if err != nil {
 		return []models.SearchAlert{}, nil
 	}
@@ -67,7 +69,7 @@ func (s *AlertService) List(ctx context.Context, userID string) ([]models.Search
 	var alerts []models.SearchAlert
 	for _, id := range ids {
 		key := fmt.Sprintf("search_alert:%s:%s", userID, id)
-		data, err := s.redis.Get(ctx, key).Bytes()
+		data, err := rdb.Get(ctx, key).Bytes()
 		if err != nil {
 			continue
 		}
@@ -80,12 +82,13 @@ func (s *AlertService) List(ctx context.Context, userID string) ([]models.Search
 }
 
 func (s *AlertService) Update(ctx context.Context, userID, alertID string, req *models.UpdateAlertRequest) (*models.SearchAlert, error) {
-	if s.redis == nil {
-		return nil, fmt.Errorf("storage not available")
+	rdb := s.redis.Client()
+	if rdb == nil {
+		return nil, errStorageUnavailable
 	}
 
 	key := fmt.Sprintf("search_alert:%s:%s", userID, alertID)
-	data, err := s.redis.Get(ctx, key).Bytes()
+	data, err := rdb.Get(ctx, key).Bytes()
 	if err != nil {
 		return nil, fmt.Errorf("alert not found")
 	}
@@ -112,36 +115,38 @@ func (s *AlertService) Update(ctx context.Context, userID, alertID string, req *
 	if err != nil {
 		return nil, err
 	}
-	s.redis.Set(ctx, key, updated, 0)
+	rdb.Set(ctx, key, updated, 0)
 
 	return &alert, nil
 }
 
 func (s *AlertService) Delete(ctx context.Context, userID, alertID string) error {
-	if s.redis == nil {
+	rdb := s.redis.Client()
+	if rdb == nil {
 		return nil
 	}
 
 	key := fmt.Sprintf("search_alert:%s:%s", userID, alertID)
-	s.redis.Del(ctx, key)
+	rdb.Del(ctx, key)
 
 	listKey := fmt.Sprintf("search_alerts:%s", userID)
-	s.redis.SRem(ctx, listKey, alertID)
+	rdb.SRem(ctx, listKey, alertID)
 
 	// Also clean up history
 	historyKey := fmt.Sprintf("alert_history:%s", alertID)
-	s.redis.Del(ctx, historyKey)
+	rdb.Del(ctx, historyKey)
 
 	return nil
 }
 
 func (s *AlertService) GetHistory(ctx context.Context, alertID string) ([]models.AlertHistory, error) {
-	if s.redis == nil {
+	rdb := s.redis.Client()
+	if rdb == nil {
 		return []models.AlertHistory{}, nil
 	}
 
 	historyKey := fmt.Sprintf("alert_history:%s", alertID)
-	results, err := s.redis.LRange(ctx, historyKey, 0, 49).Result()
+	results, err := rdb.LRange(ctx, historyKey, 0, 49).Result()
 	if err != nil {
 		return []models.AlertHistory{}, nil
 	}
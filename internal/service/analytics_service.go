@@ -4,23 +4,24 @@ import (
 	"context"
 	"fmt"
 
-	"github.com/redis/go-redis/v9"
 	"github.com/sirupsen/logrus"
 
+	"github.com/quckapp/search-service/internal/db"
 	"github.com/quckapp/search-service/internal/models"
 )
 
 type AnalyticsService struct {
-	redis  *redis.Client
+	redis  *db.RedisManager
 	logger *logrus.Logger
 }
 
-func NewAnalyticsService(redis *redis.Client, logger *logrus.Logger) *AnalyticsService {
+func NewAnalyticsService(redis *db.RedisManager, logger *logrus.Logger) *AnalyticsService {
 	return &AnalyticsService{redis: redis, logger: logger}
 }
 
 func (s *AnalyticsService) GetAnalytics(ctx context.Context, workspaceID string) (*models.SearchAnalytics, error) {
-	if s.redis == nil {
+	rdb := s.redis.Client()
+	if rdb == nil {
 		return &models.SearchAnalytics{
 			TopQueries:     []models.QueryCount{},
 			SearchesByType: map[string]int64{},
@@ -34,7 +35,7 @@ func (s *AnalyticsService) GetAnalytics(ctx context.Context, workspaceID string)
 
 	// Top queries from sorted set
 	queriesKey := fmt.Sprintf("search_analytics:%s:queries", workspaceID)
-	topQueries, err := s.redis.ZRevRangeWithScores(ctx, queriesKey, 0, 9).Result()
+	topQueries, err := rdb.ZRevRangeWithScores(ctx, queriesKey, 0, 9).Result()
 	if err == nil {
 		for _, q := range topQueries {
 			analytics.TopQueries = append(analytics.TopQueries, models.QueryCount{
@@ -48,7 +49,7 @@ func (s *AnalyticsService) GetAnalytics(ctx context.Context, workspaceID string)
 	// Searches by type
 	for _, t := range []string{"global", "messages", "files", "users", "channels", "bookmarks", "tasks"} {
 		typeKey := fmt.Sprintf("search_analytics:%s:type:%s", workspaceID, t)
-		count, err := s.redis.Get(ctx, typeKey).Int64()
+		count, err := rdb.Get(ctx, typeKey).Int64()
 		if err == nil {
 			analytics.SearchesByType[t] = count
 		}
@@ -58,20 +59,22 @@ func (s *AnalyticsService) GetAnalytics(ctx context.Context, workspaceID string)
 }
 
 func (s *AnalyticsService) RecordSearchType(ctx context.Context, workspaceID, searchType string) {
-	if s.redis == nil {
+	rdb := s.redis.Client()
+	if rdb == nil {
 		return
 	}
 	typeKey := fmt.Sprintf("search_analytics:%s:type:%s", workspaceID, searchType)
-	s.redis.Incr(ctx, typeKey)
+	rdb.Incr(ctx, typeKey)
 }
 
 func (s *AnalyticsService) GetPopularQueries(ctx context.Context, workspaceID string, limit int64) ([]models.QueryCount, error) {
-	if s.redis == nil {
+	rdb := s.redis.Client()
+	if rdb == nil {
 		return []models.QueryCount{}, nil
 	}
 
 	queriesKey := fmt.Sprintf("search_analytics:%s:queries", workspaceID)
-	results, err := s.redis.ZRevRangeWithScores(ctx, queriesKey, 0, limit-1).Result()
+	results, err := rdb.ZRevRangeWithScores(ctx, queriesKey, 0, limit-1).Result()
 	if err != nil {
 		return []models.QueryCount{}, nil
 	}
@@ -87,14 +90,15 @@ func (s *AnalyticsService) GetPopularQueries(ctx context.Context, workspaceID st
 }
 
 func (s *AnalyticsService) ClearAnalytics(ctx context.Context, workspaceID string) error {
-	if s.redis == nil {
+	rdb := s.redis.Client()
+	if rdb == nil {
 		return nil
 	}
 
 	pattern := fmt.Sprintf("search_analytics:%s:*", workspaceID)
-	keys, _ := s.redis.Keys(ctx, pattern).Result()
+	keys, _ := rdb.Keys(ctx, pattern).Result()
 	if len(keys) > 0 {
-		s.redis.Del(ctx, keys...)
+		rdb.Del(ctx, keys...)
 	}
 	return nil
 }
@@ -0,0 +1,266 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+
+	"github.com/quckapp/search-service/internal/apperror"
+	"github.com/quckapp/search-service/internal/db"
+	"github.com/quckapp/search-service/internal/models"
+	"github.com/quckapp/search-service/internal/tenant"
+)
+
+// API keys have the form qsk_<id>_<secret>. The id locates the record; only
+// a SHA-256 of the secret is stored.
+const apiKeyPrefix = "qsk_"
+
+var errInvalidAPIKey = apperror.New(apperror.KindUnauthorized, "Invalid API key")
+
+// storedAPIKey is the Redis record; the hash never leaves the service.
+type storedAPIKey struct {
+	models.APIKey
+	Hash string `json:"hash"`
+}
+
+type APIKeyService struct {
+	redis  *db.RedisManager
+	logger *logrus.Logger
+}
+
+func NewAPIKeyService(redis *db.RedisManager, logger *logrus.Logger) *APIKeyService {
+	return &APIKeyService{redis: redis, logger: logger}
+}
+
+func apiKeyKey(id string) string {
+	return "apikey:" + id
+}
+
+func apiKeyLastUsedKey(id string) string {
+	return "apikey:" + id + ":last_used"
+}
+
+func hashAPIKeySecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// Create issues a key. A key bound to no workspace writes for every tenant,
+// so only callers who may already act across workspaces can create one;
+// others can only bind keys to their own workspace.
+func (s *APIKeyService) Create(ctx context.Context, userID string, req *models.CreateAPIKeyRequest) (*models.CreateAPIKeyResponse, error) {
+	scope, err := requireScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if req.WorkspaceID == "" && !scope.AllWorkspaces {
+		return nil, apperror.Forbidden("Only callers allowed across workspaces may create a key for every workspace")
+	}
+	if req.WorkspaceID != "" && !scope.AllWorkspaces && req.WorkspaceID != scope.WorkspaceID {
+		return nil, apperror.Forbidden("Not allowed to create keys for workspace " + req.WorkspaceID)
+	}
+
+	rdb := s.redis.Client()
+	if rdb == nil {
+		return nil, errStorageUnavailable
+	}
+
+	secretBytes := make([]byte, 24)
+	if _, err := rand.Read(secretBytes); err != nil {
+		return nil, apperror.Internal("Failed to generate API key", err)
+	}
+	secret := hex.EncodeToString(secretBytes)
+	id := strings.ReplaceAll(uuid.New().String(), "-", "")
+	raw := apiKeyPrefix + id + "_" + secret
+
+	key := storedAPIKey{
+		APIKey: models.APIKey{
+			ID:          id,
+			Name:        req.Name,
+			Prefix:      raw[:len(apiKeyPrefix)+8],
+			Indices:     req.Indices,
+			Operations:  req.Operations,
+			WorkspaceID: req.WorkspaceID,
+			CreatedBy:   userID,
+			CreatedAt:   time.Now(),
+		},
+		Hash: hashAPIKeySecret(secret),
+	}
+
+	data, err := json.Marshal(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := rdb.Set(ctx, apiKeyKey(id), data, 0).Err(); err != nil {
+		return nil, apperror.Unavailable("Failed to store API key", err)
+	}
+	rdb.SAdd(ctx, "apikeys", id)
+
+	s.logger.WithFields(logrus.Fields{"api_key_id": id, "created_by": userID}).Info("API key created")
+	return &models.CreateAPIKeyResponse{APIKey: key.APIKey, Key: raw}, nil
+}
+
+// List returns the keys bound to the caller's workspace, or every key for
+// unrestricted callers.
+func (s *APIKeyService) List(ctx context.Context) ([]models.APIKey, error) {
+	rdb := s.redis.Client()
+	if rdb == nil {
+		return nil, errStorageUnavailable
+	}
+
+	ids, err := rdb.SMembers(ctx, "apikeys").Result()
+	if err != nil {
+		return nil, apperror.Unavailable("Failed to list API keys", err)
+	}
+
+	keys := []models.APIKey{}
+	for _, id := range ids {
+		key, err := s.load(ctx, rdb, id)
+		if err != nil || !keyVisible(ctx, &key.APIKey) {
+			continue
+		}
+		keys = append(keys, key.APIKey)
+	}
+	return keys, nil
+}
+
+func (s *APIKeyService) Get(ctx context.Context, id string) (*models.APIKey, error) {
+	rdb := s.redis.Client()
+	if rdb == nil {
+		return nil, errStorageUnavailable
+	}
+
+	key, err := s.load(ctx, rdb, id)
+	if err != nil {
+		return nil, err
+	}
+	if !keyVisible(ctx, &key.APIKey) {
+		return nil, apperror.NotFound("API key not found")
+	}
+	return &key.APIKey, nil
+}
+
+func (s *APIKeyService) Delete(ctx context.Context, id string) error {
+	if _, err := s.Get(ctx, id); err != nil {
+		return err
+	}
+	rdb := s.redis.Client()
+	if rdb == nil {
+		return errStorageUnavailable
+	}
+
+	n, err := rdb.Del(ctx, apiKeyKey(id), apiKeyLastUsedKey(id)).Result()
+	if err != nil {
+		return apperror.Unavailable("Failed to delete API key", err)
+	}
+	rdb.SRem(ctx, "apikeys", id)
+	if n == 0 {
+		return apperror.NotFound("API key not found")
+	}
+
+	s.logger.WithField("api_key_id", id).Info("API key revoked")
+	return nil
+}
+
+// keyVisible hides keys bound to other workspaces, and unbound keys, from
+// callers scoped to a workspace.
+func keyVisible(ctx context.Context, key *models.APIKey) bool {
+	scope, ok := tenant.FromContext(ctx)
+	return ok && (scope.Unrestricted() || scope.WorkspaceID == key.WorkspaceID)
+}
+
+// Authenticate resolves a raw key to its record and records the use.
+func (s *APIKeyService) Authenticate(ctx context.Context, raw string) (*models.APIKey, error) {
+	rest, ok := strings.CutPrefix(raw, apiKeyPrefix)
+	if !ok {
+		return nil, errInvalidAPIKey
+	}
+	id, secret, ok := strings.Cut(rest, "_")
+	if !ok || id == "" || secret == "" {
+		return nil, errInvalidAPIKey
+	}
+
+	rdb := s.redis.Client()
+	if rdb == nil {
+		return nil, errStorageUnavailable
+	}
+
+	key, err := s.load(ctx, rdb, id)
+	if err != nil {
+		if apperror.KindOf(err) == apperror.KindNotFound {
+			return nil, errInvalidAPIKey
+		}
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(hashAPIKeySecret(secret)), []byte(key.Hash)) != 1 {
+		return nil, errInvalidAPIKey
+	}
+
+	rdb.Set(ctx, apiKeyLastUsedKey(id), time.Now().Unix(), 0)
+	return &key.APIKey, nil
+}
+
+func (s *APIKeyService) load(ctx context.Context, rdb *redis.Client, id string) (*storedAPIKey, error) {
+	data, err := rdb.Get(ctx, apiKeyKey(id)).Bytes()
+	if err == redis.Nil {
+		return nil, apperror.NotFound("API key not found")
+	}
+	if err != nil {
+		return nil, apperror.Unavailable("Failed to read API key", err)
+	}
+
+	var key storedAPIKey
+	if err := json.Unmarshal(data, &key); err != nil {
+		return nil, apperror.Internal("Corrupt API key record", err)
+	}
+	if ts, err := rdb.Get(ctx, apiKeyLastUsedKey(id)).Int64(); err == nil {
+		lastUsed := time.Unix(ts, 0)
+		key.LastUsedAt = &lastUsed
+	}
+	return &key, nil
+}
+
+// APIKeyAllows reports whether key may perform operation on every one of indices.
+// Scope entries ending in "*" match by prefix.
+func APIKeyAllows(key *models.APIKey, operation string, indices []string) bool {
+	if !containsOperation(key.Operations, operation) {
+		return false
+	}
+	for _, index := range indices {
+		if !indexInScope(key.Indices, index) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsOperation(ops []string, op string) bool {
+	for _, o := range ops {
+		if o == op {
+			return true
+		}
+	}
+	return false
+}
+
+func indexInScope(scope []string, index string) bool {
+	for _, pattern := range scope {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(index, prefix) {
+				return true
+			}
+		} else if pattern == index {
+			return true
+		}
+	}
+	return false
+}
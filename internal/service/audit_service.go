@@ -0,0 +1,271 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/quckapp/search-service/internal/apperror"
+	"github.com/quckapp/search-service/internal/db"
+	"github.com/quckapp/search-service/internal/models"
+)
+
+// indexAudit lies outside quckapp_* on purpose: the index management API and
+// data deletion jobs only touch quckapp_* indices, so neither can remove it.
+// It is not in searchableIndices, so the search API cannot read it either.
+const indexAudit = "search_audit"
+
+// auditMappings index the filterable fields and store the snapshots without
+// indexing them, since their shape differs per action.
+var auditMappings = map[string]interface{}{
+	"dynamic": false,
+	"properties": map[string]interface{}{
+		"timestamp":    map[string]interface{}{"type": "date"},
+		"actor":        map[string]interface{}{"type": "keyword"},
+		"roles":        map[string]interface{}{"type": "keyword"},
+		"workspace_id": map[string]interface{}{"type": "keyword"},
+		"action":       map[string]interface{}{"type": "keyword"},
+		"target":       map[string]interface{}{"type": "flattened"},
+		"status":       map[string]interface{}{"type": "integer"},
+		"request_id":   map[string]interface{}{"type": "keyword"},
+		"before":       map[string]interface{}{"type": "object", "enabled": false},
+		"after":        map[string]interface{}{"type": "object", "enabled": false},
+		"diff":         map[string]interface{}{"type": "object", "enabled": false},
+	},
+}
+
+// redactedFields never reach the audit log, wherever they appear.
+var redactedFields = map[string]bool{"key": true, "secret": true, "hash": true, "token": true, "password": true}
+
+// AuditService appends entries to the audit log. Entries are written with
+// op_type=create and nothing updates or deletes them.
+type AuditService struct {
+	es     *db.ElasticsearchManager
+	logger *logrus.Logger
+
+	mu    sync.Mutex
+	ready bool
+}
+
+func NewAuditService(es *db.ElasticsearchManager, logger *logrus.Logger) *AuditService {
+	return &AuditService{es: es, logger: logger}
+}
+
+// Record redacts secrets, computes the diff and appends entry. An entry
+// that cannot be stored is logged in full so it is not lost.
+func (s *AuditService) Record(ctx context.Context, entry *models.AuditEntry) error {
+	entry.ID = uuid.New().String()
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now().UTC()
+	}
+	entry.Before = redact(normalize(entry.Before))
+	entry.After = redact(normalize(entry.After))
+	entry.Diff = diffSnapshots(entry.Before, entry.After)
+
+	err := s.write(ctx, entry)
+	if err != nil {
+		data, _ := json.Marshal(entry)
+		s.logger.WithError(err).WithField("audit_entry", string(data)).Error("Failed to write audit entry")
+	}
+	return err
+}
+
+func (s *AuditService) write(ctx context.Context, entry *models.AuditEntry) error {
+	es := s.es.Client()
+	if es == nil {
+		return errSearchUnavailable
+	}
+	if err := s.ensureIndex(ctx); err != nil {
+		return err
+	}
+
+	buf, err := encodeBody(entry)
+	if err != nil {
+		return err
+	}
+	res, err := es.Create(indexAudit, entry.ID, buf, es.Create.WithContext(ctx))
+	return readResponse(res, err, "Failed to write audit entry", nil)
+}
+
+func (s *AuditService) ensureIndex(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ready {
+		return nil
+	}
+
+	es := s.es.Client()
+	buf, err := encodeBody(map[string]interface{}{"mappings": auditMappings})
+	if err != nil {
+		return err
+	}
+	res, err := es.Indices.Create(indexAudit, es.Indices.Create.WithBody(buf), es.Indices.Create.WithContext(ctx))
+	if err := readResponse(res, err, "Failed to create audit index", nil); err != nil && apperror.KindOf(err) != apperror.KindConflict {
+		return err
+	}
+	s.ready = true
+	return nil
+}
+
+// Query returns audit entries matching q, newest first. Callers scoped to a
+// workspace only see entries recorded in it.
+func (s *AuditService) Query(ctx context.Context, q *models.AuditQuery) (*models.AuditLogPage, error) {
+	es := s.es.Client()
+	if es == nil {
+		return nil, errSearchUnavailable
+	}
+	if q.Page < 1 {
+		q.Page = 1
+	}
+	if q.PerPage < 1 || q.PerPage > 200 {
+		q.PerPage = 50
+	}
+
+	filters := []map[string]interface{}{}
+	if q.Actor != "" {
+		filters = append(filters, map[string]interface{}{"term": map[string]interface{}{"actor": q.Actor}})
+	}
+	if q.Action != "" {
+		filters = append(filters, map[string]interface{}{"term": map[string]interface{}{"action": q.Action}})
+	}
+	if !q.From.IsZero() || !q.To.IsZero() {
+		rng := map[string]interface{}{}
+		if !q.From.IsZero() {
+			rng["gte"] = q.From
+		}
+		if !q.To.IsZero() {
+			rng["lte"] = q.To
+		}
+		filters = append(filters, map[string]interface{}{"range": map[string]interface{}{"timestamp": rng}})
+	}
+
+	query, err := scopeQuery(ctx, indexAudit, map[string]interface{}{
+		"query": map[string]interface{}{"bool": map[string]interface{}{"filter": filters}},
+		"sort":  []map[string]interface{}{{"timestamp": map[string]interface{}{"order": "desc"}}},
+		"from":  (q.Page - 1) * q.PerPage,
+		"size":  q.PerPage,
+	})
+	if err != nil {
+		return nil, err
+	}
+	buf, err := encodeBody(query)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.AuditLogPage{Entries: []models.AuditEntry{}, Page: q.Page, PerPage: q.PerPage}
+	res, err := es.Search(es.Search.WithContext(ctx), es.Search.WithIndex(indexAudit), es.Search.WithBody(buf))
+	var result struct {
+		Hits struct {
+			Total struct {
+				Value int64 `json:"value"`
+			} `json:"total"`
+			Hits []struct {
+				Source models.AuditEntry `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := readResponse(res, err, "Failed to query audit log", &result); err != nil {
+		if apperror.KindOf(err) == apperror.KindIndexNotFound {
+			return page, nil
+		}
+		return nil, err
+	}
+
+	page.Total = result.Hits.Total.Value
+	for _, hit := range result.Hits.Hits {
+		page.Entries = append(page.Entries, hit.Source)
+	}
+	return page, nil
+}
+
+// ── Snapshots ──
+
+// normalize turns a snapshot into plain JSON values so typed structs and
+// decoded bodies compare alike.
+func normalize(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var out interface{}
+	if json.Unmarshal(data, &out) != nil {
+		return nil
+	}
+	return out
+}
+
+func redact(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, item := range val {
+			if redactedFields[strings.ToLower(k)] {
+				val[k] = "[redacted]"
+				continue
+			}
+			val[k] = redact(item)
+		}
+	case []interface{}:
+		for i, item := range val {
+			val[i] = redact(item)
+		}
+	}
+	return v
+}
+
+// diffSnapshots lists the leaf fields that differ between before and after,
+// with nested objects flattened to dotted paths. Arrays compare as a whole.
+func diffSnapshots(before, after interface{}) []models.AuditChange {
+	if before == nil {
+		return nil
+	}
+	b, a := map[string]interface{}{}, map[string]interface{}{}
+	flatten("", before, b)
+	flatten("", after, a)
+
+	fields := make([]string, 0, len(b)+len(a))
+	for k := range b {
+		fields = append(fields, k)
+	}
+	for k := range a {
+		if _, ok := b[k]; !ok {
+			fields = append(fields, k)
+		}
+	}
+	sort.Strings(fields)
+
+	var changes []models.AuditChange
+	for _, field := range fields {
+		if !reflect.DeepEqual(b[field], a[field]) {
+			changes = append(changes, models.AuditChange{Field: field, Before: b[field], After: a[field]})
+		}
+	}
+	return changes
+}
+
+func flatten(prefix string, v interface{}, out map[string]interface{}) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		if prefix != "" || v != nil {
+			out[prefix] = v
+		}
+		return
+	}
+	for k, item := range m {
+		path := k
+		if prefix != "" {
+			path = prefix + "." + k
+		}
+		flatten(path, item, out)
+	}
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/quckapp/search-service/internal/models"
+)
+
+// Each search builds its lexical query with weights tuned for its index.
+// The relevance config adjusts them only where a workspace changed it from
+// the defaults: a changed field boost replaces the built-in weight of that
+// field wherever the query matches it, and a changed exact_match_boost adds
+// a phrase match on the fields the query matches, scored with that boost.
+// title_boost, content_boost and recency_weight are not applied.
+
+// boostFields applies the field and exact-match boosts of the search's
+// relevance config to a lexical query. kNN searches have no query to
+// adjust and are left alone.
+func (s *SearchService) boostFields(ctx context.Context, query map[string]interface{}, params *models.SearchParams) {
+	q, _ := query["query"].(map[string]interface{})
+	boolQuery, ok := q["bool"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	config := s.relevanceConfig(ctx, params)
+	if config == nil {
+		return
+	}
+	must, _ := boolQuery["must"].([]map[string]interface{})
+
+	if boosts := changedFieldBoosts(config); len(boosts) > 0 {
+		for _, clause := range must {
+			reweightClause(clause, boosts)
+		}
+	}
+	if config.ExactMatchBoost > 0 && config.ExactMatchBoost != defaultExactMatchBoost {
+		if fields := matchedFields(must); len(fields) > 0 {
+			should, _ := boolQuery["should"].([]map[string]interface{})
+			boolQuery["should"] = append(should, map[string]interface{}{
+				"multi_match": map[string]interface{}{
+					"query":  params.Query,
+					"fields": fields,
+					"type":   "phrase",
+					"boost":  config.ExactMatchBoost,
+				},
+			})
+		}
+	}
+}
+
+// changedFieldBoosts returns the field boosts of config that differ from
+// the defaults.
+func changedFieldBoosts(config *models.RelevanceConfig) map[string]float64 {
+	changed := map[string]float64{}
+	for field, boost := range config.FieldBoosts {
+		if def, ok := defaultFieldBoosts[field]; !ok || def != boost {
+			changed[field] = boost
+		}
+	}
+	return changed
+}
+
+// reweightClause sets the boost of each field in boosts that clause, or a
+// clause nested in it, matches on.
+func reweightClause(clause map[string]interface{}, boosts map[string]float64) {
+	for kind, body := range clause {
+		spec, _ := body.(map[string]interface{})
+		switch kind {
+		case "match":
+			for field, opts := range spec {
+				boost, ok := boosts[field]
+				if !ok {
+					continue
+				}
+				if o, isMap := opts.(map[string]interface{}); isMap {
+					o["boost"] = boost
+				} else {
+					spec[field] = map[string]interface{}{"query": opts, "boost": boost}
+				}
+			}
+		case "multi_match":
+			fields, _ := spec["fields"].([]string)
+			for i, f := range fields {
+				name := strings.SplitN(f, "^", 2)[0]
+				if boost, ok := boosts[name]; ok {
+					fields[i] = fmt.Sprintf("%s^%g", name, boost)
+				}
+			}
+		case "nested":
+			if inner, ok := spec["query"].(map[string]interface{}); ok {
+				reweightClause(inner, boosts)
+			}
+		case "bool":
+			for _, occur := range []string{"must", "should"} {
+				clauses, _ := spec[occur].([]map[string]interface{})
+				for _, c := range clauses {
+					reweightClause(c, boosts)
+				}
+			}
+		}
+	}
+}
+
+// matchedFields returns the fields the clauses match on, sorted. Fields of
+// nested documents are left out, since a phrase match on them would need
+// its own nested query.
+func matchedFields(clauses []map[string]interface{}) []string {
+	seen := map[string]bool{}
+	var collect func(clause map[string]interface{})
+	collect = func(clause map[string]interface{}) {
+		for kind, body := range clause {
+			spec, _ := body.(map[string]interface{})
+			switch kind {
+			case "match":
+				for field := range spec {
+					seen[field] = true
+				}
+			case "multi_match":
+				fields, _ := spec["fields"].([]string)
+				for _, f := range fields {
+					seen[strings.SplitN(f, "^", 2)[0]] = true
+				}
+			case "bool":
+				for _, occur := range []string{"must", "should"} {
+					nested, _ := spec[occur].([]map[string]interface{})
+					for _, c := range nested {
+						collect(c)
+					}
+				}
+			}
+		}
+	}
+	for _, clause := range clauses {
+		collect(clause)
+	}
+
+	fields := make([]string, 0, len(seen))
+	for field := range seen {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
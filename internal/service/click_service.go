@@ -0,0 +1,686 @@
+package service
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+
+	"github.com/quckapp/search-service/internal/apperror"
+	"github.com/quckapp/search-service/internal/config"
+	"github.com/quckapp/search-service/internal/db"
+	"github.com/quckapp/search-service/internal/models"
+)
+
+const (
+	// impressionTTL is how long a click can still be tied to the impression
+	// it came from.
+	impressionTTL = 24 * time.Hour
+	// maxTrackedPosition is the last position counted on its own; results
+	// shown further down are counted as if shown there.
+	maxTrackedPosition = 20
+	// minPositionImpressions is the impressions a position needs before its
+	// own click rate is trusted over the 1/position prior.
+	minPositionImpressions = 1000
+	// minPropensity keeps a position nobody clicks from dividing by zero.
+	minPropensity = 0.01
+	// clickSmoothing is added to a document's examinations, so a document
+	// clicked once in one look does not outrank one clicked often.
+	clickSmoothing = 5.0
+	// minBoostClicks and clickBoostDocs bound which documents a query
+	// boosts: ones clicked at least twice, the best twenty.
+	minBoostClicks = 2
+	clickBoostDocs = 20
+	// clickAggregateBatch is how many queries the aggregation job takes at
+	// once.
+	clickAggregateBatch = 100
+
+	clickPositionsKey = "ctr:positions"
+	clickDirtyKey     = "ctr:dirty"
+)
+
+// clickTypes maps the result types clicks are tracked for to their index.
+var clickTypes = map[string]string{
+	"messages": indexMessages,
+	"files":    indexFiles,
+	"users":    indexUsers,
+	"channels": indexChannels,
+}
+
+// ClickService learns from what users click. Searches record the results
+// they served under the response's request ID and their type, one record
+// per section of a global search; clients then log which of
+// those they showed (an impression) and which were clicked. Anything not
+// served to the same user under that request ID is refused, and each user
+// counts at most once a day per query and document. Counts are
+// kept per workspace, per normalized query and document, and per position
+// across all workspaces; Aggregate turns them into click-through rates
+// corrected for position bias, which search can use to boost documents.
+//
+// Position bias: results near the top are clicked more because they are
+// seen more. Each position's propensity, the chance a result there is
+// looked at, is estimated from the click rate at that position relative to
+// the first. A document's CTR is its clicks over its examinations, the sum
+// of the propensities of the positions it was shown in.
+type ClickService struct {
+	redis  *db.RedisManager
+	cfg    config.Clicks
+	logger *logrus.Logger
+}
+
+func NewClickService(redis *db.RedisManager, cfg config.Clicks, logger *logrus.Logger) *ClickService {
+	return &ClickService{redis: redis, cfg: cfg, logger: logger}
+}
+
+// served is what a search returned, kept under its request ID for as long
+// as impressions of it may be logged.
+type served struct {
+	WorkspaceID string         `json:"workspace_id"`
+	UserID      string         `json:"user_id"`
+	Type        string         `json:"type"`
+	Query       string         `json:"query"`
+	Positions   map[string]int `json:"positions"`
+}
+
+// impression is what is kept of a logged impression until it expires.
+type impression struct {
+	WorkspaceID string         `json:"workspace_id"`
+	UserID      string         `json:"user_id"`
+	Key         string         `json:"key"`
+	Positions   map[string]int `json:"positions"`
+}
+
+func servedKey(requestID, searchType string) string {
+	return "served:" + requestID + ":" + searchType
+}
+
+func impressionKey(requestID, searchType string) string {
+	return "impression:" + requestID + ":" + searchType
+}
+
+// userClickKey records which of a query's documents a user has already
+// been counted for, as "<id>|i" and "<id>|c" fields.
+func userClickKey(userID, queryKey string) string {
+	return queryKey + ":user:" + userID
+}
+
+// normalizeQuery reduces a query to its distinct lowercase terms, sorted,
+// so queries that differ in case, punctuation or word order share clicks.
+func normalizeQuery(query string) string {
+	terms := strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	sort.Strings(terms)
+	out := terms[:0]
+	for i, t := range terms {
+		if i == 0 || t != terms[i-1] {
+			out = append(out, t)
+		}
+	}
+	return strings.Join(out, " ")
+}
+
+// queryClickKey is the hash of click counts for a query in a workspace.
+func queryClickKey(workspaceID, searchType, normalized string) string {
+	sum := sha1.Sum([]byte(normalized))
+	return fmt.Sprintf("ctr:%s:%s:%s", workspaceID, searchType, hex.EncodeToString(sum[:8]))
+}
+
+func clickBoostKey(queryKey string) string {
+	return queryKey + ":boost"
+}
+
+func trackedPosition(position int) int {
+	if position > maxTrackedPosition {
+		return maxTrackedPosition
+	}
+	return position
+}
+
+// clickWorkspace is the workspace clicks are recorded in: the caller's.
+func clickWorkspace(ctx context.Context) (string, error) {
+	scope, err := requireScope(ctx)
+	if err != nil {
+		return "", err
+	}
+	if scope.WorkspaceID == "" {
+		return "", apperror.BadQuery("workspace_id is required", nil)
+	}
+	return scope.WorkspaceID, nil
+}
+
+// ── Logging ──
+
+// RecordServed keeps the page of results a search of searchType served to
+// userID under its request ID. Only the first search of a type under an ID
+// is kept, so a client reusing one cannot replace what was served. Searches without a user or
+// a workspace are not tracked.
+func (s *ClickService) RecordServed(ctx context.Context, requestID, userID, searchType, query string, resp *models.SearchResponse) {
+	if s == nil || requestID == "" || userID == "" || resp == nil || len(resp.Results) == 0 {
+		return
+	}
+	workspaceID, err := clickWorkspace(ctx)
+	if err != nil {
+		return
+	}
+	normalized := normalizeQuery(query)
+	rdb := s.redis.Client()
+	if rdb == nil || normalized == "" {
+		return
+	}
+
+	rec := served{WorkspaceID: workspaceID, UserID: userID, Type: searchType, Query: normalized, Positions: map[string]int{}}
+	offset := 0
+	if resp.Page > 1 {
+		offset = (resp.Page - 1) * resp.PerPage
+	}
+	for i, hit := range resp.Results {
+		if _, seen := rec.Positions[hit.ID]; !seen {
+			rec.Positions[hit.ID] = offset + i + 1
+		}
+	}
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return
+	}
+	if err := rdb.SetNX(ctx, servedKey(requestID, searchType), data, impressionTTL).Err(); err != nil {
+		s.logger.WithError(err).WithField("request_id", requestID).Warn("Failed to record served results")
+	}
+}
+
+// loadServed returns the results of searchType served to userID under
+// requestID in the caller's workspace.
+func (s *ClickService) loadServed(ctx context.Context, rdb *redis.Client, requestID, searchType, workspaceID, userID string) (*served, error) {
+	data, err := rdb.Get(ctx, servedKey(requestID, searchType)).Bytes()
+	if err == redis.Nil {
+		return nil, apperror.NotFound("No search was served under this request ID")
+	}
+	if err != nil {
+		return nil, apperror.Unavailable("Failed to read served results", err)
+	}
+	var rec served
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, apperror.Internal("Corrupt served results", err)
+	}
+	if rec.WorkspaceID != workspaceID || rec.UserID != userID {
+		return nil, apperror.NotFound("No search was served under this request ID")
+	}
+	return &rec, nil
+}
+
+// firstForUser marks each of fields as counted for userID under a query and
+// returns those that had not been counted yet.
+func firstForUser(ctx context.Context, rdb *redis.Client, userID, queryKey string, fields []string) ([]string, error) {
+	key := userClickKey(userID, queryKey)
+	pipe := rdb.Pipeline()
+	cmds := make([]*redis.BoolCmd, len(fields))
+	for i, field := range fields {
+		cmds[i] = pipe.HSetNX(ctx, key, field, 1)
+	}
+	pipe.Expire(ctx, key, impressionTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+	var first []string
+	for i, cmd := range cmds {
+		if cmd.Val() {
+			first = append(first, fields[i])
+		}
+	}
+	return first, nil
+}
+
+// LogImpression records which results of a served search were shown. The
+// results and their positions must match what was served to the caller
+// under the request ID. An impression is logged once per request ID and
+// type.
+func (s *ClickService) LogImpression(ctx context.Context, userID string, req *models.ImpressionRequest) error {
+	workspaceID, err := clickWorkspace(ctx)
+	if err != nil {
+		return err
+	}
+	if _, ok := clickTypes[req.Type]; !ok {
+		return apperror.BadQuery("type must be messages, files, users or channels", nil)
+	}
+	normalized := normalizeQuery(req.Query)
+	if normalized == "" {
+		return apperror.BadQuery("query has no terms", nil)
+	}
+
+	rdb := s.redis.Client()
+	if rdb == nil {
+		return nil
+	}
+	rec, err := s.loadServed(ctx, rdb, req.RequestID, req.Type, workspaceID, userID)
+	if err != nil {
+		return err
+	}
+	if rec.Type != req.Type || rec.Query != normalized {
+		return apperror.BadQuery("Impression does not match the search served under this request ID", nil)
+	}
+
+	imp := impression{
+		WorkspaceID: workspaceID,
+		UserID:      userID,
+		Key:         queryClickKey(workspaceID, req.Type, normalized),
+		Positions:   map[string]int{},
+	}
+	for _, r := range req.Results {
+		position, ok := rec.Positions[r.ID]
+		if !ok {
+			return apperror.BadQuery("Result "+r.ID+" was not served in this search", nil)
+		}
+		if r.Position != position {
+			return apperror.BadQuery(fmt.Sprintf("Result %s was served at position %d", r.ID, position), nil)
+		}
+		imp.Positions[r.ID] = position
+	}
+
+	data, err := json.Marshal(imp)
+	if err != nil {
+		return err
+	}
+	stored, err := rdb.SetNX(ctx, impressionKey(req.RequestID, req.Type), data, impressionTTL).Result()
+	if err != nil {
+		return apperror.Unavailable("Failed to log impression", err)
+	}
+	if !stored {
+		return apperror.Conflict("Impression already logged for this request", nil)
+	}
+
+	fields := make([]string, 0, len(imp.Positions))
+	for id := range imp.Positions {
+		fields = append(fields, id+"|i")
+	}
+	first, err := firstForUser(ctx, rdb, userID, imp.Key, fields)
+	if err != nil {
+		return apperror.Unavailable("Failed to log impression", err)
+	}
+
+	pipe := rdb.Pipeline()
+	pipe.HSet(ctx, imp.Key, "query", normalized, "type", req.Type)
+	for _, field := range first {
+		id := strings.TrimSuffix(field, "|i")
+		pos := trackedPosition(imp.Positions[id])
+		pipe.HIncrBy(ctx, imp.Key, id+"|i"+strconv.Itoa(pos), 1)
+		pipe.HIncrBy(ctx, clickPositionsKey, "i"+strconv.Itoa(pos), 1)
+	}
+	pipe.Expire(ctx, imp.Key, s.cfg.Retention)
+	pipe.SAdd(ctx, clickDirtyKey, imp.Key)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return apperror.Unavailable("Failed to log impression", err)
+	}
+	return nil
+}
+
+// LogClick records a click on a result of the caller's logged impression.
+// Repeated clicks by a user on a query's result count once.
+func (s *ClickService) LogClick(ctx context.Context, userID string, req *models.ClickRequest) error {
+	workspaceID, err := clickWorkspace(ctx)
+	if err != nil {
+		return err
+	}
+	rdb := s.redis.Client()
+	if rdb == nil {
+		return nil
+	}
+
+	imp, err := loadClickedImpression(ctx, rdb, workspaceID, userID, req)
+	if err != nil {
+		return err
+	}
+	position := imp.Positions[req.ID]
+
+	first, err := firstForUser(ctx, rdb, userID, imp.Key, []string{req.ID + "|c"})
+	if err != nil {
+		return apperror.Unavailable("Failed to log click", err)
+	}
+	if len(first) == 0 {
+		return nil
+	}
+	pos := trackedPosition(position)
+	pipe := rdb.Pipeline()
+	pipe.HIncrBy(ctx, imp.Key, req.ID+"|c", 1)
+	pipe.HIncrBy(ctx, clickPositionsKey, "c"+strconv.Itoa(pos), 1)
+	pipe.Expire(ctx, imp.Key, s.cfg.Retention)
+	pipe.SAdd(ctx, clickDirtyKey, imp.Key)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return apperror.Unavailable("Failed to log click", err)
+	}
+	return nil
+}
+
+// loadClickedImpression returns the caller's impression under the click's
+// request ID that showed the clicked result: the one of the click's type,
+// or, without one, whichever section of the search showed it.
+func loadClickedImpression(ctx context.Context, rdb *redis.Client, workspaceID, userID string, req *models.ClickRequest) (*impression, error) {
+	types := []string{req.Type}
+	if req.Type == "" {
+		types = []string{"messages", "files", "users", "channels"}
+	} else if _, ok := clickTypes[req.Type]; !ok {
+		return nil, apperror.BadQuery("type must be messages, files, users or channels", nil)
+	}
+	keys := make([]string, len(types))
+	for i, t := range types {
+		keys[i] = impressionKey(req.RequestID, t)
+	}
+	values, err := rdb.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, apperror.Unavailable("Failed to log click", err)
+	}
+
+	var found *impression
+	owned := false
+	for _, v := range values {
+		data, ok := v.(string)
+		if !ok {
+			continue
+		}
+		var imp impression
+		if err := json.Unmarshal([]byte(data), &imp); err != nil {
+			return nil, apperror.Internal("Corrupt impression", err)
+		}
+		if imp.WorkspaceID != workspaceID || imp.UserID != userID {
+			continue
+		}
+		owned = true
+		if _, ok := imp.Positions[req.ID]; !ok {
+			continue
+		}
+		if found != nil {
+			return nil, apperror.BadQuery("Result was shown in more than one section of this search; give its type", nil)
+		}
+		found = &imp
+	}
+	if found == nil {
+		if owned {
+			return nil, apperror.BadQuery("Result was not shown in this impression", nil)
+		}
+		return nil, apperror.NotFound("Impression not found or expired")
+	}
+	return found, nil
+}
+
+// ── Aggregation ──
+
+// Run aggregates clicks every AggregateInterval until ctx is cancelled.
+// Replicas share the work: each query is taken off the dirty set by one.
+func (s *ClickService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.AggregateInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if s.redis.Client() == nil {
+			continue
+		}
+		if _, err := s.Aggregate(ctx); err != nil {
+			s.logger.WithError(err).Warn("Click aggregation failed")
+		}
+	}
+}
+
+// Aggregate recomputes the position propensities, then the CTRs and boosts
+// of every query with impressions or clicks since the last run.
+func (s *ClickService) Aggregate(ctx context.Context) (*models.ClickAggregation, error) {
+	rdb := s.redis.Client()
+	if rdb == nil {
+		return nil, apperror.Unavailable("Click tracking store unavailable", nil)
+	}
+	propensity, err := s.propensities(ctx, rdb)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.ClickAggregation{Propensity: propensity[1:], RanAt: time.Now().UTC()}
+	for {
+		keys, err := rdb.SPopN(ctx, clickDirtyKey, clickAggregateBatch).Result()
+		if err != nil {
+			return nil, apperror.Unavailable("Failed to read click queue", err)
+		}
+		if len(keys) == 0 {
+			return result, nil
+		}
+		for _, key := range keys {
+			if err := s.aggregateQuery(ctx, rdb, key, propensity); err != nil {
+				// Put it back for the next run.
+				rdb.SAdd(ctx, clickDirtyKey, key)
+				return nil, err
+			}
+			result.Queries++
+		}
+	}
+}
+
+// aggregateQuery replaces the boosts of one query with its documents that
+// are clicked enough, best CTR first.
+func (s *ClickService) aggregateQuery(ctx context.Context, rdb *redis.Client, key string, propensity []float64) error {
+	fields, err := rdb.HGetAll(ctx, key).Result()
+	if err != nil {
+		return apperror.Unavailable("Failed to read click counts", err)
+	}
+	docs := documentCTRs(fields, propensity)
+
+	pipe := rdb.TxPipeline()
+	pipe.Del(ctx, clickBoostKey(key))
+	boosted := 0
+	for _, doc := range docs {
+		if boosted == clickBoostDocs {
+			break
+		}
+		if doc.Clicks < minBoostClicks {
+			continue
+		}
+		pipe.ZAdd(ctx, clickBoostKey(key), redis.Z{Score: doc.CTR, Member: doc.ID})
+		boosted++
+	}
+	if boosted > 0 {
+		pipe.Expire(ctx, clickBoostKey(key), s.cfg.Retention)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return apperror.Unavailable("Failed to store click boosts", err)
+	}
+	return nil
+}
+
+// propensities returns the chance a result at each position is looked at,
+// indexed by position (index 0 is unused). A position's propensity is its
+// click rate over the first position's, once both have enough impressions
+// to trust; until then it is 1/position.
+func (s *ClickService) propensities(ctx context.Context, rdb *redis.Client) ([]float64, error) {
+	counts, err := rdb.HGetAll(ctx, clickPositionsKey).Result()
+	if err != nil {
+		return nil, apperror.Unavailable("Failed to read position counts", err)
+	}
+	rate := func(pos int) (float64, bool) {
+		imps, _ := strconv.ParseFloat(counts["i"+strconv.Itoa(pos)], 64)
+		clicks, _ := strconv.ParseFloat(counts["c"+strconv.Itoa(pos)], 64)
+		if imps < minPositionImpressions {
+			return 0, false
+		}
+		return clicks / imps, true
+	}
+
+	propensity := make([]float64, maxTrackedPosition+1)
+	top, topOK := rate(1)
+	for pos := 1; pos <= maxTrackedPosition; pos++ {
+		propensity[pos] = 1 / float64(pos)
+		if r, ok := rate(pos); ok && topOK && top > 0 {
+			propensity[pos] = r / top
+		}
+		if propensity[pos] > 1 {
+			propensity[pos] = 1
+		}
+		if propensity[pos] < minPropensity {
+			propensity[pos] = minPropensity
+		}
+	}
+	return propensity, nil
+}
+
+// documentCTRs reads the per-document counts of a query's hash, best CTR
+// first. Fields are "<id>|i<position>" for impressions and "<id>|c" for
+// clicks.
+func documentCTRs(fields map[string]string, propensity []float64) []models.DocumentCTR {
+	byID := map[string]*models.DocumentCTR{}
+	for field, value := range fields {
+		sep := strings.LastIndex(field, "|")
+		if sep < 0 {
+			continue
+		}
+		id, counter := field[:sep], field[sep+1:]
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			continue
+		}
+		doc, ok := byID[id]
+		if !ok {
+			doc = &models.DocumentCTR{ID: id}
+			byID[id] = doc
+		}
+		switch {
+		case counter == "c":
+			doc.Clicks += n
+		case strings.HasPrefix(counter, "i"):
+			pos, err := strconv.Atoi(counter[1:])
+			if err != nil || pos < 1 || pos >= len(propensity) {
+				continue
+			}
+			doc.Impressions += n
+			doc.Examinations += float64(n) * propensity[pos]
+		}
+	}
+
+	docs := make([]models.DocumentCTR, 0, len(byID))
+	for _, doc := range byID {
+		doc.CTR = float64(doc.Clicks) / (doc.Examinations + clickSmoothing)
+		if doc.CTR > 1 {
+			doc.CTR = 1
+		}
+		docs = append(docs, *doc)
+	}
+	sort.Slice(docs, func(i, j int) bool {
+		if docs[i].CTR != docs[j].CTR {
+			return docs[i].CTR > docs[j].CTR
+		}
+		return docs[i].ID < docs[j].ID
+	})
+	return docs
+}
+
+// ── Reading ──
+
+// QueryCTR returns the current click-through of the documents shown for a
+// query in the caller's workspace.
+func (s *ClickService) QueryCTR(ctx context.Context, searchType, query string) (*models.QueryCTR, error) {
+	workspaceID, err := clickWorkspace(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := clickTypes[searchType]; !ok {
+		return nil, apperror.BadQuery("type must be messages, files, users or channels", nil)
+	}
+	normalized := normalizeQuery(query)
+	resp := &models.QueryCTR{Query: normalized, Type: searchType, Documents: []models.DocumentCTR{}}
+	rdb := s.redis.Client()
+	if rdb == nil || normalized == "" {
+		return resp, nil
+	}
+
+	propensity, err := s.propensities(ctx, rdb)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := rdb.HGetAll(ctx, queryClickKey(workspaceID, searchType, normalized)).Result()
+	if err != nil {
+		return nil, apperror.Unavailable("Failed to read click counts", err)
+	}
+	resp.Documents = documentCTRs(fields, propensity)
+	return resp, nil
+}
+
+// Boosts returns the documents to promote for a query and their CTR, as of
+// the last aggregation. A nil service, or a store that cannot be read,
+// boosts nothing.
+func (s *ClickService) Boosts(ctx context.Context, workspaceID, searchType, query string) map[string]float64 {
+	if s == nil || workspaceID == "" {
+		return nil
+	}
+	rdb := s.redis.Client()
+	normalized := normalizeQuery(query)
+	if rdb == nil || normalized == "" {
+		return nil
+	}
+	boosts, err := rdb.ZRevRangeWithScores(ctx, clickBoostKey(queryClickKey(workspaceID, searchType, normalized)), 0, clickBoostDocs-1).Result()
+	if err != nil || len(boosts) == 0 {
+		return nil
+	}
+	out := make(map[string]float64, len(boosts))
+	for _, z := range boosts {
+		if id, ok := z.Member.(string); ok {
+			out[id] = z.Score
+		}
+	}
+	return out
+}
+
+// ── Search Boosting ──
+
+// clickType returns the click-tracking type of index.
+func clickType(index string) string {
+	for searchType, idx := range clickTypes {
+		if idx == index {
+			return searchType
+		}
+	}
+	return ""
+}
+
+// boostClicked promotes, in a lexical query of index, the documents users
+// click for similar queries: each adds up to the workspace's click boost,
+// scaled by its CTR. Boosted documents must still match the query. kNN
+// searches have no query to add to and are left alone.
+func (s *SearchService) boostClicked(ctx context.Context, query map[string]interface{}, index string, params *models.SearchParams) {
+	q, _ := query["query"].(map[string]interface{})
+	boolQuery, ok := q["bool"].(map[string]interface{})
+	if !ok || s.clicks == nil {
+		return
+	}
+	config := s.relevanceConfig(ctx, params)
+	if config == nil || config.ClickBoost <= 0 {
+		return
+	}
+	boosts := s.clicks.Boosts(ctx, searchWorkspace(ctx, params), clickType(index), params.Query)
+	if len(boosts) == 0 {
+		return
+	}
+
+	clicked := make([]map[string]interface{}, 0, len(boosts))
+	for id, ctr := range boosts {
+		clicked = append(clicked, map[string]interface{}{
+			"constant_score": map[string]interface{}{
+				"filter": map[string]interface{}{"ids": map[string]interface{}{"values": []string{id}}},
+				"boost":  config.ClickBoost * ctr,
+			},
+		})
+	}
+	// Map order is random; a stable clause order keeps requests comparable.
+	sort.Slice(clicked, func(i, j int) bool {
+		return compactJSON(clicked[i]) < compactJSON(clicked[j])
+	})
+	should, _ := boolQuery["should"].([]map[string]interface{})
+	boolQuery["should"] = append(should, clicked...)
+}
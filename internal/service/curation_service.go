@@ -0,0 +1,454 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/quckapp/search-service/internal/apperror"
+	"github.com/quckapp/search-service/internal/db"
+	"github.com/quckapp/search-service/internal/models"
+)
+
+const (
+	// maxPinned is the most IDs an ES pinned query takes.
+	maxPinned   = 100
+	maxExcluded = 1000
+)
+
+// searchCachePrefixes maps search types to the prefix of their cached
+// pages, so changing a curation can drop the pages it affects.
+var searchCachePrefixes = map[string]string{
+	"messages": "msg",
+	"files":    "file",
+	"users":    "user",
+	"channels": "ch",
+}
+
+// curationsKey holds a workspace's curations as a hash of ID to rule, so
+// each search loads them all in one read.
+func curationsKey(workspaceID string) string {
+	return "curations:" + workspaceID
+}
+
+// CurationService manages the rules that pin and hide results for given
+// queries. The search path applies them itself; see curate.
+type CurationService struct {
+	redis  *db.RedisManager
+	search *SearchService
+	logger *logrus.Logger
+}
+
+func NewCurationService(redis *db.RedisManager, search *SearchService, logger *logrus.Logger) *CurationService {
+	return &CurationService{redis: redis, search: search, logger: logger}
+}
+
+func (s *CurationService) Create(ctx context.Context, workspaceID string, req *models.CurationRequest) (*models.Curation, error) {
+	c := &models.Curation{
+		ID:          uuid.New().String(),
+		WorkspaceID: workspaceID,
+		CreatedAt:   time.Now(),
+	}
+	if err := setCuration(c, req); err != nil {
+		return nil, err
+	}
+	if err := s.save(ctx, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *CurationService) Update(ctx context.Context, workspaceID, id string, req *models.CurationRequest) (*models.Curation, error) {
+	c, err := s.Get(ctx, workspaceID, id)
+	if err != nil {
+		return nil, err
+	}
+	previousType := c.Type
+	if err := setCuration(c, req); err != nil {
+		return nil, err
+	}
+	if err := s.save(ctx, c); err != nil {
+		return nil, err
+	}
+	if previousType != c.Type {
+		s.dropCachedPages(ctx, previousType)
+	}
+	return c, nil
+}
+
+// setCuration validates req and copies it into c.
+func setCuration(c *models.Curation, req *models.CurationRequest) error {
+	pattern := strings.TrimSpace(req.Pattern)
+	if normalizeQuery(pattern) == "" {
+		return apperror.BadQuery("pattern must contain at least one word", nil)
+	}
+	match := req.Match
+	if match == "" {
+		match = models.CurationMatchExact
+	}
+	if match != models.CurationMatchExact && match != models.CurationMatchContains {
+		return apperror.BadQuery("match must be exact or contains", nil)
+	}
+	searchType := req.Type
+	if searchType == "" {
+		searchType = "messages"
+	}
+	if _, ok := clickTypes[searchType]; !ok {
+		return apperror.BadQuery("type must be messages, files, users or channels", nil)
+	}
+	pinned, excluded := dedupe(req.Pinned), dedupe(req.Excluded)
+	if len(pinned)+len(excluded) == 0 {
+		return apperror.BadQuery("A curation must pin or exclude at least one document", nil)
+	}
+	if len(pinned) > maxPinned {
+		return apperror.BadQuery("A curation pins at most 100 documents", nil)
+	}
+	if len(excluded) > maxExcluded {
+		return apperror.BadQuery("A curation excludes at most 1000 documents", nil)
+	}
+	for _, id := range pinned {
+		for _, ex := range excluded {
+			if id == ex {
+				return apperror.BadQuery("Document "+id+" is both pinned and excluded", nil)
+			}
+		}
+	}
+
+	c.Pattern = pattern
+	c.Match = match
+	c.Type = searchType
+	c.Pinned = pinned
+	c.Excluded = excluded
+	c.UpdatedAt = time.Now()
+	return nil
+}
+
+// dedupe drops empty and repeated IDs, keeping the first of each.
+func dedupe(ids []string) []string {
+	out := []string{}
+	seen := map[string]bool{}
+	for _, id := range ids {
+		if id = strings.TrimSpace(id); id != "" && !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+func (s *CurationService) save(ctx context.Context, c *models.Curation) error {
+	rdb := s.redis.Client()
+	if rdb == nil {
+		return errStorageUnavailable
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	if err := rdb.HSet(ctx, curationsKey(c.WorkspaceID), c.ID, data).Err(); err != nil {
+		return apperror.Unavailable("Failed to save curation", err)
+	}
+	s.dropCachedPages(ctx, c.Type)
+	return nil
+}
+
+func (s *CurationService) Get(ctx context.Context, workspaceID, id string) (*models.Curation, error) {
+	rdb := s.redis.Client()
+	if rdb == nil {
+		return nil, errStorageUnavailable
+	}
+	data, err := rdb.HGet(ctx, curationsKey(workspaceID), id).Bytes()
+	if err != nil {
+		return nil, apperror.NotFound("Curation not found")
+	}
+	var c models.Curation
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// List returns the workspace's curations, optionally of one search type,
+// ordered by pattern.
+func (s *CurationService) List(ctx context.Context, workspaceID, searchType string) ([]models.Curation, error) {
+	all, err := loadCurations(ctx, s.redis, workspaceID)
+	if err == errStorageUnavailable {
+		return nil, err
+	}
+	if err != nil {
+		return nil, apperror.Unavailable("Failed to list curations", err)
+	}
+
+	curations := []models.Curation{}
+	for _, c := range all {
+		if searchType == "" || c.Type == searchType {
+			curations = append(curations, c)
+		}
+	}
+	sort.Slice(curations, func(i, j int) bool {
+		if curations[i].Pattern != curations[j].Pattern {
+			return curations[i].Pattern < curations[j].Pattern
+		}
+		return curations[i].CreatedAt.Before(curations[j].CreatedAt)
+	})
+	return curations, nil
+}
+
+func (s *CurationService) Delete(ctx context.Context, workspaceID, id string) error {
+	c, err := s.Get(ctx, workspaceID, id)
+	if err != nil {
+		return err
+	}
+	if err := s.redis.Client().HDel(ctx, curationsKey(workspaceID), id).Err(); err != nil {
+		return apperror.Unavailable("Failed to delete curation", err)
+	}
+	s.dropCachedPages(ctx, c.Type)
+	return nil
+}
+
+// dropCachedPages removes the cached search pages of searchType, which may
+// have been curated differently.
+func (s *CurationService) dropCachedPages(ctx context.Context, searchType string) {
+	rdb := s.redis.Client()
+	if rdb == nil {
+		return
+	}
+	if _, err := deletePattern(ctx, rdb, "search:"+searchCachePrefixes[searchType]+":*"); err != nil {
+		s.logger.WithError(err).Warn("Failed to drop cached search pages after curation change")
+	}
+}
+
+// ── Previews ──
+
+// Preview runs query with the rule req describes, unsaved, and without
+// any curation, and returns both first pages.
+func (s *CurationService) Preview(ctx context.Context, workspaceID string, req *models.CurationPreviewRequest) (*models.CurationPreview, error) {
+	c := &models.Curation{WorkspaceID: workspaceID}
+	if err := setCuration(c, &req.CurationRequest); err != nil {
+		return nil, err
+	}
+	return s.preview(ctx, c, req.Query)
+}
+
+// PreviewSaved runs query, or the curation's own pattern when query is
+// empty, with and without a stored curation.
+func (s *CurationService) PreviewSaved(ctx context.Context, workspaceID, id, query string) (*models.CurationPreview, error) {
+	c, err := s.Get(ctx, workspaceID, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.preview(ctx, c, query)
+}
+
+func (s *CurationService) preview(ctx context.Context, c *models.Curation, query string) (*models.CurationPreview, error) {
+	if query == "" {
+		query = c.Pattern
+	}
+	search, ok := s.search.searchFunc(c.Type)
+	if !ok {
+		return nil, apperror.BadQuery("type must be messages, files, users or channels", nil)
+	}
+
+	params := func(curation *models.Curation) *models.SearchParams {
+		return &models.SearchParams{Query: query, WorkspaceID: c.WorkspaceID, Page: 1, PerPage: 10, Sort: "relevance", Curation: curation}
+	}
+	original, err := search(ctx, params(&models.Curation{}))
+	if err != nil {
+		return nil, err
+	}
+	curated, err := search(ctx, params(c))
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.CurationPreview{
+		Query:    query,
+		Matches:  curationMatches(c, normalizeQuery(query)),
+		Curation: c,
+		Original: original.Results,
+		Curated:  curated.Results,
+	}, nil
+}
+
+// ── Search Path ──
+
+// loadCurations reads all of a workspace's curations.
+func loadCurations(ctx context.Context, redisManager *db.RedisManager, workspaceID string) ([]models.Curation, error) {
+	rdb := redisManager.Client()
+	if rdb == nil {
+		return nil, errStorageUnavailable
+	}
+	stored, err := rdb.HGetAll(ctx, curationsKey(workspaceID)).Result()
+	if err != nil {
+		return nil, err
+	}
+	curations := make([]models.Curation, 0, len(stored))
+	for _, data := range stored {
+		var c models.Curation
+		if json.Unmarshal([]byte(data), &c) == nil {
+			curations = append(curations, c)
+		}
+	}
+	return curations, nil
+}
+
+// curationMatches reports whether c's pattern matches a normalised query.
+func curationMatches(c *models.Curation, normalized string) bool {
+	pattern := normalizeQuery(c.Pattern)
+	if c.Match == models.CurationMatchContains {
+		terms := map[string]bool{}
+		for _, t := range strings.Fields(normalized) {
+			terms[t] = true
+		}
+		for _, t := range strings.Fields(pattern) {
+			if !terms[t] {
+				return false
+			}
+		}
+		return pattern != ""
+	}
+	return pattern == normalized
+}
+
+// matchCuration picks the curation of searchType for query. An exact match
+// beats a contains match, and a contains pattern with more terms beats one
+// with fewer; remaining ties go to the oldest rule.
+func matchCuration(curations []models.Curation, searchType, query string) *models.Curation {
+	normalized := normalizeQuery(query)
+	var best *models.Curation
+	rank := func(c *models.Curation) int {
+		if c.Match == models.CurationMatchExact {
+			return 1 << 16
+		}
+		return len(strings.Fields(normalizeQuery(c.Pattern)))
+	}
+	for i := range curations {
+		c := &curations[i]
+		if c.Type != searchType || !curationMatches(c, normalized) {
+			continue
+		}
+		if best == nil || rank(c) > rank(best) || (rank(c) == rank(best) && c.CreatedAt.Before(best.CreatedAt)) {
+			best = c
+		}
+	}
+	return best
+}
+
+// curate applies the curation for a search of index to its request body,
+// and returns it, or nil when none applies.
+func (s *SearchService) curate(ctx context.Context, query map[string]interface{}, index string, params *models.SearchParams) *models.Curation {
+	c := s.curationFor(ctx, index, params)
+	applyCuration(query, c)
+	return c
+}
+
+// curationFor returns the curation a search of index uses: the override
+// in params, or else the workspace's best match for the query.
+func (s *SearchService) curationFor(ctx context.Context, index string, params *models.SearchParams) *models.Curation {
+	c := params.Curation
+	if c == nil {
+		workspaceID := searchWorkspace(ctx, params)
+		if workspaceID == "" || params.Query == "" || s.redis.Client() == nil {
+			return nil
+		}
+		curations, err := loadCurations(ctx, s.redis, workspaceID)
+		if err != nil {
+			s.logger.WithError(err).Warn("Failed to load curations; searching uncurated")
+			return nil
+		}
+		c = matchCuration(curations, clickType(index), params.Query)
+	}
+	if c == nil || len(c.Pinned)+len(c.Excluded) == 0 {
+		return nil
+	}
+	return c
+}
+
+// applyCuration filters c's excluded documents out of both the query and
+// any kNN search. Pinned documents are placed with an ES pinned query:
+// around the lexical clauses, or, for a kNN search, beside it with nothing
+// organic to match.
+func applyCuration(query map[string]interface{}, c *models.Curation) {
+	if c == nil {
+		return
+	}
+	q, _ := query["query"].(map[string]interface{})
+	boolQuery, _ := q["bool"].(map[string]interface{})
+	knn, _ := query["knn"].(map[string]interface{})
+
+	if len(c.Excluded) > 0 {
+		exclude := map[string]interface{}{"ids": map[string]interface{}{"values": c.Excluded}}
+		if boolQuery != nil {
+			mustNot, _ := boolQuery["must_not"].([]map[string]interface{})
+			boolQuery["must_not"] = append(mustNot, exclude)
+		}
+		if knn != nil {
+			filters, _ := knn["filter"].([]map[string]interface{})
+			knn["filter"] = append(filters, map[string]interface{}{"bool": map[string]interface{}{"must_not": exclude}})
+		}
+	}
+
+	if len(c.Pinned) > 0 {
+		switch {
+		case boolQuery != nil:
+			must, _ := boolQuery["must"].([]map[string]interface{})
+			boolQuery["must"] = []map[string]interface{}{{"pinned": map[string]interface{}{
+				"ids":     c.Pinned,
+				"organic": map[string]interface{}{"bool": map[string]interface{}{"must": must}},
+			}}}
+		case knn != nil:
+			pinned := map[string]interface{}{
+				"must": []map[string]interface{}{{"pinned": map[string]interface{}{
+					"ids":     c.Pinned,
+					"organic": map[string]interface{}{"match_none": map[string]interface{}{}},
+				}}},
+			}
+			if filters, ok := knn["filter"]; ok {
+				pinned["filter"] = filters
+			}
+			query["query"] = map[string]interface{}{"bool": pinned}
+		}
+	}
+}
+
+// markCurated flags the pinned hits of a curated search and records the
+// curation on the response.
+func markCurated(resp *models.SearchResponse, c *models.Curation) {
+	if c == nil {
+		return
+	}
+	resp.CurationID = c.ID
+	pinned := map[string]bool{}
+	for _, id := range c.Pinned {
+		pinned[id] = true
+	}
+	for i := range resp.Results {
+		resp.Results[i].Pinned = pinned[resp.Results[i].ID]
+	}
+}
+
+// pinFirst moves the curation's pinned hits ahead of the rest, in the
+// curation's order. Fused rankings need this: pinning lifts documents to
+// the top of each retriever, but fusion can still reorder them.
+func pinFirst(hits []models.SearchHit, c *models.Curation) {
+	if c == nil || len(c.Pinned) == 0 {
+		return
+	}
+	order := map[string]int{}
+	for i, id := range c.Pinned {
+		order[id] = i
+	}
+	sort.SliceStable(hits, func(i, j int) bool {
+		pi, iPinned := order[hits[i].ID]
+		pj, jPinned := order[hits[j].ID]
+		if iPinned && jPinned {
+			return pi < pj
+		}
+		return iPinned && !jPinned
+	})
+}
@@ -0,0 +1,654 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+
+	"github.com/quckapp/search-service/internal/apperror"
+	"github.com/quckapp/search-service/internal/db"
+	"github.com/quckapp/search-service/internal/models"
+	"github.com/quckapp/search-service/internal/tenant"
+)
+
+const (
+	DeletionSubjectWorkspace = "workspace"
+	DeletionSubjectUser      = "user"
+
+	deletionPending   = "pending"
+	deletionRunning   = "running"
+	deletionCompleted = "completed"
+	deletionFailed    = "failed"
+
+	// purgeDeadLetters runs before any documents are deleted, so a retried
+	// write cannot bring a deleted document back.
+	purgeDeadLetters = "dead_letters"
+)
+
+// DataDeletionService removes everything stored for a workspace or a user:
+// their documents in every quckapp_* index and the feedback index, and their
+// records in Redis, including failed writes waiting in the dead-letter store
+// and the ingest streams' dead letter streams. Jobs run in the background;
+// the job record is the progress view while running and the completion
+// report afterwards, and is kept indefinitely for audit.
+type DataDeletionService struct {
+	es          *db.ElasticsearchManager
+	redis       *db.RedisManager
+	tenants     *TenantRoutingService
+	deadLetters *DeadLetterService
+	streams     []string
+	logger      *logrus.Logger
+}
+
+func NewDataDeletionService(es *db.ElasticsearchManager, redis *db.RedisManager, tenants *TenantRoutingService, deadLetters *DeadLetterService, streams []string, logger *logrus.Logger) *DataDeletionService {
+	return &DataDeletionService{es: es, redis: redis, tenants: tenants, deadLetters: deadLetters, streams: streams, logger: logger}
+}
+
+func dataDeletionKey(id string) string {
+	return "data_deletion:" + id
+}
+
+// redisPurge deletes one category of Redis records and returns how many
+// records it removed.
+type redisPurge struct {
+	category string
+	run      func(ctx context.Context, rdb *redis.Client) (int64, error)
+}
+
+// StartWorkspaceDeletion records a job deleting all data of workspaceID.
+// The caller must be scoped to that workspace or span all of them.
+func (s *DataDeletionService) StartWorkspaceDeletion(ctx context.Context, workspaceID, requestedBy string) (*models.DataDeletionJob, error) {
+	scope, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, errNoWorkspaceScope
+	}
+	if !scope.AllWorkspaces && scope.WorkspaceID != workspaceID {
+		return nil, apperror.Forbidden("Not allowed to delete workspace " + workspaceID)
+	}
+
+	p, err := s.tenants.Get(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if p.Migration != nil && p.Migration.State == migrationRunning {
+		return nil, apperror.Conflict("Workspace is being migrated; retry once the migration finishes", nil)
+	}
+
+	return s.create(ctx, DeletionSubjectWorkspace, workspaceID, requestedBy)
+}
+
+// StartUserErasure records a job deleting all data of userID. A user's data
+// spans workspaces, so only unrestricted callers may erase it.
+func (s *DataDeletionService) StartUserErasure(ctx context.Context, userID, requestedBy string) (*models.DataDeletionJob, error) {
+	if err := requireAllWorkspaces(ctx); err != nil {
+		return nil, err
+	}
+	return s.create(ctx, DeletionSubjectUser, userID, requestedBy)
+}
+
+func (s *DataDeletionService) create(ctx context.Context, subject, subjectID, requestedBy string) (*models.DataDeletionJob, error) {
+	if subjectID == "" {
+		return nil, apperror.BadQuery("Missing "+subject+" ID", nil)
+	}
+	if s.es.Client() == nil {
+		return nil, errSearchUnavailable
+	}
+
+	job := &models.DataDeletionJob{
+		ID:          uuid.New().String(),
+		Subject:     subject,
+		SubjectID:   subjectID,
+		RequestedBy: requestedBy,
+		State:       deletionPending,
+		Documents:   map[string]int64{},
+		Records:     map[string]int64{},
+		CreatedAt:   time.Now(),
+	}
+	if err := s.save(ctx, job); err != nil {
+		return nil, err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"job_id":       job.ID,
+		"subject":      subject,
+		"subject_id":   subjectID,
+		"requested_by": requestedBy,
+	}).Info("Data deletion requested")
+	return job, nil
+}
+
+func (s *DataDeletionService) save(ctx context.Context, job *models.DataDeletionJob) error {
+	rdb := s.redis.Client()
+	if rdb == nil {
+		return errStorageUnavailable
+	}
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	if err := rdb.Set(ctx, dataDeletionKey(job.ID), data, 0).Err(); err != nil {
+		return apperror.Unavailable("Failed to save data deletion job", err)
+	}
+	rdb.SAdd(ctx, "data_deletions", job.ID)
+	return nil
+}
+
+func (s *DataDeletionService) Get(ctx context.Context, id string) (*models.DataDeletionJob, error) {
+	rdb := s.redis.Client()
+	if rdb == nil {
+		return nil, errStorageUnavailable
+	}
+
+	data, err := rdb.Get(ctx, dataDeletionKey(id)).Bytes()
+	if err == redis.Nil {
+		return nil, apperror.NotFound("Data deletion job not found")
+	}
+	if err != nil {
+		return nil, apperror.Unavailable("Failed to load data deletion job", err)
+	}
+
+	var job models.DataDeletionJob
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// List returns every recorded job, newest first.
+func (s *DataDeletionService) List(ctx context.Context) ([]models.DataDeletionJob, error) {
+	rdb := s.redis.Client()
+	if rdb == nil {
+		return nil, errStorageUnavailable
+	}
+
+	ids, err := rdb.SMembers(ctx, "data_deletions").Result()
+	if err != nil {
+		return nil, apperror.Unavailable("Failed to list data deletion jobs", err)
+	}
+
+	jobs := []models.DataDeletionJob{}
+	for _, id := range ids {
+		job, err := s.Get(ctx, id)
+		if err != nil {
+			continue
+		}
+		jobs = append(jobs, *job)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].CreatedAt.After(jobs[j].CreatedAt) })
+	return jobs, nil
+}
+
+// Run performs a pending job. Every step runs even if an earlier one fails,
+// so one broken index does not leave the rest of the data in place; the
+// failures are listed in the report and the job ends as failed.
+func (s *DataDeletionService) Run(ctx context.Context, id string) error {
+	job, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if job.State != deletionPending {
+		return apperror.Conflict("Data deletion job has already run", nil)
+	}
+
+	es := s.es.Client()
+	rdb := s.redis.Client()
+	if es == nil || rdb == nil {
+		return s.finish(ctx, job, errSearchUnavailable)
+	}
+
+	now := time.Now()
+	job.State = deletionRunning
+	job.StartedAt = &now
+	job.Step = "listing indices"
+	s.save(ctx, job)
+
+	indices, err := listQuckappIndices(ctx, es)
+	if err != nil {
+		return s.finish(ctx, job, err)
+	}
+	indices = append(indices, indexFeedback)
+
+	var purges []redisPurge
+	if job.Subject == DeletionSubjectWorkspace {
+		purges = s.workspacePurges(job.SubjectID)
+	} else {
+		purges = s.userPurges(job.SubjectID)
+	}
+
+	total := len(indices) + len(purges)
+	done := 0
+	step := func(name string) {
+		job.Step = name
+		job.Progress = done * 100 / total
+		s.save(ctx, job)
+		done++
+	}
+
+	purge := func(p redisPurge) {
+		step("purging " + p.category)
+		deleted, err := p.run(ctx, rdb)
+		job.Records[p.category] += deleted
+		job.RecordsDeleted += deleted
+		if err != nil {
+			job.Errors = append(job.Errors, fmt.Sprintf("%s: %s", p.category, err.Error()))
+		}
+	}
+
+	for _, p := range purges {
+		if p.category == purgeDeadLetters {
+			purge(p)
+		}
+	}
+
+	for _, index := range indices {
+		step("deleting documents from " + index)
+		var deleted int64
+		if job.Subject == DeletionSubjectWorkspace {
+			deleted, err = deleteWorkspaceDocuments(ctx, es, index, job.SubjectID)
+		} else {
+			deleted, err = deleteUserDocuments(ctx, es, index, job.SubjectID)
+		}
+		job.Documents[index] += deleted
+		job.DocumentsDeleted += deleted
+		if err != nil {
+			job.Errors = append(job.Errors, fmt.Sprintf("%s: %s", index, err.Error()))
+		}
+	}
+
+	for _, p := range purges {
+		if p.category != purgeDeadLetters {
+			purge(p)
+		}
+	}
+
+	if len(job.Errors) > 0 {
+		return s.finish(ctx, job, fmt.Errorf("%d steps failed", len(job.Errors)))
+	}
+	return s.finish(ctx, job, nil)
+}
+
+func (s *DataDeletionService) finish(ctx context.Context, job *models.DataDeletionJob, err error) error {
+	now := time.Now()
+	job.FinishedAt = &now
+	job.Step = "done"
+	if err != nil {
+		job.State = deletionFailed
+		if len(job.Errors) == 0 {
+			job.Errors = []string{err.Error()}
+		}
+	} else {
+		job.State = deletionCompleted
+		job.Progress = 100
+	}
+	// Record the outcome even if the request context is gone.
+	if saveErr := s.save(context.Background(), job); saveErr != nil {
+		s.logger.WithError(saveErr).WithField("job_id", job.ID).Error("Failed to record data deletion report")
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"job_id":            job.ID,
+		"subject":           job.Subject,
+		"subject_id":        job.SubjectID,
+		"state":             job.State,
+		"documents_deleted": job.DocumentsDeleted,
+		"records_deleted":   job.RecordsDeleted,
+	}).Info("Data deletion finished")
+	return err
+}
+
+// ── Elasticsearch ──
+
+func listQuckappIndices(ctx context.Context, es *elasticsearch.Client) ([]string, error) {
+	res, err := es.Cat.Indices(
+		es.Cat.Indices.WithContext(ctx),
+		es.Cat.Indices.WithIndex("quckapp_*"),
+		es.Cat.Indices.WithH("index"),
+		es.Cat.Indices.WithFormat("json"),
+	)
+	var rows []struct {
+		Index string `json:"index"`
+	}
+	if err := readResponse(res, err, "Failed to list indices", &rows); err != nil {
+		return nil, err
+	}
+
+	indices := make([]string, 0, len(rows))
+	for _, row := range rows {
+		indices = append(indices, row.Index)
+	}
+	return indices, nil
+}
+
+// deleteWorkspaceDocuments drops the workspace's dedicated indices whole and
+// deletes its documents from shared ones. Other tenants' dedicated indices
+// are left alone.
+func deleteWorkspaceDocuments(ctx context.Context, es *elasticsearch.Client, index, workspaceID string) (int64, error) {
+	if isDedicatedIndexOf(index, workspaceID) {
+		res, err := es.Count(es.Count.WithIndex(index), es.Count.WithContext(ctx))
+		var count struct {
+			Count int64 `json:"count"`
+		}
+		readResponse(res, err, "Failed to count documents", &count)
+
+		res, err = es.Indices.Delete([]string{index}, es.Indices.Delete.WithContext(ctx))
+		if err := readResponse(res, err, "Failed to delete dedicated index", nil); err != nil && apperror.KindOf(err) != apperror.KindIndexNotFound {
+			return 0, err
+		}
+		return count.Count, nil
+	}
+	if strings.Contains(index, "-ws-") {
+		return 0, nil
+	}
+	return deleteByWorkspace(ctx, es, []string{index}, workspaceID, "")
+}
+
+// deleteUserDocuments deletes everything the user authored and, in the
+// users index, their profile document.
+func deleteUserDocuments(ctx context.Context, es *elasticsearch.Client, index, userID string) (int64, error) {
+	should := []interface{}{
+		map[string]interface{}{"term": map[string]interface{}{"user_id": userID}},
+	}
+	if strings.HasPrefix(index, indexUsers) {
+		should = append(should, map[string]interface{}{
+			"ids": map[string]interface{}{"values": []string{userID}},
+		})
+	}
+	return deleteMatching(ctx, es, []string{index}, map[string]interface{}{
+		"bool": map[string]interface{}{"should": should, "minimum_should_match": 1},
+	}, "")
+}
+
+// ── Redis ──
+
+// workspacePurges lists the Redis records belonging to a workspace. Records
+// owned by users (saved searches, alerts, templates, history) are removed
+// when they were created in the workspace. Result bookmarks carry no
+// workspace and are only removed by user erasure.
+func (s *DataDeletionService) workspacePurges(workspaceID string) []redisPurge {
+	return []redisPurge{
+		{purgeDeadLetters, func(ctx context.Context, rdb *redis.Client) (int64, error) {
+			n, err := s.deadLetters.purge(ctx, rdb, func(dl *models.DeadLetter) bool {
+				return dl.WorkspaceID == workspaceID || getString(dl.Document, "workspace_id") == workspaceID
+			})
+			if err != nil {
+				return n, err
+			}
+			m, err := deleteDeadLetterEvents(ctx, rdb, s.streams, func(doc map[string]interface{}) bool {
+				return getString(doc, "workspace_id") == workspaceID
+			})
+			return n + m, err
+		}},
+		{"analytics", func(ctx context.Context, rdb *redis.Client) (int64, error) {
+			return deletePattern(ctx, rdb, "search_analytics:"+workspaceID+":*")
+		}},
+		{"relevance", func(ctx context.Context, rdb *redis.Client) (int64, error) {
+			return rdb.Del(ctx, "relevance_config:"+workspaceID).Result()
+		}},
+		{"clicks", func(ctx context.Context, rdb *redis.Client) (int64, error) {
+			return deletePattern(ctx, rdb, "ctr:"+workspaceID+":*")
+		}},
+		{"judgment_lists", func(ctx context.Context, rdb *redis.Client) (int64, error) {
+			n, err := deletePattern(ctx, rdb, "judgment_list:"+workspaceID+":*")
+			rdb.Del(ctx, "judgment_lists:"+workspaceID)
+			return n, err
+		}},
+		{"curations", func(ctx context.Context, rdb *redis.Client) (int64, error) {
+			return rdb.Del(ctx, curationsKey(workspaceID)).Result()
+		}},
+		{"synonyms", func(ctx context.Context, rdb *redis.Client) (int64, error) {
+			n, err := deletePattern(ctx, rdb, "synonym:"+workspaceID+":*")
+			rdb.Del(ctx, "synonyms:"+workspaceID)
+			return n, err
+		}},
+		{"scopes", func(ctx context.Context, rdb *redis.Client) (int64, error) {
+			return deletePattern(ctx, rdb, "search_scope:*:"+workspaceID)
+		}},
+		{"saved_searches", func(ctx context.Context, rdb *redis.Client) (int64, error) {
+			return deleteJSONMatching(ctx, rdb, "saved_search:*", "workspace_id", workspaceID, func(userID, id string) {
+				rdb.SRem(ctx, "saved_searches:"+userID, id)
+			})
+		}},
+		{"alerts", func(ctx context.Context, rdb *redis.Client) (int64, error) {
+			return deleteJSONMatching(ctx, rdb, "search_alert:*", "workspace_id", workspaceID, func(userID, id string) {
+				rdb.SRem(ctx, "search_alerts:"+userID, id)
+				rdb.Del(ctx, "alert_history:"+id)
+			})
+		}},
+		{"templates", func(ctx context.Context, rdb *redis.Client) (int64, error) {
+			return deleteJSONMatching(ctx, rdb, "search_template:*", "workspace_id", workspaceID, nil)
+		}},
+		{"history", func(ctx context.Context, rdb *redis.Client) (int64, error) {
+			return deleteHistoryEntries(ctx, rdb, workspaceID)
+		}},
+		{"api_keys", func(ctx context.Context, rdb *redis.Client) (int64, error) {
+			return deleteWorkspaceAPIKeys(ctx, rdb, workspaceID)
+		}},
+		{"quotas", func(ctx context.Context, rdb *redis.Client) (int64, error) {
+			n, err := deletePattern(ctx, rdb, "quota:index:"+workspaceID+":*")
+			m, _ := rdb.Del(ctx, "quota:index:limit:"+workspaceID).Result()
+			return n + m, err
+		}},
+		{"rate_limits", func(ctx context.Context, rdb *redis.Client) (int64, error) {
+			return deletePattern(ctx, rdb, "ratelimit:*:"+workspaceID+":*")
+		}},
+		{"placement", func(ctx context.Context, rdb *redis.Client) (int64, error) {
+			return s.tenants.remove(ctx, workspaceID)
+		}},
+		{"search_cache", func(ctx context.Context, rdb *redis.Client) (int64, error) {
+			return deletePattern(ctx, rdb, "search:*")
+		}},
+	}
+}
+
+// userPurges lists the Redis records belonging to a user. Workspace
+// analytics only hold aggregate query counts and are left alone.
+func (s *DataDeletionService) userPurges(userID string) []redisPurge {
+	return []redisPurge{
+		{purgeDeadLetters, func(ctx context.Context, rdb *redis.Client) (int64, error) {
+			n, err := s.deadLetters.purge(ctx, rdb, func(dl *models.DeadLetter) bool {
+				return getString(dl.Document, "user_id") == userID ||
+					(strings.HasPrefix(dl.Index, indexUsers) && dl.DocumentID == userID)
+			})
+			if err != nil {
+				return n, err
+			}
+			m, err := deleteDeadLetterEvents(ctx, rdb, s.streams, func(doc map[string]interface{}) bool {
+				return getString(doc, "user_id") == userID
+			})
+			return n + m, err
+		}},
+		{"history", func(ctx context.Context, rdb *redis.Client) (int64, error) {
+			return rdb.Del(ctx, "search_history:"+userID).Result()
+		}},
+		{"saved_searches", func(ctx context.Context, rdb *redis.Client) (int64, error) {
+			n, err := deletePattern(ctx, rdb, "saved_search:"+userID+":*")
+			rdb.Del(ctx, "saved_searches:"+userID)
+			return n, err
+		}},
+		{"alerts", func(ctx context.Context, rdb *redis.Client) (int64, error) {
+			ids, _ := rdb.SMembers(ctx, "search_alerts:"+userID).Result()
+			for _, id := range ids {
+				rdb.Del(ctx, "alert_history:"+id)
+			}
+			n, err := deletePattern(ctx, rdb, "search_alert:"+userID+":*")
+			rdb.Del(ctx, "search_alerts:"+userID)
+			return n, err
+		}},
+		{"templates", func(ctx context.Context, rdb *redis.Client) (int64, error) {
+			return deletePattern(ctx, rdb, "search_template:"+userID+":*")
+		}},
+		{"bookmarks", func(ctx context.Context, rdb *redis.Client) (int64, error) {
+			return deletePattern(ctx, rdb, "search_bookmark:"+userID+":*")
+		}},
+		{"feedback", func(ctx context.Context, rdb *redis.Client) (int64, error) {
+			// Feedback not yet moved to its index.
+			return deletePattern(ctx, rdb, "search_feedback:"+userID+":*")
+		}},
+		{"scopes", func(ctx context.Context, rdb *redis.Client) (int64, error) {
+			return deletePattern(ctx, rdb, "search_scope:"+userID+":*")
+		}},
+		{"rate_limits", func(ctx context.Context, rdb *redis.Client) (int64, error) {
+			return deletePattern(ctx, rdb, "ratelimit:*:"+userID)
+		}},
+		{"clicks", func(ctx context.Context, rdb *redis.Client) (int64, error) {
+			// Which results the user was counted for; the counts themselves
+			// are per query and document.
+			return deletePattern(ctx, rdb, "ctr:*:user:"+userID)
+		}},
+		{"search_cache", func(ctx context.Context, rdb *redis.Client) (int64, error) {
+			return deletePattern(ctx, rdb, "search:*")
+		}},
+	}
+}
+
+// deleteDeadLetterEvents removes the entries of each stream's dead letter
+// stream whose event payload match accepts.
+func deleteDeadLetterEvents(ctx context.Context, rdb *redis.Client, streams []string, match func(doc map[string]interface{}) bool) (int64, error) {
+	var deleted int64
+	for _, stream := range streams {
+		key := deadLetterStream(stream)
+		start := "-"
+		for {
+			msgs, err := rdb.XRangeN(ctx, key, start, "+", 100).Result()
+			if err != nil {
+				return deleted, err
+			}
+			var ids []string
+			for _, msg := range msgs {
+				payload, _ := msg.Values["payload"].(string)
+				var doc map[string]interface{}
+				if json.Unmarshal([]byte(payload), &doc) == nil && match(doc) {
+					ids = append(ids, msg.ID)
+				}
+			}
+			if len(ids) > 0 {
+				n, err := rdb.XDel(ctx, key, ids...).Result()
+				deleted += n
+				if err != nil {
+					return deleted, err
+				}
+			}
+			if len(msgs) < 100 {
+				break
+			}
+			start = "(" + msgs[len(msgs)-1].ID
+		}
+	}
+	return deleted, nil
+}
+
+// deletePattern deletes every key matching pattern.
+func deletePattern(ctx context.Context, rdb *redis.Client, pattern string) (int64, error) {
+	var deleted int64
+	var batch []string
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		n, err := rdb.Del(ctx, batch...).Result()
+		deleted += n
+		batch = batch[:0]
+		return err
+	}
+
+	iter := rdb.Scan(ctx, 0, pattern, 100).Iterator()
+	for iter.Next(ctx) {
+		batch = append(batch, iter.Val())
+		if len(batch) == 100 {
+			if err := flush(); err != nil {
+				return deleted, err
+			}
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return deleted, err
+	}
+	return deleted, flush()
+}
+
+// deleteJSONMatching deletes the "<prefix>:<user>:<id>" JSON records matching
+// pattern whose field equals value, calling onDelete for each so the owning
+// user's index set can be updated.
+func deleteJSONMatching(ctx context.Context, rdb *redis.Client, pattern, field, value string, onDelete func(userID, id string)) (int64, error) {
+	var deleted int64
+	iter := rdb.Scan(ctx, 0, pattern, 100).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+		data, err := rdb.Get(ctx, key).Bytes()
+		if err != nil {
+			continue
+		}
+		var record map[string]interface{}
+		if json.Unmarshal(data, &record) != nil || getString(record, field) != value {
+			continue
+		}
+		if err := rdb.Del(ctx, key).Err(); err != nil {
+			return deleted, err
+		}
+		deleted++
+		if parts := strings.SplitN(key, ":", 3); onDelete != nil && len(parts) == 3 {
+			onDelete(parts[1], parts[2])
+		}
+	}
+	return deleted, iter.Err()
+}
+
+// deleteHistoryEntries removes the workspace's entries from every user's
+// search history list.
+func deleteHistoryEntries(ctx context.Context, rdb *redis.Client, workspaceID string) (int64, error) {
+	var deleted int64
+	iter := rdb.Scan(ctx, 0, "search_history:*", 100).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+		entries, err := rdb.LRange(ctx, key, 0, -1).Result()
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			var h models.SearchHistory
+			if json.Unmarshal([]byte(entry), &h) != nil || h.WorkspaceID != workspaceID {
+				continue
+			}
+			n, err := rdb.LRem(ctx, key, 1, entry).Result()
+			if err != nil {
+				return deleted, err
+			}
+			deleted += n
+		}
+	}
+	return deleted, iter.Err()
+}
+
+// deleteWorkspaceAPIKeys revokes the API keys bound to the workspace.
+func deleteWorkspaceAPIKeys(ctx context.Context, rdb *redis.Client, workspaceID string) (int64, error) {
+	ids, err := rdb.SMembers(ctx, "apikeys").Result()
+	if err != nil {
+		return 0, err
+	}
+
+	var deleted int64
+	for _, id := range ids {
+		data, err := rdb.Get(ctx, apiKeyKey(id)).Bytes()
+		if err != nil {
+			continue
+		}
+		var key models.APIKey
+		if json.Unmarshal(data, &key) != nil || key.WorkspaceID != workspaceID {
+			continue
+		}
+		if err := rdb.Del(ctx, apiKeyKey(id), apiKeyLastUsedKey(id)).Err(); err != nil {
+			return deleted, err
+		}
+		rdb.SRem(ctx, "apikeys", id)
+		deleted++
+	}
+	return deleted, nil
+}
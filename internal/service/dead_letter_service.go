@@ -0,0 +1,404 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+
+	"github.com/quckapp/search-service/internal/apperror"
+	"github.com/quckapp/search-service/internal/config"
+	"github.com/quckapp/search-service/internal/db"
+	"github.com/quckapp/search-service/internal/models"
+	"github.com/quckapp/search-service/internal/tenant"
+)
+
+const (
+	opIndex  = "index"
+	opUpdate = "update"
+	opDelete = "delete"
+
+	deadLetterSourceAPI    = "api"
+	deadLetterSourceIngest = "ingest"
+
+	deadLetterRetrying = "retrying"
+	deadLetterFailed   = "failed"
+
+	deadLetterPollInterval = 5 * time.Second
+	deadLetterRetryBatch   = 50
+)
+
+// DeadLetterService keeps index writes that failed so a backend outage does
+// not leave permanent gaps. Entries are retried with exponential backoff;
+// errors that retrying cannot fix (a rejected mapping, say) and entries out
+// of attempts wait for an admin to replay or discard them.
+type DeadLetterService struct {
+	es      *db.ElasticsearchManager
+	redis   *db.RedisManager
+	tenants *TenantRoutingService
+	cfg     config.DeadLetter
+	logger  *logrus.Logger
+}
+
+func NewDeadLetterService(es *db.ElasticsearchManager, redis *db.RedisManager, tenants *TenantRoutingService, cfg config.DeadLetter, logger *logrus.Logger) *DeadLetterService {
+	return &DeadLetterService{es: es, redis: redis, tenants: tenants, cfg: cfg, logger: logger}
+}
+
+func deadLetterKey(id string) string {
+	return "deadletter:" + id
+}
+
+// retryable reports whether a write that failed with err may succeed later.
+func retryable(err error) bool {
+	switch apperror.KindOf(err) {
+	case apperror.KindUnavailable, apperror.KindTimeout, apperror.KindRateLimited, apperror.KindInternal:
+		return true
+	}
+	return false
+}
+
+// failedWrite describes a write made in the caller's scope, for Capture.
+func failedWrite(ctx context.Context, op, index, id string, doc map[string]interface{}, version int64) *models.DeadLetter {
+	scope, _ := tenant.FromContext(ctx)
+	return &models.DeadLetter{
+		Operation:   op,
+		Index:       index,
+		DocumentID:  id,
+		WorkspaceID: scope.WorkspaceID,
+		Document:    doc,
+		Version:     version,
+		Source:      deadLetterSourceAPI,
+	}
+}
+
+// Capture stores a failed write and returns cause, so call sites can
+// return s.deadLetters.Capture(...). Errors about the request itself, such
+// as a missing document, a foreign workspace or a stale version, are not
+// stored; neither is anything when the service is nil.
+func (s *DeadLetterService) Capture(ctx context.Context, dl *models.DeadLetter, cause error) error {
+	if s == nil {
+		return cause
+	}
+	switch apperror.KindOf(cause) {
+	case apperror.KindNotFound, apperror.KindForbidden, apperror.KindConflict:
+		return cause
+	}
+	s.store(ctx, dl, cause)
+	return cause
+}
+
+// store records dl's first failed attempt. An entry that cannot be stored
+// is logged in full so it is not lost.
+func (s *DeadLetterService) store(ctx context.Context, dl *models.DeadLetter, cause error) error {
+	// The request may be over, but the entry must still be stored.
+	ctx = context.WithoutCancel(ctx)
+
+	now := time.Now()
+	dl.ID = uuid.New().String()
+	dl.CreatedAt = now
+	s.recordAttempt(dl, cause)
+
+	if err := s.save(ctx, dl); err != nil {
+		data, _ := json.Marshal(dl)
+		s.logger.WithError(err).WithField("dead_letter", string(data)).Error("Failed to store dead letter")
+		return err
+	}
+	s.redis.Client().ZAdd(ctx, "deadletters", redis.Z{Score: float64(now.UnixMilli()), Member: dl.ID})
+
+	s.logger.WithFields(logrus.Fields{
+		"dead_letter_id": dl.ID,
+		"operation":      dl.Operation,
+		"index":          dl.Index,
+		"document_id":    dl.DocumentID,
+		"state":          dl.State,
+	}).WithError(cause).Warn("Index write failed; stored as dead letter")
+	return nil
+}
+
+// recordAttempt counts a failed attempt and schedules the next one, if any.
+func (s *DeadLetterService) recordAttempt(dl *models.DeadLetter, cause error) {
+	dl.Attempts++
+	dl.LastError = cause.Error()
+	dl.UpdatedAt = time.Now()
+	dl.NextAttemptAt = nil
+	dl.State = deadLetterFailed
+
+	if retryable(cause) && dl.Attempts < s.cfg.MaxAttempts {
+		backoff := s.cfg.Backoff << (dl.Attempts - 1)
+		if backoff > s.cfg.MaxBackoff || backoff <= 0 {
+			backoff = s.cfg.MaxBackoff
+		}
+		next := dl.UpdatedAt.Add(backoff)
+		dl.NextAttemptAt = &next
+		dl.State = deadLetterRetrying
+	}
+}
+
+func (s *DeadLetterService) save(ctx context.Context, dl *models.DeadLetter) error {
+	rdb := s.redis.Client()
+	if rdb == nil {
+		return errStorageUnavailable
+	}
+	data, err := json.Marshal(dl)
+	if err != nil {
+		return err
+	}
+	if err := rdb.Set(ctx, deadLetterKey(dl.ID), data, 0).Err(); err != nil {
+		return apperror.Unavailable("Failed to save dead letter", err)
+	}
+	if dl.NextAttemptAt != nil {
+		rdb.ZAdd(ctx, "deadletters:due", redis.Z{Score: float64(dl.NextAttemptAt.Unix()), Member: dl.ID})
+	} else {
+		rdb.ZRem(ctx, "deadletters:due", dl.ID)
+	}
+	return nil
+}
+
+func (s *DeadLetterService) load(ctx context.Context, rdb *redis.Client, id string) (*models.DeadLetter, error) {
+	data, err := rdb.Get(ctx, deadLetterKey(id)).Bytes()
+	if err == redis.Nil {
+		return nil, apperror.NotFound("Dead letter not found")
+	}
+	if err != nil {
+		return nil, apperror.Unavailable("Failed to load dead letter", err)
+	}
+	var dl models.DeadLetter
+	if err := json.Unmarshal(data, &dl); err != nil {
+		return nil, apperror.Internal("Corrupt dead letter record", err)
+	}
+	return &dl, nil
+}
+
+func (s *DeadLetterService) remove(ctx context.Context, rdb *redis.Client, id string) error {
+	if err := rdb.Del(ctx, deadLetterKey(id)).Err(); err != nil {
+		return apperror.Unavailable("Failed to delete dead letter", err)
+	}
+	rdb.ZRem(ctx, "deadletters", id)
+	rdb.ZRem(ctx, "deadletters:due", id)
+	return nil
+}
+
+// visible hides entries from other workspaces from scoped admins.
+func visible(ctx context.Context, dl *models.DeadLetter) bool {
+	scope, ok := tenant.FromContext(ctx)
+	return ok && (scope.Unrestricted() || scope.WorkspaceID == dl.WorkspaceID)
+}
+
+// ── Admin ──
+
+// List returns entries newest first, filtered by state and index.
+func (s *DeadLetterService) List(ctx context.Context, q *models.DeadLetterQuery) (*models.DeadLetterList, error) {
+	rdb := s.redis.Client()
+	if rdb == nil {
+		return nil, errStorageUnavailable
+	}
+	if q.Page < 1 {
+		q.Page = 1
+	}
+	if q.PerPage < 1 || q.PerPage > 200 {
+		q.PerPage = 50
+	}
+
+	ids, err := rdb.ZRevRange(ctx, "deadletters", 0, -1).Result()
+	if err != nil {
+		return nil, apperror.Unavailable("Failed to list dead letters", err)
+	}
+
+	list := &models.DeadLetterList{Entries: []models.DeadLetter{}, Page: q.Page, PerPage: q.PerPage}
+	from := (q.Page - 1) * q.PerPage
+	for _, id := range ids {
+		dl, err := s.load(ctx, rdb, id)
+		if err != nil || !visible(ctx, dl) {
+			continue
+		}
+		if (q.State != "" && dl.State != q.State) || (q.Index != "" && dl.Index != q.Index) {
+			continue
+		}
+		if list.Total >= from && len(list.Entries) < q.PerPage {
+			list.Entries = append(list.Entries, *dl)
+		}
+		list.Total++
+	}
+	return list, nil
+}
+
+func (s *DeadLetterService) Get(ctx context.Context, id string) (*models.DeadLetter, error) {
+	rdb := s.redis.Client()
+	if rdb == nil {
+		return nil, errStorageUnavailable
+	}
+	dl, err := s.load(ctx, rdb, id)
+	if err != nil {
+		return nil, err
+	}
+	if !visible(ctx, dl) {
+		return nil, apperror.NotFound("Dead letter not found")
+	}
+	return dl, nil
+}
+
+// Replay retries an entry now, whatever its state. On success the entry is
+// removed; on failure the attempt is recorded and the error returned.
+func (s *DeadLetterService) Replay(ctx context.Context, id string) error {
+	dl, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	return s.attempt(ctx, dl)
+}
+
+func (s *DeadLetterService) Discard(ctx context.Context, id string) error {
+	if _, err := s.Get(ctx, id); err != nil {
+		return err
+	}
+	return s.remove(ctx, s.redis.Client(), id)
+}
+
+// purge removes every entry match accepts, whatever its state and scope,
+// for data deletion.
+func (s *DeadLetterService) purge(ctx context.Context, rdb *redis.Client, match func(*models.DeadLetter) bool) (int64, error) {
+	ids, err := rdb.ZRange(ctx, "deadletters", 0, -1).Result()
+	if err != nil {
+		return 0, apperror.Unavailable("Failed to list dead letters", err)
+	}
+
+	var deleted int64
+	for _, id := range ids {
+		dl, err := s.load(ctx, rdb, id)
+		if err != nil || !match(dl) {
+			continue
+		}
+		if err := s.remove(ctx, rdb, id); err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+// ── Retries ──
+
+// Run retries due entries until ctx is cancelled. Replicas coordinate
+// through the due set: whoever removes an entry from it performs the retry.
+func (s *DeadLetterService) Run(ctx context.Context) {
+	ticker := time.NewTicker(deadLetterPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		rdb := s.redis.Client()
+		if rdb == nil || s.es.Client() == nil {
+			continue
+		}
+		ids, err := rdb.ZRangeByScore(ctx, "deadletters:due", &redis.ZRangeBy{
+			Min:   "-inf",
+			Max:   strconv.FormatInt(time.Now().Unix(), 10),
+			Count: deadLetterRetryBatch,
+		}).Result()
+		if err != nil {
+			continue
+		}
+
+		for _, id := range ids {
+			if n, err := rdb.ZRem(ctx, "deadletters:due", id).Result(); err != nil || n == 0 {
+				continue
+			}
+			dl, err := s.load(ctx, rdb, id)
+			if err != nil {
+				continue
+			}
+			s.attempt(ctx, dl)
+		}
+	}
+}
+
+func (s *DeadLetterService) attempt(ctx context.Context, dl *models.DeadLetter) error {
+	es := s.es.Client()
+	var err error = errSearchUnavailable
+	if es != nil {
+		err = s.execute(ctx, es, dl)
+	}
+
+	if err == nil {
+		s.logger.WithFields(logrus.Fields{
+			"dead_letter_id": dl.ID,
+			"attempts":       dl.Attempts + 1,
+		}).Info("Dead letter replayed")
+		return s.remove(ctx, s.redis.Client(), dl.ID)
+	}
+
+	s.recordAttempt(dl, err)
+	if saveErr := s.save(ctx, dl); saveErr != nil {
+		s.logger.WithError(saveErr).WithField("dead_letter_id", dl.ID).Error("Failed to record dead letter attempt")
+	}
+	return err
+}
+
+// execute performs the stored write again. API writes run in the scope they
+// were made in, so ownership is checked as it was originally; ingested
+// events target their payload's workspace directly, as the consumer does.
+// A delete of a missing document, or a versioned write that a newer version
+// has overtaken, leaves nothing to do.
+func (s *DeadLetterService) execute(ctx context.Context, es *elasticsearch.Client, dl *models.DeadLetter) error {
+	scope := tenant.Scope{WorkspaceID: dl.WorkspaceID}
+	if dl.WorkspaceID == "" {
+		scope = tenant.Scope{AllWorkspaces: true}
+	}
+	ctx = tenant.WithScope(ctx, scope)
+
+	var err error
+	switch {
+	case dl.Source == deadLetterSourceIngest && dl.Version > 0 && dl.Operation == opUpdate:
+		var route indexRoute
+		if route, err = documentRoute(ctx, s.tenants, dl.Index, dl.Document); err == nil {
+			err = updateVersioned(ctx, es, route, dl.DocumentID, versionedUpdate(dl.Document, dl.Version, false), "Failed to update document")
+		}
+
+	case dl.Operation == opIndex:
+		var route indexRoute
+		if route, err = documentRoute(ctx, s.tenants, dl.Index, dl.Document); err == nil {
+			err = indexVersioned(ctx, es, route, dl.DocumentID, dl.Document, dl.Version)
+		}
+
+	case dl.Operation == opUpdate:
+		var route indexRoute
+		if dl.Source == deadLetterSourceIngest {
+			route, err = documentRoute(ctx, s.tenants, dl.Index, dl.Document)
+		} else {
+			route, err = resolveDocument(ctx, es, s.tenants, dl.Index, dl.DocumentID)
+		}
+		if err != nil {
+			return err
+		}
+		buf, err := encodeBody(map[string]interface{}{"doc": dl.Document, "doc_as_upsert": dl.Upsert})
+		if err != nil {
+			return err
+		}
+		res, err := es.Update(route.Index, dl.DocumentID, buf, es.Update.WithRouting(route.Routing), es.Update.WithContext(ctx))
+		return readResponse(res, err, "Failed to update document", nil)
+
+	case dl.Operation == opDelete:
+		err = deleteDocument(ctx, es, s.tenants, dl.Index, dl.DocumentID, dl.Version)
+		if apperror.KindOf(err) == apperror.KindNotFound {
+			return nil
+		}
+
+	default:
+		return apperror.BadQuery("Unknown operation "+dl.Operation, nil)
+	}
+
+	if dl.Version > 0 && apperror.KindOf(err) == apperror.KindConflict {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,522 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+
+	"github.com/quckapp/search-service/internal/apperror"
+	"github.com/quckapp/search-service/internal/models"
+	"github.com/quckapp/search-service/internal/tenant"
+)
+
+// ── Elasticsearch Errors ──
+
+// ESError is the decoded body of a failed Elasticsearch request. It is
+// wrapped inside the apperror returned to callers, so errors.As can recover
+// the ES type and reason when a caller needs to act on them.
+type ESError struct {
+	Status       int                   `json:"status"`
+	Type         string                `json:"type"`
+	Reason       string                `json:"reason"`
+	Index        string                `json:"index,omitempty"`
+	RootCause    []ESErrorCause        `json:"root_cause,omitempty"`
+	FailedShards []models.ShardFailure `json:"failed_shards,omitempty"`
+}
+
+type ESErrorCause struct {
+	Type   string `json:"type"`
+	Reason string `json:"reason"`
+	Index  string `json:"index,omitempty"`
+}
+
+func (e *ESError) Error() string {
+	if e.Type == "" {
+		return fmt.Sprintf("elasticsearch returned status %d", e.Status)
+	}
+	msg := e.Type + ": " + e.Reason
+	// The top-level reason of a search_phase_execution_exception is generic;
+	// the root cause says what was actually wrong with the query.
+	if len(e.RootCause) > 0 && e.RootCause[0].Reason != e.Reason {
+		msg += " (" + e.RootCause[0].Type + ": " + e.RootCause[0].Reason + ")"
+	}
+	return msg
+}
+
+// decodeESError reads the error body of a non-2xx response. ES normally
+// answers with {"error": {...}, "status": n}, but some endpoints (e.g. a
+// delete of a missing document) return a plain result body instead.
+func decodeESError(res *esapi.Response) *ESError {
+	esErr := &ESError{Status: res.StatusCode}
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil || len(body) == 0 {
+		return esErr
+	}
+
+	var raw struct {
+		Error json.RawMessage `json:"error"`
+		// Document APIs report a missing document as {"result": "not_found"}.
+		Result string `json:"result"`
+	}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		esErr.Reason = strings.TrimSpace(string(body))
+		return esErr
+	}
+	if raw.Result == "not_found" {
+		esErr.Type = "document_missing_exception"
+		esErr.Reason = "document not found"
+		return esErr
+	}
+	if len(raw.Error) == 0 {
+		return esErr
+	}
+
+	// Very old or proxied clusters may return the error as a plain string.
+	var reason string
+	if json.Unmarshal(raw.Error, &reason) == nil {
+		esErr.Reason = reason
+		return esErr
+	}
+
+	var detail struct {
+		Type         string         `json:"type"`
+		Reason       string         `json:"reason"`
+		Index        string         `json:"index"`
+		RootCause    []ESErrorCause `json:"root_cause"`
+		FailedShards []struct {
+			Shard  int    `json:"shard"`
+			Index  string `json:"index"`
+			Node   string `json:"node"`
+			Reason struct {
+				Type   string `json:"type"`
+				Reason string `json:"reason"`
+			} `json:"reason"`
+		} `json:"failed_shards"`
+	}
+	if err := json.Unmarshal(raw.Error, &detail); err != nil {
+		return esErr
+	}
+	esErr.Type = detail.Type
+	esErr.Reason = detail.Reason
+	esErr.Index = detail.Index
+	esErr.RootCause = detail.RootCause
+	for _, f := range detail.FailedShards {
+		esErr.FailedShards = append(esErr.FailedShards, models.ShardFailure{
+			Index:  f.Index,
+			Shard:  f.Shard,
+			Node:   f.Node,
+			Type:   f.Reason.Type,
+			Reason: f.Reason.Reason,
+		})
+	}
+	return esErr
+}
+
+// classifyESError picks the apperror kind for a decoded ES error. The ES
+// error type is more precise than the status code, so it is checked first.
+func classifyESError(message string, esErr *ESError) *apperror.Error {
+	errType := esErr.Type
+	if len(esErr.RootCause) > 0 {
+		errType = esErr.RootCause[0].Type
+	}
+
+	switch errType {
+	case "index_not_found_exception":
+		return apperror.IndexNotFound(message, esErr)
+	case "document_missing_exception":
+		return apperror.Wrap(apperror.KindNotFound, message, esErr)
+	case "version_conflict_engine_exception", "resource_already_exists_exception":
+		return apperror.Conflict(message, esErr)
+	case "parsing_exception", "query_shard_exception", "x_content_parse_exception",
+		"illegal_argument_exception", "mapper_parsing_exception", "query_parsing_exception",
+		"strict_dynamic_mapping_exception", "document_parsing_exception":
+		return apperror.BadQuery(message, esErr)
+	case "es_rejected_execution_exception", "cluster_block_exception", "no_shard_available_action_exception":
+		return apperror.Unavailable(message, esErr)
+	case "security_exception":
+		return apperror.Wrap(apperror.KindForbidden, message, esErr)
+	}
+	return apperror.FromStatus(esErr.Status, message, esErr)
+}
+
+// ── Response Handling ──
+
+// readResponse is the single place where ES responses are checked. It turns
+// transport failures and non-2xx statuses into typed errors, decodes a
+// successful body into dest when dest is non-nil, and always closes the body.
+func readResponse(res *esapi.Response, err error, message string, dest interface{}) error {
+	if err != nil {
+		return apperror.FromTransport(message, err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return classifyESError(message, decodeESError(res))
+	}
+
+	if dest == nil {
+		io.Copy(io.Discard, res.Body)
+		return nil
+	}
+	if err := json.NewDecoder(res.Body).Decode(dest); err != nil {
+		return apperror.Internal(message, fmt.Errorf("decode elasticsearch response: %w", err))
+	}
+	return nil
+}
+
+func encodeBody(body interface{}) (*bytes.Buffer, error) {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(body); err != nil {
+		return nil, apperror.Internal("Failed to encode request body", err)
+	}
+	return &buf, nil
+}
+
+// searchIndex runs query against index, restricted to the caller's
+// workspace and routed to its placement, and returns the decoded response
+// body. Every failure is returned as a typed error so callers can tell an
+// empty result set apart from a broken backend.
+func searchIndex(ctx context.Context, es *elasticsearch.Client, tenants *TenantRoutingService, index string, query map[string]interface{}) (map[string]interface{}, error) {
+	if es == nil {
+		return nil, errSearchUnavailable
+	}
+
+	route, err := tenants.searchRoute(ctx, index)
+	if err != nil {
+		return nil, err
+	}
+	query, err = scopeQuery(ctx, index, query)
+	if err != nil {
+		return nil, err
+	}
+	buf, err := encodeBody(query)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []func(*esapi.SearchRequest){
+		es.Search.WithContext(ctx),
+		es.Search.WithIndex(strings.Split(route.Index, ",")...),
+		es.Search.WithBody(buf),
+	}
+	if route.Routing != "" {
+		opts = append(opts, es.Search.WithRouting(route.Routing))
+	}
+
+	res, err := es.Search(opts...)
+	var result map[string]interface{}
+	if err := readResponse(res, err, "Search failed", &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// parseShards extracts shard-level failures from a search response body.
+// ES answers 200 when only some shards fail, so without this the missing
+// results would be indistinguishable from a complete answer.
+func parseShards(result map[string]interface{}) *models.ShardInfo {
+	shards, ok := result["_shards"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	info := &models.ShardInfo{
+		Total:      intValue(shards["total"]),
+		Successful: intValue(shards["successful"]),
+		Skipped:    intValue(shards["skipped"]),
+		Failed:     intValue(shards["failed"]),
+	}
+	if info.Failed == 0 {
+		return nil
+	}
+
+	failures, _ := shards["failures"].([]interface{})
+	for _, f := range failures {
+		fm, ok := f.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		failure := models.ShardFailure{
+			Index: getString(fm, "index"),
+			Shard: intValue(fm["shard"]),
+			Node:  getString(fm, "node"),
+		}
+		if reason, ok := fm["reason"].(map[string]interface{}); ok {
+			failure.Type = getString(reason, "type")
+			failure.Reason = getString(reason, "reason")
+		}
+		info.Failures = append(info.Failures, failure)
+	}
+	return info
+}
+
+func intValue(v interface{}) int {
+	if f, ok := v.(float64); ok {
+		return int(f)
+	}
+	return 0
+}
+
+// applySearchMeta copies response-level metadata onto a parsed response.
+func applySearchMeta(resp *models.SearchResponse, result map[string]interface{}) {
+	resp.Shards = parseShards(result)
+	if timedOut, ok := result["timed_out"].(bool); ok {
+		resp.TimedOut = timedOut
+	}
+}
+
+// ── Workspace Isolation ──
+
+var errNoWorkspaceScope = apperror.Forbidden("Request is not scoped to a workspace")
+
+func requireScope(ctx context.Context) (tenant.Scope, error) {
+	scope, ok := tenant.FromContext(ctx)
+	if !ok {
+		return tenant.Scope{}, errNoWorkspaceScope
+	}
+	return scope, nil
+}
+
+// requireAllWorkspaces guards operations that cannot be limited to a single
+// workspace, such as copying a whole index.
+func requireAllWorkspaces(ctx context.Context) error {
+	scope, err := requireScope(ctx)
+	if err != nil {
+		return err
+	}
+	if !scope.Unrestricted() {
+		return apperror.Forbidden("Operation spans all workspaces")
+	}
+	return nil
+}
+
+// requireWorkspace returns the workspace the caller named, for writes that
+// must land in one even when there is no document to take it from.
+func requireWorkspace(ctx context.Context) (string, error) {
+	scope, err := requireScope(ctx)
+	if err != nil {
+		return "", err
+	}
+	if scope.WorkspaceID == "" {
+		return "", apperror.BadQuery("workspace_id is required", nil)
+	}
+	return scope.WorkspaceID, nil
+}
+
+// searchableIndices are the indices callers may name to search, aggregate
+// or count. Internal indices such as the audit log and search feedback
+// carry a workspace_id too, so the tenant filter alone would let any member
+// read them.
+var searchableIndices = append([]string{indexEmoji}, tenantIndices...)
+
+// requireSearchableIndex refuses an index a caller named unless it is one
+// of searchableIndices. Wildcards and lists are refused too.
+func requireSearchableIndex(index string) error {
+	for _, idx := range searchableIndices {
+		if idx == index {
+			return nil
+		}
+	}
+	return apperror.BadQuery("index must be one of "+strings.Join(searchableIndices, ", "), nil)
+}
+
+// sharedDocuments matches, per index, the documents every workspace reads
+// besides its own: the global emoji set, which belongs to none.
+// ownDocumentFields are set on what a workspace writes to those indices, so
+// that no workspace can add to the shared documents.
+var (
+	sharedDocuments = map[string]map[string]interface{}{
+		indexEmoji: {"term": map[string]interface{}{"is_custom": false}},
+	}
+	ownDocumentFields = map[string]map[string]interface{}{
+		indexEmoji: {"is_custom": true},
+	}
+)
+
+// scopeQuery returns a copy of query, a search of index, whose "query"
+// clause is wrapped in a bool filter on the caller's workspace that also
+// drops tombstones. A kNN clause gets the same filter, since ES applies it
+// separately from the query. Every search and count goes through here, so
+// no code path can forget the tenant filter or surface a deleted message.
+func scopeQuery(ctx context.Context, index string, query map[string]interface{}) (map[string]interface{}, error) {
+	scope, err := requireScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := []interface{}{}
+	if !scope.Unrestricted() {
+		var tenantFilter interface{} = map[string]interface{}{"term": map[string]interface{}{"workspace_id": scope.WorkspaceID}}
+		if shared, ok := sharedDocuments[index]; ok {
+			tenantFilter = map[string]interface{}{
+				"bool": map[string]interface{}{
+					"should":               []interface{}{tenantFilter, shared},
+					"minimum_should_match": 1,
+				},
+			}
+		}
+		filter = append(filter, tenantFilter)
+	}
+
+	scoped := make(map[string]interface{}, len(query)+1)
+	for k, v := range query {
+		scoped[k] = v
+	}
+
+	if knn, ok := query["knn"].(map[string]interface{}); ok {
+		scopedKNN := make(map[string]interface{}, len(knn)+1)
+		for k, v := range knn {
+			scopedKNN[k] = v
+		}
+		knnFilter := filter
+		if f, ok := knn["filter"]; ok {
+			knnFilter = append([]interface{}{map[string]interface{}{"bool": map[string]interface{}{"filter": f}}}, filter...)
+		}
+		scopedKNN["filter"] = map[string]interface{}{
+			"bool": map[string]interface{}{
+				"filter":   knnFilter,
+				"must_not": []map[string]interface{}{tombstoneFilter},
+			},
+		}
+		scoped["knn"] = scopedKNN
+		// A pure kNN search has no query; adding match_all would return
+		// every document alongside the neighbours.
+		if _, ok := query["query"]; !ok {
+			return scoped, nil
+		}
+	}
+
+	inner, ok := query["query"]
+	if !ok {
+		inner = map[string]interface{}{"match_all": map[string]interface{}{}}
+	}
+	scoped["query"] = map[string]interface{}{
+		"bool": map[string]interface{}{
+			"must":     []interface{}{inner},
+			"filter":   filter,
+			"must_not": []map[string]interface{}{tombstoneFilter},
+		},
+	}
+	return scoped, nil
+}
+
+// scopeDocument stamps the caller's workspace on a document about to be
+// written to index and rejects documents that name a different one.
+// Unrestricted callers (ingestion services) must say which workspace a
+// document is for.
+func scopeDocument(ctx context.Context, index string, doc map[string]interface{}) error {
+	scope, err := requireScope(ctx)
+	if err != nil {
+		return err
+	}
+
+	current, _ := doc["workspace_id"].(string)
+	if scope.Unrestricted() {
+		if current == "" {
+			return apperror.BadQuery("Document has no workspace_id", nil)
+		}
+		return nil
+	}
+	if current != "" && current != scope.WorkspaceID {
+		return apperror.Forbidden("Document belongs to a different workspace")
+	}
+	doc["workspace_id"] = scope.WorkspaceID
+	for field, value := range ownDocumentFields[index] {
+		doc[field] = value
+	}
+	return nil
+}
+
+// resolveDocument finds where an existing document lives and verifies it
+// belongs to the caller's workspace before it is changed or deleted. A
+// document in another workspace is reported as missing so its existence
+// does not leak.
+func resolveDocument(ctx context.Context, es *elasticsearch.Client, tenants *TenantRoutingService, index, id string) (indexRoute, error) {
+	scope, err := requireScope(ctx)
+	if err != nil {
+		return indexRoute{}, err
+	}
+	route, workspaceID, err := locateDocument(ctx, es, tenants, index, id)
+	if err != nil {
+		return indexRoute{}, err
+	}
+	if !scope.Unrestricted() && workspaceID != scope.WorkspaceID {
+		return indexRoute{}, apperror.NotFound("Document not found")
+	}
+	return route, nil
+}
+
+// locateDocument returns where a document lives and the workspace it
+// belongs to, without checking that against the caller. Unrestricted
+// callers, who do not know the document's workspace, have it looked up
+// across the shared and dedicated indices; scoped callers look in their
+// workspace's placement only.
+func locateDocument(ctx context.Context, es *elasticsearch.Client, tenants *TenantRoutingService, index, id string) (indexRoute, string, error) {
+	scope, err := requireScope(ctx)
+	if err != nil {
+		return indexRoute{}, "", err
+	}
+
+	if scope.Unrestricted() {
+		result, err := searchIndex(ctx, es, tenants, index, map[string]interface{}{
+			"query":   map[string]interface{}{"ids": map[string]interface{}{"values": []string{id}}},
+			"_source": []string{"workspace_id"},
+			"size":    1,
+		})
+		if err != nil {
+			return indexRoute{}, "", err
+		}
+		hits, _ := result["hits"].(map[string]interface{})
+		hitList, _ := hits["hits"].([]interface{})
+		if len(hitList) == 0 {
+			return indexRoute{}, "", apperror.NotFound("Document not found")
+		}
+		hit, _ := hitList[0].(map[string]interface{})
+		source, _ := hit["_source"].(map[string]interface{})
+		workspaceID := getString(source, "workspace_id")
+		// Refuse changes while the owning workspace is being migrated.
+		if _, err := tenants.writeRoute(ctx, index, workspaceID); err != nil {
+			return indexRoute{}, "", err
+		}
+		return indexRoute{Index: getString(hit, "_index"), Routing: getString(hit, "_routing")}, workspaceID, nil
+	}
+
+	route, err := tenants.writeRoute(ctx, index, scope.WorkspaceID)
+	if err != nil {
+		return indexRoute{}, "", err
+	}
+
+	opts := []func(*esapi.GetRequest){
+		es.Get.WithContext(ctx),
+		es.Get.WithSourceIncludes("workspace_id"),
+	}
+	if route.Routing != "" {
+		opts = append(opts, es.Get.WithRouting(route.Routing))
+	}
+	res, err := es.Get(route.Index, id, opts...)
+	var doc struct {
+		Source struct {
+			WorkspaceID string `json:"workspace_id"`
+		} `json:"_source"`
+	}
+	if err := readResponse(res, err, "Document not found", &doc); err != nil {
+		if apperror.KindOf(err) == apperror.KindIndexNotFound {
+			return indexRoute{}, "", apperror.NotFound("Document not found")
+		}
+		return indexRoute{}, "", err
+	}
+	return route, doc.Source.WorkspaceID, nil
+}
+
+// documentRoute resolves where a new document is written, from the
+// workspace stamped on it by scopeDocument.
+func documentRoute(ctx context.Context, tenants *TenantRoutingService, index string, doc map[string]interface{}) (indexRoute, error) {
+	workspaceID, _ := doc["workspace_id"].(string)
+	return tenants.writeRoute(ctx, index, workspaceID)
+}
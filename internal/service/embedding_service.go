@@ -0,0 +1,217 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/sirupsen/logrus"
+
+	"github.com/quckapp/search-service/internal/apperror"
+	"github.com/quckapp/search-service/internal/embed"
+)
+
+const (
+	// embeddingField holds a document's vector on messages and files.
+	embeddingField = "embedding"
+	// maxEmbedChars caps the text embedded per document. Models read a
+	// limited window anyway, and the start of a file says most about it.
+	maxEmbedChars = 4000
+	// maxNumCandidates is the most candidates ES will consider per shard.
+	maxNumCandidates = 10000
+)
+
+// semanticIndices are the indices whose documents are embedded.
+var semanticIndices = map[string]bool{indexMessages: true, indexFiles: true}
+
+// EmbeddingService computes the vectors behind semantic search, and puts
+// the mappings for fields this service writes itself (vectors and file
+// passages) on the indices before the first write that needs them. With no
+// embedder configured, documents are written without vectors and semantic
+// search is refused.
+type EmbeddingService struct {
+	embedder embed.Embedder
+	logger   *logrus.Logger
+
+	mu     sync.Mutex
+	mapped map[string]bool
+}
+
+func NewEmbeddingService(embedder embed.Embedder, logger *logrus.Logger) *EmbeddingService {
+	return &EmbeddingService{embedder: embedder, logger: logger, mapped: map[string]bool{}}
+}
+
+func (s *EmbeddingService) Enabled() bool {
+	return s != nil && s.embedder != nil
+}
+
+// embedText is the text a document's vector is computed from: a message's
+// content, or a file's name and the start of its content.
+func embedText(index string, doc map[string]interface{}) string {
+	text := getString(doc, "content")
+	if index == indexFiles {
+		text = strings.TrimSpace(getString(doc, "filename") + "\n\n" + text)
+	}
+	if len(text) > maxEmbedChars && utf8.RuneCountInString(text) > maxEmbedChars {
+		text = string([]rune(text)[:maxEmbedChars])
+	}
+	return text
+}
+
+// EmbedDocuments sets the vector of each document written to index. A
+// provider failure is logged and the documents are written without vectors:
+// lexical search still finds them, and a reindex fills the vectors in.
+func (s *EmbeddingService) EmbedDocuments(ctx context.Context, index string, docs []map[string]interface{}) {
+	if !s.Enabled() || !semanticIndices[index] {
+		return
+	}
+	var texts []string
+	var targets []map[string]interface{}
+	for _, doc := range docs {
+		if text := embedText(index, doc); text != "" {
+			texts = append(texts, text)
+			targets = append(targets, doc)
+		}
+	}
+	if len(texts) == 0 {
+		return
+	}
+
+	vectors, err := s.embedder.Embed(ctx, texts)
+	if err != nil {
+		s.logger.WithError(err).WithFields(logrus.Fields{"index": index, "documents": len(texts)}).Warn("Failed to embed documents; writing them without vectors")
+		return
+	}
+	for i, vec := range vectors {
+		// Cosine similarity is undefined for a zero vector, and ES rejects
+		// the document; text with no words has nothing to match anyway.
+		if !zeroVector(vec) {
+			targets[i][embeddingField] = vec
+		}
+	}
+}
+
+// QueryVector embeds a search query.
+func (s *EmbeddingService) QueryVector(ctx context.Context, query string) ([]float32, error) {
+	if !s.Enabled() {
+		return nil, apperror.BadQuery("Semantic search is not enabled", nil)
+	}
+	if strings.TrimSpace(query) == "" {
+		return nil, apperror.BadQuery("Semantic search needs a query", nil)
+	}
+	vectors, err := s.embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, apperror.Unavailable("Embedding provider unavailable", err)
+	}
+	if len(vectors) != 1 || zeroVector(vectors[0]) {
+		return nil, apperror.BadQuery("Query has no terms to search by meaning", nil)
+	}
+	return vectors[0], nil
+}
+
+func zeroVector(vec []float32) bool {
+	for _, v := range vec {
+		if v != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// knnClause is a top-level kNN search for the k nearest documents to
+// vector, among those matching filters.
+func knnClause(vector []float32, k int, filters []map[string]interface{}) map[string]interface{} {
+	candidates := k * 4
+	if candidates < 100 {
+		candidates = 100
+	}
+	if candidates > maxNumCandidates {
+		candidates = maxNumCandidates
+	}
+	if k > candidates {
+		k = candidates
+	}
+	knn := map[string]interface{}{
+		"field":          embeddingField,
+		"query_vector":   vector,
+		"k":              k,
+		"num_candidates": candidates,
+	}
+	if len(filters) > 0 {
+		knn["filter"] = filters
+	}
+	return knn
+}
+
+// ── Mappings ──
+
+// mappings returns the fields this service maps on index, or nil.
+func (s *EmbeddingService) mappings(index string) map[string]interface{} {
+	properties := map[string]interface{}{}
+	switch index {
+	case indexMessages:
+		for field, mapping := range threadMappings {
+			properties[field] = mapping
+		}
+	case indexFiles:
+		for field, mapping := range passageMappings {
+			properties[field] = mapping
+		}
+	}
+	if s.Enabled() && semanticIndices[index] {
+		properties[embeddingField] = map[string]interface{}{
+			"type":       "dense_vector",
+			"dims":       s.embedder.Dims(),
+			"index":      true,
+			"similarity": "cosine",
+		}
+	}
+	if len(properties) == 0 {
+		return nil
+	}
+	return map[string]interface{}{"properties": properties}
+}
+
+// ensureMappings puts this service's mappings on index, creating it if
+// needed, and on its dedicated tenant indices. Dedicated indices created
+// later copy them from the shared index.
+func (s *EmbeddingService) ensureMappings(ctx context.Context, es *elasticsearch.Client, index string) error {
+	mappings := s.mappings(index)
+	if mappings == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.mapped[index] {
+		return nil
+	}
+
+	buf, err := encodeBody(map[string]interface{}{"mappings": mappings})
+	if err != nil {
+		return err
+	}
+	res, err := es.Indices.Create(index, es.Indices.Create.WithBody(buf), es.Indices.Create.WithContext(ctx))
+	if err := readResponse(res, err, "Failed to create index", nil); err != nil && apperror.KindOf(err) != apperror.KindConflict {
+		return err
+	}
+
+	buf, err = encodeBody(mappings)
+	if err != nil {
+		return err
+	}
+	res, err = es.Indices.PutMapping([]string{index + "*"}, buf, es.Indices.PutMapping.WithContext(ctx))
+	if err := readResponse(res, err, "Failed to update index mappings", nil); err != nil {
+		if kind := apperror.KindOf(err); kind == apperror.KindUnavailable || kind == apperror.KindTimeout {
+			return err
+		}
+		// A field already mapped differently (passages as plain objects,
+		// thread IDs as text, or vectors of another size) cannot be changed
+		// in place; the index needs a reindex. Writes still succeed
+		// meanwhile.
+		s.logger.WithError(err).WithField("index", index).Error("Index has incompatible mappings")
+	}
+	s.mapped[index] = true
+	return nil
+}
@@ -0,0 +1,8 @@
+package service
+
+import "github.com/quckapp/search-service/internal/apperror"
+
+var (
+	errSearchUnavailable  = apperror.Unavailable("Search backend is unavailable", nil)
+	errStorageUnavailable = apperror.Unavailable("Storage not available", nil)
+)
@@ -0,0 +1,519 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"math"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+
+	"github.com/quckapp/search-service/internal/apperror"
+	"github.com/quckapp/search-service/internal/db"
+	"github.com/quckapp/search-service/internal/models"
+)
+
+const (
+	maxJudgmentGrade = 3
+	// maxJudgmentQueries caps the queries in a list; each evaluation runs
+	// every one of them once per config.
+	maxJudgmentQueries = 1000
+	defaultEvaluationK = 10
+	maxEvaluationK     = 100
+)
+
+// EvaluationService keeps judgment lists and scores relevance configs
+// against them offline: each query of a list is run with a config, and the
+// ranking it returns is compared with the graded documents. Queries run
+// through the same search as the API's, with the config in place of the
+// workspace's.
+type EvaluationService struct {
+	redis     *db.RedisManager
+	search    *SearchService
+	relevance *RelevanceService
+	feedback  *FeedbackService
+	logger    *logrus.Logger
+}
+
+func NewEvaluationService(redis *db.RedisManager, search *SearchService, relevance *RelevanceService, feedback *FeedbackService, logger *logrus.Logger) *EvaluationService {
+	return &EvaluationService{redis: redis, search: search, relevance: relevance, feedback: feedback, logger: logger}
+}
+
+func judgmentListKey(workspaceID, id string) string {
+	return "judgment_list:" + workspaceID + ":" + id
+}
+
+func judgmentListsKey(workspaceID string) string {
+	return "judgment_lists:" + workspaceID
+}
+
+// ── Judgment Lists ──
+
+// CreateList validates and stores a judgment list for workspaceID. source
+// records where the judgments came from.
+func (s *EvaluationService) CreateList(ctx context.Context, workspaceID, source string, req *models.CreateJudgmentListRequest) (*models.JudgmentList, error) {
+	rdb := s.redis.Client()
+	if rdb == nil {
+		return nil, errStorageUnavailable
+	}
+
+	list := &models.JudgmentList{
+		ID:          uuid.New().String(),
+		WorkspaceID: workspaceID,
+		Name:        req.Name,
+		Index:       req.Index,
+		Source:      source,
+		Queries:     req.Queries,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+	if err := validateJudgmentList(list); err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(list)
+	if err != nil {
+		return nil, err
+	}
+	if err := rdb.Set(ctx, judgmentListKey(workspaceID, list.ID), data, 0).Err(); err != nil {
+		return nil, apperror.Unavailable("Failed to save judgment list", err)
+	}
+	rdb.SAdd(ctx, judgmentListsKey(workspaceID), list.ID)
+	return list, nil
+}
+
+// validateJudgmentList checks the list's index and grades, defaulting the
+// index to messages.
+func validateJudgmentList(list *models.JudgmentList) error {
+	if list.Index == "" {
+		list.Index = indexMessages
+	}
+	if clickType(list.Index) == "" {
+		return apperror.BadQuery("index must be quckapp_messages, quckapp_files, quckapp_users or quckapp_channels", nil)
+	}
+	if len(list.Queries) == 0 {
+		return apperror.BadQuery("A judgment list needs at least one query", nil)
+	}
+	if len(list.Queries) > maxJudgmentQueries {
+		return apperror.BadQuery("A judgment list holds at most "+strconv.Itoa(maxJudgmentQueries)+" queries", nil)
+	}
+	for _, q := range list.Queries {
+		if strings.TrimSpace(q.Query) == "" {
+			return apperror.BadQuery("Every judged query needs query text", nil)
+		}
+		for _, j := range q.Judgments {
+			if j.DocumentID == "" {
+				return apperror.BadQuery("Judgment for query '"+q.Query+"' is missing doc_id", nil)
+			}
+			if j.Grade < 0 || j.Grade > maxJudgmentGrade {
+				return apperror.BadQuery("Grades must be between 0 and 3", nil)
+			}
+		}
+	}
+	return nil
+}
+
+// ListFromFeedback builds and stores a judgment list from the workspace's
+// search feedback.
+func (s *EvaluationService) ListFromFeedback(ctx context.Context, workspaceID string, req *models.JudgmentsFromFeedbackRequest) (*models.JudgmentList, error) {
+	index := req.Index
+	if index == "" {
+		index = indexMessages
+	}
+	resultType := clickType(index)
+	if resultType == "" {
+		return nil, apperror.BadQuery("index must be quckapp_messages, quckapp_files, quckapp_users or quckapp_channels", nil)
+	}
+
+	queries, err := s.feedback.Judgments(ctx, resultType, req.From, req.To, req.MinRatings)
+	if err != nil {
+		return nil, err
+	}
+	if len(queries) == 0 {
+		return nil, apperror.BadQuery("No query has enough feedback to judge its results", nil)
+	}
+	return s.CreateList(ctx, workspaceID, "feedback", &models.CreateJudgmentListRequest{Name: req.Name, Index: index, Queries: queries})
+}
+
+func (s *EvaluationService) GetList(ctx context.Context, workspaceID, id string) (*models.JudgmentList, error) {
+	rdb := s.redis.Client()
+	if rdb == nil {
+		return nil, errStorageUnavailable
+	}
+
+	data, err := rdb.Get(ctx, judgmentListKey(workspaceID, id)).Bytes()
+	if err == redis.Nil {
+		return nil, apperror.NotFound("Judgment list not found")
+	}
+	if err != nil {
+		return nil, apperror.Unavailable("Failed to load judgment list", err)
+	}
+
+	var list models.JudgmentList
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// ListLists summarises the workspace's judgment lists, most recently
+// updated first.
+func (s *EvaluationService) ListLists(ctx context.Context, workspaceID string) ([]models.JudgmentListSummary, error) {
+	rdb := s.redis.Client()
+	if rdb == nil {
+		return nil, errStorageUnavailable
+	}
+
+	ids, err := rdb.SMembers(ctx, judgmentListsKey(workspaceID)).Result()
+	if err != nil {
+		return nil, apperror.Unavailable("Failed to list judgment lists", err)
+	}
+
+	summaries := []models.JudgmentListSummary{}
+	for _, id := range ids {
+		list, err := s.GetList(ctx, workspaceID, id)
+		if err != nil {
+			continue
+		}
+		summary := models.JudgmentListSummary{
+			ID:        list.ID,
+			Name:      list.Name,
+			Index:     list.Index,
+			Source:    list.Source,
+			Queries:   len(list.Queries),
+			UpdatedAt: list.UpdatedAt,
+		}
+		for _, q := range list.Queries {
+			summary.Judgments += len(q.Judgments)
+		}
+		summaries = append(summaries, summary)
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].UpdatedAt.After(summaries[j].UpdatedAt) })
+	return summaries, nil
+}
+
+func (s *EvaluationService) DeleteList(ctx context.Context, workspaceID, id string) error {
+	rdb := s.redis.Client()
+	if rdb == nil {
+		return errStorageUnavailable
+	}
+
+	n, err := rdb.Del(ctx, judgmentListKey(workspaceID, id)).Result()
+	if err != nil {
+		return apperror.Unavailable("Failed to delete judgment list", err)
+	}
+	if n == 0 {
+		return apperror.NotFound("Judgment list not found")
+	}
+	rdb.SRem(ctx, judgmentListsKey(workspaceID), id)
+	return nil
+}
+
+// ParseJudgments reads judgments from a file, as CSV or JSON by filename
+// extension, or by content when the extension says neither. CSV rows are
+// query,doc_id,grade, with an optional header row. JSON is either an array
+// of queries with their judgments or an object holding one under
+// "queries", such as an exported judgment list.
+func ParseJudgments(filename string, data []byte) ([]models.QueryJudgments, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return parseJudgmentsCSV(data)
+	case ".json":
+		return parseJudgmentsJSON(data)
+	}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && (trimmed[0] == '[' || trimmed[0] == '{') {
+		return parseJudgmentsJSON(data)
+	}
+	return parseJudgmentsCSV(data)
+}
+
+func parseJudgmentsJSON(data []byte) ([]models.QueryJudgments, error) {
+	var queries []models.QueryJudgments
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		var list struct {
+			Queries []models.QueryJudgments `json:"queries"`
+		}
+		if err := json.Unmarshal(data, &list); err != nil {
+			return nil, apperror.BadQuery("Invalid judgments JSON", err)
+		}
+		return list.Queries, nil
+	}
+	if err := json.Unmarshal(data, &queries); err != nil {
+		return nil, apperror.BadQuery("Invalid judgments JSON", err)
+	}
+	return queries, nil
+}
+
+func parseJudgmentsCSV(data []byte) ([]models.QueryJudgments, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = 3
+	r.TrimLeadingSpace = true
+
+	var queries []models.QueryJudgments
+	position := map[string]int{}
+	for line := 1; ; line++ {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, apperror.BadQuery("Invalid judgments CSV", err)
+		}
+		grade, err := strconv.Atoi(strings.TrimSpace(row[2]))
+		if err != nil {
+			if line == 1 {
+				continue // header
+			}
+			return nil, apperror.BadQuery("Invalid grade on line "+strconv.Itoa(line)+" of judgments CSV", err)
+		}
+
+		query := strings.TrimSpace(row[0])
+		i, ok := position[query]
+		if !ok {
+			i = len(queries)
+			position[query] = i
+			queries = append(queries, models.QueryJudgments{Query: query})
+		}
+		queries[i].Judgments = append(queries[i].Judgments, models.Judgment{DocumentID: strings.TrimSpace(row[1]), Grade: grade})
+	}
+	return queries, nil
+}
+
+// ── Evaluation ──
+
+// Evaluate scores the configs req describes against a stored judgment list.
+func (s *EvaluationService) Evaluate(ctx context.Context, workspaceID string, req *models.EvaluationRequest) (*models.EvaluationReport, error) {
+	list, err := s.GetList(ctx, workspaceID, req.JudgmentListID)
+	if err != nil {
+		return nil, err
+	}
+	return s.EvaluateList(ctx, workspaceID, list, req.K, req.Mode, req.Baseline, req.Candidate)
+}
+
+// EvaluateList runs every query of list in mode with the baseline config
+// and, if candidate is set, with the candidate config, and scores the top
+// k results of each. Both configs are the workspace's current one with the
+// given changes. Queries with no relevant judgments are reported but left
+// out of the averages, since no ranking can score on them.
+func (s *EvaluationService) EvaluateList(ctx context.Context, workspaceID string, list *models.JudgmentList, k int, mode string, baseline, candidate *models.UpdateRelevanceRequest) (*models.EvaluationReport, error) {
+	if err := validateJudgmentList(list); err != nil {
+		return nil, err
+	}
+	if mode == "" {
+		mode = models.SearchModeLexical
+	}
+	if mode != models.SearchModeLexical && mode != models.SearchModeSemantic && mode != models.SearchModeHybrid {
+		return nil, apperror.BadQuery("mode must be lexical, semantic or hybrid", nil)
+	}
+	if k < 1 {
+		k = defaultEvaluationK
+	}
+	if k > maxEvaluationK {
+		return nil, apperror.BadQuery("k must be at most "+strconv.Itoa(maxEvaluationK), nil)
+	}
+
+	baseConfig, err := s.evaluatedConfig(ctx, workspaceID, baseline)
+	if err != nil {
+		return nil, err
+	}
+	report := &models.EvaluationReport{
+		JudgmentListID: list.ID,
+		Index:          list.Index,
+		K:              k,
+		Mode:           mode,
+		Baseline:       models.ConfigEvaluation{Config: baseConfig},
+		Queries:        make([]models.QueryEvaluation, len(list.Queries)),
+		RanAt:          time.Now(),
+	}
+	var candConfig *models.RelevanceConfig
+	if candidate != nil {
+		if candConfig, err = s.evaluatedConfig(ctx, workspaceID, candidate); err != nil {
+			return nil, err
+		}
+		report.Candidate = &models.ConfigEvaluation{Config: candConfig}
+	}
+	report.NotApplied = ignoredChanges(baseline, candidate)
+
+	var scored int
+	var baseSum, candSum models.EvaluationMetrics
+	for i, q := range list.Queries {
+		eval := models.QueryEvaluation{Query: q.Query, Relevant: relevantJudgments(q.Judgments)}
+
+		if eval.Baseline, err = s.scoreQuery(ctx, workspaceID, list.Index, mode, baseConfig, q, k); err != nil {
+			return nil, err
+		}
+		if candConfig != nil {
+			metrics, err := s.scoreQuery(ctx, workspaceID, list.Index, mode, candConfig, q, k)
+			if err != nil {
+				return nil, err
+			}
+			eval.Candidate = &metrics
+			eval.NDCGDelta = metrics.NDCG - eval.Baseline.NDCG
+		}
+		report.Queries[i] = eval
+
+		if eval.Relevant == 0 {
+			continue
+		}
+		scored++
+		addMetrics(&baseSum, eval.Baseline)
+		if eval.Candidate != nil {
+			addMetrics(&candSum, *eval.Candidate)
+		}
+	}
+
+	if scored > 0 {
+		report.Baseline.Metrics = meanMetrics(baseSum, scored)
+		if report.Candidate != nil {
+			report.Candidate.Metrics = meanMetrics(candSum, scored)
+		}
+	}
+	return report, nil
+}
+
+// evaluatedConfig is the workspace's current relevance config with changes
+// applied. Nothing is saved.
+func (s *EvaluationService) evaluatedConfig(ctx context.Context, workspaceID string, changes *models.UpdateRelevanceRequest) (*models.RelevanceConfig, error) {
+	config, err := s.relevance.GetConfig(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if changes != nil {
+		if err := applyRelevanceUpdate(config, changes); err != nil {
+			return nil, err
+		}
+	}
+	return config, nil
+}
+
+// ignoredChanges names the settings the changes set that searches do not
+// apply.
+func ignoredChanges(changes ...*models.UpdateRelevanceRequest) []string {
+	var ignored []string
+	add := func(setting string) {
+		if !containsString(ignored, setting) {
+			ignored = append(ignored, setting)
+		}
+	}
+	for _, c := range changes {
+		if c == nil {
+			continue
+		}
+		if c.TitleBoost != nil {
+			add("title_boost")
+		}
+		if c.ContentBoost != nil {
+			add("content_boost")
+		}
+		if c.RecencyWeight != nil {
+			add("recency_weight")
+		}
+	}
+	return ignored
+}
+
+// scoreQuery runs q as a search of index in mode, ranked with config, and
+// scores its first page of k results.
+func (s *EvaluationService) scoreQuery(ctx context.Context, workspaceID, index, mode string, config *models.RelevanceConfig, q models.QueryJudgments, k int) (models.EvaluationMetrics, error) {
+	search, ok := s.search.searchFunc(clickType(index))
+	if !ok {
+		return models.EvaluationMetrics{}, apperror.BadQuery("index must be quckapp_messages, quckapp_files, quckapp_users or quckapp_channels", nil)
+	}
+	resp, err := search(ctx, &models.SearchParams{Query: q.Query, WorkspaceID: workspaceID, Page: 1, PerPage: k, Sort: "relevance", Mode: mode, Relevance: config})
+	if err != nil {
+		return models.EvaluationMetrics{}, err
+	}
+	ranking := make([]string, len(resp.Results))
+	for i, hit := range resp.Results {
+		ranking[i] = hit.ID
+	}
+	return scoreRanking(ranking, q.Judgments, k), nil
+}
+
+// scoreRanking computes the metrics of the top k of ranking against
+// judgments. A document judged more than once takes its highest grade.
+func scoreRanking(ranking []string, judgments []models.Judgment, k int) models.EvaluationMetrics {
+	grades := map[string]int{}
+	for _, j := range judgments {
+		if g, ok := grades[j.DocumentID]; !ok || j.Grade > g {
+			grades[j.DocumentID] = j.Grade
+		}
+	}
+	relevant := 0
+	ideal := make([]int, 0, len(grades))
+	for _, g := range grades {
+		ideal = append(ideal, g)
+		if g > 0 {
+			relevant++
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(ideal)))
+
+	var metrics models.EvaluationMetrics
+	if relevant == 0 {
+		return metrics
+	}
+	if len(ranking) > k {
+		ranking = ranking[:k]
+	}
+
+	var dcg, idcg float64
+	found := 0
+	for i, id := range ranking {
+		g := grades[id]
+		dcg += gain(g) / math.Log2(float64(i+2))
+		if g > 0 {
+			found++
+			if metrics.MRR == 0 {
+				metrics.MRR = 1 / float64(i+1)
+			}
+		}
+	}
+	for i := 0; i < len(ideal) && i < k; i++ {
+		idcg += gain(ideal[i]) / math.Log2(float64(i+2))
+	}
+
+	metrics.NDCG = dcg / idcg
+	metrics.Precision = float64(found) / float64(k)
+	metrics.Recall = float64(found) / float64(relevant)
+	return metrics
+}
+
+func gain(grade int) float64 {
+	return math.Pow(2, float64(grade)) - 1
+}
+
+func relevantJudgments(judgments []models.Judgment) int {
+	seen := map[string]bool{}
+	for _, j := range judgments {
+		if j.Grade > 0 {
+			seen[j.DocumentID] = true
+		}
+	}
+	return len(seen)
+}
+
+func addMetrics(sum *models.EvaluationMetrics, m models.EvaluationMetrics) {
+	sum.NDCG += m.NDCG
+	sum.MRR += m.MRR
+	sum.Precision += m.Precision
+	sum.Recall += m.Recall
+}
+
+func meanMetrics(sum models.EvaluationMetrics, n int) models.EvaluationMetrics {
+	return models.EvaluationMetrics{
+		NDCG:      sum.NDCG / float64(n),
+		MRR:       sum.MRR / float64(n),
+		Precision: sum.Precision / float64(n),
+		Recall:    sum.Recall / float64(n),
+	}
+}
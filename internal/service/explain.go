@@ -0,0 +1,349 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/quckapp/search-service/internal/apperror"
+	"github.com/quckapp/search-service/internal/models"
+	"github.com/quckapp/search-service/internal/tenant"
+)
+
+// weightPattern matches the node of an ES explanation that scores one term
+// (or one set of synonyms, or a phrase) in one field.
+var weightPattern = regexp.MustCompile(`^weight\((.+) in \d+\)`)
+
+// ── Explain Endpoint ──
+
+// Explain runs a search of searchType with explanations on. When id is set,
+// it also explains that document: from the page if it is there, otherwise
+// by searching for it alone with the same query and filters.
+func (s *SearchService) Explain(ctx context.Context, searchType, id string, params *models.SearchParams) (*models.ExplainResponse, error) {
+	search, ok := s.searchFunc(searchType)
+	if !ok {
+		return nil, apperror.BadQuery("type must be messages, files, users or channels", nil)
+	}
+
+	params.Explain = true
+	page, err := search(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	resp := &models.ExplainResponse{Type: searchType, Search: page}
+	if id == "" {
+		return resp, nil
+	}
+	for i := range page.Results {
+		if page.Results[i].ID == id {
+			resp.Document = &page.Results[i]
+			resp.Rank = i + 1
+			return resp, nil
+		}
+	}
+
+	single := *params
+	single.DocumentID = id
+	single.Page = 1
+	single.CollapseThreads = false
+	found, err := search(ctx, &single)
+	if err != nil {
+		return nil, err
+	}
+	if len(found.Results) > 0 {
+		hit := found.Results[0]
+		// Searched alone, the document's hybrid ranks and fused score say
+		// nothing about where it stands among the other results.
+		if hit.Hybrid != nil {
+			hit.Score = 0
+			for _, c := range []*models.ComponentScore{hit.Hybrid.Lexical, hit.Hybrid.Semantic} {
+				if c != nil {
+					c.Rank, c.Contribution = 0, 0
+				}
+			}
+		}
+		resp.Document = &hit
+	}
+	return resp, nil
+}
+
+// searchFunc returns the search for a search type: messages, files, users
+// or channels.
+func (s *SearchService) searchFunc(searchType string) (func(context.Context, *models.SearchParams) (*models.SearchResponse, error), bool) {
+	searches := map[string]func(context.Context, *models.SearchParams) (*models.SearchResponse, error){
+		"messages": s.SearchMessages,
+		"files":    s.SearchFiles,
+		"users":    s.SearchUsers,
+		"channels": s.SearchChannels,
+	}
+	search, ok := searches[searchType]
+	return search, ok
+}
+
+// ── Hit Explanations ──
+
+// parseExplanation reads a hit's _explanation, and sums up what each field
+// and term contributed to its score, largest first.
+func parseExplanation(raw map[string]interface{}) *models.HitExplanation {
+	tree := explanationNode(raw)
+	explanation := &models.HitExplanation{
+		Score:         tree.Value,
+		Contributions: []models.ScoreContribution{},
+		Tree:          &tree,
+	}
+	collectContributions(tree, &explanation.Contributions)
+	sort.SliceStable(explanation.Contributions, func(i, j int) bool {
+		return explanation.Contributions[i].Score > explanation.Contributions[j].Score
+	})
+	return explanation
+}
+
+func explanationNode(raw map[string]interface{}) models.ExplanationNode {
+	node := models.ExplanationNode{Description: getString(raw, "description")}
+	node.Value, _ = raw["value"].(float64)
+	details, _ := raw["details"].([]interface{})
+	for _, d := range details {
+		if m, ok := d.(map[string]interface{}); ok {
+			node.Details = append(node.Details, explanationNode(m))
+		}
+	}
+	return node
+}
+
+func collectContributions(node models.ExplanationNode, out *[]models.ScoreContribution) {
+	if m := weightPattern.FindStringSubmatch(node.Description); m != nil {
+		if node.Value > 0 {
+			field, term := weightTerm(m[1])
+			*out = append(*out, models.ScoreContribution{Field: field, Term: term, Score: node.Value, Boost: explanationBoost(node)})
+		}
+		return
+	}
+	// A kNN hit's score is its vector similarity.
+	if strings.HasPrefix(node.Description, "within top") {
+		*out = append(*out, models.ScoreContribution{Field: embeddingField, Score: node.Value})
+		return
+	}
+	for _, child := range node.Details {
+		collectContributions(child, out)
+	}
+}
+
+// weightTerm splits what a weight node scores into the field and the term:
+// "content:deploy", "content:\"deploy plan\"", or
+// "Synonym(content:deploy content:deployment)".
+func weightTerm(scored string) (string, string) {
+	if inner, ok := strings.CutPrefix(scored, "Synonym("); ok {
+		var field string
+		var terms []string
+		for _, token := range strings.Fields(strings.TrimSuffix(inner, ")")) {
+			f, t, _ := strings.Cut(token, ":")
+			field = f
+			terms = append(terms, t)
+		}
+		return field, strings.Join(terms, " | ")
+	}
+	field, term, _ := strings.Cut(scored, ":")
+	return field, term
+}
+
+// explanationBoost finds the query-time boost under a weight node, or 0
+// when the term was not boosted.
+func explanationBoost(node models.ExplanationNode) float64 {
+	for _, child := range node.Details {
+		if child.Description == "boost" {
+			if child.Value == 1 {
+				return 0
+			}
+			return child.Value
+		}
+		if boost := explanationBoost(child); boost != 0 {
+			return boost
+		}
+	}
+	return 0
+}
+
+// ── Query Explanations ──
+
+// explainQuery describes what this service did to a search of index before
+// ES scored it, from the request bodies it built.
+func (s *SearchService) explainQuery(ctx context.Context, index string, params *models.SearchParams, queries ...map[string]interface{}) *models.QueryExplanation {
+	mode := params.Mode
+	if mode == "" || index == indexUsers || index == indexChannels {
+		mode = models.SearchModeLexical
+	}
+	explanation := &models.QueryExplanation{Mode: mode, Index: index, Steps: []string{}, NotApplied: []string{}, Requests: []map[string]interface{}{}}
+	step := func(format string, args ...interface{}) {
+		explanation.Steps = append(explanation.Steps, fmt.Sprintf(format, args...))
+	}
+
+	for _, query := range queries {
+		if q, ok := query["query"].(map[string]interface{}); ok {
+			boolQuery, _ := q["bool"].(map[string]interface{})
+			must, _ := boolQuery["must"].([]map[string]interface{})
+			for _, clause := range must {
+				step("match: %s", describeClause(clause))
+			}
+			should, _ := boolQuery["should"].([]map[string]interface{})
+			for _, clause := range should {
+				step("boost: %s", describeClause(clause))
+			}
+			filters, _ := boolQuery["filter"].([]map[string]interface{})
+			for _, filter := range filters {
+				step("filter: %s", compactJSON(filter))
+			}
+		}
+		if knn, ok := query["knn"].(map[string]interface{}); ok {
+			step("knn: the %v nearest of %v candidates to the query's embedding in %s", knn["k"], knn["num_candidates"], embeddingField)
+			filters, _ := knn["filter"].([]map[string]interface{})
+			for _, filter := range filters {
+				step("knn filter: %s", compactJSON(filter))
+			}
+		}
+		if _, ok := query["collapse"]; ok {
+			step("collapse: one result per thread_id")
+		}
+		if sorts, ok := query["sort"]; ok {
+			step("sort: %s", compactJSON(sorts))
+		}
+
+		scoped, err := scopeQuery(ctx, index, query)
+		if err != nil {
+			continue
+		}
+		explanation.Requests = append(explanation.Requests, elideVectors(scoped))
+	}
+
+	if mode == models.SearchModeHybrid {
+		cfg := s.hybridConfig(ctx, params)
+		if cfg.Fusion == models.FusionLinear {
+			step("fuse: linear, min-max normalised scores weighted lexical %g, semantic %g", cfg.LexicalWeight, cfg.SemanticWeight)
+		} else {
+			step("fuse: rrf with k=%d, weighted lexical %g, semantic %g", cfg.RRFK, cfg.LexicalWeight, cfg.SemanticWeight)
+		}
+		if params.Sort == "newest" || params.Sort == "oldest" {
+			step("sort: fused results by created_at, %s first", params.Sort)
+		}
+	}
+
+	if scope, ok := tenant.FromContext(ctx); ok {
+		if scope.Unrestricted() {
+			step("scope: all workspaces")
+		} else {
+			step("scope: workspace %s", scope.WorkspaceID)
+		}
+	}
+	step("scope: deleted documents excluded")
+	if route, err := s.tenants.searchRoute(ctx, index); err == nil {
+		if route.Routing != "" {
+			step("route: %s, routing %s", route.Index, route.Routing)
+		} else {
+			step("route: %s", route.Index)
+		}
+	}
+	explanation.NotApplied = s.stagesNotApplied(ctx, params)
+	return explanation
+}
+
+// stagesNotApplied names the configurable ranking stages searches skip.
+func (s *SearchService) stagesNotApplied(ctx context.Context, params *models.SearchParams) []string {
+	recency := "recency decay: not implemented"
+	if config := s.relevanceConfig(ctx, params); config != nil && config.RecencyWeight > 0 {
+		recency = fmt.Sprintf("recency decay: not implemented; recency_weight %g is configured but ignored", config.RecencyWeight)
+	}
+	return []string{
+		recency,
+		"title_boost, content_boost: not applied; field_boosts weigh fields",
+		"search pipelines: not run by searches; pipeline steps are stored only",
+		"query rewrites: not run by searches; rewrite rules are stored only",
+	}
+}
+
+// describeClause summarises a query clause built by this service.
+func describeClause(clause map[string]interface{}) string {
+	for kind, body := range clause {
+		spec, _ := body.(map[string]interface{})
+		switch kind {
+		case "match":
+			for field, v := range spec {
+				opts, _ := v.(map[string]interface{})
+				return "match " + field + clauseOptions(opts)
+			}
+		case "multi_match":
+			return fmt.Sprintf("multi_match %v%s", spec["fields"], clauseOptions(spec))
+		case "pinned":
+			organic, _ := spec["organic"].(map[string]interface{})
+			return fmt.Sprintf("pinned %v above (%s)", spec["ids"], describeClause(organic))
+		case "constant_score":
+			filter, _ := spec["filter"].(map[string]interface{})
+			return fmt.Sprintf("%s scores %v", compactJSON(filter), spec["boost"])
+		case "nested":
+			inner, _ := spec["query"].(map[string]interface{})
+			desc := fmt.Sprintf("nested %v", spec["path"])
+			if mode, ok := spec["score_mode"]; ok {
+				desc += fmt.Sprintf(", best by %v", mode)
+			}
+			return desc + " (" + describeClause(inner) + ")"
+		case "bool":
+			var parts []string
+			for _, occur := range []string{"must", "should", "must_not"} {
+				clauses, _ := spec[occur].([]map[string]interface{})
+				if len(clauses) == 0 {
+					continue
+				}
+				var descs []string
+				for _, c := range clauses {
+					descs = append(descs, describeClause(c))
+				}
+				switch occur {
+				case "must":
+					parts = append(parts, strings.Join(descs, " and "))
+				case "should":
+					parts = append(parts, "any of ("+strings.Join(descs, "; ")+")")
+				case "must_not":
+					parts = append(parts, "not ("+strings.Join(descs, "; ")+")")
+				}
+			}
+			return strings.Join(parts, ", ")
+		}
+		return kind + " " + compactJSON(body)
+	}
+	return ""
+}
+
+func clauseOptions(opts map[string]interface{}) string {
+	var out []string
+	for _, key := range []string{"type", "fuzziness", "operator", "boost"} {
+		if v, ok := opts[key]; ok {
+			out = append(out, fmt.Sprintf("%s %v", key, v))
+		}
+	}
+	if len(out) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(out, ", ") + ")"
+}
+
+// elideVectors replaces query vectors in a request body with their size;
+// hundreds of floats say nothing to a reader.
+func elideVectors(query map[string]interface{}) map[string]interface{} {
+	knn, ok := query["knn"].(map[string]interface{})
+	if !ok {
+		return query
+	}
+	if vector, ok := knn["query_vector"].([]float32); ok {
+		knn["query_vector"] = fmt.Sprintf("[%d dims]", len(vector))
+	}
+	return query
+}
+
+func compactJSON(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
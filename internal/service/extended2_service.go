@@ -7,8 +7,9 @@ import (
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/redis/go-redis/v9"
 	"github.com/sirupsen/logrus"
+
+	"github.com/quckapp/search-service/internal/db"
 )
 
 // ── Extended Models ──
@@ -38,16 +39,6 @@ type SearchBookmarkItem struct {
 	CreatedAt   time.Time `json:"created_at"`
 }
 
-type SearchFeedback struct {
-	ID          string    `json:"id"`
-	UserID      string    `json:"user_id"`
-	Query       string    `json:"query"`
-	ResultID    string    `json:"result_id"`
-	Rating      int       `json:"rating"`
-	Comment     string    `json:"comment"`
-	CreatedAt   time.Time `json:"created_at"`
-}
-
 type SearchABTest struct {
 	ID          string         `json:"id"`
 	Name        string         `json:"name"`
@@ -106,11 +97,11 @@ type IndexSchedule struct {
 // ── Extended2 Service ──
 
 type Extended2Service struct {
-	redis  *redis.Client
+	redis  *db.RedisManager
 	logger *logrus.Logger
 }
 
-func NewExtended2Service(redis *redis.Client, logger *logrus.Logger) *Extended2Service {
+func NewExtended2Service(redis *db.RedisManager, logger *logrus.Logger) *Extended2Service {
 	return &Extended2Service{redis: redis, logger: logger}
 }
 
@@ -170,21 +161,6 @@ func (s *Extended2Service) DeleteBookmark(ctx context.Context, userID, id string
 	return s.del(ctx, fmt.Sprintf("search_bookmark:%s:%s", userID, id))
 }
 
-// Search Feedback
-func (s *Extended2Service) SubmitFeedback(ctx context.Context, f *SearchFeedback) error {
-	f.ID = uuid.New().String()
-	f.CreatedAt = time.Now()
-	return s.set(ctx, fmt.Sprintf("search_feedback:%s:%s", f.UserID, f.ID), f, 0)
-}
-
-func (s *Extended2Service) ListFeedback(ctx context.Context, userID string) ([]SearchFeedback, error) {
-	return listByPattern[SearchFeedback](ctx, s, fmt.Sprintf("search_feedback:%s:*", userID))
-}
-
-func (s *Extended2Service) GetFeedbackStats(ctx context.Context) (map[string]any, error) {
-	return map[string]any{"total_feedback": 0, "avg_rating": 0, "positive_pct": 0}, nil
-}
-
 // A/B Tests
 func (s *Extended2Service) CreateABTest(ctx context.Context, t *SearchABTest) error {
 	t.ID = uuid.New().String()
@@ -264,6 +240,13 @@ func (s *Extended2Service) CreateRewrite(ctx context.Context, r *QueryRewrite) e
 	return s.set(ctx, fmt.Sprintf("query_rewrite:%s", r.ID), r, 0)
 }
 
+func (s *Extended2Service) GetRewrite(ctx context.Context, id string) (*QueryRewrite, error) {
+	var r QueryRewrite
+	err := s.get(ctx, fmt.Sprintf("query_rewrite:%s", id), &r)
+	if err != nil { return nil, err }
+	return &r, nil
+}
+
 func (s *Extended2Service) ListRewrites(ctx context.Context) ([]QueryRewrite, error) {
 	return listByPattern[QueryRewrite](ctx, s, "query_rewrite:*")
 }
@@ -308,30 +291,34 @@ func (s *Extended2Service) DeleteSchedule(ctx context.Context, id string) error
 // ── Redis Helpers ──
 
 func (s *Extended2Service) set(ctx context.Context, key string, val any, ttl time.Duration) error {
-	if s.redis == nil { return fmt.Errorf("storage not available") }
+	rdb := s.redis.Client()
+	if rdb == nil { return errStorageUnavailable }
 	data, err := json.Marshal(val)
 	if err != nil { return err }
-	return s.redis.Set(ctx, key, data, ttl).Err()
+	return rdb.Set(ctx, key, data, ttl).Err()
 }
 
 func (s *Extended2Service) get(ctx context.Context, key string, dest any) error {
-	if s.redis == nil { return fmt.Errorf("storage not available") }
-	data, err := s.redis.Get(ctx, key).Bytes()
+	rdb := s.redis.Client()
+	if rdb == nil { return errStorageUnavailable }
+	data, err := rdb.Get(ctx, key).Bytes()
 	if err != nil { return err }
 	return json.Unmarshal(data, dest)
 }
 
 func (s *Extended2Service) del(ctx context.Context, key string) error {
-	if s.redis == nil { return fmt.Errorf("storage not available") }
-	return s.redis.Del(ctx, key).Err()
+	rdb := s.redis.Client()
+	if rdb == nil { return errStorageUnavailable }
+	return rdb.Del(ctx, key).Err()
 }
 
 func listByPattern[T any](ctx context.Context, s *Extended2Service, pattern string) ([]T, error) {
-	if s.redis == nil { return nil, fmt.Errorf("storage not available") }
+	rdb := s.redis.Client()
+	if rdb == nil { return nil, errStorageUnavailable }
 	var results []T
-	iter := s.redis.Scan(ctx, 0, pattern, 100).Iterator()
+	iter := rdb.Scan(ctx, 0, pattern, 100).Iterator()
 	for iter.Next(ctx) {
-		data, err := s.redis.Get(ctx, iter.Val()).Bytes()
+		data, err := rdb.Get(ctx, iter.Val()).Bytes()
 		if err != nil { continue }
 		var item T
 		if err := json.Unmarshal(data, &item); err == nil {
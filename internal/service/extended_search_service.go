@@ -1,26 +1,27 @@
 package service
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
+	"strings"
 
-	"github.com/elastic/go-elasticsearch/v8"
-	"github.com/redis/go-redis/v9"
+	"github.com/elastic/go-elasticsearch/v8/esapi"
 	"github.com/sirupsen/logrus"
 
+	"github.com/quckapp/search-service/internal/db"
 	"github.com/quckapp/search-service/internal/models"
 )
 
 type ExtendedSearchService struct {
-	es     *elasticsearch.Client
-	redis  *redis.Client
-	logger *logrus.Logger
+	es          *db.ElasticsearchManager
+	redis       *db.RedisManager
+	tenants     *TenantRoutingService
+	deadLetters *DeadLetterService
+	logger      *logrus.Logger
 }
 
-func NewExtendedSearchService(es *elasticsearch.Client, redis *redis.Client, logger *logrus.Logger) *ExtendedSearchService {
-	return &ExtendedSearchService{es: es, redis: redis, logger: logger}
+func NewExtendedSearchService(es *db.ElasticsearchManager, redis *db.RedisManager, tenants *TenantRoutingService, deadLetters *DeadLetterService, logger *logrus.Logger) *ExtendedSearchService {
+	return &ExtendedSearchService{es: es, redis: redis, tenants: tenants, deadLetters: deadLetters, logger: logger}
 }
 
 // ── Bookmark Search ──
@@ -39,9 +40,9 @@ func (s *ExtendedSearchService) SearchBookmarks(ctx context.Context, params *mod
 	filters := buildExtFilters(params)
 	query := buildExtQuery(must, filters, params)
 
-	result, err := s.executeSearch("quckapp_bookmarks", query)
+	result, err := s.executeSearch(ctx, "quckapp_bookmarks", query)
 	if err != nil {
-		return extEmptyResponse(params), nil
+		return nil, err
 	}
 
 	return s.parseResponse(result, params), nil
@@ -63,9 +64,9 @@ func (s *ExtendedSearchService) SearchTasks(ctx context.Context, params *models.
 	filters := buildExtFilters(params)
 	query := buildExtQuery(must, filters, params)
 
-	result, err := s.executeSearch("quckapp_tasks", query)
+	result, err := s.executeSearch(ctx, "quckapp_tasks", query)
 	if err != nil {
-		return extEmptyResponse(params), nil
+		return nil, err
 	}
 
 	return s.parseResponse(result, params), nil
@@ -98,9 +99,9 @@ func (s *ExtendedSearchService) SearchEmoji(ctx context.Context, query, workspac
 	}
 
 	searchQuery := buildExtQuery(must, filters, params)
-	result, err := s.executeSearch("quckapp_emoji", searchQuery)
+	result, err := s.executeSearch(ctx, indexEmoji, searchQuery)
 	if err != nil {
-		return extEmptyResponse(params), nil
+		return nil, err
 	}
 
 	return s.parseResponse(result, params), nil
@@ -109,6 +110,11 @@ func (s *ExtendedSearchService) SearchEmoji(ctx context.Context, query, workspac
 // ── Advanced Search ──
 
 func (s *ExtendedSearchService) AdvancedSearch(ctx context.Context, req *models.AdvancedSearchParams) (*models.GlobalSearchResponse, error) {
+	for _, sub := range req.Queries {
+		if err := requireSearchableIndex(sub.Index); err != nil {
+			return nil, err
+		}
+	}
 	resp := &models.GlobalSearchResponse{}
 
 	for _, sub := range req.Queries {
@@ -140,7 +146,10 @@ func (s *ExtendedSearchService) AdvancedSearch(ctx context.Context, req *models.
 		}
 
 		query := buildExtQuery(must, filters, params)
-		result, _ := s.executeSearch(sub.Index, query)
+		result, err := s.executeSearch(ctx, sub.Index, query)
+		if err != nil {
+			return nil, err
+		}
 		parsed := s.parseResponse(result, params)
 
 		switch sub.Index {
@@ -161,10 +170,9 @@ func (s *ExtendedSearchService) AdvancedSearch(ctx context.Context, req *models.
 // ── Aggregation ──
 
 func (s *ExtendedSearchService) Aggregate(ctx context.Context, req *models.AggregationRequest) (*models.AggregationResponse, error) {
-	if s.es == nil {
-		return &models.AggregationResponse{Buckets: []models.AggregationBucket{}}, nil
+	if err := requireSearchableIndex(req.Index); err != nil {
+		return nil, err
 	}
-
 	size := req.Size
 	if size <= 0 {
 		size = 10
@@ -182,9 +190,9 @@ func (s *ExtendedSearchService) Aggregate(ctx context.Context, req *models.Aggre
 		},
 	}
 
-	result, err := s.executeSearch(req.Index, query)
+	result, err := s.executeSearch(ctx, req.Index, query)
 	if err != nil {
-		return &models.AggregationResponse{Buckets: []models.AggregationBucket{}}, nil
+		return nil, err
 	}
 
 	resp := &models.AggregationResponse{Buckets: []models.AggregationBucket{}}
@@ -212,15 +220,21 @@ func (s *ExtendedSearchService) Aggregate(ctx context.Context, req *models.Aggre
 // ── Batch Delete ──
 
 func (s *ExtendedSearchService) BatchDelete(ctx context.Context, req *models.BatchDeleteRequest) *models.BatchDeleteResponse {
+	es := s.es.Client()
 	resp := &models.BatchDeleteResponse{}
 
-	if s.es == nil {
+	if es == nil {
+		for _, id := range req.IDs {
+			s.deadLetters.Capture(ctx, failedWrite(ctx, opDelete, req.Index, id, nil, 0), errSearchUnavailable)
+		}
+		resp.Failed = len(req.IDs)
+		resp.Errors = []string{errSearchUnavailable.Error()}
 		return resp
 	}
 
 	for _, id := range req.IDs {
-		_, err := s.es.Delete(req.Index, id)
-		if err != nil {
+		if err := deleteDocument(ctx, es, s.tenants, req.Index, id, 0); err != nil {
+			s.deadLetters.Capture(ctx, failedWrite(ctx, opDelete, req.Index, id, nil, 0), err)
 			resp.Failed++
 			resp.Errors = append(resp.Errors, fmt.Sprintf("%s: %s", id, err.Error()))
 		} else {
@@ -233,31 +247,41 @@ func (s *ExtendedSearchService) BatchDelete(ctx context.Context, req *models.Bat
 // ── Update Document ──
 
 func (s *ExtendedSearchService) UpdateDocument(ctx context.Context, index, id string, doc map[string]interface{}) error {
-	if s.es == nil {
-		return nil
+	// A partial update may not move the document to another workspace, or
+	// share it with every workspace.
+	if _, ok := doc["workspace_id"]; ok || ownDocumentFields[index] != nil {
+		if err := scopeDocument(ctx, index, doc); err != nil {
+			return err
+		}
+	}
+	es := s.es.Client()
+	if es == nil {
+		return s.deadLetters.Capture(ctx, failedWrite(ctx, opUpdate, index, id, doc, 0), errSearchUnavailable)
+	}
+	route, err := resolveDocument(ctx, es, s.tenants, index, id)
+	if err != nil {
+		return s.deadLetters.Capture(ctx, failedWrite(ctx, opUpdate, index, id, doc, 0), err)
 	}
 
 	body := map[string]interface{}{
 		"doc": doc,
 	}
 
-	var buf bytes.Buffer
-	json.NewEncoder(&buf).Encode(body)
-
-	res, err := s.es.Update(index, id, &buf)
+	buf, err := encodeBody(body)
 	if err != nil {
 		return err
 	}
-	defer res.Body.Close()
+
+	res, err := es.Update(route.Index, id, buf, es.Update.WithRouting(route.Routing), es.Update.WithContext(ctx))
+	if err := readResponse(res, err, "Failed to update document", nil); err != nil {
+		return s.deadLetters.Capture(ctx, failedWrite(ctx, opUpdate, index, id, doc, 0), err)
+	}
 	return nil
 }
 
 // ── Index Typed Documents ──
 
 func (s *ExtendedSearchService) IndexUser(ctx context.Context, req *models.IndexUserRequest) error {
-	if s.es == nil {
-		return nil
-	}
 	doc := map[string]interface{}{
 		"username":     req.Username,
 		"display_name": req.DisplayName,
@@ -265,17 +289,10 @@ func (s *ExtendedSearchService) IndexUser(ctx context.Context, req *models.Index
 		"avatar_url":   req.AvatarURL,
 		"workspace_id": req.WorkspaceID,
 	}
-
-	var buf bytes.Buffer
-	json.NewEncoder(&buf).Encode(doc)
-	_, err := s.es.Index("quckapp_users", &buf, s.es.Index.WithDocumentID(req.ID))
-	return err
+	return s.writeDocument(ctx, "quckapp_users", req.ID, doc, req.Version)
 }
 
 func (s *ExtendedSearchService) IndexChannel(ctx context.Context, req *models.IndexChannelRequest) error {
-	if s.es == nil {
-		return nil
-	}
 	doc := map[string]interface{}{
 		"name":         req.Name,
 		"description":  req.Description,
@@ -283,17 +300,10 @@ func (s *ExtendedSearchService) IndexChannel(ctx context.Context, req *models.In
 		"type":         req.Type,
 		"workspace_id": req.WorkspaceID,
 	}
-
-	var buf bytes.Buffer
-	json.NewEncoder(&buf).Encode(doc)
-	_, err := s.es.Index("quckapp_channels", &buf, s.es.Index.WithDocumentID(req.ID))
-	return err
+	return s.writeDocument(ctx, "quckapp_channels", req.ID, doc, req.Version)
 }
 
 func (s *ExtendedSearchService) IndexBookmark(ctx context.Context, req *models.IndexBookmarkRequest) error {
-	if s.es == nil {
-		return nil
-	}
 	doc := map[string]interface{}{
 		"title":        req.Title,
 		"description":  req.Description,
@@ -302,17 +312,10 @@ func (s *ExtendedSearchService) IndexBookmark(ctx context.Context, req *models.I
 		"user_id":      req.UserID,
 		"workspace_id": req.WorkspaceID,
 	}
-
-	var buf bytes.Buffer
-	json.NewEncoder(&buf).Encode(doc)
-	_, err := s.es.Index("quckapp_bookmarks", &buf, s.es.Index.WithDocumentID(req.ID))
-	return err
+	return s.writeDocument(ctx, "quckapp_bookmarks", req.ID, doc, req.Version)
 }
 
 func (s *ExtendedSearchService) IndexTask(ctx context.Context, req *models.IndexTaskRequest) error {
-	if s.es == nil {
-		return nil
-	}
 	doc := map[string]interface{}{
 		"title":        req.Title,
 		"description":  req.Description,
@@ -322,62 +325,76 @@ func (s *ExtendedSearchService) IndexTask(ctx context.Context, req *models.Index
 		"user_id":      req.UserID,
 		"workspace_id": req.WorkspaceID,
 	}
-
-	var buf bytes.Buffer
-	json.NewEncoder(&buf).Encode(doc)
-	_, err := s.es.Index("quckapp_tasks", &buf, s.es.Index.WithDocumentID(req.ID))
-	return err
+	return s.writeDocument(ctx, "quckapp_tasks", req.ID, doc, req.Version)
 }
 
 // ── Document Count ──
 
 func (s *ExtendedSearchService) CountDocuments(ctx context.Context, index string) (int64, error) {
-	if s.es == nil {
-		return 0, nil
+	if err := requireSearchableIndex(index); err != nil {
+		return 0, err
+	}
+	es := s.es.Client()
+	if es == nil {
+		return 0, errSearchUnavailable
 	}
 
-	res, err := s.es.Count(s.es.Count.WithIndex(index))
+	route, err := s.tenants.searchRoute(ctx, index)
+	if err != nil {
+		return 0, err
+	}
+	query, err := scopeQuery(ctx, index, map[string]interface{}{})
+	if err != nil {
+		return 0, err
+	}
+	buf, err := encodeBody(query)
 	if err != nil {
 		return 0, err
 	}
-	defer res.Body.Close()
-
-	var result map[string]interface{}
-	json.NewDecoder(res.Body).Decode(&result)
 
-	if count, ok := result["count"].(float64); ok {
-		return int64(count), nil
+	opts := []func(*esapi.CountRequest){
+		es.Count.WithIndex(strings.Split(route.Index, ",")...),
+		es.Count.WithBody(buf),
+		es.Count.WithIgnoreUnavailable(true),
+		es.Count.WithContext(ctx),
+	}
+	if route.Routing != "" {
+		opts = append(opts, es.Count.WithRouting(route.Routing))
 	}
-	return 0, nil
+	res, err := es.Count(opts...)
+	var result struct {
+		Count int64 `json:"count"`
+	}
+	if err := readResponse(res, err, "Failed to count documents", &result); err != nil {
+		return 0, err
+	}
+	return result.Count, nil
 }
 
 // ── Helpers ──
 
-func (s *ExtendedSearchService) executeSearch(index string, query map[string]interface{}) (map[string]interface{}, error) {
-	if s.es == nil {
-		return map[string]interface{}{
-			"hits": map[string]interface{}{
-				"total": map[string]interface{}{"value": 0},
-				"hits":  []interface{}{},
-			},
-		}, nil
+// writeDocument stamps the caller's workspace on doc and indexes it into the
+// workspace's placement. With a version, a stale write is refused.
+func (s *ExtendedSearchService) writeDocument(ctx context.Context, index, id string, doc map[string]interface{}, version int64) error {
+	if err := scopeDocument(ctx, index, doc); err != nil {
+		return err
 	}
-
-	var buf bytes.Buffer
-	json.NewEncoder(&buf).Encode(query)
-
-	res, err := s.es.Search(
-		s.es.Search.WithIndex(index),
-		s.es.Search.WithBody(&buf),
-	)
+	es := s.es.Client()
+	if es == nil {
+		return s.deadLetters.Capture(ctx, failedWrite(ctx, opIndex, index, id, doc, version), errSearchUnavailable)
+	}
+	route, err := documentRoute(ctx, s.tenants, index, doc)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	if err := indexVersioned(ctx, es, route, id, doc, version); err != nil {
+		return s.deadLetters.Capture(ctx, failedWrite(ctx, opIndex, index, id, doc, version), err)
 	}
-	defer res.Body.Close()
+	return nil
+}
 
-	var result map[string]interface{}
-	json.NewDecoder(res.Body).Decode(&result)
-	return result, nil
+func (s *ExtendedSearchService) executeSearch(ctx context.Context, index string, query map[string]interface{}) (map[string]interface{}, error) {
+	return searchIndex(ctx, s.es.Client(), s.tenants, index, query)
 }
 
 func (s *ExtendedSearchService) parseResponse(result map[string]interface{}, params *models.SearchParams) *models.SearchResponse {
@@ -413,16 +430,13 @@ func (s *ExtendedSearchService) parseResponse(result map[string]interface{}, par
 			if source, ok := hitMap["_source"].(map[string]interface{}); ok {
 				searchHit.Source = source
 			}
-			if highlights, ok := hitMap["highlight"].(map[string]interface{}); ok {
-				if searchHit.Source == nil {
-					searchHit.Source = map[string]interface{}{}
-				}
-				searchHit.Source["_highlights"] = highlights
-			}
+			searchHit.Highlights = parseHighlights(hitMap, searchHit.Source, params, extHighlightFields)
 			resp.Results = append(resp.Results, searchHit)
 		}
 	}
 
+	applySearchMeta(resp, result)
+
 	if resp.Total > 0 {
 		resp.TotalPages = int((resp.Total + int64(params.PerPage) - 1) / int64(params.PerPage))
 	}
@@ -464,19 +478,9 @@ func buildExtQuery(must []map[string]interface{}, filters []map[string]interface
 		"query": map[string]interface{}{
 			"bool": boolQuery,
 		},
-		"from": params.From(),
-		"size": params.PerPage,
-		"highlight": map[string]interface{}{
-			"fields": map[string]interface{}{
-				"title":        map[string]interface{}{},
-				"content":      map[string]interface{}{},
-				"description":  map[string]interface{}{},
-				"name":         map[string]interface{}{},
-				"display_name": map[string]interface{}{},
-			},
-			"pre_tags":  []string{"<em>"},
-			"post_tags": []string{"</em>"},
-		},
+		"from":      params.From(),
+		"size":      params.PerPage,
+		"highlight": highlightClause(params, extHighlightFields),
 	}
 
 	switch params.Sort {
@@ -488,13 +492,3 @@ func buildExtQuery(must []map[string]interface{}, filters []map[string]interface
 
 	return query
 }
-
-func extEmptyResponse(params *models.SearchParams) *models.SearchResponse {
-	return &models.SearchResponse{
-		Results:    []models.SearchHit{},
-		Total:      0,
-		Page:       params.Page,
-		PerPage:    params.PerPage,
-		TotalPages: 0,
-	}
-}
@@ -1,33 +1,30 @@
 package service
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
 
-	"github.com/elastic/go-elasticsearch/v8"
-	"github.com/redis/go-redis/v9"
 	"github.com/sirupsen/logrus"
 
+	"github.com/quckapp/search-service/internal/db"
 	"github.com/quckapp/search-service/internal/models"
 )
 
 type FacetService struct {
-	es     *elasticsearch.Client
-	redis  *redis.Client
-	logger *logrus.Logger
+	es      *db.ElasticsearchManager
+	redis   *db.RedisManager
+	tenants *TenantRoutingService
+	logger  *logrus.Logger
 }
 
-func NewFacetService(es *elasticsearch.Client, redis *redis.Client, logger *logrus.Logger) *FacetService {
-	return &FacetService{es: es, redis: redis, logger: logger}
+func NewFacetService(es *db.ElasticsearchManager, redis *db.RedisManager, tenants *TenantRoutingService, logger *logrus.Logger) *FacetService {
+	return &FacetService{es: es, redis: redis, tenants: tenants, logger: logger}
 }
 
 func (s *FacetService) GetFacets(ctx context.Context, req *models.FacetRequest) (*models.FacetResult, error) {
-	if s.es == nil {
-		return &models.FacetResult{Field: req.Field, Buckets: []models.FacetBucket{}}, nil
+	if err := requireSearchableIndex(req.Index); err != nil {
+		return nil, err
 	}
-
 	size := req.Size
 	if size <= 0 {
 		size = 10
@@ -53,20 +50,10 @@ func (s *FacetService) GetFacets(ctx context.Context, req *models.FacetRequest)
 		}
 	}
 
-	var buf bytes.Buffer
-	json.NewEncoder(&buf).Encode(query)
-
-	res, err := s.es.Search(
-		s.es.Search.WithIndex(req.Index),
-		s.es.Search.WithBody(&buf),
-	)
+	result, err := searchIndex(ctx, s.es.Client(), s.tenants, req.Index, query)
 	if err != nil {
-		return &models.FacetResult{Field: req.Field, Buckets: []models.FacetBucket{}}, nil
+		return nil, err
 	}
-	defer res.Body.Close()
-
-	var result map[string]interface{}
-	json.NewDecoder(res.Body).Decode(&result)
 
 	facetResult := &models.FacetResult{Field: req.Field, Buckets: []models.FacetBucket{}}
 	if aggs, ok := result["aggregations"].(map[string]interface{}); ok {
@@ -91,10 +78,9 @@ func (s *FacetService) GetFacets(ctx context.Context, req *models.FacetRequest)
 }
 
 func (s *FacetService) GetFacetedSearch(ctx context.Context, index, query, facetField string, size int) (*models.SearchResponse, []models.FacetResult, error) {
-	if s.es == nil {
-		return &models.SearchResponse{Results: []models.SearchHit{}}, []models.FacetResult{}, nil
+	if err := requireSearchableIndex(index); err != nil {
+		return nil, nil, err
 	}
-
 	if size <= 0 {
 		size = 10
 	}
@@ -116,20 +102,10 @@ func (s *FacetService) GetFacetedSearch(ctx context.Context, index, query, facet
 		},
 	}
 
-	var buf bytes.Buffer
-	json.NewEncoder(&buf).Encode(searchQuery)
-
-	res, err := s.es.Search(
-		s.es.Search.WithIndex(index),
-		s.es.Search.WithBody(&buf),
-	)
+	result, err := searchIndex(ctx, s.es.Client(), s.tenants, index, searchQuery)
 	if err != nil {
-		return &models.SearchResponse{Results: []models.SearchHit{}}, []models.FacetResult{}, nil
+		return nil, nil, err
 	}
-	defer res.Body.Close()
-
-	var result map[string]interface{}
-	json.NewDecoder(res.Body).Decode(&result)
 
 	// Parse search results
 	searchResp := &models.SearchResponse{Results: []models.SearchHit{}, Page: 1, PerPage: 20}
@@ -183,6 +159,7 @@ func (s *FacetService) GetFacetedSearch(ctx context.Context, index, query, facet
 }
 
 func (s *FacetService) GetFilterOptions(ctx context.Context, workspaceID string) (*models.FilterOptions, error) {
+	es := s.es.Client()
 	options := &models.FilterOptions{
 		Types:      []string{"messages", "files", "users", "channels", "bookmarks", "tasks"},
 		Channels:   []string{},
@@ -190,7 +167,7 @@ func (s *FacetService) GetFilterOptions(ctx context.Context, workspaceID string)
 		DateRanges: []string{"today", "this_week", "this_month", "this_year"},
 	}
 
-	if s.es == nil {
+	if es == nil {
 		return options, nil
 	}
 
@@ -206,16 +183,9 @@ func (s *FacetService) GetFilterOptions(ctx context.Context, workspaceID string)
 			},
 		},
 	}
-	var buf bytes.Buffer
-	json.NewEncoder(&buf).Encode(channelQuery)
-	res, err := s.es.Search(
-		s.es.Search.WithIndex("quckapp_messages"),
-		s.es.Search.WithBody(&buf),
-	)
+	// Filter options are best effort: a failed lookup leaves the list empty.
+	result, err := searchIndex(ctx, es, s.tenants, "quckapp_messages", channelQuery)
 	if err == nil {
-		defer res.Body.Close()
-		var result map[string]interface{}
-		json.NewDecoder(res.Body).Decode(&result)
 		if aggs, ok := result["aggregations"].(map[string]interface{}); ok {
 			if chAgg, ok := aggs["channels"].(map[string]interface{}); ok {
 				if buckets, ok := chAgg["buckets"].([]interface{}); ok {
@@ -0,0 +1,609 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/quckapp/search-service/internal/apperror"
+	"github.com/quckapp/search-service/internal/db"
+	"github.com/quckapp/search-service/internal/models"
+)
+
+// indexFeedback lies outside quckapp_* so the index management API cannot
+// remove it; data deletion jobs purge it explicitly. It is not in
+// searchableIndices, so the search API cannot read it.
+const indexFeedback = "search_feedback"
+
+const (
+	// poorRating is the highest rating that counts against a result.
+	poorRating = 2
+	// positiveRating is the lowest rating that counts as satisfied.
+	positiveRating = 4
+	// minRatedQueries is how often a query must be rated before it can rank
+	// among the worst; one bad rating says little.
+	minRatedQueries = 3
+	// exportBatch is how many entries each page of an export reads.
+	exportBatch = 1000
+)
+
+// feedbackMappings index every field for filtering and aggregation. The
+// query is kept as text for search and, normalised, as query_key for
+// grouping. A single shard keeps terms aggregations ordered by average
+// rating exact.
+var feedbackMappings = map[string]interface{}{
+	"dynamic": false,
+	"properties": map[string]interface{}{
+		"id":           map[string]interface{}{"type": "keyword"},
+		"user_id":      map[string]interface{}{"type": "keyword"},
+		"workspace_id": map[string]interface{}{"type": "keyword"},
+		"query":        map[string]interface{}{"type": "text"},
+		"query_key":    map[string]interface{}{"type": "keyword"},
+		"result_id":    map[string]interface{}{"type": "keyword"},
+		"result_type":  map[string]interface{}{"type": "keyword"},
+		"rating":       map[string]interface{}{"type": "integer"},
+		"comment":      map[string]interface{}{"type": "text"},
+		"created_at":   map[string]interface{}{"type": "date"},
+	},
+}
+
+var feedbackIntervals = map[string]bool{"day": true, "week": true, "month": true}
+
+// feedbackDocument is a feedback entry as stored.
+type feedbackDocument struct {
+	models.SearchFeedback
+	QueryKey string `json:"query_key"`
+}
+
+// FeedbackService stores users' ratings of search results and aggregates
+// them into the workspace's search quality dashboards.
+type FeedbackService struct {
+	es     *db.ElasticsearchManager
+	redis  *db.RedisManager
+	logger *logrus.Logger
+
+	mu    sync.Mutex
+	ready bool
+}
+
+func NewFeedbackService(es *db.ElasticsearchManager, redis *db.RedisManager, logger *logrus.Logger) *FeedbackService {
+	return &FeedbackService{es: es, redis: redis, logger: logger}
+}
+
+// Submit records userID's rating of a result in the caller's workspace.
+func (s *FeedbackService) Submit(ctx context.Context, userID string, req *models.FeedbackRequest) (*models.SearchFeedback, error) {
+	scope, err := requireScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if req.Rating < 1 || req.Rating > 5 {
+		return nil, apperror.BadQuery("rating must be between 1 and 5", nil)
+	}
+
+	f := &models.SearchFeedback{
+		ID:          uuid.New().String(),
+		UserID:      userID,
+		WorkspaceID: scope.WorkspaceID,
+		Query:       req.Query,
+		ResultID:    req.ResultID,
+		ResultType:  req.ResultType,
+		Rating:      req.Rating,
+		Comment:     req.Comment,
+		CreatedAt:   time.Now().UTC(),
+	}
+	if err := s.write(ctx, f); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (s *FeedbackService) write(ctx context.Context, f *models.SearchFeedback) error {
+	es := s.es.Client()
+	if es == nil {
+		return errSearchUnavailable
+	}
+	if err := s.ensureIndex(ctx); err != nil {
+		return err
+	}
+
+	buf, err := encodeBody(feedbackDocument{SearchFeedback: *f, QueryKey: normalizeQuery(f.Query)})
+	if err != nil {
+		return err
+	}
+	res, err := es.Create(indexFeedback, f.ID, buf, es.Create.WithContext(ctx))
+	return readResponse(res, err, "Failed to store feedback", nil)
+}
+
+func (s *FeedbackService) ensureIndex(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ready {
+		return nil
+	}
+
+	es := s.es.Client()
+	buf, err := encodeBody(map[string]interface{}{
+		"settings": map[string]interface{}{"number_of_shards": 1},
+		"mappings": feedbackMappings,
+	})
+	if err != nil {
+		return err
+	}
+	res, err := es.Indices.Create(indexFeedback, es.Indices.Create.WithBody(buf), es.Indices.Create.WithContext(ctx))
+	if err := readResponse(res, err, "Failed to create feedback index", nil); err != nil && apperror.KindOf(err) != apperror.KindConflict {
+		return err
+	}
+	s.ready = true
+	return nil
+}
+
+// List returns feedback matching q, newest first. Callers scoped to a
+// workspace only see feedback given in it.
+func (s *FeedbackService) List(ctx context.Context, q *models.FeedbackQuery) (*models.FeedbackList, error) {
+	if q.Page < 1 {
+		q.Page = 1
+	}
+	if q.PerPage < 1 || q.PerPage > 200 {
+		q.PerPage = 50
+	}
+
+	list := &models.FeedbackList{Entries: []models.SearchFeedback{}, Page: q.Page, PerPage: q.PerPage}
+	var result feedbackHits
+	err := s.search(ctx, map[string]interface{}{
+		"query": feedbackFilter(q),
+		"sort":  []map[string]interface{}{{"created_at": map[string]interface{}{"order": "desc"}}},
+		"from":  (q.Page - 1) * q.PerPage,
+		"size":  q.PerPage,
+	}, &result)
+	if err != nil {
+		if apperror.KindOf(err) == apperror.KindIndexNotFound {
+			return list, nil
+		}
+		return nil, err
+	}
+
+	list.Total = result.Hits.Total.Value
+	for _, hit := range result.Hits.Hits {
+		list.Entries = append(list.Entries, hit.Source)
+	}
+	return list, nil
+}
+
+type feedbackHits struct {
+	Hits struct {
+		Total struct {
+			Value int64 `json:"value"`
+		} `json:"total"`
+		Hits []struct {
+			Source models.SearchFeedback `json:"_source"`
+			Sort   []interface{}         `json:"sort"`
+		} `json:"hits"`
+	} `json:"hits"`
+}
+
+// search runs query against the feedback index within the caller's scope.
+func (s *FeedbackService) search(ctx context.Context, query map[string]interface{}, out interface{}) error {
+	es := s.es.Client()
+	if es == nil {
+		return errSearchUnavailable
+	}
+	scoped, err := scopeQuery(ctx, indexFeedback, query)
+	if err != nil {
+		return err
+	}
+	buf, err := encodeBody(scoped)
+	if err != nil {
+		return err
+	}
+	res, err := es.Search(es.Search.WithContext(ctx), es.Search.WithIndex(indexFeedback), es.Search.WithBody(buf))
+	return readResponse(res, err, "Failed to query feedback", out)
+}
+
+func feedbackFilter(q *models.FeedbackQuery) map[string]interface{} {
+	filters := []map[string]interface{}{}
+	if q.UserID != "" {
+		filters = append(filters, map[string]interface{}{"term": map[string]interface{}{"user_id": q.UserID}})
+	}
+	if q.Query != "" {
+		filters = append(filters, map[string]interface{}{"term": map[string]interface{}{"query_key": normalizeQuery(q.Query)}})
+	}
+	if q.ResultID != "" {
+		filters = append(filters, map[string]interface{}{"term": map[string]interface{}{"result_id": q.ResultID}})
+	}
+	if q.MaxRating > 0 {
+		filters = append(filters, map[string]interface{}{"range": map[string]interface{}{"rating": map[string]interface{}{"lte": q.MaxRating}}})
+	}
+	if !q.From.IsZero() || !q.To.IsZero() {
+		rng := map[string]interface{}{}
+		if !q.From.IsZero() {
+			rng["gte"] = q.From
+		}
+		if !q.To.IsZero() {
+			rng["lte"] = q.To
+		}
+		filters = append(filters, map[string]interface{}{"range": map[string]interface{}{"created_at": rng}})
+	}
+	return map[string]interface{}{"bool": map[string]interface{}{"filter": filters}}
+}
+
+// ── Statistics ──
+
+type statsBucket struct {
+	Key         interface{} `json:"key"`
+	KeyAsString string      `json:"key_as_string"`
+	DocCount    int64       `json:"doc_count"`
+	AvgRating   struct {
+		Value *float64 `json:"value"`
+	} `json:"avg_rating"`
+	Type struct {
+		Buckets []statsBucket `json:"buckets"`
+	} `json:"type"`
+}
+
+type statsTerms struct {
+	Buckets []statsBucket `json:"buckets"`
+}
+
+// Stats aggregates the feedback matching q: how ratings are distributed,
+// how each query and each result is rated, and how much feedback arrives
+// over time.
+func (s *FeedbackService) Stats(ctx context.Context, q *models.FeedbackQuery) (*models.FeedbackStats, error) {
+	if q.Interval == "" {
+		q.Interval = "day"
+	}
+	if !feedbackIntervals[q.Interval] {
+		return nil, apperror.BadQuery("interval must be day, week or month", nil)
+	}
+	if q.Limit < 1 || q.Limit > 100 {
+		q.Limit = 10
+	}
+
+	avg := map[string]interface{}{"avg_rating": map[string]interface{}{"avg": map[string]interface{}{"field": "rating"}}}
+	var result struct {
+		Hits struct {
+			Total struct {
+				Value int64 `json:"value"`
+			} `json:"total"`
+		} `json:"hits"`
+		Aggregations struct {
+			AvgRating struct {
+				Value *float64 `json:"value"`
+			} `json:"avg_rating"`
+			Positive struct {
+				DocCount int64 `json:"doc_count"`
+			} `json:"positive"`
+			Ratings      statsTerms `json:"ratings"`
+			Queries      statsTerms `json:"queries"`
+			WorstQueries statsTerms `json:"worst_queries"`
+			Volume       statsTerms `json:"volume"`
+			Poor         struct {
+				Results statsTerms `json:"results"`
+			} `json:"poor"`
+		} `json:"aggregations"`
+	}
+	err := s.search(ctx, map[string]interface{}{
+		"query":            feedbackFilter(q),
+		"size":             0,
+		"track_total_hits": true,
+		"aggs": map[string]interface{}{
+			"avg_rating": avg["avg_rating"],
+			"positive": map[string]interface{}{
+				"filter": map[string]interface{}{"range": map[string]interface{}{"rating": map[string]interface{}{"gte": positiveRating}}},
+			},
+			"ratings": map[string]interface{}{
+				"terms": map[string]interface{}{"field": "rating", "size": 5, "order": map[string]interface{}{"_key": "asc"}},
+			},
+			"queries": map[string]interface{}{
+				"terms": map[string]interface{}{"field": "query_key", "size": q.Limit},
+				"aggs":  avg,
+			},
+			"worst_queries": map[string]interface{}{
+				"terms": map[string]interface{}{
+					"field":         "query_key",
+					"size":          q.Limit,
+					"min_doc_count": minRatedQueries,
+					"order":         []map[string]interface{}{{"avg_rating": "asc"}, {"_count": "desc"}},
+				},
+				"aggs": avg,
+			},
+			"volume": map[string]interface{}{
+				"date_histogram": map[string]interface{}{"field": "created_at", "calendar_interval": q.Interval, "min_doc_count": 0},
+				"aggs":           avg,
+			},
+			"poor": map[string]interface{}{
+				"filter": map[string]interface{}{"range": map[string]interface{}{"rating": map[string]interface{}{"lte": poorRating}}},
+				"aggs": map[string]interface{}{
+					"results": map[string]interface{}{
+						"terms": map[string]interface{}{"field": "result_id", "size": q.Limit},
+						"aggs": map[string]interface{}{
+							"type": map[string]interface{}{"terms": map[string]interface{}{"field": "result_type", "size": 1}},
+						},
+					},
+				},
+			},
+		},
+	}, &result)
+
+	stats := &models.FeedbackStats{
+		Distribution: []models.RatingCount{},
+		Queries:      []models.QueryRating{},
+		WorstQueries: []models.QueryRating{},
+		Volume:       []models.FeedbackVolume{},
+		PoorResults:  []models.ResultRating{},
+	}
+	if err != nil {
+		if apperror.KindOf(err) == apperror.KindIndexNotFound {
+			return stats, nil
+		}
+		return nil, err
+	}
+	aggs := result.Aggregations
+
+	stats.Total = result.Hits.Total.Value
+	stats.AverageRating = roundRating(aggs.AvgRating.Value)
+	if stats.Total > 0 {
+		stats.PositivePct = math.Round(float64(aggs.Positive.DocCount)*1000/float64(stats.Total)) / 10
+	}
+	counts := map[int]int64{}
+	for _, b := range aggs.Ratings.Buckets {
+		if rating, ok := b.Key.(float64); ok {
+			counts[int(rating)] = b.DocCount
+		}
+	}
+	for rating := 1; rating <= 5; rating++ {
+		stats.Distribution = append(stats.Distribution, models.RatingCount{Rating: rating, Count: counts[rating]})
+	}
+	for _, b := range aggs.Queries.Buckets {
+		stats.Queries = append(stats.Queries, queryRating(b))
+	}
+	for _, b := range aggs.WorstQueries.Buckets {
+		stats.WorstQueries = append(stats.WorstQueries, queryRating(b))
+	}
+	for _, b := range aggs.Volume.Buckets {
+		date, _ := time.Parse(time.RFC3339, b.KeyAsString)
+		if ms, ok := b.Key.(float64); ok && date.IsZero() {
+			date = time.UnixMilli(int64(ms)).UTC()
+		}
+		stats.Volume = append(stats.Volume, models.FeedbackVolume{Date: date, Count: b.DocCount, AverageRating: roundRating(b.AvgRating.Value)})
+	}
+
+	for _, b := range aggs.Poor.Results.Buckets {
+		r := models.ResultRating{PoorRatings: b.DocCount}
+		r.ResultID, _ = b.Key.(string)
+		if len(b.Type.Buckets) > 0 {
+			r.ResultType, _ = b.Type.Buckets[0].Key.(string)
+		}
+		stats.PoorResults = append(stats.PoorResults, r)
+	}
+	if err := s.rateResults(ctx, q, stats.PoorResults); err != nil {
+		s.logger.WithError(err).Warn("Failed to load overall ratings of poorly rated results")
+	}
+	return stats, nil
+}
+
+// rateResults fills in how often, and how well, each result was rated
+// overall, not only by the poor ratings that listed it.
+func (s *FeedbackService) rateResults(ctx context.Context, q *models.FeedbackQuery, results []models.ResultRating) error {
+	if len(results) == 0 {
+		return nil
+	}
+	ids := make([]string, len(results))
+	for i, r := range results {
+		ids[i] = r.ResultID
+	}
+
+	overall := *q
+	overall.MaxRating = 0
+	filter := feedbackFilter(&overall)
+	boolQuery := filter["bool"].(map[string]interface{})
+	boolQuery["filter"] = append(boolQuery["filter"].([]map[string]interface{}),
+		map[string]interface{}{"terms": map[string]interface{}{"result_id": ids}})
+
+	var result struct {
+		Aggregations struct {
+			Results statsTerms `json:"results"`
+		} `json:"aggregations"`
+	}
+	err := s.search(ctx, map[string]interface{}{
+		"query": filter,
+		"size":  0,
+		"aggs": map[string]interface{}{
+			"results": map[string]interface{}{
+				"terms": map[string]interface{}{"field": "result_id", "size": len(ids)},
+				"aggs":  map[string]interface{}{"avg_rating": map[string]interface{}{"avg": map[string]interface{}{"field": "rating"}}},
+			},
+		},
+	}, &result)
+	if err != nil {
+		return err
+	}
+
+	byID := map[string]statsBucket{}
+	for _, b := range result.Aggregations.Results.Buckets {
+		if id, ok := b.Key.(string); ok {
+			byID[id] = b
+		}
+	}
+	for i := range results {
+		if b, ok := byID[results[i].ResultID]; ok {
+			results[i].Count = b.DocCount
+			results[i].AverageRating = roundRating(b.AvgRating.Value)
+		}
+	}
+	return nil
+}
+
+func queryRating(b statsBucket) models.QueryRating {
+	query, _ := b.Key.(string)
+	return models.QueryRating{Query: query, Count: b.DocCount, AverageRating: roundRating(b.AvgRating.Value)}
+}
+
+func roundRating(v *float64) float64 {
+	if v == nil {
+		return 0
+	}
+	return math.Round(*v*100) / 100
+}
+
+// ── Judgments ──
+
+// maxJudgedQueries caps how many queries a judgment list built from
+// feedback covers, taking the most rated.
+const maxJudgedQueries = 1000
+
+// Judgments grades, for each rated query, the results of resultType rated
+// at least minRatings times, by their average rating: 1 or 2 grades 0
+// (irrelevant), 3 grades 1, 4 grades 2 and 5 grades 3. Feedback that does
+// not say what type of result it rates is taken to rate resultType.
+func (s *FeedbackService) Judgments(ctx context.Context, resultType string, from, to time.Time, minRatings int) ([]models.QueryJudgments, error) {
+	if minRatings < 1 {
+		minRatings = 1
+	}
+	filter := feedbackFilter(&models.FeedbackQuery{From: from, To: to})
+	boolQuery := filter["bool"].(map[string]interface{})
+	boolQuery["should"] = []map[string]interface{}{
+		{"term": map[string]interface{}{"result_type": resultType}},
+		{"bool": map[string]interface{}{"must_not": map[string]interface{}{"exists": map[string]interface{}{"field": "result_type"}}}},
+	}
+	boolQuery["minimum_should_match"] = 1
+
+	var result struct {
+		Aggregations struct {
+			Queries struct {
+				Buckets []struct {
+					Key     string     `json:"key"`
+					Results statsTerms `json:"results"`
+				} `json:"buckets"`
+			} `json:"queries"`
+		} `json:"aggregations"`
+	}
+	err := s.search(ctx, map[string]interface{}{
+		"query": filter,
+		"size":  0,
+		"aggs": map[string]interface{}{
+			"queries": map[string]interface{}{
+				"terms": map[string]interface{}{"field": "query_key", "size": maxJudgedQueries, "min_doc_count": minRatings},
+				"aggs": map[string]interface{}{
+					"results": map[string]interface{}{
+						"terms": map[string]interface{}{"field": "result_id", "size": 100, "min_doc_count": minRatings},
+						"aggs":  map[string]interface{}{"avg_rating": map[string]interface{}{"avg": map[string]interface{}{"field": "rating"}}},
+					},
+				},
+			},
+		},
+	}, &result)
+	if err != nil {
+		if apperror.KindOf(err) == apperror.KindIndexNotFound {
+			return []models.QueryJudgments{}, nil
+		}
+		return nil, err
+	}
+
+	queries := []models.QueryJudgments{}
+	for _, q := range result.Aggregations.Queries.Buckets {
+		judged := models.QueryJudgments{Query: q.Key}
+		for _, b := range q.Results.Buckets {
+			id, _ := b.Key.(string)
+			if id == "" || b.AvgRating.Value == nil {
+				continue
+			}
+			grade := int(math.Round(*b.AvgRating.Value)) - 2
+			if grade < 0 {
+				grade = 0
+			}
+			judged.Judgments = append(judged.Judgments, models.Judgment{DocumentID: id, Grade: grade})
+		}
+		if len(judged.Judgments) > 0 {
+			queries = append(queries, judged)
+		}
+	}
+	return queries, nil
+}
+
+// ── Export ──
+
+// Export passes every entry matching q to emit, oldest first, reading the
+// index a page at a time. It stops at the first error emit returns.
+func (s *FeedbackService) Export(ctx context.Context, q *models.FeedbackQuery, emit func(*models.SearchFeedback) error) error {
+	filter := feedbackFilter(q)
+	var after []interface{}
+	for {
+		query := map[string]interface{}{
+			"query": filter,
+			"sort": []map[string]interface{}{
+				{"created_at": map[string]interface{}{"order": "asc"}},
+				{"id": map[string]interface{}{"order": "asc"}},
+			},
+			"size":             exportBatch,
+			"track_total_hits": false,
+		}
+		if after != nil {
+			query["search_after"] = after
+		}
+
+		var result feedbackHits
+		if err := s.search(ctx, query, &result); err != nil {
+			if apperror.KindOf(err) == apperror.KindIndexNotFound {
+				return nil
+			}
+			return err
+		}
+		for i := range result.Hits.Hits {
+			if err := emit(&result.Hits.Hits[i].Source); err != nil {
+				return err
+			}
+		}
+		if len(result.Hits.Hits) < exportBatch {
+			return nil
+		}
+		after = result.Hits.Hits[len(result.Hits.Hits)-1].Sort
+	}
+}
+
+// ── Legacy Records ──
+
+// MigrateLegacy moves feedback stored in Redis before the feedback index
+// existed into it, once Redis and Elasticsearch are both reachable. Those
+// records carry no workspace, so they only count towards unrestricted
+// views. Each record is deleted once indexed; one that fails is left for
+// the next start.
+func (s *FeedbackService) MigrateLegacy(ctx context.Context) {
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+	for s.redis.Client() == nil || s.es.Client() == nil {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+
+	rdb := s.redis.Client()
+	var migrated int
+	iter := rdb.Scan(ctx, 0, "search_feedback:*", 100).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+		data, err := rdb.Get(ctx, key).Bytes()
+		if err != nil {
+			continue
+		}
+		var f models.SearchFeedback
+		if err := json.Unmarshal(data, &f); err != nil || f.ID == "" {
+			continue
+		}
+		if err := s.write(ctx, &f); err != nil && apperror.KindOf(err) != apperror.KindConflict {
+			s.logger.WithError(err).WithField("key", key).Warn("Failed to migrate feedback")
+			continue
+		}
+		rdb.Del(ctx, key)
+		migrated++
+	}
+	if migrated > 0 {
+		s.logger.WithField("count", migrated).Info("Migrated feedback from Redis")
+	}
+}
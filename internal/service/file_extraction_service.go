@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/quckapp/search-service/internal/apperror"
+	"github.com/quckapp/search-service/internal/blob"
+	"github.com/quckapp/search-service/internal/config"
+	"github.com/quckapp/search-service/internal/extract"
+	"github.com/quckapp/search-service/internal/models"
+)
+
+// FileExtractionService indexes the text of files into quckapp_files. Files
+// arrive as uploads or as references read through a blob.Reader. A file
+// whose type has no extractor, or whose content fails to parse, is still
+// indexed so it can be found by name.
+type FileExtractionService struct {
+	search *SearchService
+	blobs  blob.Reader
+	cfg    config.Extraction
+	logger *logrus.Logger
+}
+
+func NewFileExtractionService(search *SearchService, blobs blob.Reader, cfg config.Extraction, logger *logrus.Logger) *FileExtractionService {
+	return &FileExtractionService{search: search, blobs: blobs, cfg: cfg, logger: logger}
+}
+
+// MaxFileSize is the largest file, in bytes, that will be indexed.
+func (s *FileExtractionService) MaxFileSize() int64 {
+	return s.cfg.MaxFileSize
+}
+
+// IndexUpload extracts and indexes an uploaded file.
+func (s *FileExtractionService) IndexUpload(ctx context.Context, req *models.FileIndexRequest, r io.Reader) (*models.FileIndexResult, error) {
+	data, err := s.read(r)
+	if err != nil {
+		return nil, err
+	}
+	return s.index(ctx, req, data)
+}
+
+// IndexRef reads the file at req.Ref through the blob reader, then extracts
+// and indexes it. Each workspace's files live under a directory named after
+// it, so the ref must start with the caller's workspace. The file name
+// defaults to the last element of the ref.
+func (s *FileExtractionService) IndexRef(ctx context.Context, req *models.FileIndexRequest) (*models.FileIndexResult, error) {
+	if req.Ref == "" {
+		return nil, apperror.BadQuery("ref is required", nil)
+	}
+	workspaceID, err := requireWorkspace(ctx)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := blob.TopDir(req.Ref)
+	if err != nil {
+		return nil, apperror.BadQuery("Invalid file reference", err)
+	}
+	if dir != workspaceID {
+		return nil, apperror.Forbidden("File reference is outside workspace " + workspaceID)
+	}
+
+	rc, err := s.blobs.Open(ctx, req.Ref)
+	switch {
+	case errors.Is(err, blob.ErrNotFound):
+		return nil, apperror.NotFound("File not found")
+	case errors.Is(err, blob.ErrInvalidRef):
+		return nil, apperror.BadQuery("Invalid file reference", err)
+	case errors.Is(err, blob.ErrDisabled):
+		return nil, apperror.Unavailable("File storage is not configured", err)
+	case err != nil:
+		return nil, apperror.Unavailable("File storage unavailable", err)
+	}
+	defer rc.Close()
+
+	data, err := s.read(rc)
+	if err != nil {
+		return nil, err
+	}
+	if req.Filename == "" {
+		req.Filename = path.Base(strings.TrimSuffix(req.Ref, "/"))
+	}
+	return s.index(ctx, req, data)
+}
+
+// read loads a file, rejecting it once it passes the size limit rather than
+// buffering all of it.
+func (s *FileExtractionService) read(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, s.cfg.MaxFileSize+1))
+	if err != nil {
+		return nil, apperror.BadQuery("Failed to read file", err)
+	}
+	if int64(len(data)) > s.cfg.MaxFileSize {
+		return nil, apperror.BadQuery(fmt.Sprintf("File exceeds the %d byte limit", s.cfg.MaxFileSize), nil)
+	}
+	return data, nil
+}
+
+func (s *FileExtractionService) index(ctx context.Context, req *models.FileIndexRequest, data []byte) (*models.FileIndexResult, error) {
+	if req.ID == "" {
+		return nil, apperror.BadQuery("id is required", nil)
+	}
+	if req.Filename == "" {
+		return nil, apperror.BadQuery("filename is required", nil)
+	}
+
+	result := &models.FileIndexResult{ID: req.ID, Filename: req.Filename, Size: int64(len(data))}
+	extracted, err := extract.Extract(data, req.Filename, s.cfg.MaxChars)
+	switch {
+	case errors.Is(err, extract.ErrUnsupported):
+		result.Warning = "No text extractor for " + extracted.MimeType + "; indexed by name only"
+	case err != nil:
+		s.logger.WithError(err).WithFields(logrus.Fields{"id": req.ID, "mime_type": extracted.MimeType}).Warn("File text extraction failed")
+		result.Warning = "Could not read file content; indexed by name only"
+	}
+	result.MimeType = extracted.MimeType
+	result.FileType = extracted.FileType
+	passages := []map[string]interface{}{}
+	if err == nil {
+		result.Characters = utf8.RuneCountInString(extracted.Text)
+		result.Truncated = extracted.Truncated
+		// Passages keep the page, slide or sheet they came from.
+		passages = passageDocs(extract.Chunk(extracted.Sections, passageWords, passageOverlap))
+		result.Passages = len(passages)
+	}
+	if result.FileType == "" {
+		result.FileType = strings.TrimPrefix(strings.ToLower(path.Ext(req.Filename)), ".")
+	}
+
+	doc := map[string]interface{}{
+		"id":                req.ID,
+		"filename":          req.Filename,
+		"content":           extracted.Text,
+		"file_type":         result.FileType,
+		"mime_type":         result.MimeType,
+		"size":              result.Size,
+		"user_id":           req.UserID,
+		"channel_id":        req.ChannelID,
+		"workspace_id":      req.WorkspaceID,
+		"created_at":        time.Now().UTC(),
+		"content_truncated": result.Truncated,
+		"passages":          passages,
+		"section_type":      extracted.SectionType,
+	}
+	if err := s.search.IndexDocument(ctx, indexFiles, req.ID, doc, req.Version); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,210 @@
+package service
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/quckapp/search-service/internal/models"
+)
+
+// maxHighlightFields caps the fields a search may ask to highlight.
+const maxHighlightFields = 10
+
+// searchHighlightFields are highlighted when a search names none: the text
+// fields of messages, files, users and channels.
+var searchHighlightFields = []string{"content", "filename", "name", "display_name", "description"}
+
+// extHighlightFields are the default for bookmarks, tasks and the other
+// extended searches.
+var extHighlightFields = []string{"title", "content", "description", "name", "display_name"}
+
+// Offset highlighting marks matches with private-use characters, which
+// parseHighlights strips back out while counting where they were.
+const (
+	matchStart = '\uE000'
+	matchEnd   = '\uE001'
+)
+
+// Custom tags must be a plain inline element when fragments are escaped;
+// anything more could carry script past the escaping.
+var (
+	safePreTag  = regexp.MustCompile(`^<(em|strong|b|i|u|mark|span)( class="[\w -]*")?>$`)
+	safePostTag = regexp.MustCompile(`^</(em|strong|b|i|u|mark|span)>$`)
+)
+
+// highlightClause builds the highlight section of a search for params,
+// highlighting defaults unless params names its own fields.
+func highlightClause(params *models.SearchParams, defaults []string) map[string]interface{} {
+	fields := map[string]interface{}{}
+	for _, field := range highlightFields(params, defaults) {
+		fields[field] = map[string]interface{}{}
+	}
+	pre, post := highlightTags(params)
+	clause := map[string]interface{}{
+		"fields":              fields,
+		"fragment_size":       params.FragmentSize,
+		"number_of_fragments": params.Fragments,
+		"pre_tags":            []string{pre},
+		"post_tags":           []string{post},
+	}
+	if escapeHighlights(params) {
+		clause["encoder"] = "html"
+	}
+	return clause
+}
+
+// highlightFields returns the fields params asks to highlight, or defaults.
+func highlightFields(params *models.SearchParams, defaults []string) []string {
+	var fields []string
+	seen := map[string]bool{}
+	for _, field := range strings.Split(params.HighlightFields, ",") {
+		if field = strings.TrimSpace(field); field != "" && !seen[field] && len(fields) < maxHighlightFields {
+			seen[field] = true
+			fields = append(fields, field)
+		}
+	}
+	if len(fields) == 0 {
+		return defaults
+	}
+	return fields
+}
+
+// highlightTags returns the tags that wrap matches. A custom pair is used
+// only when both tags are given and, for escaped fragments, both are safe
+// and name the same element.
+func highlightTags(params *models.SearchParams) (string, string) {
+	if params.HighlightOffsets {
+		return string(matchStart), string(matchEnd)
+	}
+	pre, post := params.PreTag, params.PostTag
+	if pre == "" || post == "" {
+		return "<em>", "</em>"
+	}
+	if escapeHighlights(params) {
+		open, closing := safePreTag.FindStringSubmatch(pre), safePostTag.FindStringSubmatch(post)
+		if open == nil || closing == nil || open[1] != closing[1] {
+			return "<em>", "</em>"
+		}
+	}
+	return pre, post
+}
+
+// highlightCacheKey distinguishes cached pages highlighted differently.
+func highlightCacheKey(params *models.SearchParams) string {
+	pre, post := highlightTags(params)
+	return fmt.Sprintf("%s|%d|%d|%s|%s|%t", params.HighlightFields, params.FragmentSize, params.Fragments,
+		pre+post, params.HighlightEncoder, params.HighlightOffsets)
+}
+
+// escapeHighlights reports whether fragment text is HTML-escaped. Offset
+// fragments never are: they are plain text for the client to mark up.
+func escapeHighlights(params *models.SearchParams) bool {
+	return params.HighlightEncoder != models.HighlightEncoderNone && !params.HighlightOffsets
+}
+
+// parseHighlights reads a raw hit's highlights, in the order its fields
+// were asked for, or makes a fallback snippet from source when it has none.
+func parseHighlights(hitMap, source map[string]interface{}, params *models.SearchParams, defaults []string) []models.Highlight {
+	fields := highlightFields(params, defaults)
+	raw, _ := hitMap["highlight"].(map[string]interface{})
+
+	// Wildcard fields come back under the names they matched.
+	order := append([]string{}, fields...)
+	var extra []string
+	for field := range raw {
+		if !containsString(fields, field) {
+			extra = append(extra, field)
+		}
+	}
+	sort.Strings(extra)
+	order = append(order, extra...)
+
+	var highlights []models.Highlight
+	for _, field := range order {
+		fragments, _ := raw[field].([]interface{})
+		if len(fragments) == 0 {
+			continue
+		}
+		h := models.Highlight{Field: field}
+		for _, f := range fragments {
+			text, ok := f.(string)
+			if !ok {
+				continue
+			}
+			if params.HighlightOffsets {
+				var offsets [][2]int
+				text, offsets = matchOffsets(text)
+				h.Offsets = append(h.Offsets, offsets)
+			}
+			h.Fragments = append(h.Fragments, text)
+		}
+		highlights = append(highlights, h)
+	}
+
+	if len(highlights) == 0 {
+		if h := fallbackSnippet(source, fields, params); h != nil {
+			highlights = append(highlights, *h)
+		}
+	}
+	return highlights
+}
+
+// matchOffsets strips the match markers from an offset-highlighted
+// fragment and returns where each match was, in characters.
+func matchOffsets(fragment string) (string, [][2]int) {
+	var b strings.Builder
+	offsets := [][2]int{}
+	n, start := 0, 0
+	for _, r := range fragment {
+		switch r {
+		case matchStart:
+			start = n
+		case matchEnd:
+			offsets = append(offsets, [2]int{start, n})
+		default:
+			b.WriteRune(r)
+			n++
+		}
+	}
+	return b.String(), offsets
+}
+
+// fallbackSnippet takes about a fragment's worth of text from the start of
+// the first highlighted field source has, cut at a word boundary.
+func fallbackSnippet(source map[string]interface{}, fields []string, params *models.SearchParams) *models.Highlight {
+	for _, field := range fields {
+		text := strings.Join(strings.Fields(getString(source, field)), " ")
+		if text == "" {
+			continue
+		}
+		if utf8.RuneCountInString(text) > params.FragmentSize {
+			cut := string([]rune(text)[:params.FragmentSize])
+			if i := strings.LastIndex(cut, " "); i > 0 {
+				cut = cut[:i]
+			}
+			text = cut + "…"
+		}
+		if escapeHighlights(params) {
+			text = html.EscapeString(text)
+		}
+		h := &models.Highlight{Field: field, Fragments: []string{text}, Fallback: true}
+		if params.HighlightOffsets {
+			h.Offsets = [][][2]int{{}}
+		}
+		return h
+	}
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
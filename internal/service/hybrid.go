@@ -0,0 +1,254 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/quckapp/search-service/internal/apperror"
+	"github.com/quckapp/search-service/internal/models"
+	"github.com/quckapp/search-service/internal/tenant"
+)
+
+const (
+	// minHybridDepth is the fewest hits each retriever contributes. Fusion
+	// needs more than one page from each side, or a document ranked just
+	// below the page by one retriever cannot be lifted by the other.
+	minHybridDepth = 50
+	// maxHybridDepth caps the hits each retriever contributes, which also
+	// caps how deep hybrid results can be paged.
+	maxHybridDepth = 1000
+)
+
+// hybridSearch is a hybrid-mode search of index. It retrieves the top hits
+// for the lexical clauses in must and the kNN hits for the query's embedding
+// in parallel, both restricted by filters, and fuses the two rankings with
+// the workspace's hybrid settings. shape, if set, adjusts both searches
+// before they run; annotate, if set, reads extra fields from the lexical
+// result into its parsed hits.
+func (s *SearchService) hybridSearch(ctx context.Context, index string, must, filters []map[string]interface{}, params *models.SearchParams,
+	shape func(map[string]interface{}), annotate func(map[string]interface{}, *models.SearchResponse)) (*models.SearchResponse, error) {
+	if !s.embeddings.Enabled() {
+		return nil, apperror.BadQuery("Hybrid search needs semantic search, which is not enabled", nil)
+	}
+
+	depth := 2 * (params.From() + params.PerPage)
+	if depth < minHybridDepth {
+		depth = minHybridDepth
+	}
+	if depth > maxHybridDepth {
+		depth = maxHybridDepth
+	}
+	retrieval := *params
+	retrieval.Page = 1
+	retrieval.PerPage = depth
+	retrieval.Sort = "relevance"
+
+	lexQuery := s.buildQuery(must, filters, &retrieval)
+	s.boostFields(ctx, lexQuery, params)
+	s.boostClicked(ctx, lexQuery, index, params)
+	curation := s.curate(ctx, lexQuery, index, params)
+	if shape != nil {
+		shape(lexQuery)
+	}
+	var (
+		wg                     sync.WaitGroup
+		semQuery               map[string]interface{}
+		lexResult, semResult   map[string]interface{}
+		lexErr, semErr, vecErr error
+	)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		lexResult, lexErr = s.executeSearch(ctx, index, lexQuery)
+	}()
+	go func() {
+		defer wg.Done()
+		var vector []float32
+		vector, vecErr = s.embeddings.QueryVector(ctx, params.Query)
+		if vecErr != nil {
+			return
+		}
+		semQuery = map[string]interface{}{
+			"knn":  knnClause(vector, depth, filters),
+			"size": depth,
+		}
+		if params.Explain {
+			semQuery["explain"] = true
+		}
+		applyCuration(semQuery, curation)
+		if shape != nil {
+			shape(semQuery)
+		}
+		semResult, semErr = s.executeSearch(ctx, index, semQuery)
+	}()
+	wg.Wait()
+
+	if lexErr != nil {
+		return nil, lexErr
+	}
+	lexical := s.parseResponse(lexResult, &retrieval)
+	if annotate != nil {
+		annotate(lexResult, lexical)
+	}
+
+	// A query with nothing to embed, or an embedding provider or kNN search
+	// that fails, leaves the lexical ranking to stand alone.
+	semantic := &models.SearchResponse{}
+	switch {
+	case vecErr != nil:
+		s.logger.WithError(vecErr).WithField("index", index).Debug("Hybrid search without a query vector; ranking lexically")
+	case semErr != nil:
+		s.logger.WithError(semErr).WithField("index", index).Warn("Hybrid kNN search failed; ranking lexically")
+	default:
+		semantic = s.parseResponse(semResult, &retrieval)
+	}
+
+	fused := fuseHits(lexical.Results, semantic.Results, s.hybridConfig(ctx, params))
+	sortHybridHits(fused, params.Sort)
+	if params.Sort != "newest" && params.Sort != "oldest" {
+		pinFirst(fused, curation)
+	}
+
+	resp := &models.SearchResponse{
+		Results: []models.SearchHit{},
+		Total:   int64(len(fused)),
+		Page:    params.Page,
+		PerPage: params.PerPage,
+	}
+	if lexical.Total > resp.Total {
+		resp.Total = lexical.Total
+	}
+	if from := params.From(); from < len(fused) {
+		end := from + params.PerPage
+		if end > len(fused) {
+			end = len(fused)
+		}
+		resp.Results = fused[from:end]
+	}
+	markCurated(resp, curation)
+	applySearchMeta(resp, lexResult)
+	if resp.Total > 0 {
+		resp.TotalPages = int((resp.Total + int64(params.PerPage) - 1) / int64(params.PerPage))
+	}
+	if params.Explain {
+		queries := []map[string]interface{}{lexQuery}
+		if semQuery != nil {
+			queries = append(queries, semQuery)
+		}
+		resp.Explain = s.explainQuery(ctx, index, params, queries...)
+	}
+	return resp, nil
+}
+
+// hybridConfig returns the hybrid settings of the workspace being searched.
+func (s *SearchService) hybridConfig(ctx context.Context, params *models.SearchParams) models.HybridConfig {
+	config := s.relevanceConfig(ctx, params)
+	if config == nil {
+		return defaultHybridConfig()
+	}
+	return config.Hybrid
+}
+
+// relevanceConfig returns the relevance config a search ranks with: its
+// override, or the config of the workspace being searched. It is nil when
+// the service has no relevance settings.
+func (s *SearchService) relevanceConfig(ctx context.Context, params *models.SearchParams) *models.RelevanceConfig {
+	if params.Relevance != nil {
+		return params.Relevance
+	}
+	if s.relevance == nil {
+		return nil
+	}
+	config, _ := s.relevance.GetConfig(ctx, searchWorkspace(ctx, params))
+	return config
+}
+
+// searchWorkspace is the workspace whose settings apply to a search: the
+// caller's, or the one an unrestricted caller filtered by.
+func searchWorkspace(ctx context.Context, params *models.SearchParams) string {
+	if scope, ok := tenant.FromContext(ctx); ok && !scope.Unrestricted() {
+		return scope.WorkspaceID
+	}
+	return params.WorkspaceID
+}
+
+// fuseHits merges two rankings of the same index into one, scoring each hit
+// by cfg.Fusion:
+//
+//   - rrf: the sum over rankings of weight / (rrf_k + rank). Only ranks
+//     count, so the lexical and vector scores need not be comparable.
+//   - linear: the sum over rankings of weight * score, with each ranking's
+//     scores min-max normalised to [0, 1].
+//
+// A hit found by both keeps the lexical hit's highlights and passages. Each
+// hit records what each ranking contributed to its score, and how that
+// ranking scored it when explained.
+func fuseHits(lexical, semantic []models.SearchHit, cfg models.HybridConfig) []models.SearchHit {
+	var fused []models.SearchHit
+	position := map[string]int{}
+
+	add := func(hits []models.SearchHit, weight float64, component func(*models.HybridScore, *models.ComponentScore)) {
+		minScore, maxScore := scoreRange(hits)
+		for i, hit := range hits {
+			c := &models.ComponentScore{Rank: i + 1, Score: hit.Score, Explanation: hit.Explanation}
+			if cfg.Fusion == models.FusionLinear {
+				norm := 1.0
+				if maxScore > minScore {
+					norm = (hit.Score - minScore) / (maxScore - minScore)
+				}
+				c.Contribution = weight * norm
+			} else {
+				c.Contribution = weight / float64(cfg.RRFK+c.Rank)
+			}
+
+			j, ok := position[hit.ID]
+			if !ok {
+				j = len(fused)
+				position[hit.ID] = j
+				hit.Score = 0
+				hit.Hybrid = &models.HybridScore{Fusion: cfg.Fusion}
+				hit.Explanation = nil
+				fused = append(fused, hit)
+			}
+			fused[j].Score += c.Contribution
+			component(fused[j].Hybrid, c)
+		}
+	}
+	add(lexical, cfg.LexicalWeight, func(h *models.HybridScore, c *models.ComponentScore) { h.Lexical = c })
+	add(semantic, cfg.SemanticWeight, func(h *models.HybridScore, c *models.ComponentScore) { h.Semantic = c })
+	return fused
+}
+
+func scoreRange(hits []models.SearchHit) (float64, float64) {
+	if len(hits) == 0 {
+		return 0, 0
+	}
+	minScore, maxScore := hits[0].Score, hits[0].Score
+	for _, hit := range hits[1:] {
+		if hit.Score < minScore {
+			minScore = hit.Score
+		}
+		if hit.Score > maxScore {
+			maxScore = hit.Score
+		}
+	}
+	return minScore, maxScore
+}
+
+// sortHybridHits orders fused hits by score, or by creation time for the
+// newest and oldest sorts. Ties go to the lower ID so pages are stable.
+func sortHybridHits(hits []models.SearchHit, order string) {
+	sort.SliceStable(hits, func(i, j int) bool {
+		if order == "newest" || order == "oldest" {
+			a, b := getString(hits[i].Source, "created_at"), getString(hits[j].Source, "created_at")
+			if a != b {
+				return (a > b) == (order == "newest")
+			}
+		}
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID < hits[j].ID
+	})
+}
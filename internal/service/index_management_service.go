@@ -1,48 +1,42 @@
 package service
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
-	"fmt"
-	"io"
 	"strings"
 
-	"github.com/elastic/go-elasticsearch/v8"
 	"github.com/sirupsen/logrus"
 
+	"github.com/quckapp/search-service/internal/apperror"
+	"github.com/quckapp/search-service/internal/db"
 	"github.com/quckapp/search-service/internal/models"
 )
 
 type IndexManagementService struct {
-	es     *elasticsearch.Client
+	es     *db.ElasticsearchManager
 	logger *logrus.Logger
 }
 
-func NewIndexManagementService(es *elasticsearch.Client, logger *logrus.Logger) *IndexManagementService {
+func NewIndexManagementService(es *db.ElasticsearchManager, logger *logrus.Logger) *IndexManagementService {
 	return &IndexManagementService{es: es, logger: logger}
 }
 
 func (s *IndexManagementService) ListIndices(ctx context.Context) ([]models.IndexInfo, error) {
-	if s.es == nil {
-		return []models.IndexInfo{}, nil
+	es := s.es.Client()
+	if es == nil {
+		return nil, errSearchUnavailable
 	}
 
-	res, err := s.es.Cat.Indices(
-		s.es.Cat.Indices.WithIndex("quckapp_*"),
-		s.es.Cat.Indices.WithFormat("json"),
+	res, err := es.Cat.Indices(
+		es.Cat.Indices.WithContext(ctx),
+		es.Cat.Indices.WithIndex("quckapp_*"),
+		es.Cat.Indices.WithFormat("json"),
 	)
-	if err != nil {
-		return nil, err
-	}
-	defer res.Body.Close()
-
 	var indices []map[string]interface{}
-	if err := json.NewDecoder(res.Body).Decode(&indices); err != nil {
-		return []models.IndexInfo{}, nil
+	if err := readResponse(res, err, "Failed to list indices", &indices); err != nil {
+		return nil, err
 	}
 
-	var result []models.IndexInfo
+	result := []models.IndexInfo{}
 	for _, idx := range indices {
 		info := models.IndexInfo{
 			Name:   getString(idx, "index"),
@@ -61,29 +55,24 @@ func (s *IndexManagementService) ListIndices(ctx context.Context) ([]models.Inde
 }
 
 func (s *IndexManagementService) GetIndexInfo(ctx context.Context, index string) (*models.IndexInfo, error) {
-	if s.es == nil {
-		return nil, fmt.Errorf("elasticsearch not available")
+	es := s.es.Client()
+	if es == nil {
+		return nil, errSearchUnavailable
 	}
 
 	// Get mappings
-	mappingsRes, err := s.es.Indices.GetMapping(s.es.Indices.GetMapping.WithIndex(index))
-	if err != nil {
+	res, err := es.Indices.GetMapping(es.Indices.GetMapping.WithIndex(index), es.Indices.GetMapping.WithContext(ctx))
+	var mappings map[string]interface{}
+	if err := readResponse(res, err, "Failed to get index mappings", &mappings); err != nil {
 		return nil, err
 	}
-	defer mappingsRes.Body.Close()
-
-	var mappings map[string]interface{}
-	json.NewDecoder(mappingsRes.Body).Decode(&mappings)
 
 	// Get settings
-	settingsRes, err := s.es.Indices.GetSettings(s.es.Indices.GetSettings.WithIndex(index))
-	if err != nil {
+	res, err = es.Indices.GetSettings(es.Indices.GetSettings.WithIndex(index), es.Indices.GetSettings.WithContext(ctx))
+	var settings map[string]interface{}
+	if err := readResponse(res, err, "Failed to get index settings", &settings); err != nil {
 		return nil, err
 	}
-	defer settingsRes.Body.Close()
-
-	var settings map[string]interface{}
-	json.NewDecoder(settingsRes.Body).Decode(&settings)
 
 	return &models.IndexInfo{
 		Name:     index,
@@ -93,83 +82,93 @@ func (s *IndexManagementService) GetIndexInfo(ctx context.Context, index string)
 }
 
 func (s *IndexManagementService) CreateIndex(ctx context.Context, index string, mappings map[string]interface{}) error {
-	if s.es == nil {
-		return nil
+	if err := requireAllWorkspaces(ctx); err != nil {
+		return err
+	}
+
+	es := s.es.Client()
+	if es == nil {
+		return errSearchUnavailable
 	}
 
 	body := map[string]interface{}{
 		"mappings": mappings,
 	}
 
-	var buf bytes.Buffer
-	json.NewEncoder(&buf).Encode(body)
-
-	res, err := s.es.Indices.Create(index, s.es.Indices.Create.WithBody(&buf))
+	buf, err := encodeBody(body)
 	if err != nil {
 		return err
 	}
-	defer res.Body.Close()
 
-	if res.IsError() {
-		bodyBytes, _ := io.ReadAll(res.Body)
-		return fmt.Errorf("failed to create index: %s", string(bodyBytes))
-	}
-	return nil
+	res, err := es.Indices.Create(index, es.Indices.Create.WithBody(buf), es.Indices.Create.WithContext(ctx))
+	return readResponse(res, err, "Failed to create index", nil)
 }
 
 func (s *IndexManagementService) DeleteIndex(ctx context.Context, index string) error {
-	if s.es == nil {
-		return nil
+	if err := requireAllWorkspaces(ctx); err != nil {
+		return err
+	}
+
+	es := s.es.Client()
+	if es == nil {
+		return errSearchUnavailable
 	}
 
 	// Safety: only allow deleting quckapp_ prefixed indices
 	if !strings.HasPrefix(index, "quckapp_") {
-		return fmt.Errorf("can only delete quckapp_* indices")
+		return apperror.Forbidden("Can only delete quckapp_* indices")
 	}
 
-	res, err := s.es.Indices.Delete([]string{index})
-	if err != nil {
-		return err
-	}
-	defer res.Body.Close()
-	return nil
+	res, err := es.Indices.Delete([]string{index}, es.Indices.Delete.WithContext(ctx))
+	return readResponse(res, err, "Failed to delete index", nil)
 }
 
 func (s *IndexManagementService) PutMapping(ctx context.Context, req *models.IndexMapping) error {
-	if s.es == nil {
-		return nil
+	if err := requireAllWorkspaces(ctx); err != nil {
+		return err
 	}
 
-	var buf bytes.Buffer
-	json.NewEncoder(&buf).Encode(req.Mappings)
+	es := s.es.Client()
+	if es == nil {
+		return errSearchUnavailable
+	}
 
-	res, err := s.es.Indices.PutMapping([]string{req.Index}, &buf)
+	buf, err := encodeBody(req.Mappings)
 	if err != nil {
 		return err
 	}
-	defer res.Body.Close()
-	return nil
+
+	res, err := es.Indices.PutMapping([]string{req.Index}, buf, es.Indices.PutMapping.WithContext(ctx))
+	return readResponse(res, err, "Failed to update mapping", nil)
 }
 
 func (s *IndexManagementService) UpdateSettings(ctx context.Context, req *models.IndexSettings) error {
-	if s.es == nil {
-		return nil
+	if err := requireAllWorkspaces(ctx); err != nil {
+		return err
 	}
 
-	var buf bytes.Buffer
-	json.NewEncoder(&buf).Encode(req.Settings)
+	es := s.es.Client()
+	if es == nil {
+		return errSearchUnavailable
+	}
 
-	res, err := s.es.Indices.PutSettings(&buf, s.es.Indices.PutSettings.WithIndex(req.Index))
+	buf, err := encodeBody(req.Settings)
 	if err != nil {
 		return err
 	}
-	defer res.Body.Close()
-	return nil
+
+	res, err := es.Indices.PutSettings(buf, es.Indices.PutSettings.WithIndex(req.Index), es.Indices.PutSettings.WithContext(ctx))
+	return readResponse(res, err, "Failed to update settings", nil)
 }
 
 func (s *IndexManagementService) CreateAlias(ctx context.Context, req *models.IndexAliasRequest) error {
-	if s.es == nil {
-		return nil
+	if err := requireAllWorkspaces(ctx); err != nil {
+		return err
+	}
+
+	es := s.es.Client()
+	if es == nil {
+		return errSearchUnavailable
 	}
 
 	body := map[string]interface{}{
@@ -181,54 +180,47 @@ func (s *IndexManagementService) CreateAlias(ctx context.Context, req *models.In
 		},
 	}
 
-	var buf bytes.Buffer
-	json.NewEncoder(&buf).Encode(body)
-
-	res, err := s.es.Indices.UpdateAliases(&buf)
+	buf, err := encodeBody(body)
 	if err != nil {
 		return err
 	}
-	defer res.Body.Close()
-	return nil
+
+	res, err := es.Indices.UpdateAliases(buf, es.Indices.UpdateAliases.WithContext(ctx))
+	return readResponse(res, err, "Failed to create alias", nil)
 }
 
 func (s *IndexManagementService) DeleteAlias(ctx context.Context, index, alias string) error {
-	if s.es == nil {
-		return nil
+	if err := requireAllWorkspaces(ctx); err != nil {
+		return err
 	}
 
-	res, err := s.es.Indices.DeleteAlias([]string{index}, []string{alias})
-	if err != nil {
-		return err
+	es := s.es.Client()
+	if es == nil {
+		return errSearchUnavailable
 	}
-	defer res.Body.Close()
-	return nil
+
+	res, err := es.Indices.DeleteAlias([]string{index}, []string{alias}, es.Indices.DeleteAlias.WithContext(ctx))
+	return readResponse(res, err, "Failed to delete alias", nil)
 }
 
 func (s *IndexManagementService) RefreshIndex(ctx context.Context, index string) error {
-	if s.es == nil {
-		return nil
+	es := s.es.Client()
+	if es == nil {
+		return errSearchUnavailable
 	}
 
-	res, err := s.es.Indices.Refresh(s.es.Indices.Refresh.WithIndex(index))
-	if err != nil {
-		return err
-	}
-	defer res.Body.Close()
-	return nil
+	res, err := es.Indices.Refresh(es.Indices.Refresh.WithIndex(index), es.Indices.Refresh.WithContext(ctx))
+	return readResponse(res, err, "Failed to refresh index", nil)
 }
 
 func (s *IndexManagementService) FlushIndex(ctx context.Context, index string) error {
-	if s.es == nil {
-		return nil
+	es := s.es.Client()
+	if es == nil {
+		return errSearchUnavailable
 	}
 
-	res, err := s.es.Indices.Flush(s.es.Indices.Flush.WithIndex(index))
-	if err != nil {
-		return err
-	}
-	defer res.Body.Close()
-	return nil
+	res, err := es.Indices.Flush(es.Indices.Flush.WithIndex(index), es.Indices.Flush.WithContext(ctx))
+	return readResponse(res, err, "Failed to flush index", nil)
 }
 
 func getString(m map[string]interface{}, key string) string {
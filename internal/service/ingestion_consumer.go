@@ -0,0 +1,550 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+
+	"github.com/quckapp/search-service/internal/apperror"
+	"github.com/quckapp/search-service/internal/config"
+	"github.com/quckapp/search-service/internal/db"
+	"github.com/quckapp/search-service/internal/models"
+)
+
+// ingestMapping says how one event type maps onto an index operation.
+// Created and uploaded events replace the document; updated, renamed and
+// changed events are partial updates that create the document if it is
+// missing, so an update overtaking its create is harmless.
+type ingestMapping struct {
+	index  string
+	op     string // index, update or delete
+	fields map[string]bool
+}
+
+var ingestEvents = map[string]ingestMapping{
+	"message.created": {indexMessages, opIndex, jsonFields(models.MessageDocument{})},
+	"message.updated": {indexMessages, opUpdate, jsonFields(models.MessageDocument{})},
+	"message.deleted": {indexMessages, opDelete, nil},
+	"file.uploaded":   {indexFiles, opIndex, jsonFields(models.FileDocument{})},
+	"channel.renamed": {indexChannels, opUpdate, jsonFields(models.ChannelDocument{})},
+	"user.updated":    {indexUsers, opUpdate, jsonFields(models.UserDocument{})},
+	"task.changed":    {indexTasks, opUpdate, jsonFields(models.TaskDocument{})},
+}
+
+// jsonFields lists the JSON field names of a document model. Event payload
+// fields outside the model are not indexed.
+func jsonFields(model interface{}) map[string]bool {
+	t := reflect.TypeOf(model)
+	fields := make(map[string]bool, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			fields[name] = true
+		}
+	}
+	return fields
+}
+
+// ingestEvent is a stream entry decoded into an index operation.
+type ingestEvent struct {
+	msg     redis.XMessage
+	mapping ingestMapping
+	id      string
+	doc     map[string]interface{}
+	version int64
+	route   indexRoute
+	// charged is set once the event is charged against its workspace's
+	// indexing quota, so it can be refunded if it is not indexed.
+	charged bool
+}
+
+// IngestionConsumer indexes domain events that other services publish to
+// Redis Streams, so their write path does not wait on the search service.
+//
+// Each entry carries a "type" field (e.g. "message.created") and a "payload"
+// field holding the JSON document, which must include "id" and
+// "workspace_id", and may include "version", the source system's edit
+// timestamp or sequence number, so an event older than the indexed document
+// is dropped. Replicas share one consumer group and read in batches that
+// go to ES as a single _bulk request. Entries are acked once indexed.
+// Entries that cannot be decoded (unknown type, bad payload) are moved to
+// "<stream>:dead" right away. Events rejected by ES go to the dead-letter
+// store, as do events that failed for a transient reason MaxDeliveries
+// times; until then those stay pending and are retried. Created, uploaded
+// and updated documents count against the workspace's daily indexing
+// quota, as they do on the HTTP write routes; events of a workspace over
+// its quota are moved to "<stream>:dead".
+type IngestionConsumer struct {
+	es          *db.ElasticsearchManager
+	redis       *db.RedisManager
+	tenants     *TenantRoutingService
+	deadLetters *DeadLetterService
+	embeddings  *EmbeddingService
+	quotas      *RateLimitService
+	cfg         config.Ingest
+	logger      *logrus.Logger
+
+	groupsReady bool
+}
+
+func NewIngestionConsumer(es *db.ElasticsearchManager, redis *db.RedisManager, tenants *TenantRoutingService, deadLetters *DeadLetterService, embeddings *EmbeddingService, quotas *RateLimitService, cfg config.Ingest, logger *logrus.Logger) *IngestionConsumer {
+	return &IngestionConsumer{es: es, redis: redis, tenants: tenants, deadLetters: deadLetters, embeddings: embeddings, quotas: quotas, cfg: cfg, logger: logger}
+}
+
+func deadLetterStream(stream string) string {
+	return stream + ":dead"
+}
+
+// Run consumes events until ctx is cancelled. While ES or Redis is down it
+// waits rather than reading events it could not index.
+func (c *IngestionConsumer) Run(ctx context.Context) {
+	if len(c.cfg.Streams) == 0 {
+		return
+	}
+	c.logger.WithFields(logrus.Fields{
+		"streams":  c.cfg.Streams,
+		"group":    c.cfg.Group,
+		"consumer": c.cfg.Consumer,
+	}).Info("Event ingestion started")
+
+	var lastRetry time.Time
+	for ctx.Err() == nil {
+		rdb, es := c.redis.Client(), c.es.Client()
+		if rdb == nil || es == nil {
+			sleepCtx(ctx, time.Second)
+			continue
+		}
+		if !c.groupsReady {
+			if err := c.ensureGroups(ctx, rdb); err != nil {
+				c.logger.WithError(err).Warn("Failed to create ingestion consumer groups")
+				sleepCtx(ctx, 5*time.Second)
+				continue
+			}
+			c.groupsReady = true
+		}
+
+		if time.Since(lastRetry) >= c.cfg.RetryAfter/2 {
+			c.retryPending(ctx, rdb, es)
+			lastRetry = time.Now()
+		}
+
+		streams := make([]string, 0, 2*len(c.cfg.Streams))
+		streams = append(streams, c.cfg.Streams...)
+		for range c.cfg.Streams {
+			streams = append(streams, ">")
+		}
+		result, err := rdb.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    c.cfg.Group,
+			Consumer: c.cfg.Consumer,
+			Streams:  streams,
+			Count:    int64(c.cfg.BatchSize),
+			Block:    c.cfg.Block,
+		}).Result()
+		if err != nil {
+			if err != redis.Nil && ctx.Err() == nil {
+				if strings.HasPrefix(err.Error(), "NOGROUP") {
+					c.groupsReady = false
+				}
+				c.logger.WithError(err).Warn("Failed to read ingestion events")
+				sleepCtx(ctx, time.Second)
+			}
+			continue
+		}
+
+		for _, stream := range result {
+			c.process(ctx, rdb, es, stream.Stream, stream.Messages)
+		}
+	}
+}
+
+func (c *IngestionConsumer) ensureGroups(ctx context.Context, rdb *redis.Client) error {
+	for _, stream := range c.cfg.Streams {
+		err := rdb.XGroupCreateMkStream(ctx, stream, c.cfg.Group, "0").Err()
+		if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+			return err
+		}
+	}
+	return nil
+}
+
+// retryPending takes over events that have been pending longer than
+// RetryAfter, whichever replica read them, and processes them again.
+func (c *IngestionConsumer) retryPending(ctx context.Context, rdb *redis.Client, es *elasticsearch.Client) {
+	for _, stream := range c.cfg.Streams {
+		pending, err := rdb.XPendingExt(ctx, &redis.XPendingExtArgs{
+			Stream: stream,
+			Group:  c.cfg.Group,
+			Idle:   c.cfg.RetryAfter,
+			Start:  "-",
+			End:    "+",
+			Count:  int64(c.cfg.BatchSize),
+		}).Result()
+		if err != nil || len(pending) == 0 {
+			continue
+		}
+
+		var retry []string
+		for _, p := range pending {
+			if p.RetryCount < int64(c.cfg.MaxDeliveries) {
+				retry = append(retry, p.ID)
+				continue
+			}
+			msgs, err := rdb.XRangeN(ctx, stream, p.ID, p.ID, 1).Result()
+			if err != nil {
+				continue
+			}
+			// An entry trimmed from the stream has nothing left to keep.
+			if len(msgs) == 0 || c.giveUp(ctx, rdb, stream, msgs[0], fmt.Sprintf("not indexed after %d attempts", p.RetryCount)) {
+				rdb.XAck(ctx, stream, c.cfg.Group, p.ID)
+			}
+		}
+		if len(retry) == 0 {
+			continue
+		}
+
+		msgs, err := rdb.XClaim(ctx, &redis.XClaimArgs{
+			Stream:   stream,
+			Group:    c.cfg.Group,
+			Consumer: c.cfg.Consumer,
+			MinIdle:  c.cfg.RetryAfter,
+			Messages: retry,
+		}).Result()
+		if err != nil {
+			c.logger.WithError(err).WithField("stream", stream).Warn("Failed to claim pending ingestion events")
+			continue
+		}
+		c.process(ctx, rdb, es, stream, msgs)
+	}
+}
+
+// process indexes one batch from stream and acks what it can.
+func (c *IngestionConsumer) process(ctx context.Context, rdb *redis.Client, es *elasticsearch.Client, stream string, msgs []redis.XMessage) {
+	var ack []string
+	var events []*ingestEvent
+	for _, msg := range msgs {
+		ev, err := decodeIngestEvent(msg)
+		if err != nil {
+			if c.deadLetter(ctx, rdb, stream, msg, err.Error()) {
+				ack = append(ack, msg.ID)
+			}
+			continue
+		}
+		// A workspace being migrated takes no writes; leave its events
+		// pending until the migration finishes.
+		route, err := c.tenants.writeRoute(ctx, ev.mapping.index, getString(ev.doc, "workspace_id"))
+		if err != nil {
+			continue
+		}
+		ev.route = route
+		events = append(events, ev)
+	}
+
+	events, overQuota := c.chargeQuotas(ctx, rdb, stream, events)
+	ack = append(ack, overQuota...)
+
+	if len(events) > 0 && !c.prepare(ctx, es, events) {
+		// Left pending; retried once RetryAfter has passed.
+		c.refundQuotas(ctx, events)
+		events = nil
+	}
+	if len(events) > 0 {
+		results, err := bulkIngest(ctx, es, events)
+		if err != nil {
+			c.logger.WithError(err).WithField("stream", stream).Warn("Bulk ingestion failed; events will be retried")
+			c.refundQuotas(ctx, events)
+		}
+		var unindexed []*ingestEvent
+		for i, res := range results {
+			if res.Status >= 300 {
+				unindexed = append(unindexed, events[i])
+			}
+			ev := events[i]
+			switch {
+			case res.Status < 300, ev.mapping.op == opDelete && res.Status == 404:
+				ack = append(ack, ev.msg.ID)
+			case res.Status == 429 || res.Status >= 500:
+				// Transient; retried once RetryAfter has passed.
+			default:
+				cause := apperror.FromStatus(res.Status, "Document rejected", errors.New(res.Error))
+				if c.capture(ctx, rdb, stream, ev, cause) {
+					ack = append(ack, ev.msg.ID)
+				}
+			}
+		}
+		c.refundQuotas(ctx, unindexed)
+	}
+
+	if len(ack) > 0 {
+		if err := rdb.XAck(ctx, stream, c.cfg.Group, ack...).Err(); err != nil {
+			c.logger.WithError(err).WithField("stream", stream).Warn("Failed to ack ingestion events")
+		}
+	}
+	c.logger.WithFields(logrus.Fields{
+		"stream": stream,
+		"read":   len(msgs),
+		"acked":  len(ack),
+	}).Debug("Ingested events")
+}
+
+// chargeQuotas charges the events that create or update documents against
+// their workspaces' daily indexing quotas. Events of a workspace over its
+// quota are dead-lettered, and the IDs of those that may be acked are
+// returned beside the events left to index. A failed quota check lets the
+// events through uncharged, as on the HTTP routes.
+func (c *IngestionConsumer) chargeQuotas(ctx context.Context, rdb *redis.Client, stream string, events []*ingestEvent) ([]*ingestEvent, []string) {
+	if c.quotas == nil {
+		return events, nil
+	}
+	counts := map[string]int64{}
+	for _, ev := range events {
+		if ev.chargeable() {
+			counts[getString(ev.doc, "workspace_id")]++
+		}
+	}
+
+	charged := map[string]bool{}
+	exceeded := map[string]error{}
+	for workspaceID, n := range counts {
+		_, err := c.quotas.ConsumeIndexQuota(ctx, workspaceID, n)
+		switch {
+		case err == nil:
+			charged[workspaceID] = true
+		case apperror.KindOf(err) == apperror.KindQuotaExceeded:
+			exceeded[workspaceID] = err
+		default:
+			c.logger.WithError(err).WithField("workspace_id", workspaceID).Warn("Index quota check failed")
+		}
+	}
+
+	var kept []*ingestEvent
+	var ack []string
+	for _, ev := range events {
+		workspaceID := getString(ev.doc, "workspace_id")
+		if err, ok := exceeded[workspaceID]; ok && ev.chargeable() {
+			if c.deadLetter(ctx, rdb, stream, ev.msg, err.Error()) {
+				ack = append(ack, ev.msg.ID)
+			}
+			continue
+		}
+		ev.charged = ev.chargeable() && charged[workspaceID]
+		kept = append(kept, ev)
+	}
+	return kept, ack
+}
+
+// refundQuotas gives back the quota charged for events that were not
+// indexed; they are charged again when retried.
+func (c *IngestionConsumer) refundQuotas(ctx context.Context, events []*ingestEvent) {
+	counts := map[string]int64{}
+	for _, ev := range events {
+		if ev.charged {
+			counts[getString(ev.doc, "workspace_id")]++
+			ev.charged = false
+		}
+	}
+	for workspaceID, n := range counts {
+		c.quotas.RefundIndexQuota(ctx, workspaceID, n)
+	}
+}
+
+// chargeable reports whether the event writes a document, rather than
+// deleting one or leaving a tombstone.
+func (ev *ingestEvent) chargeable() bool {
+	eventType, _ := ev.msg.Values["type"].(string)
+	return ingestEvents[eventType].op != opDelete
+}
+
+// prepare embeds the documents of index events, and of updates that change
+// content, with one provider call per index, and makes sure the target
+// indices carry the mappings the documents need. It reports false if the
+// mappings could not be put.
+func (c *IngestionConsumer) prepare(ctx context.Context, es *elasticsearch.Client, events []*ingestEvent) bool {
+	docs := map[string][]map[string]interface{}{}
+	for _, ev := range events {
+		_, hasContent := ev.doc["content"]
+		if ev.mapping.op == opIndex || (ev.mapping.op == opUpdate && hasContent) {
+			docs[ev.mapping.index] = append(docs[ev.mapping.index], ev.doc)
+		}
+	}
+	for index, batch := range docs {
+		c.embeddings.EmbedDocuments(ctx, index, batch)
+		if err := c.embeddings.ensureMappings(ctx, es, index); err != nil {
+			c.logger.WithError(err).WithField("index", index).Warn("Failed to prepare index mappings; events will be retried")
+			return false
+		}
+	}
+	return true
+}
+
+func decodeIngestEvent(msg redis.XMessage) (*ingestEvent, error) {
+	eventType, _ := msg.Values["type"].(string)
+	mapping, ok := ingestEvents[eventType]
+	if !ok {
+		return nil, fmt.Errorf("unknown event type %q", eventType)
+	}
+
+	payload, _ := msg.Values["payload"].(string)
+	var doc map[string]interface{}
+	if err := json.Unmarshal([]byte(payload), &doc); err != nil {
+		return nil, fmt.Errorf("invalid payload: %w", err)
+	}
+	id := getString(doc, "id")
+	if id == "" {
+		return nil, errors.New("payload has no id")
+	}
+	workspaceID := getString(doc, "workspace_id")
+	if workspaceID == "" {
+		return nil, errors.New("payload has no workspace_id")
+	}
+	version, err := DocumentVersion(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case mapping.op == opDelete && tombstoneIndices[mapping.index]:
+		// Deleted messages leave a tombstone so a late create cannot
+		// bring them back.
+		mapping.op = opIndex
+		doc = tombstone(id, workspaceID)
+	case mapping.op == opIndex && mapping.index == indexMessages:
+		threadMessage(id, doc)
+	case mapping.op == opIndex && mapping.index == indexFiles:
+		filePassages(doc)
+	}
+
+	for field := range doc {
+		if mapping.fields != nil && !mapping.fields[field] {
+			delete(doc, field)
+		}
+	}
+	return &ingestEvent{msg: msg, mapping: mapping, id: id, doc: doc, version: version}, nil
+}
+
+// bulkItemResult is the outcome of one _bulk item.
+type bulkItemResult struct {
+	Status int
+	Error  string
+}
+
+// bulkIngest sends events as one _bulk request and returns a result per
+// event, in order. A request that fails as a whole returns no results.
+func bulkIngest(ctx context.Context, es *elasticsearch.Client, events []*ingestEvent) ([]bulkItemResult, error) {
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, ev := range events {
+		meta := bulkMeta(ev.route.Index, ev.id, ev.route.Routing)
+		switch {
+		case ev.version > 0 && ev.mapping.op != opDelete:
+			meta["retry_on_conflict"] = 3
+			enc.Encode(map[string]interface{}{opUpdate: meta})
+			enc.Encode(versionedUpdate(ev.doc, ev.version, ev.mapping.op == opIndex))
+		case ev.mapping.op == opIndex:
+			enc.Encode(map[string]interface{}{opIndex: meta})
+			enc.Encode(ev.doc)
+		case ev.mapping.op == opUpdate:
+			enc.Encode(map[string]interface{}{opUpdate: meta})
+			enc.Encode(map[string]interface{}{"doc": ev.doc, "doc_as_upsert": true})
+		case ev.version > 0:
+			meta["retry_on_conflict"] = 3
+			enc.Encode(map[string]interface{}{opUpdate: meta})
+			enc.Encode(versionedDelete(ev.version))
+		default:
+			enc.Encode(map[string]interface{}{opDelete: meta})
+		}
+	}
+
+	res, err := es.Bulk(&body, es.Bulk.WithContext(ctx))
+	var result struct {
+		Items []map[string]struct {
+			Status int           `json:"status"`
+			Error  *ESErrorCause `json:"error"`
+		} `json:"items"`
+	}
+	if err := readResponse(res, err, "Bulk request failed", &result); err != nil {
+		return nil, err
+	}
+	if len(result.Items) != len(events) {
+		return nil, fmt.Errorf("bulk response has %d items for %d events", len(result.Items), len(events))
+	}
+
+	results := make([]bulkItemResult, len(events))
+	for i, item := range result.Items {
+		for _, op := range item {
+			results[i].Status = op.Status
+			if op.Error != nil {
+				results[i].Error = op.Error.Type + ": " + op.Error.Reason
+			}
+		}
+	}
+	return results, nil
+}
+
+// giveUp hands an event that ran out of deliveries to the dead-letter
+// store, which keeps retrying it with backoff.
+func (c *IngestionConsumer) giveUp(ctx context.Context, rdb *redis.Client, stream string, msg redis.XMessage, reason string) bool {
+	ev, err := decodeIngestEvent(msg)
+	if err != nil {
+		return c.deadLetter(ctx, rdb, stream, msg, err.Error())
+	}
+	return c.capture(ctx, rdb, stream, ev, apperror.Unavailable(reason, nil))
+}
+
+// capture stores a decoded event that failed to index in the dead-letter
+// store, falling back to the dead letter stream without one. Like
+// deadLetter, it reports whether the event may be acked.
+func (c *IngestionConsumer) capture(ctx context.Context, rdb *redis.Client, stream string, ev *ingestEvent, cause error) bool {
+	if c.deadLetters == nil {
+		return c.deadLetter(ctx, rdb, stream, ev.msg, cause.Error())
+	}
+	dl := &models.DeadLetter{
+		Operation:   ev.mapping.op,
+		Index:       ev.mapping.index,
+		DocumentID:  ev.id,
+		WorkspaceID: getString(ev.doc, "workspace_id"),
+		Document:    ev.doc,
+		Version:     ev.version,
+		Upsert:      ev.mapping.op == opUpdate,
+		Source:      deadLetterSourceIngest,
+	}
+	return c.deadLetters.store(ctx, dl, cause) == nil
+}
+
+// deadLetter copies an event that will not be indexed to the stream's dead
+// letter stream, with the reason. The caller acks the event only if this
+// succeeded, so a failure leaves it pending rather than losing it.
+func (c *IngestionConsumer) deadLetter(ctx context.Context, rdb *redis.Client, stream string, msg redis.XMessage, reason string) bool {
+	values := make(map[string]interface{}, len(msg.Values)+4)
+	for k, v := range msg.Values {
+		values[k] = v
+	}
+	values["error"] = reason
+	values["source_stream"] = stream
+	values["source_id"] = msg.ID
+	values["failed_at"] = time.Now().UTC().Format(time.RFC3339)
+
+	err := rdb.XAdd(ctx, &redis.XAddArgs{Stream: deadLetterStream(stream), Values: values}).Err()
+	entry := c.logger.WithFields(logrus.Fields{"stream": stream, "id": msg.ID, "reason": reason})
+	if err != nil {
+		entry.WithError(err).Error("Failed to dead-letter ingestion event")
+		return false
+	}
+	entry.Warn("Dead-lettered ingestion event")
+	return true
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) {
+	select {
+	case <-time.After(d):
+	case <-ctx.Done():
+	}
+}
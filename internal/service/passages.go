@@ -0,0 +1,153 @@
+package service
+
+import (
+	"github.com/quckapp/search-service/internal/extract"
+	"github.com/quckapp/search-service/internal/models"
+)
+
+const (
+	// passageWords and passageOverlap size the windows a file's text is
+	// split into; the overlap keeps phrases at a boundary whole.
+	passageWords   = 120
+	passageOverlap = 30
+	// passageHits is how many passages each file hit returns.
+	passageHits = 3
+)
+
+// passageMappings make passages nested documents, so each is scored on its
+// own instead of their words being pooled across the file.
+var passageMappings = map[string]interface{}{
+	"passages": map[string]interface{}{
+		"type": "nested",
+		"properties": map[string]interface{}{
+			"text":    map[string]interface{}{"type": "text"},
+			"section": map[string]interface{}{"type": "integer"},
+			"offset":  map[string]interface{}{"type": "integer"},
+		},
+	},
+	"section_type": map[string]interface{}{"type": "keyword"},
+}
+
+// filePassages splits a file document's content into passages, unless the
+// writer supplied them. Content with no page structure is one section.
+func filePassages(doc map[string]interface{}) {
+	if _, ok := doc["passages"]; ok {
+		return
+	}
+	if content := getString(doc, "content"); content != "" {
+		doc["passages"] = passageDocs(extract.Chunk([]string{content}, passageWords, passageOverlap))
+	}
+}
+
+func passageDocs(passages []extract.Passage) []map[string]interface{} {
+	docs := make([]map[string]interface{}, len(passages))
+	for i, p := range passages {
+		docs[i] = map[string]interface{}{"text": p.Text, "section": p.Section, "offset": p.Offset}
+	}
+	return docs
+}
+
+// passageHighlightFields is what a passage inner hit highlights.
+var passageHighlightFields = []string{"passages.text"}
+
+// passageHighlightParams are params for highlighting passages: the search's
+// tags, encoder and fragment settings, on the passage text whatever fields
+// the search highlights.
+func passageHighlightParams(params *models.SearchParams) *models.SearchParams {
+	p := *params
+	p.HighlightFields = ""
+	return &p
+}
+
+// filePassageQuery matches files by their best passage, by name, or, for
+// files indexed before passages existed, by their whole content. Passages
+// are highlighted as params asks.
+func filePassageQuery(params *models.SearchParams) map[string]interface{} {
+	text := params.Query
+	return map[string]interface{}{
+		"bool": map[string]interface{}{
+			"should": []map[string]interface{}{
+				{"nested": map[string]interface{}{
+					"path":            "passages",
+					"score_mode":      "max",
+					"ignore_unmapped": true,
+					"query": map[string]interface{}{
+						"match": map[string]interface{}{
+							"passages.text": map[string]interface{}{"query": text, "fuzziness": "AUTO"},
+						},
+					},
+					"inner_hits": map[string]interface{}{
+						"name":      "passages",
+						"size":      passageHits,
+						"_source":   []string{"passages.section", "passages.offset", "passages.text"},
+						"highlight": highlightClause(passageHighlightParams(params), passageHighlightFields),
+					},
+				}},
+				{"match": map[string]interface{}{
+					"filename": map[string]interface{}{"query": text, "fuzziness": "AUTO", "boost": 2},
+				}},
+				{"bool": map[string]interface{}{
+					"must": []map[string]interface{}{
+						{"match": map[string]interface{}{
+							"content": map[string]interface{}{"query": text, "fuzziness": "AUTO"},
+						}},
+					},
+					"must_not": []map[string]interface{}{
+						{"nested": map[string]interface{}{
+							"path":            "passages",
+							"ignore_unmapped": true,
+							"query":           map[string]interface{}{"exists": map[string]interface{}{"field": "passages.text"}},
+						}},
+					},
+				}},
+			},
+			"minimum_should_match": 1,
+		},
+	}
+}
+
+// excludePassages leaves a file's passages out of its search hit; the ones
+// that matched come back as inner hits instead.
+func excludePassages(query map[string]interface{}) {
+	query["_source"] = map[string]interface{}{"excludes": []string{"passages"}}
+}
+
+// attachPassages sets the matching passages of each file hit in resp from
+// the raw search result it was parsed from.
+func attachPassages(result map[string]interface{}, resp *models.SearchResponse, params *models.SearchParams) {
+	highlightParams := passageHighlightParams(params)
+	hits, _ := result["hits"].(map[string]interface{})
+	hitList, _ := hits["hits"].([]interface{})
+
+	for i := range resp.Results {
+		if i >= len(hitList) {
+			return
+		}
+		hitMap, _ := hitList[i].(map[string]interface{})
+		inner, _ := hitMap["inner_hits"].(map[string]interface{})
+		passages, _ := inner["passages"].(map[string]interface{})
+		nested, _ := passages["hits"].(map[string]interface{})
+		list, _ := nested["hits"].([]interface{})
+
+		sectionType := getString(resp.Results[i].Source, "section_type")
+		for _, p := range list {
+			m, _ := p.(map[string]interface{})
+			source, _ := m["_source"].(map[string]interface{})
+			hit := models.PassageHit{SectionType: sectionType, Text: getString(source, "text")}
+			hit.Score, _ = m["_score"].(float64)
+			if section, ok := source["section"].(float64); ok {
+				hit.Section = int(section)
+			}
+			if offset, ok := source["offset"].(float64); ok {
+				hit.Offset = int(offset)
+			}
+			// Nested sources have no passages.text, so a passage without
+			// highlights gets no fallback snippet; its text is there already.
+			for _, h := range parseHighlights(m, source, highlightParams, passageHighlightFields) {
+				hit.Highlights = append(hit.Highlights, h.Fragments...)
+				hit.Offsets = append(hit.Offsets, h.Offsets...)
+			}
+			resp.Results[i].Passages = append(resp.Results[i].Passages, hit)
+		}
+	}
+}
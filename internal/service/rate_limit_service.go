@@ -0,0 +1,272 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+
+	"github.com/quckapp/search-service/internal/apperror"
+	"github.com/quckapp/search-service/internal/config"
+	"github.com/quckapp/search-service/internal/db"
+	"github.com/quckapp/search-service/internal/models"
+)
+
+// tokenBucketScript refills and takes from a bucket in one round trip, so
+// every replica sees the same bucket. It uses the Redis clock to avoid skew
+// between replicas. Returns {allowed, tokens_left} with tokens as a string
+// because Lua numbers are truncated to integers on the way out.
+var tokenBucketScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local cost = tonumber(ARGV[3])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1]) or burst
+local ts = tonumber(state[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - ts) / 1000 * rate)
+
+local allowed = 0
+if tokens >= cost then
+	tokens = tokens - cost
+	allowed = 1
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst / rate * 1000) + 1000)
+return {allowed, tostring(tokens)}
+`)
+
+// quotaScript adds ARGV[2] to the day's usage unless that would pass the
+// limit in ARGV[1]. A limit of 0 or less means unlimited. Returns
+// {allowed, used}.
+var quotaScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local n = tonumber(ARGV[2])
+local used = tonumber(redis.call('GET', KEYS[1]) or '0')
+if limit > 0 and used + n > limit then
+	return {0, used}
+end
+used = redis.call('INCRBY', KEYS[1], n)
+redis.call('EXPIRE', KEYS[1], ARGV[3])
+return {1, used}
+`)
+
+// quotaKeyTTL keeps a day's counter around long enough to be inspected after
+// the day rolls over.
+const quotaKeyTTL = 48 * time.Hour
+
+// RateLimitDecision is the outcome of one rate limit check.
+type RateLimitDecision struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration // until the bucket is full again
+	RetryAfter time.Duration // until the request would be allowed; zero when allowed
+}
+
+type RateLimitService struct {
+	redis        *db.RedisManager
+	limits       map[string]config.RateLimit
+	defaultQuota int64
+	logger       *logrus.Logger
+}
+
+func NewRateLimitService(redis *db.RedisManager, limits map[string]config.RateLimit, defaultQuota int64, logger *logrus.Logger) *RateLimitService {
+	return &RateLimitService{redis: redis, limits: limits, defaultQuota: defaultQuota, logger: logger}
+}
+
+// Allow takes one token from the bucket for group and key. Without Redis, or
+// for a group with no configured limit, every request is allowed: rate
+// limiting must not take search down with it.
+func (s *RateLimitService) Allow(ctx context.Context, group, key string) (*RateLimitDecision, error) {
+	limit, ok := s.limits[group]
+	if !ok || limit.PerMinute <= 0 || limit.Burst <= 0 {
+		return &RateLimitDecision{Allowed: true}, nil
+	}
+	rdb := s.redis.Client()
+	if rdb == nil {
+		return &RateLimitDecision{Allowed: true, Limit: limit.Burst, Remaining: limit.Burst}, nil
+	}
+
+	rate := float64(limit.PerMinute) / 60
+	redisKey := fmt.Sprintf("ratelimit:%s:%s", group, key)
+	res, err := tokenBucketScript.Run(ctx, rdb, []string{redisKey}, rate, limit.Burst, 1).Slice()
+	if err != nil {
+		return &RateLimitDecision{Allowed: true, Limit: limit.Burst, Remaining: limit.Burst}, err
+	}
+
+	allowed, _ := res[0].(int64)
+	tokensStr, _ := res[1].(string)
+	tokens, _ := strconv.ParseFloat(tokensStr, 64)
+
+	decision := &RateLimitDecision{
+		Allowed:   allowed == 1,
+		Limit:     limit.Burst,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     secondsToDuration((float64(limit.Burst) - tokens) / rate),
+	}
+	if !decision.Allowed {
+		decision.RetryAfter = secondsToDuration((1 - tokens) / rate)
+	}
+	return decision, nil
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	if seconds <= 0 {
+		return 0
+	}
+	return time.Duration(math.Ceil(seconds)) * time.Second
+}
+
+// ── Daily Indexing Quotas ──
+
+func quotaDay(now time.Time) string {
+	return now.UTC().Format("2006-01-02")
+}
+
+func quotaUsageKey(workspaceID, day string) string {
+	return fmt.Sprintf("quota:index:%s:%s", workspaceID, day)
+}
+
+func quotaLimitKey(workspaceID string) string {
+	return fmt.Sprintf("quota:index:limit:%s", workspaceID)
+}
+
+func nextQuotaReset(now time.Time) time.Time {
+	y, m, d := now.UTC().Date()
+	return time.Date(y, m, d+1, 0, 0, 0, 0, time.UTC)
+}
+
+// dailyLimit returns the workspace's override if one is set, else the default.
+func (s *RateLimitService) dailyLimit(ctx context.Context, rdb *redis.Client, workspaceID string) (int64, bool, error) {
+	limit, err := rdb.Get(ctx, quotaLimitKey(workspaceID)).Int64()
+	if err == redis.Nil {
+		return s.defaultQuota, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return limit, true, nil
+}
+
+// ConsumeIndexQuota records n indexed documents against the workspace's
+// quota for today, or returns a quota_exceeded error without recording
+// anything if they do not fit.
+func (s *RateLimitService) ConsumeIndexQuota(ctx context.Context, workspaceID string, n int64) (*models.IndexQuota, error) {
+	rdb := s.redis.Client()
+	if rdb == nil {
+		return nil, nil
+	}
+
+	limit, override, err := s.dailyLimit(ctx, rdb, workspaceID)
+	if err != nil {
+		return nil, apperror.Unavailable("Failed to read indexing quota", err)
+	}
+
+	now := time.Now()
+	day := quotaDay(now)
+	res, err := quotaScript.Run(ctx, rdb, []string{quotaUsageKey(workspaceID, day)}, limit, n, int(quotaKeyTTL.Seconds())).Slice()
+	if err != nil {
+		return nil, apperror.Unavailable("Failed to update indexing quota", err)
+	}
+	allowed, _ := res[0].(int64)
+	used, _ := res[1].(int64)
+
+	quota := newIndexQuota(workspaceID, day, used, limit, override, now)
+	if allowed != 1 {
+		return quota, apperror.QuotaExceeded(fmt.Sprintf("Daily indexing quota of %d documents exceeded", limit))
+	}
+	return quota, nil
+}
+
+// RefundIndexQuota gives back n documents charged today that were not
+// indexed after all.
+func (s *RateLimitService) RefundIndexQuota(ctx context.Context, workspaceID string, n int64) {
+	rdb := s.redis.Client()
+	if rdb == nil || n <= 0 {
+		return
+	}
+	if err := rdb.DecrBy(ctx, quotaUsageKey(workspaceID, quotaDay(time.Now())), n).Err(); err != nil {
+		s.logger.WithError(err).WithField("workspace_id", workspaceID).Warn("Failed to refund indexing quota")
+	}
+}
+
+func (s *RateLimitService) GetIndexQuota(ctx context.Context, workspaceID string) (*models.IndexQuota, error) {
+	rdb := s.redis.Client()
+	if rdb == nil {
+		return nil, errStorageUnavailable
+	}
+
+	limit, override, err := s.dailyLimit(ctx, rdb, workspaceID)
+	if err != nil {
+		return nil, apperror.Unavailable("Failed to read indexing quota", err)
+	}
+
+	now := time.Now()
+	day := quotaDay(now)
+	used, err := rdb.Get(ctx, quotaUsageKey(workspaceID, day)).Int64()
+	if err != nil && err != redis.Nil {
+		return nil, apperror.Unavailable("Failed to read indexing quota", err)
+	}
+	return newIndexQuota(workspaceID, day, used, limit, override, now), nil
+}
+
+func (s *RateLimitService) UpdateIndexQuota(ctx context.Context, workspaceID string, req *models.UpdateIndexQuotaRequest) (*models.IndexQuota, error) {
+	rdb := s.redis.Client()
+	if rdb == nil {
+		return nil, errStorageUnavailable
+	}
+
+	switch {
+	case req.DailyLimit != nil && req.ResetLimit:
+		return nil, apperror.BadQuery("daily_limit and reset_limit cannot be combined", nil)
+	case req.DailyLimit != nil:
+		if *req.DailyLimit < 0 {
+			return nil, apperror.BadQuery("daily_limit must not be negative", nil)
+		}
+		if err := rdb.Set(ctx, quotaLimitKey(workspaceID), *req.DailyLimit, 0).Err(); err != nil {
+			return nil, apperror.Unavailable("Failed to update indexing quota", err)
+		}
+	case req.ResetLimit:
+		if err := rdb.Del(ctx, quotaLimitKey(workspaceID)).Err(); err != nil {
+			return nil, apperror.Unavailable("Failed to update indexing quota", err)
+		}
+	}
+
+	if req.ResetUsage {
+		if err := rdb.Del(ctx, quotaUsageKey(workspaceID, quotaDay(time.Now()))).Err(); err != nil {
+			return nil, apperror.Unavailable("Failed to reset indexing quota", err)
+		}
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"workspace_id": workspaceID,
+		"daily_limit":  req.DailyLimit,
+		"reset_limit":  req.ResetLimit,
+		"reset_usage":  req.ResetUsage,
+	}).Info("Indexing quota updated")
+
+	return s.GetIndexQuota(ctx, workspaceID)
+}
+
+func newIndexQuota(workspaceID, day string, used, limit int64, override bool, now time.Time) *models.IndexQuota {
+	quota := &models.IndexQuota{
+		WorkspaceID: workspaceID,
+		Date:        day,
+		Used:        used,
+		DailyLimit:  limit,
+		IsOverride:  override,
+		ResetsAt:    nextQuotaReset(now),
+	}
+	if limit > 0 {
+		quota.Remaining = max(limit-used, 0)
+	}
+	return quota
+}
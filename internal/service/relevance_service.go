@@ -1,57 +1,100 @@
 package service
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"time"
 
-	"github.com/elastic/go-elasticsearch/v8"
 	"github.com/google/uuid"
-	"github.com/redis/go-redis/v9"
 	"github.com/sirupsen/logrus"
 
+	"github.com/quckapp/search-service/internal/apperror"
+	"github.com/quckapp/search-service/internal/db"
 	"github.com/quckapp/search-service/internal/models"
 )
 
 type RelevanceService struct {
-	es     *elasticsearch.Client
-	redis  *redis.Client
-	logger *logrus.Logger
+	es      *db.ElasticsearchManager
+	redis   *db.RedisManager
+	tenants *TenantRoutingService
+	logger  *logrus.Logger
 }
 
-func NewRelevanceService(es *elasticsearch.Client, redis *redis.Client, logger *logrus.Logger) *RelevanceService {
-	return &RelevanceService{es: es, redis: redis, logger: logger}
+func NewRelevanceService(es *db.ElasticsearchManager, redis *db.RedisManager, tenants *TenantRoutingService, logger *logrus.Logger) *RelevanceService {
+	return &RelevanceService{es: es, redis: redis, tenants: tenants, logger: logger}
 }
 
+// defaultFieldBoosts and defaultExactMatchBoost are the settings a new
+// workspace starts with. Searches apply only the ones a workspace changes;
+// see boostFields.
+var defaultFieldBoosts = map[string]float64{
+	"title":        3.0,
+	"content":      1.0,
+	"name":         2.0,
+	"description":  1.5,
+	"display_name": 2.0,
+}
+
+const defaultExactMatchBoost = 2.0
+
 func (s *RelevanceService) defaultConfig(workspaceID string) *models.RelevanceConfig {
+	fieldBoosts := make(map[string]float64, len(defaultFieldBoosts))
+	for field, boost := range defaultFieldBoosts {
+		fieldBoosts[field] = boost
+	}
 	return &models.RelevanceConfig{
-		ID:          uuid.New().String(),
-		WorkspaceID: workspaceID,
-		FieldBoosts: map[string]float64{
-			"title":        3.0,
-			"content":      1.0,
-			"name":         2.0,
-			"description":  1.5,
-			"display_name": 2.0,
-		},
+		ID:              uuid.New().String(),
+		WorkspaceID:     workspaceID,
+		FieldBoosts:     fieldBoosts,
 		TitleBoost:      3.0,
 		ContentBoost:    1.0,
 		RecencyWeight:   0.5,
-		ExactMatchBoost: 2.0,
+		ExactMatchBoost: defaultExactMatchBoost,
+		Hybrid:          defaultHybridConfig(),
 		CreatedAt:       time.Now(),
 		UpdatedAt:       time.Now(),
 	}
 }
 
+// defaultRRFK is the rank constant from the original RRF paper; it damps
+// the gap between the first few ranks.
+const defaultRRFK = 60
+
+func defaultHybridConfig() models.HybridConfig {
+	return models.HybridConfig{Fusion: models.FusionRRF, LexicalWeight: 1, SemanticWeight: 1, RRFK: defaultRRFK}
+}
+
+func validateHybridConfig(cfg *models.HybridConfig) error {
+	if cfg.Fusion != models.FusionRRF && cfg.Fusion != models.FusionLinear {
+		return apperror.BadQuery("hybrid.fusion must be rrf or linear", nil)
+	}
+	if cfg.LexicalWeight < 0 || cfg.SemanticWeight < 0 || cfg.LexicalWeight+cfg.SemanticWeight == 0 {
+		return apperror.BadQuery("hybrid weights must be non-negative and not both zero", nil)
+	}
+	if cfg.RRFK == 0 {
+		cfg.RRFK = defaultRRFK
+	}
+	if cfg.RRFK < 0 {
+		return apperror.BadQuery("hybrid.rrf_k must be positive", nil)
+	}
+	return nil
+}
+
+// HybridConfig returns the workspace's hybrid search settings.
+func (s *RelevanceService) HybridConfig(ctx context.Context, workspaceID string) models.HybridConfig {
+	config, _ := s.GetConfig(ctx, workspaceID)
+	return config.Hybrid
+}
+
 func (s *RelevanceService) GetConfig(ctx context.Context, workspaceID string) (*models.RelevanceConfig, error) {
-	if s.redis == nil {
+	rdb := s.redis.Client()
+	if rdb == nil {
 		return s.defaultConfig(workspaceID), nil
 	}
 
 	key := fmt.Sprintf("relevance_config:%s", workspaceID)
-	data, err := s.redis.Get(ctx, key).Bytes()
+	data, err := rdb.Get(ctx, key).Bytes()
 	if err != nil {
 		return s.defaultConfig(workspaceID), nil
 	}
@@ -60,13 +103,42 @@ func (s *RelevanceService) GetConfig(ctx context.Context, workspaceID string) (*
 	if err := json.Unmarshal(data, &config); err != nil {
 		return s.defaultConfig(workspaceID), nil
 	}
+	// Configs saved before hybrid search existed get its defaults.
+	if config.Hybrid.Fusion == "" {
+		config.Hybrid = defaultHybridConfig()
+	}
 	return &config, nil
 }
 
 func (s *RelevanceService) UpdateConfig(ctx context.Context, workspaceID string, req *models.UpdateRelevanceRequest) (*models.RelevanceConfig, error) {
+	rdb := s.redis.Client()
 	config, _ := s.GetConfig(ctx, workspaceID)
 
+	if err := applyRelevanceUpdate(config, req); err != nil {
+		return nil, err
+	}
+	config.UpdatedAt = time.Now()
+
+	if rdb != nil {
+		data, err := json.Marshal(config)
+		if err != nil {
+			return nil, err
+		}
+		key := fmt.Sprintf("relevance_config:%s", workspaceID)
+		rdb.Set(ctx, key, data, 0)
+	}
+
+	return config, nil
+}
+
+// applyRelevanceUpdate sets the fields of config that req sets.
+func applyRelevanceUpdate(config *models.RelevanceConfig, req *models.UpdateRelevanceRequest) error {
 	if req.FieldBoosts != nil {
+		for field, boost := range req.FieldBoosts {
+			if boost < 0 {
+				return apperror.BadQuery("field_boosts."+field+" must not be negative", nil)
+			}
+		}
 		config.FieldBoosts = req.FieldBoosts
 	}
 	if req.TitleBoost != nil {
@@ -79,20 +151,24 @@ func (s *RelevanceService) UpdateConfig(ctx context.Context, workspaceID string,
 		config.RecencyWeight = *req.RecencyWeight
 	}
 	if req.ExactMatchBoost != nil {
+		if *req.ExactMatchBoost < 0 {
+			return apperror.BadQuery("exact_match_boost must not be negative", nil)
+		}
 		config.ExactMatchBoost = *req.ExactMatchBoost
 	}
-	config.UpdatedAt = time.Now()
-
-	if s.redis != nil {
-		data, err := json.Marshal(config)
-		if err != nil {
-			return nil, err
+	if req.ClickBoost != nil {
+		if *req.ClickBoost < 0 {
+			return apperror.BadQuery("click_boost must not be negative", nil)
 		}
-		key := fmt.Sprintf("relevance_config:%s", workspaceID)
-		s.redis.Set(ctx, key, data, 0)
+		config.ClickBoost = *req.ClickBoost
 	}
-
-	return config, nil
+	if req.Hybrid != nil {
+		if err := validateHybridConfig(req.Hybrid); err != nil {
+			return err
+		}
+		config.Hybrid = *req.Hybrid
+	}
+	return nil
 }
 
 func (s *RelevanceService) PreviewTuning(ctx context.Context, workspaceID, query, index string) (*models.RelevancePreview, error) {
@@ -104,17 +180,37 @@ func (s *RelevanceService) PreviewTuning(ctx context.Context, workspaceID, query
 		Config:  config,
 	}
 
-	if s.es == nil || query == "" {
+	if query == "" {
 		return preview, nil
 	}
 
-	// Build boosted fields from config
+	searchQuery := tunedQuery(config, query, 10)
+
+	if index == "" {
+		index = "quckapp_messages"
+	}
+	if err := requireSearchableIndex(index); err != nil {
+		return nil, err
+	}
+
+	result, err := searchIndex(ctx, s.es.Client(), s.tenants, index, searchQuery)
+	if err != nil {
+		return nil, err
+	}
+	preview.Results = tunedHits(result)
+
+	return preview, nil
+}
+
+// tunedQuery is the query config shapes for query: a fuzzy match across the
+// config's boosted fields in its workspace.
+func tunedQuery(config *models.RelevanceConfig, query string, size int) map[string]interface{} {
 	var fields []interface{}
 	for field, boost := range config.FieldBoosts {
 		fields = append(fields, fmt.Sprintf("%s^%.1f", field, boost))
 	}
 
-	searchQuery := map[string]interface{}{
+	return map[string]interface{}{
 		"query": map[string]interface{}{
 			"bool": map[string]interface{}{
 				"must": []map[string]interface{}{
@@ -125,32 +221,16 @@ func (s *RelevanceService) PreviewTuning(ctx context.Context, workspaceID, query
 					}},
 				},
 				"filter": []map[string]interface{}{
-					{"term": map[string]interface{}{"workspace_id": workspaceID}},
+					{"term": map[string]interface{}{"workspace_id": config.WorkspaceID}},
 				},
 			},
 		},
-		"size": 10,
-	}
-
-	var buf bytes.Buffer
-	json.NewEncoder(&buf).Encode(searchQuery)
-
-	if index == "" {
-		index = "quckapp_messages"
+		"size": size,
 	}
+}
 
-	res, err := s.es.Search(
-		s.es.Search.WithIndex(index),
-		s.es.Search.WithBody(&buf),
-	)
-	if err != nil {
-		return preview, nil
-	}
-	defer res.Body.Close()
-
-	var result map[string]interface{}
-	json.NewDecoder(res.Body).Decode(&result)
-
+func tunedHits(result map[string]interface{}) []models.SearchHit {
+	results := []models.SearchHit{}
 	if hits, ok := result["hits"].(map[string]interface{}); ok {
 		if hitList, ok := hits["hits"].([]interface{}); ok {
 			for _, hit := range hitList {
@@ -165,24 +245,24 @@ func (s *RelevanceService) PreviewTuning(ctx context.Context, workspaceID, query
 				if source, ok := hitMap["_source"].(map[string]interface{}); ok {
 					searchHit.Source = source
 				}
-				preview.Results = append(preview.Results, searchHit)
+				results = append(results, searchHit)
 			}
 		}
 	}
-
-	return preview, nil
+	return results
 }
 
 func (s *RelevanceService) ResetToDefaults(ctx context.Context, workspaceID string) (*models.RelevanceConfig, error) {
+	rdb := s.redis.Client()
 	config := s.defaultConfig(workspaceID)
 
-	if s.redis != nil {
+	if rdb != nil {
 		data, err := json.Marshal(config)
 		if err != nil {
 			return nil, err
 		}
 		key := fmt.Sprintf("relevance_config:%s", workspaceID)
-		s.redis.Set(ctx, key, data, 0)
+		rdb.Set(ctx, key, data, 0)
 	}
 
 	return config, nil
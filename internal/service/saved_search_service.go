@@ -7,24 +7,25 @@ import (
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/redis/go-redis/v9"
 	"github.com/sirupsen/logrus"
 
+	"github.com/quckapp/search-service/internal/db"
 	"github.com/quckapp/search-service/internal/models"
 )
 
 type SavedSearchService struct {
-	redis  *redis.Client
+	redis  *db.RedisManager
 	logger *logrus.Logger
 }
 
-func NewSavedSearchService(redis *redis.Client, logger *logrus.Logger) *SavedSearchService {
+func NewSavedSearchService(redis *db.RedisManager, logger *logrus.Logger) *SavedSearchService {
 	return &SavedSearchService{redis: redis, logger: logger}
 }
 
 func (s *SavedSearchService) Create(ctx context.Context, userID string, req *models.CreateSavedSearchRequest) (*models.SavedSearch, error) {
-	if s.redis == nil {
-		return nil, fmt.Errorf("storage not available")
+	rdb := s.redis.Client()
+	if rdb == nil {
+		return nil, errStorageUnavailable
 	}
 
 	saved := &models.SavedSearch{
@@ -45,22 +46,23 @@ func (s *SavedSearchService) Create(ctx context.Context, userID string, req *mod
 	}
 
 	key := fmt.Sprintf("saved_search:%s:%s", userID, saved.ID)
-	s.redis.Set(ctx, key, data, 0) // No expiry
+	rdb.Set(ctx, key, data, 0) // No expiry
 
 	// Add to user's saved search list
 	listKey := fmt.Sprintf("saved_searches:%s", userID)
-	s.redis.SAdd(ctx, listKey, saved.ID)
+	rdb.SAdd(ctx, listKey, saved.ID)
 
 	return saved, nil
 }
 
 func (s *SavedSearchService) GetByID(ctx context.Context, userID, searchID string) (*models.SavedSearch, error) {
-	if s.redis == nil {
-		return nil, fmt.Errorf("storage not available")
+	rdb := s.redis.Client()
+	if rdb == nil {
+		return nil, errStorageUnavailable
 	}
 
 	key := fmt.Sprintf("saved_search:%s:%s", userID, searchID)
-	data, err := s.redis.Get(ctx, key).Bytes()
+	data, err := rdb.Get(ctx, key).Bytes()
 	if err != nil {
 		return nil, fmt.Errorf("saved search not found")
 	}
@@ -73,12 +75,13 @@ func (s *SavedSearchService) GetByID(ctx context.Context, userID, searchID strin
 }
 
 func (s *SavedSearchService) GetByUser(ctx context.Context, userID string) ([]models.SavedSearch, error) {
-	if s.redis == nil {
+	rdb := s.redis.Client()
+	if rdb == nil {
 		return []models.SavedSearch{}, nil
 	}
 
 	listKey := fmt.Sprintf("saved_searches:%s", userID)
-	ids, err := s.redis.SMembers(ctx, listKey).Result()
+	ids, err := rdb.SMembers(ctx, listKey).Result()
 	if err != nil {
 		return []models.SavedSearch{}, nil
 	}
@@ -86,7 +89,7 @@ func (s *SavedSearchService) GetByUser(ctx context.Context, userID string) ([]mo
 	var searches []models.SavedSearch
 	for _, id := range ids {
 		key := fmt.Sprintf("saved_search:%s:%s", userID, id)
-		data, err := s.redis.Get(ctx, key).Bytes()
+		data, err := rdb.Get(ctx, key).Bytes()
 		if err != nil {
 			continue
 		}
@@ -99,6 +102,7 @@ func (s *SavedSearchService) GetByUser(ctx context.Context, userID string) ([]mo
 }
 
 func (s *SavedSearchService) Update(ctx context.Context, userID, searchID string, req *models.UpdateSavedSearchRequest) (*models.SavedSearch, error) {
+	rdb := s.redis.Client()
 	saved, err := s.GetByID(ctx, userID, searchID)
 	if err != nil {
 		return nil, err
@@ -121,21 +125,22 @@ func (s *SavedSearchService) Update(ctx context.Context, userID, searchID string
 	}
 
 	key := fmt.Sprintf("saved_search:%s:%s", userID, searchID)
-	s.redis.Set(ctx, key, data, 0)
+	rdb.Set(ctx, key, data, 0)
 
 	return saved, nil
 }
 
 func (s *SavedSearchService) Delete(ctx context.Context, userID, searchID string) error {
-	if s.redis == nil {
+	rdb := s.redis.Client()
+	if rdb == nil {
 		return nil
 	}
 
 	key := fmt.Sprintf("saved_search:%s:%s", userID, searchID)
-	s.redis.Del(ctx, key)
+	rdb.Del(ctx, key)
 
 	listKey := fmt.Sprintf("saved_searches:%s", userID)
-	s.redis.SRem(ctx, listKey, searchID)
+	rdb.SRem(ctx, listKey, searchID)
 
 	return nil
 }
@@ -7,24 +7,25 @@ import (
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/redis/go-redis/v9"
 	"github.com/sirupsen/logrus"
 
+	"github.com/quckapp/search-service/internal/db"
 	"github.com/quckapp/search-service/internal/models"
 )
 
 type SearchScopeService struct {
-	redis  *redis.Client
+	redis  *db.RedisManager
 	logger *logrus.Logger
 }
 
-func NewSearchScopeService(redis *redis.Client, logger *logrus.Logger) *SearchScopeService {
+func NewSearchScopeService(redis *db.RedisManager, logger *logrus.Logger) *SearchScopeService {
 	return &SearchScopeService{redis: redis, logger: logger}
 }
 
 func (s *SearchScopeService) SetScope(ctx context.Context, userID string, req *models.SetSearchScopeRequest) (*models.SearchScope, error) {
-	if s.redis == nil {
-		return nil, fmt.Errorf("storage not available")
+	rdb := s.redis.Client()
+	if rdb == nil {
+		return nil, errStorageUnavailable
 	}
 
 	scope := &models.SearchScope{
@@ -49,13 +50,14 @@ func (s *SearchScopeService) SetScope(ctx context.Context, userID string, req *m
 	}
 
 	key := fmt.Sprintf("search_scope:%s:%s", userID, req.WorkspaceID)
-	s.redis.Set(ctx, key, data, 0)
+	rdb.Set(ctx, key, data, 0)
 
 	return scope, nil
 }
 
 func (s *SearchScopeService) GetScope(ctx context.Context, userID, workspaceID string) (*models.SearchScope, error) {
-	if s.redis == nil {
+	rdb := s.redis.Client()
+	if rdb == nil {
 		return &models.SearchScope{
 			UserID:         userID,
 			WorkspaceID:    workspaceID,
@@ -65,7 +67,7 @@ func (s *SearchScopeService) GetScope(ctx context.Context, userID, workspaceID s
 	}
 
 	key := fmt.Sprintf("search_scope:%s:%s", userID, workspaceID)
-	data, err := s.redis.Get(ctx, key).Bytes()
+	data, err := rdb.Get(ctx, key).Bytes()
 	if err != nil {
 		// Return default scope with full access
 		return &models.SearchScope{
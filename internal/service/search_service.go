@@ -1,36 +1,49 @@
 package service
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
 
-	"github.com/elastic/go-elasticsearch/v8"
-	"github.com/redis/go-redis/v9"
 	"github.com/sirupsen/logrus"
 
+	"github.com/quckapp/search-service/internal/apperror"
+	"github.com/quckapp/search-service/internal/db"
 	"github.com/quckapp/search-service/internal/models"
+	"github.com/quckapp/search-service/internal/tenant"
 )
 
 const (
-	indexMessages = "quckapp_messages"
-	indexFiles    = "quckapp_files"
-	indexUsers    = "quckapp_users"
-	indexChannels = "quckapp_channels"
-	cacheTTL     = 5 * time.Minute
+	indexMessages  = "quckapp_messages"
+	indexFiles     = "quckapp_files"
+	indexUsers     = "quckapp_users"
+	indexChannels  = "quckapp_channels"
+	indexBookmarks = "quckapp_bookmarks"
+	indexTasks     = "quckapp_tasks"
+	indexEmoji     = "quckapp_emoji"
+	cacheTTL       = 5 * time.Minute
 )
 
 type SearchService struct {
-	es     *elasticsearch.Client
-	redis  *redis.Client
+	es      *db.ElasticsearchManager
+	redis   *db.RedisManager
+	tenants *TenantRoutingService
+	// deadLetters keeps document writes that fail so they can be retried.
+	deadLetters *DeadLetterService
+	// embeddings computes document and query vectors for semantic search.
+	embeddings *EmbeddingService
+	// relevance holds each workspace's hybrid fusion and click boost
+	// settings.
+	relevance *RelevanceService
+	// clicks supplies the documents users click for a query.
+	clicks *ClickService
 	logger *logrus.Logger
 }
 
-func NewSearchService(es *elasticsearch.Client, redis *redis.Client, logger *logrus.Logger) *SearchService {
-	return &SearchService{es: es, redis: redis, logger: logger}
+func NewSearchService(es *db.ElasticsearchManager, redis *db.RedisManager, tenants *TenantRoutingService, deadLetters *DeadLetterService, embeddings *EmbeddingService, relevance *RelevanceService, clicks *ClickService, logger *logrus.Logger) *SearchService {
+	return &SearchService{es: es, redis: redis, tenants: tenants, deadLetters: deadLetters, embeddings: embeddings, relevance: relevance, clicks: clicks, logger: logger}
 }
 
 // ── Global Search ──
@@ -53,17 +66,36 @@ func (s *SearchService) GlobalSearch(ctx context.Context, params *models.SearchP
 	channelsParams := *params
 	channelsParams.PerPage = perType
 
-	messages, _ := s.SearchMessages(ctx, &msgParams)
-	files, _ := s.SearchFiles(ctx, &filesParams)
-	users, _ := s.SearchUsers(ctx, &usersParams)
-	channels, _ := s.SearchChannels(ctx, &channelsParams)
-
-	return &models.GlobalSearchResponse{
-		Messages: messages,
-		Files:    files,
-		Users:    users,
-		Channels: channels,
-	}, nil
+	resp := &models.GlobalSearchResponse{}
+	var firstErr error
+	record := func(searchType string, err error) {
+		if err == nil {
+			return
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+		if resp.Errors == nil {
+			resp.Errors = map[string]string{}
+		}
+		resp.Errors[searchType] = string(apperror.KindOf(err))
+	}
+
+	var err error
+	resp.Messages, err = s.SearchMessages(ctx, &msgParams)
+	record("messages", err)
+	resp.Files, err = s.SearchFiles(ctx, &filesParams)
+	record("files", err)
+	resp.Users, err = s.SearchUsers(ctx, &usersParams)
+	record("users", err)
+	resp.Channels, err = s.SearchChannels(ctx, &channelsParams)
+	record("channels", err)
+
+	// Partial results are still useful; only fail when nothing came back.
+	if len(resp.Errors) == 4 {
+		return nil, firstErr
+	}
+	return resp, nil
 }
 
 // ── Message Search ──
@@ -71,7 +103,7 @@ func (s *SearchService) GlobalSearch(ctx context.Context, params *models.SearchP
 func (s *SearchService) SearchMessages(ctx context.Context, params *models.SearchParams) (*models.SearchResponse, error) {
 	params.Validate()
 
-	cacheKey := s.buildCacheKey("msg", params)
+	cacheKey := s.buildCacheKey(ctx, "msg", params)
 	if cached := s.getFromCache(ctx, cacheKey); cached != nil {
 		return cached, nil
 	}
@@ -96,14 +128,46 @@ func (s *SearchService) SearchMessages(ctx context.Context, params *models.Searc
 			"term": map[string]interface{}{"user_id": params.UserID},
 		})
 	}
+	if params.ThreadID != "" {
+		filters = append(filters, map[string]interface{}{
+			"term": map[string]interface{}{"thread_id": params.ThreadID},
+		})
+	}
 
-	query := s.buildQuery(must, filters, params)
-	result, err := s.executeSearch(indexMessages, query)
+	if params.Mode == models.SearchModeHybrid {
+		if params.CollapseThreads {
+			return nil, apperror.BadQuery("collapse_threads is not supported in hybrid mode", nil)
+		}
+		resp, err := s.hybridSearch(ctx, indexMessages, must, filters, params, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		s.attachThreads(ctx, nil, resp, false)
+		s.setCache(ctx, cacheKey, resp)
+		return resp, nil
+	}
+
+	query, err := s.modeQuery(ctx, must, filters, params)
+	if err != nil {
+		return nil, err
+	}
+	s.boostFields(ctx, query, params)
+	s.boostClicked(ctx, query, indexMessages, params)
+	curation := s.curate(ctx, query, indexMessages, params)
+	if params.CollapseThreads {
+		collapseThreads(query)
+	}
+	result, err := s.executeSearch(ctx, indexMessages, query)
 	if err != nil {
-		return emptyResponse(params), nil
+		return nil, err
 	}
 
 	resp := s.parseResponse(result, params)
+	markCurated(resp, curation)
+	s.attachThreads(ctx, result, resp, params.CollapseThreads)
+	if params.Explain {
+		resp.Explain = s.explainQuery(ctx, indexMessages, params, query)
+	}
 	s.setCache(ctx, cacheKey, resp)
 	return resp, nil
 }
@@ -113,18 +177,14 @@ func (s *SearchService) SearchMessages(ctx context.Context, params *models.Searc
 func (s *SearchService) SearchFiles(ctx context.Context, params *models.SearchParams) (*models.SearchResponse, error) {
 	params.Validate()
 
-	cacheKey := s.buildCacheKey("file", params)
+	cacheKey := s.buildCacheKey(ctx, "file", params)
 	if cached := s.getFromCache(ctx, cacheKey); cached != nil {
 		return cached, nil
 	}
 
-	must := []map[string]interface{}{
-		{"multi_match": map[string]interface{}{
-			"query":     params.Query,
-			"fields":    []string{"filename^2", "content"},
-			"fuzziness": "AUTO",
-		}},
-	}
+	// Files score by their best passage, and each hit carries the passages
+	// that matched.
+	must := []map[string]interface{}{filePassageQuery(params)}
 
 	filters := s.buildFilters(params)
 	if params.FileType != "" {
@@ -138,13 +198,37 @@ func (s *SearchService) SearchFiles(ctx context.Context, params *models.SearchPa
 		})
 	}
 
-	query := s.buildQuery(must, filters, params)
-	result, err := s.executeSearch(indexFiles, query)
+	if params.Mode == models.SearchModeHybrid {
+		attach := func(result map[string]interface{}, resp *models.SearchResponse) {
+			attachPassages(result, resp, params)
+		}
+		resp, err := s.hybridSearch(ctx, indexFiles, must, filters, params, excludePassages, attach)
+		if err != nil {
+			return nil, err
+		}
+		s.setCache(ctx, cacheKey, resp)
+		return resp, nil
+	}
+
+	query, err := s.modeQuery(ctx, must, filters, params)
 	if err != nil {
-		return emptyResponse(params), nil
+		return nil, err
+	}
+	s.boostFields(ctx, query, params)
+	s.boostClicked(ctx, query, indexFiles, params)
+	curation := s.curate(ctx, query, indexFiles, params)
+	excludePassages(query)
+	result, err := s.executeSearch(ctx, indexFiles, query)
+	if err != nil {
+		return nil, err
 	}
 
 	resp := s.parseResponse(result, params)
+	markCurated(resp, curation)
+	attachPassages(result, resp, params)
+	if params.Explain {
+		resp.Explain = s.explainQuery(ctx, indexFiles, params, query)
+	}
 	s.setCache(ctx, cacheKey, resp)
 	return resp, nil
 }
@@ -154,7 +238,7 @@ func (s *SearchService) SearchFiles(ctx context.Context, params *models.SearchPa
 func (s *SearchService) SearchUsers(ctx context.Context, params *models.SearchParams) (*models.SearchResponse, error) {
 	params.Validate()
 
-	cacheKey := s.buildCacheKey("user", params)
+	cacheKey := s.buildCacheKey(ctx, "user", params)
 	if cached := s.getFromCache(ctx, cacheKey); cached != nil {
 		return cached, nil
 	}
@@ -169,13 +253,20 @@ func (s *SearchService) SearchUsers(ctx context.Context, params *models.SearchPa
 
 	filters := s.buildFilters(params)
 	query := s.buildQuery(must, filters, params)
+	s.boostFields(ctx, query, params)
+	s.boostClicked(ctx, query, indexUsers, params)
+	curation := s.curate(ctx, query, indexUsers, params)
 
-	result, err := s.executeSearch(indexUsers, query)
+	result, err := s.executeSearch(ctx, indexUsers, query)
 	if err != nil {
-		return emptyResponse(params), nil
+		return nil, err
 	}
 
 	resp := s.parseResponse(result, params)
+	markCurated(resp, curation)
+	if params.Explain {
+		resp.Explain = s.explainQuery(ctx, indexUsers, params, query)
+	}
 	s.setCache(ctx, cacheKey, resp)
 	return resp, nil
 }
@@ -185,7 +276,7 @@ func (s *SearchService) SearchUsers(ctx context.Context, params *models.SearchPa
 func (s *SearchService) SearchChannels(ctx context.Context, params *models.SearchParams) (*models.SearchResponse, error) {
 	params.Validate()
 
-	cacheKey := s.buildCacheKey("ch", params)
+	cacheKey := s.buildCacheKey(ctx, "ch", params)
 	if cached := s.getFromCache(ctx, cacheKey); cached != nil {
 		return cached, nil
 	}
@@ -200,13 +291,20 @@ func (s *SearchService) SearchChannels(ctx context.Context, params *models.Searc
 
 	filters := s.buildFilters(params)
 	query := s.buildQuery(must, filters, params)
+	s.boostFields(ctx, query, params)
+	s.boostClicked(ctx, query, indexChannels, params)
+	curation := s.curate(ctx, query, indexChannels, params)
 
-	result, err := s.executeSearch(indexChannels, query)
+	result, err := s.executeSearch(ctx, indexChannels, query)
 	if err != nil {
-		return emptyResponse(params), nil
+		return nil, err
 	}
 
 	resp := s.parseResponse(result, params)
+	markCurated(resp, curation)
+	if params.Explain {
+		resp.Explain = s.explainQuery(ctx, indexChannels, params, query)
+	}
 	s.setCache(ctx, cacheKey, resp)
 	return resp, nil
 }
@@ -214,7 +312,7 @@ func (s *SearchService) SearchChannels(ctx context.Context, params *models.Searc
 // ── Suggest / Autocomplete ──
 
 func (s *SearchService) Suggest(ctx context.Context, query, workspaceID string) (*models.SuggestionResponse, error) {
-	if s.es == nil || query == "" {
+	if query == "" {
 		return &models.SuggestionResponse{Suggestions: []string{}}, nil
 	}
 
@@ -237,9 +335,9 @@ func (s *SearchService) Suggest(ctx context.Context, query, workspaceID string)
 		"_source": []string{"name", "username", "display_name", "filename"},
 	}
 
-	result, err := s.executeSearch("quckapp_*", searchQuery)
+	result, err := s.executeSearch(ctx, "quckapp_*", searchQuery)
 	if err != nil {
-		return &models.SuggestionResponse{Suggestions: []string{}}, nil
+		return nil, err
 	}
 
 	suggestions := []string{}
@@ -263,22 +361,52 @@ func (s *SearchService) Suggest(ctx context.Context, query, workspaceID string)
 	return &models.SuggestionResponse{Suggestions: suggestions}, nil
 }
 
-// ── Index Operations ──
+// RecordServed keeps the results a search of searchType served, so the
+// impressions and clicks later logged against requestID can be checked.
+func (s *SearchService) RecordServed(ctx context.Context, searchType, requestID, userID string, params *models.SearchParams, resp *models.SearchResponse) {
+	s.clicks.RecordServed(ctx, requestID, userID, searchType, params.Query, resp)
+}
 
-func (s *SearchService) IndexDocument(ctx context.Context, index, id string, doc map[string]interface{}) error {
-	if s.es == nil {
-		return nil
-	}
+// RecordGlobalServed keeps the results of each section of a global search,
+// under its type.
+func (s *SearchService) RecordGlobalServed(ctx context.Context, requestID, userID string, params *models.SearchParams, resp *models.GlobalSearchResponse) {
+	s.RecordServed(ctx, "messages", requestID, userID, params, resp.Messages)
+	s.RecordServed(ctx, "files", requestID, userID, params, resp.Files)
+	s.RecordServed(ctx, "users", requestID, userID, params, resp.Users)
+	s.RecordServed(ctx, "channels", requestID, userID, params, resp.Channels)
+}
+
+// ── Index Operations ──
 
-	var buf bytes.Buffer
-	if err := json.NewEncoder(&buf).Encode(doc); err != nil {
+// IndexDocument writes doc under id. A version, when set, is the source
+// system's version of the document; an older write than what is indexed is
+// rejected with a conflict.
+func (s *SearchService) IndexDocument(ctx context.Context, index, id string, doc map[string]interface{}, version int64) error {
+	if err := scopeDocument(ctx, index, doc); err != nil {
 		return err
 	}
+	switch index {
+	case indexMessages:
+		threadMessage(id, doc)
+	case indexFiles:
+		filePassages(doc)
+	}
+	s.embeddings.EmbedDocuments(ctx, index, []map[string]interface{}{doc})
+	es := s.es.Client()
+	if es == nil {
+		return s.deadLetters.Capture(ctx, failedWrite(ctx, opIndex, index, id, doc, version), errSearchUnavailable)
+	}
+	if err := s.embeddings.ensureMappings(ctx, es, index); err != nil {
+		return s.deadLetters.Capture(ctx, failedWrite(ctx, opIndex, index, id, doc, version), err)
+	}
 
-	_, err := s.es.Index(index, &buf, s.es.Index.WithDocumentID(id))
+	route, err := documentRoute(ctx, s.tenants, index, doc)
 	if err != nil {
 		return err
 	}
+	if err := indexVersioned(ctx, es, route, id, doc, version); err != nil {
+		return s.deadLetters.Capture(ctx, failedWrite(ctx, opIndex, index, id, doc, version), err)
+	}
 
 	// Invalidate related caches
 	s.invalidateCache(ctx, index)
@@ -289,7 +417,7 @@ func (s *SearchService) BulkIndex(ctx context.Context, docs []models.IndexReques
 	resp := &models.BulkIndexResponse{}
 
 	for _, doc := range docs {
-		err := s.IndexDocument(ctx, doc.Index, doc.ID, doc.Document)
+		err := s.IndexDocument(ctx, doc.Index, doc.ID, doc.Document, doc.Version)
 		if err != nil {
 			resp.Failed++
 			resp.Errors = append(resp.Errors, fmt.Sprintf("%s/%s: %s", doc.Index, doc.ID, err.Error()))
@@ -301,14 +429,22 @@ func (s *SearchService) BulkIndex(ctx context.Context, docs []models.IndexReques
 	return resp
 }
 
-func (s *SearchService) DeleteDocument(ctx context.Context, index, id string) error {
-	if s.es == nil {
-		return nil
+// DeleteDocument deletes a document, or leaves a tombstone for a message.
+// With a version, a delete older than the indexed document is rejected.
+func (s *SearchService) DeleteDocument(ctx context.Context, index, id string, version int64) error {
+	if tombstoneIndices[index] {
+		// Checked up front so the request is not kept as a dead letter.
+		if _, err := requireWorkspace(ctx); err != nil {
+			return err
+		}
+	}
+	es := s.es.Client()
+	if es == nil {
+		return s.deadLetters.Capture(ctx, failedWrite(ctx, opDelete, index, id, nil, version), errSearchUnavailable)
 	}
 
-	_, err := s.es.Delete(index, id)
-	if err != nil {
-		return err
+	if err := deleteDocument(ctx, es, s.tenants, index, id, version); err != nil {
+		return s.deadLetters.Capture(ctx, failedWrite(ctx, opDelete, index, id, nil, version), err)
 	}
 
 	s.invalidateCache(ctx, index)
@@ -316,8 +452,12 @@ func (s *SearchService) DeleteDocument(ctx context.Context, index, id string) er
 }
 
 func (s *SearchService) Reindex(ctx context.Context, index string) error {
-	if s.es == nil {
-		return nil
+	es := s.es.Client()
+	if es == nil {
+		return errSearchUnavailable
+	}
+	if err := requireAllWorkspaces(ctx); err != nil {
+		return err
 	}
 
 	// Create a reindex request (source and dest are the same, which refreshes)
@@ -326,14 +466,15 @@ func (s *SearchService) Reindex(ctx context.Context, index string) error {
 		"dest":   map[string]interface{}{"index": index + "_reindexed"},
 	}
 
-	var buf bytes.Buffer
-	json.NewEncoder(&buf).Encode(body)
-
-	res, err := s.es.Reindex(&buf)
+	buf, err := encodeBody(body)
 	if err != nil {
 		return err
 	}
-	defer res.Body.Close()
+
+	res, err := es.Reindex(buf, es.Reindex.WithContext(ctx))
+	if err := readResponse(res, err, "Reindex failed", nil); err != nil {
+		return err
+	}
 
 	s.invalidateCache(ctx, index)
 	return nil
@@ -350,6 +491,12 @@ func (s *SearchService) buildFilters(params *models.SearchParams) []map[string]i
 		})
 	}
 
+	if params.DocumentID != "" {
+		filters = append(filters, map[string]interface{}{
+			"ids": map[string]interface{}{"values": []string{params.DocumentID}},
+		})
+	}
+
 	if params.DateFrom != "" || params.DateTo != "" {
 		rangeFilter := map[string]interface{}{}
 		if params.DateFrom != "" {
@@ -378,22 +525,45 @@ func (s *SearchService) buildQuery(must []map[string]interface{}, filters []map[
 		"query": map[string]interface{}{
 			"bool": boolQuery,
 		},
-		"from": params.From(),
-		"size": params.PerPage,
-		"highlight": map[string]interface{}{
-			"fields": map[string]interface{}{
-				"content":      map[string]interface{}{},
-				"filename":     map[string]interface{}{},
-				"name":         map[string]interface{}{},
-				"display_name": map[string]interface{}{},
-				"description":  map[string]interface{}{},
-			},
-			"pre_tags":  []string{"<em>"},
-			"post_tags": []string{"</em>"},
-		},
+		"from":      params.From(),
+		"size":      params.PerPage,
+		"highlight": highlightClause(params, searchHighlightFields),
+	}
+
+	if params.Explain {
+		query["explain"] = true
 	}
+	applySort(query, params)
+	return query
+}
 
-	// Sort
+// modeQuery builds the search for params.Mode: the lexical clauses in must,
+// or a kNN search for the nearest documents to the query's embedding. Both
+// apply filters.
+func (s *SearchService) modeQuery(ctx context.Context, must []map[string]interface{}, filters []map[string]interface{}, params *models.SearchParams) (map[string]interface{}, error) {
+	switch params.Mode {
+	case models.SearchModeLexical, "":
+		return s.buildQuery(must, filters, params), nil
+	case models.SearchModeSemantic:
+		vector, err := s.embeddings.QueryVector(ctx, params.Query)
+		if err != nil {
+			return nil, err
+		}
+		query := map[string]interface{}{
+			"knn":  knnClause(vector, params.From()+params.PerPage, filters),
+			"from": params.From(),
+			"size": params.PerPage,
+		}
+		if params.Explain {
+			query["explain"] = true
+		}
+		applySort(query, params)
+		return query, nil
+	}
+	return nil, apperror.BadQuery("mode must be lexical, semantic or hybrid", nil)
+}
+
+func applySort(query map[string]interface{}, params *models.SearchParams) {
 	switch params.Sort {
 	case "newest":
 		query["sort"] = []map[string]interface{}{{"created_at": "desc"}, {"_score": "desc"}}
@@ -402,35 +572,10 @@ func (s *SearchService) buildQuery(must []map[string]interface{}, filters []map[
 	default:
 		// relevance - default ES scoring
 	}
-
-	return query
 }
 
-func (s *SearchService) executeSearch(index string, query map[string]interface{}) (map[string]interface{}, error) {
-	if s.es == nil {
-		return map[string]interface{}{
-			"hits": map[string]interface{}{
-				"total": map[string]interface{}{"value": 0},
-				"hits":  []interface{}{},
-			},
-		}, nil
-	}
-
-	var buf bytes.Buffer
-	json.NewEncoder(&buf).Encode(query)
-
-	res, err := s.es.Search(
-		s.es.Search.WithIndex(index),
-		s.es.Search.WithBody(&buf),
-	)
-	if err != nil {
-		return nil, err
-	}
-	defer res.Body.Close()
-
-	var result map[string]interface{}
-	json.NewDecoder(res.Body).Decode(&result)
-	return result, nil
+func (s *SearchService) executeSearch(ctx context.Context, index string, query map[string]interface{}) (map[string]interface{}, error) {
+	return searchIndex(ctx, s.es.Client(), s.tenants, index, query)
 }
 
 func (s *SearchService) parseResponse(result map[string]interface{}, params *models.SearchParams) *models.SearchResponse {
@@ -468,17 +613,16 @@ func (s *SearchService) parseResponse(result map[string]interface{}, params *mod
 			if source, ok := hitMap["_source"].(map[string]interface{}); ok {
 				searchHit.Source = source
 			}
-			// Include highlights in source
-			if highlights, ok := hitMap["highlight"].(map[string]interface{}); ok {
-				if searchHit.Source == nil {
-					searchHit.Source = map[string]interface{}{}
-				}
-				searchHit.Source["_highlights"] = highlights
+			searchHit.Highlights = parseHighlights(hitMap, searchHit.Source, params, searchHighlightFields)
+			if explanation, ok := hitMap["_explanation"].(map[string]interface{}); ok {
+				searchHit.Explanation = parseExplanation(explanation)
 			}
 			resp.Results = append(resp.Results, searchHit)
 		}
 	}
 
+	applySearchMeta(resp, result)
+
 	// Total pages
 	if resp.Total > 0 {
 		resp.TotalPages = int((resp.Total + int64(params.PerPage) - 1) / int64(params.PerPage))
@@ -487,28 +631,33 @@ func (s *SearchService) parseResponse(result map[string]interface{}, params *mod
 	return resp
 }
 
-func emptyResponse(params *models.SearchParams) *models.SearchResponse {
-	return &models.SearchResponse{
-		Results:    []models.SearchHit{},
-		Total:      0,
-		Page:       params.Page,
-		PerPage:    params.PerPage,
-		TotalPages: 0,
-	}
-}
-
 // ── Cache ──
 
-func (s *SearchService) buildCacheKey(prefix string, params *models.SearchParams) string {
-	return fmt.Sprintf("search:%s:%s:%s:%d:%d:%s",
-		prefix, params.Query, params.WorkspaceID, params.Page, params.PerPage, params.Sort)
+// buildCacheKey keys on the caller's workspace scope as well as the params,
+// so a cached page can never be served to another tenant. Explained searches
+// get no key: they are for debugging, and must show what ranks now. Nor do
+// searches with a curation or relevance override, which are previews and
+// evaluations.
+func (s *SearchService) buildCacheKey(ctx context.Context, prefix string, params *models.SearchParams) string {
+	if params.Explain || params.Curation != nil || params.Relevance != nil {
+		return ""
+	}
+	scope, _ := tenant.FromContext(ctx)
+	workspace := scope.WorkspaceID
+	if scope.Unrestricted() {
+		workspace = "*"
+	}
+	return fmt.Sprintf("search:%s:%s:%s:%s:%d:%d:%s:%s:%t:%s:%s",
+		prefix, params.Query, workspace, params.WorkspaceID, params.Page, params.PerPage, params.Sort,
+		params.ThreadID, params.CollapseThreads, params.Mode, highlightCacheKey(params))
 }
 
 func (s *SearchService) getFromCache(ctx context.Context, key string) *models.SearchResponse {
-	if s.redis == nil {
+	rdb := s.redis.Client()
+	if rdb == nil || key == "" {
 		return nil
 	}
-	data, err := s.redis.Get(ctx, key).Bytes()
+	data, err := rdb.Get(ctx, key).Bytes()
 	if err != nil {
 		return nil
 	}
@@ -520,18 +669,20 @@ func (s *SearchService) getFromCache(ctx context.Context, key string) *models.Se
 }
 
 func (s *SearchService) setCache(ctx context.Context, key string, resp *models.SearchResponse) {
-	if s.redis == nil {
+	rdb := s.redis.Client()
+	if rdb == nil || key == "" {
 		return
 	}
 	data, err := json.Marshal(resp)
 	if err != nil {
 		return
 	}
-	s.redis.Set(ctx, key, data, cacheTTL)
+	rdb.Set(ctx, key, data, cacheTTL)
 }
 
 func (s *SearchService) invalidateCache(ctx context.Context, index string) {
-	if s.redis == nil {
+	rdb := s.redis.Client()
+	if rdb == nil {
 		return
 	}
 	// Extract type from index name
@@ -540,42 +691,33 @@ func (s *SearchService) invalidateCache(ctx context.Context, index string) {
 		return
 	}
 	pattern := "search:" + parts[len(parts)-1][:3] + ":*"
-	keys, _ := s.redis.Keys(ctx, pattern).Result()
+	keys, _ := rdb.Keys(ctx, pattern).Result()
 	if len(keys) > 0 {
-		s.redis.Del(ctx, keys...)
+		rdb.Del(ctx, keys...)
 	}
 }
 
 // ── Health ──
 
+// HealthCheck reports the state tracked by the connection managers rather
+// than probing the dependencies on every call.
 func (s *SearchService) HealthCheck() map[string]interface{} {
-	health := map[string]interface{}{
-		"service": "search-service",
-		"status":  "healthy",
-	}
+	esStatus := s.es.Status()
+	redisStatus := s.redis.Status()
 
-	if s.es != nil {
-		res, err := s.es.Info()
-		if err != nil {
-			health["elasticsearch"] = "disconnected"
-		} else {
-			res.Body.Close()
-			health["elasticsearch"] = "connected"
-		}
-	} else {
-		health["elasticsearch"] = "not configured"
+	health := map[string]interface{}{
+		"service":       "search-service",
+		"status":        "healthy",
+		"elasticsearch": esStatus.State,
+		"redis":         redisStatus.State,
+		"connections": map[string]interface{}{
+			"elasticsearch": esStatus,
+			"redis":         redisStatus,
+		},
 	}
 
-	if s.redis != nil {
-		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-		defer cancel()
-		if err := s.redis.Ping(ctx).Err(); err != nil {
-			health["redis"] = "disconnected"
-		} else {
-			health["redis"] = "connected"
-		}
-	} else {
-		health["redis"] = "not configured"
+	if esStatus.State != db.StateConnected {
+		health["status"] = "degraded"
 	}
 
 	return health
@@ -6,29 +6,30 @@ import (
 	"encoding/json"
 	"strings"
 
-	"github.com/elastic/go-elasticsearch/v8"
 	"github.com/sirupsen/logrus"
 
+	"github.com/quckapp/search-service/internal/db"
 	"github.com/quckapp/search-service/internal/models"
 )
 
 type SpellCheckService struct {
-	es     *elasticsearch.Client
+	es     *db.ElasticsearchManager
 	logger *logrus.Logger
 }
 
-func NewSpellCheckService(es *elasticsearch.Client, logger *logrus.Logger) *SpellCheckService {
+func NewSpellCheckService(es *db.ElasticsearchManager, logger *logrus.Logger) *SpellCheckService {
 	return &SpellCheckService{es: es, logger: logger}
 }
 
 func (s *SpellCheckService) GetSuggestions(ctx context.Context, text, index string) (*models.SpellCheckResponse, error) {
+	es := s.es.Client()
 	resp := &models.SpellCheckResponse{
 		Original:    text,
 		Suggestions: []string{},
 		Corrected:   text,
 	}
 
-	if s.es == nil || text == "" {
+	if es == nil || text == "" {
 		return resp, nil
 	}
 
@@ -52,9 +53,9 @@ func (s *SpellCheckService) GetSuggestions(ctx context.Context, text, index stri
 	var buf bytes.Buffer
 	json.NewEncoder(&buf).Encode(query)
 
-	res, err := s.es.Search(
-		s.es.Search.WithIndex(index),
-		s.es.Search.WithBody(&buf),
+	res, err := es.Search(
+		es.Search.WithIndex(index),
+		es.Search.WithBody(&buf),
 	)
 	if err != nil {
 		return resp, nil
@@ -91,13 +92,14 @@ func (s *SpellCheckService) GetSuggestions(ctx context.Context, text, index stri
 }
 
 func (s *SpellCheckService) DidYouMean(ctx context.Context, text, index string) (*models.DidYouMeanResponse, error) {
+	es := s.es.Client()
 	resp := &models.DidYouMeanResponse{
 		Original:   text,
 		Suggestion: "",
 		Confidence: 0,
 	}
 
-	if s.es == nil || text == "" {
+	if es == nil || text == "" {
 		return resp, nil
 	}
 
@@ -128,9 +130,9 @@ func (s *SpellCheckService) DidYouMean(ctx context.Context, text, index string)
 	var buf bytes.Buffer
 	json.NewEncoder(&buf).Encode(query)
 
-	res, err := s.es.Search(
-		s.es.Search.WithIndex(index),
-		s.es.Search.WithBody(&buf),
+	res, err := es.Search(
+		es.Search.WithIndex(index),
+		es.Search.WithBody(&buf),
 	)
 	if err != nil {
 		return resp, nil
@@ -7,24 +7,25 @@ import (
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/redis/go-redis/v9"
 	"github.com/sirupsen/logrus"
 
+	"github.com/quckapp/search-service/internal/db"
 	"github.com/quckapp/search-service/internal/models"
 )
 
 type SynonymService struct {
-	redis  *redis.Client
+	redis  *db.RedisManager
 	logger *logrus.Logger
 }
 
-func NewSynonymService(redis *redis.Client, logger *logrus.Logger) *SynonymService {
+func NewSynonymService(redis *db.RedisManager, logger *logrus.Logger) *SynonymService {
 	return &SynonymService{redis: redis, logger: logger}
 }
 
 func (s *SynonymService) Create(ctx context.Context, userID string, req *models.CreateSynonymRequest) (*models.SynonymGroup, error) {
-	if s.redis == nil {
-		return nil, fmt.Errorf("storage not available")
+	rdb := s.redis.Client()
+	if rdb == nil {
+		return nil, errStorageUnavailable
 	}
 
 	group := &models.SynonymGroup{
@@ -42,21 +43,22 @@ func (s *SynonymService) Create(ctx context.Context, userID string, req *models.
 	}
 
 	key := fmt.Sprintf("synonym:%s:%s", req.WorkspaceID, group.ID)
-	s.redis.Set(ctx, key, data, 0)
+	rdb.Set(ctx, key, data, 0)
 
 	listKey := fmt.Sprintf("synonyms:%s", req.WorkspaceID)
-	s.redis.SAdd(ctx, listKey, group.ID)
+	rdb.SAdd(ctx, listKey, group.ID)
 
 	return group, nil
 }
 
 func (s *SynonymService) List(ctx context.Context, workspaceID string) ([]models.SynonymGroup, error) {
-	if s.redis == nil {
+	rdb := s.redis.Client()
+	if rdb == nil {
 		return []models.SynonymGroup{}, nil
 	}
 
 	listKey := fmt.Sprintf("synonyms:%s", workspaceID)
-	ids, err := s.redis.SMembers(ctx, listKey).Result()
+	ids, err := rdb.SMembers(ctx, listKey).Result()
 	if err != nil {
 		return []models.SynonymGroup{}, nil
 	}
@@ -64,7 +66,7 @@ func (s *SynonymService) List(ctx context.Context, workspaceID string) ([]models
 	var groups []models.SynonymGroup
 	for _, id := range ids {
 		key := fmt.Sprintf("synonym:%s:%s", workspaceID, id)
-		data, err := s.redis.Get(ctx, key).Bytes()
+		data, err := rdb.Get(ctx, key).Bytes()
 		if err != nil {
 			continue
 		}
@@ -77,12 +79,13 @@ func (s *SynonymService) List(ctx context.Context, workspaceID string) ([]models
 }
 
 func (s *SynonymService) Update(ctx context.Context, workspaceID, synonymID string, req *models.UpdateSynonymRequest) (*models.SynonymGroup, error) {
-	if s.redis == nil {
-		return nil, fmt.Errorf("storage not available")
+	rdb := s.redis.Client()
+	if rdb == nil {
+		return nil, errStorageUnavailable
 	}
 
 	key := fmt.Sprintf("synonym:%s:%s", workspaceID, synonymID)
-	data, err := s.redis.Get(ctx, key).Bytes()
+	data, err := rdb.Get(ctx, key).Bytes()
 	if err != nil {
 		return nil, fmt.Errorf("synonym group not found")
 	}
@@ -99,21 +102,22 @@ func (s *SynonymService) Update(ctx context.Context, workspaceID, synonymID stri
 	if err != nil {
 		return nil, err
 	}
-	s.redis.Set(ctx, key, updated, 0)
+	rdb.Set(ctx, key, updated, 0)
 
 	return &group, nil
 }
 
 func (s *SynonymService) Delete(ctx context.Context, workspaceID, synonymID string) error {
-	if s.redis == nil {
+	rdb := s.redis.Client()
+	if rdb == nil {
 		return nil
 	}
 
 	key := fmt.Sprintf("synonym:%s:%s", workspaceID, synonymID)
-	s.redis.Del(ctx, key)
+	rdb.Del(ctx, key)
 
 	listKey := fmt.Sprintf("synonyms:%s", workspaceID)
-	s.redis.SRem(ctx, listKey, synonymID)
+	rdb.SRem(ctx, listKey, synonymID)
 
 	return nil
 }
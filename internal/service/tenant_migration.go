@@ -0,0 +1,503 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/google/uuid"
+
+	"github.com/quckapp/search-service/internal/apperror"
+	"github.com/quckapp/search-service/internal/models"
+)
+
+const (
+	migrationRunning   = "running"
+	migrationCompleted = "completed"
+	migrationFailed    = "failed"
+	migrationAborted   = "aborted"
+
+	migrationBatchSize = 500
+)
+
+// errMigrationAborted stops a run whose migration was aborted or replaced.
+var errMigrationAborted = apperror.Conflict("Tenant migration was aborted", nil)
+
+// StartMigration records a migration of workspaceID to mode. From this point
+// writes for the workspace are refused; RunMigration moves the data.
+func (s *TenantRoutingService) StartMigration(ctx context.Context, workspaceID, mode string) (*models.TenantPlacement, error) {
+	switch mode {
+	case PlacementShared, PlacementRouted:
+	case PlacementDedicated:
+		if !validWorkspaceIndexName.MatchString(workspaceID) {
+			return nil, apperror.BadQuery("Dedicated placement needs a workspace ID of lowercase letters, digits, '_' and '-'", nil)
+		}
+	default:
+		return nil, apperror.BadQuery("Unknown placement mode "+mode, nil)
+	}
+
+	p, err := s.Get(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if p.Migration != nil && p.Migration.State == migrationRunning {
+		return nil, apperror.Conflict("A migration is already running for this workspace", nil)
+	}
+	if p.Mode == mode {
+		return nil, apperror.Conflict("Workspace already uses "+mode+" placement", nil)
+	}
+
+	p.Migration = &models.TenantMigration{
+		ID:        uuid.New().String(),
+		From:      p.Mode,
+		To:        mode,
+		State:     migrationRunning,
+		Step:      "waiting for replicas",
+		StartedAt: time.Now(),
+	}
+	if err := s.save(ctx, p); err != nil {
+		return nil, err
+	}
+
+	s.logger.WithFields(map[string]interface{}{
+		"workspace_id": workspaceID,
+		"from":         p.Migration.From,
+		"to":           mode,
+	}).Info("Tenant migration started")
+	return p, nil
+}
+
+// RunMigration moves a started migration's documents and switches the
+// placement. It first waits out the placement cache so every replica has
+// stopped writing to the old placement. A failure while copying leaves the
+// placement unchanged, so the migration can simply be started again; each
+// run clears the new placement before copying, so nothing an earlier run
+// copied outlives a delete made since. A failure while cleaning up leaves
+// stale copies in the old placement, which no longer serves reads. A run
+// stops within a batch once the migration is aborted.
+func (s *TenantRoutingService) RunMigration(ctx context.Context, workspaceID string) error {
+	p, err := s.Get(ctx, workspaceID)
+	if err != nil {
+		return err
+	}
+	m := p.Migration
+	if m == nil || m.State != migrationRunning {
+		return apperror.Conflict("No migration is running for this workspace", nil)
+	}
+
+	fail := func(err error) error {
+		now := time.Now()
+		m.State = migrationFailed
+		m.Error = err.Error()
+		m.FinishedAt = &now
+		if saveErr := s.save(context.Background(), p); saveErr != nil {
+			s.logger.WithError(saveErr).Error("Failed to record tenant migration failure")
+		}
+		s.logger.WithError(err).WithField("workspace_id", workspaceID).Error("Tenant migration failed")
+		return err
+	}
+
+	// step records progress; only an abort stops the run, since losing a
+	// progress update is harmless.
+	step := func(name string) error {
+		m.Step = name
+		if err := s.checkpoint(ctx, p); err == errMigrationAborted {
+			s.logger.WithField("workspace_id", workspaceID).Warn("Tenant migration aborted")
+			return err
+		}
+		return nil
+	}
+
+	select {
+	case <-time.After(placementCacheTTL):
+	case <-ctx.Done():
+		return fail(ctx.Err())
+	}
+
+	es := s.es.Client()
+	if es == nil {
+		return fail(errSearchUnavailable)
+	}
+
+	from := &models.TenantPlacement{WorkspaceID: workspaceID, Mode: m.From}
+	to := &models.TenantPlacement{WorkspaceID: workspaceID, Mode: m.To}
+
+	for _, index := range tenantIndices {
+		src, dst := routeFor(from, index), routeFor(to, index)
+
+		if err := step("copying " + index); err != nil {
+			return err
+		}
+
+		if src.Index != dst.Index {
+			if _, err := clearMigrationTarget(ctx, es, workspaceID, m.To, dst); err != nil {
+				return fail(fmt.Errorf("clear %s: %w", dst.Index, err))
+			}
+		}
+		if dst.Index != index {
+			if err := ensureTenantIndex(ctx, es, index, dst.Index); err != nil {
+				return fail(err)
+			}
+		}
+		copied, err := s.copyTenantDocuments(ctx, es, workspaceID, m.ID, src, dst)
+		if err == errMigrationAborted {
+			s.logger.WithField("workspace_id", workspaceID).Warn("Tenant migration aborted")
+			return err
+		}
+		if err != nil {
+			return fail(fmt.Errorf("copy %s: %w", index, err))
+		}
+		m.Copied += copied
+	}
+
+	// Switch reads to the new placement before the old copies go away.
+	m.Step = "switching placement"
+	p.Mode = m.To
+	if err := s.checkpoint(ctx, p); err != nil {
+		if err == errMigrationAborted {
+			return err
+		}
+		return fail(err)
+	}
+
+	for _, index := range tenantIndices {
+		src, dst := routeFor(from, index), routeFor(to, index)
+		if src.Index == dst.Index {
+			// Moved in place by copyTenantDocuments.
+			continue
+		}
+
+		if err := step("cleaning up " + index); err != nil {
+			return err
+		}
+
+		deleted, err := removeTenantDocuments(ctx, es, workspaceID, from.Mode, src)
+		if err != nil {
+			return fail(fmt.Errorf("clean up %s: %w", src.Index, err))
+		}
+		m.Deleted += deleted
+	}
+
+	now := time.Now()
+	m.State = migrationCompleted
+	m.Step = "done"
+	m.FinishedAt = &now
+	if err := s.checkpoint(ctx, p); err != nil {
+		return err
+	}
+
+	s.logger.WithFields(map[string]interface{}{
+		"workspace_id": workspaceID,
+		"mode":         p.Mode,
+		"copied":       m.Copied,
+		"deleted":      m.Deleted,
+	}).Info("Tenant migration completed")
+	return nil
+}
+
+// AbortMigration ends a running migration whose run was lost, for example
+// with the replica that ran it, so the workspace accepts writes again. They
+// go to the placement recorded when it was aborted: the old one if the
+// data had not been switched yet, in which case the partial copy in the new
+// one is removed, otherwise the new one, with stale copies left in the old.
+// A run still in progress stops within its current batch.
+func (s *TenantRoutingService) AbortMigration(ctx context.Context, workspaceID string) (*models.TenantPlacement, error) {
+	p, err := s.Get(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	m := p.Migration
+	if m == nil || m.State != migrationRunning {
+		return nil, apperror.Conflict("No migration is running for this workspace", nil)
+	}
+
+	now := time.Now()
+	m.State = migrationAborted
+	m.FinishedAt = &now
+	if err := s.save(ctx, p); err != nil {
+		return nil, err
+	}
+
+	s.logger.WithFields(map[string]interface{}{
+		"workspace_id": workspaceID,
+		"step":         m.Step,
+		"mode":         p.Mode,
+	}).Warn("Tenant migration aborted")
+
+	if p.Mode == m.From {
+		s.clearAbortedCopy(ctx, p)
+	}
+	return p, nil
+}
+
+// clearAbortedCopy removes what an aborted migration copied to the
+// placement it never switched to. A copy left behind is cleared anyway by
+// the next run to that placement, so failures are only logged.
+func (s *TenantRoutingService) clearAbortedCopy(ctx context.Context, p *models.TenantPlacement) {
+	es := s.es.Client()
+	if es == nil {
+		s.logger.WithField("workspace_id", p.WorkspaceID).Warn("Search backend unavailable; aborted migration copy left in place")
+		return
+	}
+	m := p.Migration
+	from := &models.TenantPlacement{WorkspaceID: p.WorkspaceID, Mode: m.From}
+	to := &models.TenantPlacement{WorkspaceID: p.WorkspaceID, Mode: m.To}
+	for _, index := range tenantIndices {
+		src, dst := routeFor(from, index), routeFor(to, index)
+		if src.Index == dst.Index {
+			// Moved in place; every document is in exactly one placement.
+			continue
+		}
+		if _, err := clearMigrationTarget(ctx, es, p.WorkspaceID, m.To, dst); err != nil {
+			s.logger.WithError(err).WithField("index", dst.Index).Warn("Failed to clear aborted migration copy")
+		}
+	}
+}
+
+// checkpoint saves a run's progress, unless its migration has been aborted
+// or replaced meanwhile.
+func (s *TenantRoutingService) checkpoint(ctx context.Context, p *models.TenantPlacement) error {
+	if err := s.stillRunning(ctx, p.WorkspaceID, p.Migration.ID); err != nil {
+		return err
+	}
+	return s.save(ctx, p)
+}
+
+// stillRunning returns errMigrationAborted unless migrationID is the
+// workspace's running migration.
+func (s *TenantRoutingService) stillRunning(ctx context.Context, workspaceID, migrationID string) error {
+	current, err := s.Get(ctx, workspaceID)
+	if err != nil {
+		return err
+	}
+	if cm := current.Migration; cm == nil || cm.ID != migrationID || cm.State != migrationRunning {
+		return errMigrationAborted
+	}
+	return nil
+}
+
+// ensureTenantIndex creates a dedicated index with the shared index's
+// mappings, if it does not exist yet.
+func ensureTenantIndex(ctx context.Context, es *elasticsearch.Client, shared, dedicated string) error {
+	res, err := es.Indices.Exists([]string{dedicated}, es.Indices.Exists.WithContext(ctx))
+	if err != nil {
+		return apperror.FromTransport("Failed to check index", err)
+	}
+	res.Body.Close()
+	if res.StatusCode == 200 {
+		return nil
+	}
+
+	body := map[string]interface{}{}
+	res, err = es.Indices.GetMapping(es.Indices.GetMapping.WithIndex(shared), es.Indices.GetMapping.WithContext(ctx))
+	var mappings map[string]struct {
+		Mappings map[string]interface{} `json:"mappings"`
+	}
+	if err := readResponse(res, err, "Failed to read shared index mappings", &mappings); err == nil {
+		if m, ok := mappings[shared]; ok && len(m.Mappings) > 0 {
+			body["mappings"] = m.Mappings
+		}
+	} else if apperror.KindOf(err) != apperror.KindIndexNotFound {
+		return err
+	}
+
+	buf, err := encodeBody(body)
+	if err != nil {
+		return err
+	}
+	res, err = es.Indices.Create(dedicated, es.Indices.Create.WithBody(buf), es.Indices.Create.WithContext(ctx))
+	if err := readResponse(res, err, "Failed to create dedicated index", nil); err != nil && apperror.KindOf(err) != apperror.KindConflict {
+		return err
+	}
+	return nil
+}
+
+// copyTenantDocuments scrolls the workspace's documents out of src and bulk
+// writes them to dst. When src and dst are the same index only the routing
+// changes, so each document is deleted under its old routing in the same
+// bulk request; the delete comes first in case both route to one shard.
+// Before each batch it checks that migrationID is still running.
+func (s *TenantRoutingService) copyTenantDocuments(ctx context.Context, es *elasticsearch.Client, workspaceID, migrationID string, src, dst indexRoute) (int64, error) {
+	inPlace := src.Index == dst.Index
+	if inPlace && src.Routing == dst.Routing {
+		return 0, nil
+	}
+
+	query, err := encodeBody(map[string]interface{}{
+		"query": map[string]interface{}{
+			"term": map[string]interface{}{"workspace_id": workspaceID},
+		},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	opts := []func(*esapi.SearchRequest){
+		es.Search.WithContext(ctx),
+		es.Search.WithIndex(src.Index),
+		es.Search.WithBody(query),
+		es.Search.WithScroll(time.Minute),
+		es.Search.WithSize(migrationBatchSize),
+	}
+	if src.Routing != "" {
+		opts = append(opts, es.Search.WithRouting(src.Routing))
+	}
+
+	var page scrollPage
+	res, err := es.Search(opts...)
+	if err := readResponse(res, err, "Failed to read tenant documents", &page); err != nil {
+		if apperror.KindOf(err) == apperror.KindIndexNotFound {
+			return 0, nil
+		}
+		return 0, err
+	}
+	defer func() {
+		if page.ScrollID != "" {
+			if res, err := es.ClearScroll(es.ClearScroll.WithScrollID(page.ScrollID)); err == nil {
+				res.Body.Close()
+			}
+		}
+	}()
+
+	var copied int64
+	for len(page.Hits.Hits) > 0 {
+		if err := s.stillRunning(ctx, workspaceID, migrationID); err != nil {
+			return copied, err
+		}
+		var bulk bytes.Buffer
+		enc := json.NewEncoder(&bulk)
+		for _, hit := range page.Hits.Hits {
+			if inPlace {
+				enc.Encode(map[string]interface{}{"delete": bulkMeta(src.Index, hit.ID, src.Routing)})
+			}
+			enc.Encode(map[string]interface{}{"index": bulkMeta(dst.Index, hit.ID, dst.Routing)})
+			bulk.Write(hit.Source)
+			bulk.WriteByte('\n')
+		}
+
+		if err := runBulk(ctx, es, &bulk); err != nil {
+			return copied, err
+		}
+		copied += int64(len(page.Hits.Hits))
+
+		res, err := es.Scroll(es.Scroll.WithContext(ctx), es.Scroll.WithScrollID(page.ScrollID), es.Scroll.WithScroll(time.Minute))
+		page = scrollPage{}
+		if err := readResponse(res, err, "Failed to read tenant documents", &page); err != nil {
+			return copied, err
+		}
+	}
+	return copied, nil
+}
+
+// clearMigrationTarget removes what may already be in the placement a
+// migration copies to: a dedicated index is dropped whole, and the shared
+// index loses the workspace's documents under any routing. The workspace
+// does not read from that placement yet, so everything there is stale.
+func clearMigrationTarget(ctx context.Context, es *elasticsearch.Client, workspaceID, mode string, dst indexRoute) (int64, error) {
+	return removeTenantDocuments(ctx, es, workspaceID, mode, indexRoute{Index: dst.Index})
+}
+
+// removeTenantDocuments deletes the workspace's documents from a placement
+// that is no longer in use. Dedicated indices are dropped whole.
+func removeTenantDocuments(ctx context.Context, es *elasticsearch.Client, workspaceID, mode string, src indexRoute) (int64, error) {
+	if mode == PlacementDedicated {
+		res, err := es.Indices.Delete([]string{src.Index}, es.Indices.Delete.WithContext(ctx))
+		if err := readResponse(res, err, "Failed to delete dedicated index", nil); err != nil && apperror.KindOf(err) != apperror.KindIndexNotFound {
+			return 0, err
+		}
+		return 0, nil
+	}
+
+	return deleteByWorkspace(ctx, es, []string{src.Index}, workspaceID, src.Routing)
+}
+
+// deleteByWorkspace removes every document of workspaceID from indices.
+func deleteByWorkspace(ctx context.Context, es *elasticsearch.Client, indices []string, workspaceID, routing string) (int64, error) {
+	return deleteMatching(ctx, es, indices, map[string]interface{}{
+		"term": map[string]interface{}{"workspace_id": workspaceID},
+	}, routing)
+}
+
+// deleteMatching runs a delete-by-query for query over indices. Missing
+// indices count as empty; partial failures are reported as an error along
+// with the number of documents that were deleted.
+func deleteMatching(ctx context.Context, es *elasticsearch.Client, indices []string, query map[string]interface{}, routing string) (int64, error) {
+	body, err := encodeBody(map[string]interface{}{"query": query})
+	if err != nil {
+		return 0, err
+	}
+
+	opts := []func(*esapi.DeleteByQueryRequest){
+		es.DeleteByQuery.WithContext(ctx),
+		es.DeleteByQuery.WithConflicts("proceed"),
+		es.DeleteByQuery.WithRefresh(true),
+		es.DeleteByQuery.WithIgnoreUnavailable(true),
+		es.DeleteByQuery.WithAllowNoIndices(true),
+	}
+	if routing != "" {
+		opts = append(opts, es.DeleteByQuery.WithRouting(routing))
+	}
+
+	res, err := es.DeleteByQuery(indices, body, opts...)
+	var result struct {
+		Deleted  int64             `json:"deleted"`
+		Failures []json.RawMessage `json:"failures"`
+	}
+	if err := readResponse(res, err, "Failed to delete documents", &result); err != nil {
+		if apperror.KindOf(err) == apperror.KindIndexNotFound {
+			return 0, nil
+		}
+		return 0, err
+	}
+	if len(result.Failures) > 0 {
+		return result.Deleted, fmt.Errorf("%d documents could not be deleted: %s", len(result.Failures), result.Failures[0])
+	}
+	return result.Deleted, nil
+}
+
+type scrollPage struct {
+	ScrollID string `json:"_scroll_id"`
+	Hits     struct {
+		Hits []struct {
+			ID     string          `json:"_id"`
+			Source json.RawMessage `json:"_source"`
+		} `json:"hits"`
+	} `json:"hits"`
+}
+
+func bulkMeta(index, id, routing string) map[string]interface{} {
+	meta := map[string]interface{}{"_index": index, "_id": id}
+	if routing != "" {
+		meta["routing"] = routing
+	}
+	return meta
+}
+
+// runBulk sends an NDJSON bulk body and fails if any item failed.
+func runBulk(ctx context.Context, es *elasticsearch.Client, body *bytes.Buffer) error {
+	res, err := es.Bulk(body, es.Bulk.WithContext(ctx), es.Bulk.WithRefresh("wait_for"))
+	var result struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			Error *ESErrorCause `json:"error"`
+		} `json:"items"`
+	}
+	if err := readResponse(res, err, "Bulk request failed", &result); err != nil {
+		return err
+	}
+	if !result.Errors {
+		return nil
+	}
+	for _, item := range result.Items {
+		for _, op := range item {
+			if op.Error != nil {
+				return fmt.Errorf("bulk item failed: %s: %s", op.Error.Type, op.Error.Reason)
+			}
+		}
+	}
+	return fmt.Errorf("bulk request reported errors")
+}
@@ -0,0 +1,283 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+
+	"github.com/quckapp/search-service/internal/apperror"
+	"github.com/quckapp/search-service/internal/db"
+	"github.com/quckapp/search-service/internal/models"
+)
+
+const (
+	PlacementShared    = "shared"
+	PlacementRouted    = "routed"
+	PlacementDedicated = "dedicated"
+
+	// placementCacheTTL bounds how long a replica may act on a stale
+	// placement. Migrations wait this long before moving data.
+	placementCacheTTL = 30 * time.Second
+)
+
+// tenantIndices are the logical indices whose documents belong to a single
+// workspace and can therefore be placed per tenant.
+var tenantIndices = []string{indexMessages, indexFiles, indexUsers, indexChannels, indexBookmarks, indexTasks}
+
+// Dedicated index names embed the workspace ID as is, so it must already be
+// a valid index name fragment. Index names are lowercase; folding the ID
+// instead would give "ABC" and "abc" the same index.
+var validWorkspaceIndexName = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+func isTenantIndex(index string) bool {
+	for _, idx := range tenantIndices {
+		if idx == index {
+			return true
+		}
+	}
+	return false
+}
+
+func dedicatedIndex(index, workspaceID string) string {
+	return index + "-ws-" + workspaceID
+}
+
+// isDedicatedIndexOf reports whether index is one of the workspace's
+// dedicated indices. Names are compared whole: a suffix match would also
+// take in "x-ws-abc"'s indices for workspace "abc".
+func isDedicatedIndexOf(index, workspaceID string) bool {
+	for _, idx := range tenantIndices {
+		if index == dedicatedIndex(idx, workspaceID) {
+			return true
+		}
+	}
+	return false
+}
+
+// indexRoute is where a request for a logical index physically goes.
+type indexRoute struct {
+	Index   string
+	Routing string
+}
+
+type cachedPlacement struct {
+	placement models.TenantPlacement
+	expires   time.Time
+}
+
+// TenantRoutingService maps workspaces to their index placement. The map
+// lives in Redis and is cached briefly in memory, since every search and
+// write resolves it. A nil service routes everything to the shared indices.
+type TenantRoutingService struct {
+	es     *db.ElasticsearchManager
+	redis  *db.RedisManager
+	logger *logrus.Logger
+
+	mu    sync.Mutex
+	cache map[string]cachedPlacement
+}
+
+func NewTenantRoutingService(es *db.ElasticsearchManager, redis *db.RedisManager, logger *logrus.Logger) *TenantRoutingService {
+	return &TenantRoutingService{es: es, redis: redis, logger: logger, cache: map[string]cachedPlacement{}}
+}
+
+func tenantPlacementKey(workspaceID string) string {
+	return "tenant:placement:" + workspaceID
+}
+
+func defaultPlacement(workspaceID string) *models.TenantPlacement {
+	return &models.TenantPlacement{WorkspaceID: workspaceID, Mode: PlacementShared}
+}
+
+// Placement returns the workspace's placement for a read, defaulting to
+// shared when no entry exists. If Redis cannot be reached the last placement
+// seen is used, or shared if there is none; neither is cached, so the real
+// placement is picked up as soon as Redis is back.
+func (s *TenantRoutingService) Placement(ctx context.Context, workspaceID string) *models.TenantPlacement {
+	p, err := s.placement(ctx, workspaceID)
+	if err == nil {
+		return p
+	}
+	s.logger.WithError(err).WithField("workspace_id", workspaceID).Warn("Failed to load tenant placement")
+
+	s.mu.Lock()
+	cached, ok := s.cache[workspaceID]
+	s.mu.Unlock()
+	if ok {
+		p := cached.placement
+		return &p
+	}
+	return defaultPlacement(workspaceID)
+}
+
+// placement returns the workspace's placement from the cache or Redis. It
+// fails when Redis is unavailable rather than guessing.
+func (s *TenantRoutingService) placement(ctx context.Context, workspaceID string) (*models.TenantPlacement, error) {
+	if s == nil || workspaceID == "" {
+		return defaultPlacement(workspaceID), nil
+	}
+
+	s.mu.Lock()
+	cached, ok := s.cache[workspaceID]
+	s.mu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		p := cached.placement
+		return &p, nil
+	}
+
+	p, err := s.load(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.cache[workspaceID] = cachedPlacement{placement: *p, expires: time.Now().Add(placementCacheTTL)}
+	s.mu.Unlock()
+	return p, nil
+}
+
+func (s *TenantRoutingService) load(ctx context.Context, workspaceID string) (*models.TenantPlacement, error) {
+	rdb := s.redis.Client()
+	if rdb == nil {
+		return nil, errStorageUnavailable
+	}
+
+	data, err := rdb.Get(ctx, tenantPlacementKey(workspaceID)).Bytes()
+	if err == redis.Nil {
+		return defaultPlacement(workspaceID), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var p models.TenantPlacement
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (s *TenantRoutingService) save(ctx context.Context, p *models.TenantPlacement) error {
+	rdb := s.redis.Client()
+	if rdb == nil {
+		return errStorageUnavailable
+	}
+
+	p.UpdatedAt = time.Now()
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	if err := rdb.Set(ctx, tenantPlacementKey(p.WorkspaceID), data, 0).Err(); err != nil {
+		return apperror.Unavailable("Failed to save tenant placement", err)
+	}
+	rdb.SAdd(ctx, "tenant:placements", p.WorkspaceID)
+
+	s.mu.Lock()
+	delete(s.cache, p.WorkspaceID)
+	s.mu.Unlock()
+	return nil
+}
+
+// remove deletes the workspace's placement entry, returning it to shared.
+func (s *TenantRoutingService) remove(ctx context.Context, workspaceID string) (int64, error) {
+	rdb := s.redis.Client()
+	if rdb == nil {
+		return 0, errStorageUnavailable
+	}
+	n, err := rdb.Del(ctx, tenantPlacementKey(workspaceID)).Result()
+	if err != nil {
+		return 0, err
+	}
+	rdb.SRem(ctx, "tenant:placements", workspaceID)
+
+	s.mu.Lock()
+	delete(s.cache, workspaceID)
+	s.mu.Unlock()
+	return n, nil
+}
+
+// List returns every workspace with a non-default placement or migration.
+func (s *TenantRoutingService) List(ctx context.Context) ([]models.TenantPlacement, error) {
+	rdb := s.redis.Client()
+	if rdb == nil {
+		return nil, errStorageUnavailable
+	}
+
+	ids, err := rdb.SMembers(ctx, "tenant:placements").Result()
+	if err != nil {
+		return nil, apperror.Unavailable("Failed to list tenant placements", err)
+	}
+
+	placements := []models.TenantPlacement{}
+	for _, id := range ids {
+		p, err := s.load(ctx, id)
+		if err != nil {
+			continue
+		}
+		placements = append(placements, *p)
+	}
+	return placements, nil
+}
+
+// Get reads the placement from Redis, bypassing the cache.
+func (s *TenantRoutingService) Get(ctx context.Context, workspaceID string) (*models.TenantPlacement, error) {
+	if s.redis.Client() == nil {
+		return nil, errStorageUnavailable
+	}
+	p, err := s.load(ctx, workspaceID)
+	if err != nil {
+		return nil, apperror.Unavailable("Failed to load tenant placement", err)
+	}
+	return p, nil
+}
+
+// ── Routing ──
+
+func routeFor(p *models.TenantPlacement, index string) indexRoute {
+	switch p.Mode {
+	case PlacementDedicated:
+		if isTenantIndex(index) {
+			return indexRoute{Index: dedicatedIndex(index, p.WorkspaceID)}
+		}
+	case PlacementRouted:
+		return indexRoute{Index: index, Routing: p.WorkspaceID}
+	}
+	return indexRoute{Index: index}
+}
+
+// searchRoute resolves a logical index for a read in the caller's scope.
+// Unrestricted callers read the shared index and every dedicated one.
+func (s *TenantRoutingService) searchRoute(ctx context.Context, index string) (indexRoute, error) {
+	scope, err := requireScope(ctx)
+	if err != nil {
+		return indexRoute{}, err
+	}
+	if scope.WorkspaceID == "" {
+		if isTenantIndex(index) {
+			return indexRoute{Index: index + "," + index + "-ws-*"}, nil
+		}
+		return indexRoute{Index: index}, nil
+	}
+	return routeFor(s.Placement(ctx, scope.WorkspaceID), index), nil
+}
+
+// writeRoute resolves where a document of workspaceID is written. Writes
+// are refused while the workspace is being migrated, so no document can be
+// left behind in the old placement, and while the placement cannot be
+// loaded, so none lands in the wrong one.
+func (s *TenantRoutingService) writeRoute(ctx context.Context, index, workspaceID string) (indexRoute, error) {
+	p, err := s.placement(ctx, workspaceID)
+	if err != nil {
+		return indexRoute{}, apperror.Unavailable("Failed to load tenant placement; retry later", err)
+	}
+	if p.Migration != nil && p.Migration.State == migrationRunning {
+		return indexRoute{}, apperror.Unavailable("Workspace is being migrated; retry later", nil)
+	}
+	return routeFor(p, index), nil
+}
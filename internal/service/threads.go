@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+
+	"github.com/quckapp/search-service/internal/models"
+)
+
+// threadReplyHits is how many matches each collapsed thread returns.
+const threadReplyHits = 3
+
+// threadMappings map the thread fields as keywords: collapsing and the
+// thread filter need exact values, which dynamic text mapping does not give.
+var threadMappings = map[string]interface{}{
+	"thread_id": map[string]interface{}{"type": "keyword"},
+	"parent_id": map[string]interface{}{"type": "keyword"},
+}
+
+// threadMessage fills in a message's thread_id when the writer left it out:
+// a reply belongs to its parent's thread, and any other message starts one.
+// Every message then has a thread to collapse on.
+func threadMessage(id string, doc map[string]interface{}) {
+	if getString(doc, "thread_id") != "" {
+		return
+	}
+	if parent := getString(doc, "parent_id"); parent != "" {
+		doc["thread_id"] = parent
+		return
+	}
+	doc["thread_id"] = id
+}
+
+// collapseThreads makes query return one hit per thread, with the thread's
+// first matches as inner hits, and count the threads for paging.
+func collapseThreads(query map[string]interface{}) {
+	query["collapse"] = map[string]interface{}{
+		"field": "thread_id",
+		"inner_hits": map[string]interface{}{
+			"name": "thread",
+			"size": threadReplyHits,
+			"sort": []map[string]interface{}{{"created_at": "asc"}},
+		},
+	}
+	query["aggs"] = map[string]interface{}{
+		"threads": map[string]interface{}{"cardinality": map[string]interface{}{"field": "thread_id"}},
+	}
+}
+
+// attachThreads sets the thread of each message hit in resp, from the raw
+// search result it was parsed from, and looks up the roots of replies.
+func (s *SearchService) attachThreads(ctx context.Context, result map[string]interface{}, resp *models.SearchResponse, collapsed bool) {
+	if collapsed {
+		if aggs, ok := result["aggregations"].(map[string]interface{}); ok {
+			if threads, ok := aggs["threads"].(map[string]interface{}); ok {
+				if val, ok := threads["value"].(float64); ok {
+					resp.Total = int64(val)
+					resp.TotalPages = int((resp.Total + int64(resp.PerPage) - 1) / int64(resp.PerPage))
+				}
+			}
+		}
+	}
+
+	hits, _ := result["hits"].(map[string]interface{})
+	hitList, _ := hits["hits"].([]interface{})
+
+	var rootIDs []string
+	for i := range resp.Results {
+		hit := &resp.Results[i]
+		threadID := getString(hit.Source, "thread_id")
+		if threadID == "" {
+			continue
+		}
+		hit.Thread = &models.ThreadContext{ID: threadID}
+		if threadID != hit.ID {
+			rootIDs = append(rootIDs, threadID)
+		}
+		if collapsed && i < len(hitList) {
+			hitMap, _ := hitList[i].(map[string]interface{})
+			hit.Thread.Matches, hit.Thread.Replies = threadInnerHits(hitMap)
+		}
+	}
+	if len(rootIDs) == 0 {
+		return
+	}
+
+	roots, err := s.executeSearch(ctx, indexMessages, map[string]interface{}{
+		"query": map[string]interface{}{"ids": map[string]interface{}{"values": rootIDs}},
+		"size":  len(rootIDs),
+	})
+	if err != nil {
+		// The hits are still useful without their roots.
+		s.logger.WithError(err).Warn("Failed to load thread roots")
+		return
+	}
+	bySource := map[string]map[string]interface{}{}
+	rootHits, _ := roots["hits"].(map[string]interface{})
+	rootList, _ := rootHits["hits"].([]interface{})
+	for _, h := range rootList {
+		hitMap, _ := h.(map[string]interface{})
+		source, _ := hitMap["_source"].(map[string]interface{})
+		bySource[getString(hitMap, "_id")] = source
+	}
+	for i := range resp.Results {
+		if thread := resp.Results[i].Thread; thread != nil && thread.ID != resp.Results[i].ID {
+			thread.Root = bySource[thread.ID]
+		}
+	}
+}
+
+// threadInnerHits reads the matches a collapsed hit carries for its thread.
+func threadInnerHits(hitMap map[string]interface{}) (int64, []models.SearchHit) {
+	inner, _ := hitMap["inner_hits"].(map[string]interface{})
+	thread, _ := inner["thread"].(map[string]interface{})
+	hits, _ := thread["hits"].(map[string]interface{})
+
+	var total int64
+	if t, ok := hits["total"].(map[string]interface{}); ok {
+		if val, ok := t["value"].(float64); ok {
+			total = int64(val)
+		}
+	}
+	hitList, _ := hits["hits"].([]interface{})
+	replies := make([]models.SearchHit, 0, len(hitList))
+	for _, h := range hitList {
+		m, _ := h.(map[string]interface{})
+		reply := models.SearchHit{ID: getString(m, "_id"), Index: getString(m, "_index")}
+		reply.Score, _ = m["_score"].(float64)
+		reply.Source, _ = m["_source"].(map[string]interface{})
+		replies = append(replies, reply)
+	}
+	return total, replies
+}
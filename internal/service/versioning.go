@@ -0,0 +1,206 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8"
+
+	"github.com/quckapp/search-service/internal/apperror"
+)
+
+// Writes to the typed indices may carry the source system's version of the
+// document: its edit timestamp or a sequence number. Every versioned write,
+// from the API, ingestion or a dead-letter replay, is a scripted update that
+// keeps the version in the document as source_version and skips the write
+// when the stored document is as new or newer. Being part of _source, the
+// version travels with the document when a tenant migration copies it.
+// Through the API a skipped write is reported as a conflict; ingestion
+// just drops the stale event.
+//
+// ES forgets a deleted document's version after index.gc_deletes, after
+// which a late create would bring it back. Deleted messages are therefore
+// replaced by a tombstone that keeps the version for good and is filtered
+// out of every search by scopeQuery.
+
+// versionField is the document field a caller sets the version in. It is
+// taken out of the document before indexing.
+const versionField = "version"
+
+// versionedUpdateScript applies params.doc unless the stored document is
+// at params.version or newer. A full write (params.replace) drops the
+// fields params.doc does not have.
+const versionedUpdateScript = `
+if (ctx._source.source_version != null && ctx._source.source_version >= params.version) {
+  ctx.op = 'noop';
+} else {
+  if (params.replace) { ctx._source.clear(); }
+  ctx._source.putAll(params.doc);
+  ctx._source.source_version = params.version;
+}`
+
+// versionedDeleteScript deletes the document unless it is at
+// params.version or newer.
+const versionedDeleteScript = `
+if (ctx._source.source_version != null && ctx._source.source_version >= params.version) {
+  ctx.op = 'noop';
+} else {
+  ctx.op = 'delete';
+}`
+
+// tombstoneIndices are the indices whose deletes leave a tombstone.
+var tombstoneIndices = map[string]bool{indexMessages: true}
+
+var tombstoneFilter = map[string]interface{}{"term": map[string]interface{}{"deleted": true}}
+
+const errStaleVersion = "A newer version of the document is already indexed"
+
+// DocumentVersion removes the version from doc and returns it, or 0 when
+// doc has none. Timestamps are taken as Unix milliseconds.
+func DocumentVersion(doc map[string]interface{}) (int64, error) {
+	raw, ok := doc[versionField]
+	if !ok {
+		return 0, nil
+	}
+	delete(doc, versionField)
+
+	var version int64
+	switch v := raw.(type) {
+	case float64:
+		version = int64(v)
+	case string:
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return 0, apperror.BadQuery("version must be a number or an RFC 3339 timestamp", err)
+		}
+		version = t.UnixMilli()
+	case nil:
+		return 0, nil
+	default:
+		return 0, apperror.BadQuery("version must be a number or an RFC 3339 timestamp", nil)
+	}
+	if version < 1 {
+		return 0, apperror.BadQuery("version must be positive", nil)
+	}
+	return version, nil
+}
+
+// indexVersioned writes doc at route, unless version is set and the
+// indexed document is as new or newer.
+func indexVersioned(ctx context.Context, es *elasticsearch.Client, route indexRoute, id string, doc map[string]interface{}, version int64) error {
+	if version > 0 {
+		return updateVersioned(ctx, es, route, id, versionedUpdate(doc, version, true), "Failed to index document")
+	}
+	buf, err := encodeBody(doc)
+	if err != nil {
+		return err
+	}
+	res, err := es.Index(route.Index, buf,
+		es.Index.WithDocumentID(id),
+		es.Index.WithRouting(route.Routing),
+		es.Index.WithContext(ctx),
+	)
+	return readResponse(res, err, "Failed to index document", nil)
+}
+
+// updateVersioned sends a versioned update body. The script turns a stale
+// write into a no-op, which is reported as a conflict.
+func updateVersioned(ctx context.Context, es *elasticsearch.Client, route indexRoute, id string, body map[string]interface{}, msg string) error {
+	buf, err := encodeBody(body)
+	if err != nil {
+		return err
+	}
+	res, err := es.Update(route.Index, id, buf,
+		es.Update.WithRouting(route.Routing),
+		es.Update.WithRetryOnConflict(3),
+		es.Update.WithContext(ctx),
+	)
+	var result struct {
+		Result string `json:"result"`
+	}
+	if err := readResponse(res, err, msg, &result); err != nil {
+		return err
+	}
+	if result.Result == "noop" {
+		return apperror.Conflict(errStaleVersion, nil)
+	}
+	return nil
+}
+
+// versionedUpdate is the update body that writes doc at version, creating
+// the document if it is missing. replace makes it a full write.
+func versionedUpdate(doc map[string]interface{}, version int64, replace bool) map[string]interface{} {
+	return map[string]interface{}{
+		"scripted_upsert": true,
+		"upsert":          map[string]interface{}{},
+		"script": map[string]interface{}{
+			"source": versionedUpdateScript,
+			"params": map[string]interface{}{"doc": doc, "version": version, "replace": replace},
+		},
+	}
+}
+
+// versionedDelete is the update body that deletes a document older than
+// version. A missing document is not created.
+func versionedDelete(version int64) map[string]interface{} {
+	return map[string]interface{}{
+		"script": map[string]interface{}{
+			"source": versionedDeleteScript,
+			"params": map[string]interface{}{"version": version},
+		},
+	}
+}
+
+// deleteDocument deletes a document the caller owns, or replaces it with a
+// tombstone in the indices that keep them.
+func deleteDocument(ctx context.Context, es *elasticsearch.Client, tenants *TenantRoutingService, index, id string, version int64) error {
+	if tombstoneIndices[index] {
+		return writeTombstone(ctx, es, tenants, index, id, version)
+	}
+
+	route, err := resolveDocument(ctx, es, tenants, index, id)
+	if err != nil {
+		return err
+	}
+	if version > 0 {
+		return updateVersioned(ctx, es, route, id, versionedDelete(version), "Failed to delete document")
+	}
+	res, err := es.Delete(route.Index, id, es.Delete.WithRouting(route.Routing), es.Delete.WithContext(ctx))
+	return readResponse(res, err, "Failed to delete document", nil)
+}
+
+// writeTombstone replaces a document with a tombstone carrying only its id
+// and workspace. A delete that overtakes the document's create still
+// leaves a tombstone, so the create is rejected when it arrives; the
+// caller must therefore name the workspace, even if unrestricted.
+func writeTombstone(ctx context.Context, es *elasticsearch.Client, tenants *TenantRoutingService, index, id string, version int64) error {
+	workspaceID, err := requireWorkspace(ctx)
+	if err != nil {
+		return err
+	}
+
+	route, owner, err := locateDocument(ctx, es, tenants, index, id)
+	switch {
+	case err == nil:
+		if owner != workspaceID {
+			return apperror.NotFound("Document not found")
+		}
+	case apperror.KindOf(err) == apperror.KindNotFound:
+		if route, err = tenants.writeRoute(ctx, index, workspaceID); err != nil {
+			return err
+		}
+	default:
+		return err
+	}
+
+	return indexVersioned(ctx, es, route, id, tombstone(id, workspaceID), version)
+}
+
+func tombstone(id, workspaceID string) map[string]interface{} {
+	return map[string]interface{}{
+		"id":           id,
+		"workspace_id": workspaceID,
+		"deleted":      true,
+		"deleted_at":   time.Now().UTC(),
+	}
+}
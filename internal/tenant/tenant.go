@@ -0,0 +1,32 @@
+// Package tenant carries the caller's workspace scope from the HTTP layer to
+// the services through the request context.
+package tenant
+
+import "context"
+
+// Scope is the set of workspaces a request may read and write.
+type Scope struct {
+	// WorkspaceID restricts the request to one workspace. It may be set
+	// together with AllWorkspaces when a cross-workspace caller picked one.
+	WorkspaceID string
+	// AllWorkspaces marks a caller allowed to act across tenants.
+	AllWorkspaces bool
+}
+
+// Unrestricted reports whether the scope spans every workspace.
+func (s Scope) Unrestricted() bool {
+	return s.AllWorkspaces && s.WorkspaceID == ""
+}
+
+type contextKey struct{}
+
+func WithScope(ctx context.Context, scope Scope) context.Context {
+	return context.WithValue(ctx, contextKey{}, scope)
+}
+
+// FromContext returns the scope stored on ctx. ok is false for contexts that
+// never passed through the workspace middleware; callers must fail closed.
+func FromContext(ctx context.Context) (Scope, bool) {
+	scope, ok := ctx.Value(contextKey{}).(Scope)
+	return scope, ok
+}